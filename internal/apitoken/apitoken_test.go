@@ -0,0 +1,277 @@
+package apitoken
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"minio-admin-panel/internal/session"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func newTestStore(t *testing.T, dir string) *Store {
+	t.Helper()
+	cipher, err := session.NewCipher("test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewStore(dir, cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// createToken stores a token of owner that expires after lifetime
+func createToken(t *testing.T, store *Store, owner string, lifetime time.Duration, scopes ...string) (*Token, string) {
+	t.Helper()
+	token := &Token{
+		Name:        owner + "-token",
+		Owner:       owner,
+		Scopes:      scopes,
+		Binding:     BindingUser,
+		Credentials: session.Credentials{AccessKey: owner, SecretKey: owner + "-minio-secret"},
+		ExpiresAt:   time.Now().Add(lifetime),
+	}
+	raw, err := store.Create(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token, raw
+}
+
+func TestValidScope(t *testing.T) {
+	tests := []struct {
+		scope string
+		valid bool
+	}{
+		{"buckets:read", true},
+		{"service-accounts:write", true},
+		{"admin:read", true},
+		{"buckets:admin", false},
+		{"buckets", false},
+		{"objects:read", false},
+		{":read", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := ValidScope(tt.scope); got != tt.valid {
+			t.Errorf("ValidScope(%q) = %t, want %t", tt.scope, got, tt.valid)
+		}
+	}
+}
+
+func TestRequiredScope(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		scope  string // "" if the route is not available to tokens
+	}{
+		{http.MethodGet, "/buckets", "buckets:read"},
+		{http.MethodHead, "/buckets/photos", "buckets:read"},
+		{http.MethodPost, "/buckets", "buckets:write"},
+		{http.MethodDelete, "/buckets/photos/lifecycle", "buckets:write"},
+		{http.MethodGet, "/api/bucket-stats", "buckets:read"},
+		{http.MethodPut, "/users/alice/policy", "users:write"},
+		{http.MethodGet, "/api/groups", "groups:read"},
+		{http.MethodGet, "/api/server-info", "server:read"},
+		{http.MethodDelete, "/sessions/abc", "admin:write"},
+		{http.MethodGet, "/c/backup/buckets", "buckets:read"},
+		{http.MethodPost, "/c/backup/users", "users:write"},
+		{http.MethodGet, "/c/backup", ""},
+		{http.MethodGet, "/c/backup/account/tokens", ""},
+		{http.MethodGet, "/bucketsx", ""},
+		{http.MethodPost, "/account/tokens", ""},
+		{http.MethodGet, "/api/tokens", ""},
+		{http.MethodGet, "/", ""},
+	}
+	for _, tt := range tests {
+		scope, ok := RequiredScope(tt.method, tt.path)
+		if ok != (tt.scope != "") || scope != tt.scope {
+			t.Errorf("RequiredScope(%s %s) = %q, %t, want %q", tt.method, tt.path, scope, ok, tt.scope)
+		}
+	}
+}
+
+func TestAllows(t *testing.T) {
+	token := &Token{Scopes: []string{"buckets:write", "users:read"}}
+	tests := []struct {
+		scope string
+		allow bool
+	}{
+		{"buckets:write", true},
+		{"buckets:read", true},
+		{"users:read", true},
+		{"users:write", false},
+		{"admin:read", false},
+	}
+	for _, tt := range tests {
+		if got := token.Allows(tt.scope); got != tt.allow {
+			t.Errorf("Allows(%q) = %t, want %t", tt.scope, got, tt.allow)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	store := newTestStore(t, t.TempDir())
+	token, raw := createToken(t, store, "alice", time.Hour, "buckets:read")
+	_, expired := createToken(t, store, "alice", -time.Minute, "buckets:read")
+	id, _, _ := strings.Cut(strings.TrimPrefix(raw, Prefix), "_")
+
+	tests := []struct {
+		name string
+		raw  string
+		err  error
+	}{
+		{"valid", raw, nil},
+		{"wrong secret", Prefix + id + "_" + strings.Repeat("0", 64), ErrInvalid},
+		{"unknown ID", Prefix + "0123456789abcdef_secret", ErrInvalid},
+		{"no prefix", strings.TrimPrefix(raw, Prefix), ErrInvalid},
+		{"no secret", Prefix + id + "_", ErrInvalid},
+		{"ID not hex", Prefix + "../../../etc/x_secret", ErrInvalid},
+		{"session JWT", "eyJhbGciOiJIUzI1NiJ9.e30.sig", ErrInvalid},
+		{"expired", expired, ErrExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.Authenticate(tt.raw)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if got.ID != token.ID || got.Owner != "alice" || got.Credentials.SecretKey != "alice-minio-secret" {
+				t.Fatalf("authenticated %+v, want the token of alice", got)
+			}
+		})
+	}
+}
+
+func TestStoreIsEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	store := newTestStore(t, dir)
+	_, raw := createToken(t, store, "alice", time.Hour, "buckets:read")
+	_, secret, _ := strings.Cut(strings.TrimPrefix(raw, Prefix), "_")
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("got files %v, want one token file", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, plain := range []string{secret, "alice-minio-secret", "alice"} {
+		if strings.Contains(string(data), plain) {
+			t.Errorf("token file contains %q", plain)
+		}
+	}
+}
+
+func TestListAndDelete(t *testing.T) {
+	store := newTestStore(t, t.TempDir())
+	first, _ := createToken(t, store, "alice", time.Hour, "buckets:read")
+	second, raw := createToken(t, store, "alice", time.Hour, "users:read")
+	other, _ := createToken(t, store, "bob", time.Hour, "buckets:read")
+
+	tokens, err := store.List("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 2 || tokens[0].ID != second.ID || tokens[1].ID != first.ID {
+		t.Fatalf("List(alice) returned %d tokens, want %s then %s", len(tokens), second.ID, first.ID)
+	}
+	if all, err := store.List(""); err != nil || len(all) != 3 {
+		t.Fatalf("List() = %d tokens, %v; want 3", len(all), err)
+	}
+
+	tests := []struct {
+		name  string
+		id    string
+		owner string
+		err   error
+	}{
+		{"someone else's token", other.ID, "alice", ErrNotFound},
+		{"not an ID", "../" + other.ID, "", ErrNotFound},
+		{"own token", second.ID, "alice", nil},
+		{"already deleted", second.ID, "alice", ErrNotFound},
+		{"as administrator", other.ID, "", nil},
+	}
+	for _, tt := range tests {
+		if _, err := store.Delete(tt.id, tt.owner); !errors.Is(err, tt.err) {
+			t.Fatalf("%s: got error %v, want %v", tt.name, err, tt.err)
+		}
+	}
+
+	if _, err := store.Authenticate(raw); !errors.Is(err, ErrInvalid) {
+		t.Errorf("deleted token: got error %v, want %v", err, ErrInvalid)
+	}
+}
+
+func TestRevokeOwner(t *testing.T) {
+	store := newTestStore(t, t.TempDir())
+	keep, _ := createToken(t, store, "alice", time.Hour, "buckets:read")
+	createToken(t, store, "alice", time.Hour, "users:read")
+	createToken(t, store, "alice", time.Hour, "groups:read")
+	createToken(t, store, "bob", time.Hour, "buckets:read")
+
+	revoked, err := store.RevokeOwner("alice", keep.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked != 2 {
+		t.Errorf("revoked %d tokens, want 2", revoked)
+	}
+	if tokens, _ := store.List("alice"); len(tokens) != 1 || tokens[0].ID != keep.ID {
+		t.Errorf("alice kept %d tokens, want only %s", len(tokens), keep.ID)
+	}
+	if tokens, _ := store.List("bob"); len(tokens) != 1 {
+		t.Errorf("bob kept %d tokens, want 1", len(tokens))
+	}
+
+	var disabled *Store
+	if revoked, err := disabled.RevokeOwner("alice", ""); revoked != 0 || err != nil {
+		t.Errorf("nil store: RevokeOwner = %d, %v", revoked, err)
+	}
+}
+
+func TestStoresShareTokenFiles(t *testing.T) {
+	dir := t.TempDir()
+	panel := newTestStore(t, dir)
+	replica := newTestStore(t, dir)
+	token, raw := createToken(t, panel, "alice", time.Hour, "buckets:read")
+
+	// The replica caches the token on first use and records the use
+	used, err := replica.Authenticate(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used.LastUsedAt.IsZero() {
+		t.Error("last use was not recorded")
+	}
+	if tokens, err := panel.List("alice"); err != nil || len(tokens) != 1 || tokens[0].LastUsedAt.IsZero() {
+		t.Errorf("last use was not saved")
+	}
+
+	// A token deleted through one store is rejected by the other
+	if _, err := panel.Delete(token.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := replica.Authenticate(raw); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("token deleted elsewhere: got error %v, want %v", err, ErrInvalid)
+	}
+}
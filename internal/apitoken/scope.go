@@ -0,0 +1,95 @@
+package apitoken
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Access levels of a scope. Write implies read.
+const (
+	Read  = "read"
+	Write = "write"
+)
+
+// Areas are the parts of the panel API a token can be scoped to
+var Areas = []string{"buckets", "users", "groups", "policies", "service-accounts", "server", "admin"}
+
+// areaRoutes maps route prefixes to the area they belong to. Routes that are
+// not listed, such as account and token management, cannot be used with a
+// token at all.
+var areaRoutes = []struct {
+	prefix string
+	area   string
+}{
+	{"/buckets", "buckets"},
+	{"/api/storage-usage", "buckets"},
+	{"/api/bucket-stats", "buckets"},
+	{"/api/tiers", "buckets"},
+	{"/api/notification-targets", "buckets"},
+	{"/users", "users"},
+	{"/groups", "groups"},
+	{"/api/groups", "groups"},
+	{"/policies", "policies"},
+	{"/api/policies", "policies"},
+	{"/service-accounts", "service-accounts"},
+	{"/api/service-accounts", "service-accounts"},
+	{"/api/server-info", "server"},
+	{"/api/metrics", "server"},
+	{"/api/client-cache", "admin"},
+	{"/audit", "admin"},
+	{"/api/audit", "admin"},
+	{"/api/login-limiter", "admin"},
+	{"/sessions", "admin"},
+	{"/api/sessions", "admin"},
+}
+
+// ValidScope reports whether scope has the form "<area>:<read|write>"
+func ValidScope(scope string) bool {
+	area, level, ok := strings.Cut(scope, ":")
+	if !ok || (level != Read && level != Write) {
+		return false
+	}
+	for _, a := range Areas {
+		if a == area {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiredScope returns the scope a request needs. Routes under
+// /c/<cluster> need the same scope as their unprefixed form. ok is false for
+// routes that are not available to API tokens.
+func RequiredScope(method, path string) (scope string, ok bool) {
+	level := Write
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		level = Read
+	}
+
+	if rest, found := strings.CutPrefix(path, "/c/"); found {
+		_, route, found := strings.Cut(rest, "/")
+		if !found {
+			return "", false
+		}
+		path = "/" + route
+	}
+
+	for _, route := range areaRoutes {
+		if path == route.prefix || strings.HasPrefix(path, route.prefix+"/") {
+			return route.area + ":" + level, true
+		}
+	}
+	return "", false
+}
+
+// Allows reports whether the token grants scope
+func (t *Token) Allows(scope string) bool {
+	area, level, _ := strings.Cut(scope, ":")
+	for _, granted := range t.Scopes {
+		if granted == scope || (level == Read && granted == area+":"+Write) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,302 @@
+package apitoken
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"minio-admin-panel/internal/session"
+)
+
+// lastUsedInterval limits how often the last-used time is written to disk
+const lastUsedInterval = time.Minute
+
+var (
+	// ErrNotFound is returned for tokens that do not exist or belong to someone else
+	ErrNotFound = errors.New("API token not found")
+	// ErrInvalid is returned for malformed tokens and wrong secrets
+	ErrInvalid = errors.New("invalid API token")
+	// ErrExpired is returned for tokens past their expiry time
+	ErrExpired = errors.New("API token has expired")
+)
+
+// Store keeps API tokens as encrypted files, one per token. Decrypted tokens
+// are cached and only read again when their file changes, so authenticating
+// a request costs a stat call rather than a read and decryption.
+type Store struct {
+	mu     sync.Mutex // serializes changes to the token files
+	dir    string
+	cipher *session.Cipher
+
+	cacheMu sync.RWMutex
+	cache   map[string]cachedToken
+}
+
+// cachedToken is a decrypted token and the state of the file it came from
+type cachedToken struct {
+	token   *Token
+	modTime time.Time
+	size    int64
+}
+
+// NewStore creates a token store rooted at dir
+func NewStore(dir string, cipher *session.Cipher) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create API token directory: %v", err)
+	}
+	return &Store{dir: dir, cipher: cipher, cache: make(map[string]cachedToken)}, nil
+}
+
+// Create stores a new token and returns the value to hand to the user. The
+// value cannot be recovered later.
+func (s *Store) Create(t *Token) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, secret, raw, err := generate()
+	if err != nil {
+		return "", err
+	}
+	t.ID = id
+	t.Hash = hashSecret(secret)
+	t.CreatedAt = time.Now().UTC()
+
+	if err := s.save(t); err != nil {
+		return "", err
+	}
+
+	log.Printf("[DEBUG] Created API token '%s' (%s) for user '%s' with scopes %v", t.Name, t.ID, t.Owner, t.Scopes)
+	return raw, nil
+}
+
+// Authenticate returns the token matching a bearer value
+func (s *Store) Authenticate(raw string) (*Token, error) {
+	id, secret, ok := parse(raw)
+	if !ok {
+		return nil, ErrInvalid
+	}
+
+	t, err := s.cached(id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrInvalid
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hashSecret(secret))) != 1 {
+		return nil, ErrInvalid
+	}
+	if t.Expired() {
+		return nil, ErrExpired
+	}
+
+	if now := time.Now().UTC(); now.Sub(t.LastUsedAt) > lastUsedInterval {
+		s.touch(id, now)
+		t.LastUsedAt = now
+	}
+	return t, nil
+}
+
+// cached returns a copy of a token, reading its file again only if it
+// changed since it was cached
+func (s *Store) cached(id string) (*Token, error) {
+	info, err := os.Stat(s.path(id))
+	if err != nil {
+		s.forget(id)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	s.cacheMu.RLock()
+	entry, ok := s.cache[id]
+	s.cacheMu.RUnlock()
+	if ok && entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+		copied := *entry.token
+		return &copied, nil
+	}
+
+	t, err := s.load(id)
+	if err != nil {
+		return nil, err
+	}
+	s.remember(t, info)
+	copied := *t
+	return &copied, nil
+}
+
+// touch records that a token was used. It is skipped if the token was
+// deleted meanwhile, so that a use cannot bring a revoked token back.
+func (s *Store) touch(id string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.load(id)
+	if err != nil {
+		return
+	}
+	t.LastUsedAt = now
+	if err := s.save(t); err != nil {
+		log.Printf("[DEBUG] Failed to record use of API token %s: %v", t.ID, err)
+	}
+}
+
+// RevokeOwner deletes the tokens of owner except keep, e.g. when the user
+// is disabled or signed out everywhere. It returns the number of tokens
+// deleted and does nothing on a nil store, as used when tokens are disabled.
+func (s *Store) RevokeOwner(owner, keep string) (int, error) {
+	if s == nil || owner == "" {
+		return 0, nil
+	}
+
+	tokens, err := s.List(owner)
+	if err != nil {
+		return 0, err
+	}
+	revoked := 0
+	for _, t := range tokens {
+		if t.ID == keep {
+			continue
+		}
+		if _, err := s.Delete(t.ID, owner); err != nil && !errors.Is(err, ErrNotFound) {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// List returns the tokens of owner, newest first. An empty owner lists the
+// tokens of all users.
+func (s *Store) List(owner string) ([]*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := []*Token{}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".enc")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		t, err := s.load(id)
+		if err != nil {
+			log.Printf("[DEBUG] Skipping unreadable API token %s: %v", id, err)
+			continue
+		}
+		if owner == "" || t.Owner == owner {
+			tokens = append(tokens, t)
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt.After(tokens[j].CreatedAt) })
+	return tokens, nil
+}
+
+// Delete revokes a token. A non-empty owner must match the token's owner.
+func (s *Store) Delete(id, owner string) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !isHex(id) {
+		return nil, ErrNotFound
+	}
+	t, err := s.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if owner != "" && t.Owner != owner {
+		return nil, ErrNotFound
+	}
+
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	s.forget(id)
+	log.Printf("[DEBUG] Deleted API token '%s' (%s) of user '%s'", t.Name, t.ID, t.Owner)
+	return t, nil
+}
+
+func (s *Store) load(id string) (*Token, error) {
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	plaintext, err := s.cipher.Decrypt(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt API token: %v", err)
+	}
+
+	var t Token
+	if err := json.Unmarshal(plaintext, &t); err != nil {
+		return nil, fmt.Errorf("corrupt API token: %v", err)
+	}
+	return &t, nil
+}
+
+// save writes the token to disk atomically
+func (s *Store) save(t *Token) error {
+	plaintext, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	data, err := s.cipher.Encrypt(plaintext)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt API token: %v", err)
+	}
+
+	path := s.path(t.ID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil {
+		s.remember(t, info)
+	} else {
+		s.forget(t.ID)
+	}
+	return nil
+}
+
+// remember caches a copy of a token read from or written to the file
+// described by info
+func (s *Store) remember(t *Token, info os.FileInfo) {
+	copied := *t
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	s.cache[t.ID] = cachedToken{token: &copied, modTime: info.ModTime(), size: info.Size()}
+}
+
+// forget drops a token from the cache
+func (s *Store) forget(id string) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	delete(s.cache, id)
+}
+
+// path maps a token ID to its file. IDs are hex, so they cannot escape the
+// store directory.
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".enc")
+}
@@ -0,0 +1,262 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result values of a record
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Record is a single audit entry. Records form a hash chain: Hash covers the
+// record content and the previous record's hash, so removing or editing an
+// entry breaks every later hash.
+type Record struct {
+	Seq      int64                  `json:"seq"`
+	Time     time.Time              `json:"time"`
+	Actor    string                 `json:"actor"`
+	ClientIP string                 `json:"client_ip"`
+	Action   string                 `json:"action"`
+	Target   string                 `json:"target,omitempty"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+	Result   string                 `json:"result"`
+	Status   int                    `json:"status,omitempty"`
+	PrevHash string                 `json:"prev_hash"`
+	Hash     string                 `json:"hash"`
+}
+
+// computeHash returns the chain hash of a record
+func (r Record) computeHash() string {
+	r.Hash = ""
+	data, _ := json.Marshal(r)
+	sum := sha256.Sum256(append([]byte(r.PrevHash), data...))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeDetails converts details to the JSON types they are read back as.
+// Structs and typed values would otherwise be hashed with another encoding
+// than the one Verify sees when it reads them from the file.
+func normalizeDetails(details map[string]interface{}) (map[string]interface{}, error) {
+	if details == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(details)
+	if err != nil {
+		return nil, err
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// Options configures the audit logger
+type Options struct {
+	Path       string // current log file; rotated files are kept next to it
+	MaxSize    int64  // rotate when the file grows beyond this many bytes
+	MaxFiles   int    // number of rotated files to keep
+	WebhookURL string // optional endpoint receiving every record as JSON
+}
+
+// Logger appends hash-chained records to a rotating JSON lines file
+type Logger struct {
+	opts    Options
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	seq     int64
+	last    string
+	webhook *webhook
+}
+
+// NewLogger opens the audit log and continues the hash chain from its last record
+func NewLogger(opts Options) (*Logger, error) {
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %v", err)
+	}
+
+	l := &Logger{opts: opts}
+	if err := l.restoreChain(); err != nil {
+		return nil, err
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	if opts.WebhookURL != "" {
+		l.webhook = newWebhook(opts.WebhookURL)
+	}
+
+	log.Printf("[DEBUG] Audit log opened at '%s' (last sequence %d)", opts.Path, l.seq)
+	return l, nil
+}
+
+// Log appends a record, filling in sequence number, time and hashes
+func (l *Logger) Log(rec Record) {
+	if l == nil {
+		return
+	}
+
+	details, err := normalizeDetails(rec.Details)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to encode audit record details: %v", err)
+		return
+	}
+	rec.Details = details
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.seq++
+	rec.Seq = l.seq
+	if rec.Time.IsZero() {
+		rec.Time = time.Now().UTC()
+	}
+	rec.PrevHash = l.last
+	rec.Hash = rec.computeHash()
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to encode audit record: %v", err)
+		return
+	}
+	line = append(line, '\n')
+
+	if l.opts.MaxSize > 0 && l.size+int64(len(line)) > l.opts.MaxSize {
+		if err := l.rotate(); err != nil {
+			log.Printf("[DEBUG] Failed to rotate audit log: %v", err)
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to write audit record: %v", err)
+		return
+	}
+	l.last = rec.Hash
+
+	if l.webhook != nil {
+		l.webhook.send(rec)
+	}
+}
+
+// Close flushes and closes the log file
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+func (l *Logger) open() error {
+	file, err := os.OpenFile(l.opts.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// rotate renames the current file with a timestamp suffix and prunes old files
+func (l *Logger) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+
+	rotated := fmt.Sprintf("%s.%s", l.opts.Path, time.Now().UTC().Format("20060102T150405.000000000"))
+	if err := os.Rename(l.opts.Path, rotated); err != nil {
+		return err
+	}
+	log.Printf("[DEBUG] Rotated audit log to '%s'", rotated)
+
+	files := l.rotatedFiles()
+	if l.opts.MaxFiles > 0 && len(files) > l.opts.MaxFiles {
+		for _, old := range files[:len(files)-l.opts.MaxFiles] {
+			if err := os.Remove(old); err != nil {
+				log.Printf("[DEBUG] Failed to remove old audit log '%s': %v", old, err)
+			}
+		}
+	}
+
+	return l.open()
+}
+
+// rotatedFiles lists rotated log files, oldest first
+func (l *Logger) rotatedFiles() []string {
+	matches, _ := filepath.Glob(l.opts.Path + ".*")
+	sort.Strings(matches)
+	return matches
+}
+
+// files lists all log files, oldest first
+func (l *Logger) files() []string {
+	return append(l.rotatedFiles(), l.opts.Path)
+}
+
+// restoreChain loads the sequence number and hash of the newest record
+func (l *Logger) restoreChain() error {
+	files := l.files()
+	for i := len(files) - 1; i >= 0; i-- {
+		var last *Record
+		err := readRecords(files[i], func(rec Record) bool {
+			r := rec
+			last = &r
+			return true
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read audit log: %v", err)
+		}
+		if last != nil {
+			l.seq = last.Seq
+			l.last = last.Hash
+			return nil
+		}
+	}
+	return nil
+}
+
+// readRecords calls fn for every record in a file until fn returns false
+func readRecords(path string, fn func(Record) bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return fmt.Errorf("corrupt audit record in '%s': %v", path, err)
+		}
+		if !fn(rec) {
+			break
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,169 @@
+package audit
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// ruleDetail stands in for the rule structs handlers pass as details
+type ruleDetail struct {
+	ID      string   `json:"id"`
+	Status  string   `json:"status"`
+	Events  []string `json:"events"`
+	Days    int      `json:"days,omitempty"`
+	Enabled bool     `json:"enabled"`
+}
+
+func newTestLogger(t *testing.T, opts Options) *Logger {
+	t.Helper()
+	l, err := NewLogger(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func TestVerifyDetails(t *testing.T) {
+	tests := []struct {
+		name    string
+		details map[string]interface{}
+	}{
+		{"no details", nil},
+		{"strings", map[string]interface{}{"policy": "readwrite"}},
+		{"numbers", map[string]interface{}{"count": 3, "size": int64(1) << 40, "ratio": 0.5}},
+		{"struct", map[string]interface{}{"before": ruleDetail{ID: "expire", Status: "Enabled", Days: 30}}},
+		{"struct pointer", map[string]interface{}{"before": &ruleDetail{ID: "b", Events: []string{"s3:ObjectCreated:*"}}}},
+		{"list of structs", map[string]interface{}{"before": []ruleDetail{{ID: "z"}, {ID: "a", Enabled: true}}}},
+		{"typed map", map[string]interface{}{"tags": map[string]string{"z": "1", "a": "2"}}},
+		{"time", map[string]interface{}{"until": time.Date(2026, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*3600))}},
+		{"markup", map[string]interface{}{"prefix": "<a href=\"x\">&"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.log")
+			l := newTestLogger(t, Options{Path: path})
+			l.Log(Record{Actor: "admin", Action: "bucket.update", Target: "photos", Details: tt.details, Result: ResultSuccess})
+			l.Log(Record{Actor: "admin", Action: "bucket.delete", Target: "photos", Result: ResultSuccess})
+
+			result, err := l.Verify()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.Valid || result.Records != 2 {
+				t.Fatalf("Verify() = %+v, want 2 valid records", result)
+			}
+		})
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name     string
+		tamper   func(lines []string) []string
+		brokenAt int64
+		reason   string
+	}{
+		{
+			name: "edited record",
+			tamper: func(lines []string) []string {
+				lines[1] = strings.Replace(lines[1], `"actor":"bob"`, `"actor":"eve"`, 1)
+				return lines
+			},
+			brokenAt: 2,
+			reason:   "record hash mismatch",
+		},
+		{
+			name: "edited details",
+			tamper: func(lines []string) []string {
+				lines[0] = strings.Replace(lines[0], `"days":30`, `"days":1`, 1)
+				return lines
+			},
+			brokenAt: 1,
+			reason:   "record hash mismatch",
+		},
+		{
+			name:     "removed record",
+			tamper:   func(lines []string) []string { return append(lines[:1], lines[2:]...) },
+			brokenAt: 3,
+			reason:   "previous hash mismatch",
+		},
+		{
+			name:     "swapped records",
+			tamper:   func(lines []string) []string { lines[1], lines[2] = lines[2], lines[1]; return lines },
+			brokenAt: 3,
+			reason:   "previous hash mismatch",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.log")
+			l := newTestLogger(t, Options{Path: path})
+			l.Log(Record{Actor: "alice", Action: "lifecycle.update", Details: map[string]interface{}{"before": ruleDetail{ID: "expire", Days: 30}}})
+			l.Log(Record{Actor: "bob", Action: "user.delete"})
+			l.Log(Record{Actor: "carol", Action: "bucket.create"})
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := tt.tamper(strings.Split(strings.TrimSpace(string(data)), "\n"))
+			if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := l.Verify()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Valid || result.BrokenAt != tt.brokenAt || result.Reason != tt.reason {
+				t.Fatalf("Verify() = %+v, want broken at %d with %q", result, tt.brokenAt, tt.reason)
+			}
+		})
+	}
+}
+
+func TestChainContinuesAcrossRestartAndRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	opts := Options{Path: path, MaxSize: 600, MaxFiles: 10}
+
+	l := newTestLogger(t, opts)
+	for i := 0; i < 5; i++ {
+		l.Log(Record{Actor: "admin", Action: "user.create", Details: map[string]interface{}{"before": ruleDetail{ID: "x"}}})
+	}
+	l.Close()
+
+	l = newTestLogger(t, opts)
+	for i := 0; i < 5; i++ {
+		l.Log(Record{Actor: "admin", Action: "user.delete"})
+	}
+
+	if files := l.rotatedFiles(); len(files) == 0 {
+		t.Fatal("log was not rotated")
+	}
+	result, err := l.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.Records != 10 {
+		t.Fatalf("Verify() = %+v, want 10 valid records", result)
+	}
+
+	records, err := l.Query(Filter{Action: "create", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Seq != 5 || records[1].Seq != 4 {
+		t.Fatalf("Query returned %+v, want records 5 and 4", records)
+	}
+}
@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// Filter selects audit records. Zero values match everything.
+type Filter struct {
+	Actor  string
+	Target string
+	Action string
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
+
+// matches reports whether a record passes the filter. Actor, target and action
+// match case-insensitively on substrings.
+func (f Filter) matches(rec Record) bool {
+	if f.Actor != "" && !containsFold(rec.Actor, f.Actor) {
+		return false
+	}
+	if f.Target != "" && !containsFold(rec.Target, f.Target) {
+		return false
+	}
+	if f.Action != "" && !containsFold(rec.Action, f.Action) {
+		return false
+	}
+	if !f.From.IsZero() && rec.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && rec.Time.After(f.To) {
+		return false
+	}
+	return true
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// Query returns matching records, newest first
+func (l *Logger) Query(filter Filter) ([]Record, error) {
+	l.mu.Lock()
+	files := l.files()
+	l.mu.Unlock()
+
+	var records []Record
+	for _, file := range files {
+		err := readRecords(file, func(rec Record) bool {
+			if filter.matches(rec) {
+				records = append(records, rec)
+			}
+			return true
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	// Newest first
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		records = records[:filter.Limit]
+	}
+	return records, nil
+}
+
+// VerifyResult describes the outcome of a hash chain check
+type VerifyResult struct {
+	Valid    bool   `json:"valid"`
+	Records  int64  `json:"records"`
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Verify walks all retained records and checks the hash chain. The first
+// retained record anchors the chain, since older files may have been pruned.
+func (l *Logger) Verify() (VerifyResult, error) {
+	l.mu.Lock()
+	files := l.files()
+	l.mu.Unlock()
+
+	result := VerifyResult{Valid: true}
+	var prev *Record
+	for _, file := range files {
+		err := readRecords(file, func(rec Record) bool {
+			result.Records++
+			switch {
+			case rec.computeHash() != rec.Hash:
+				result.Reason = "record hash mismatch"
+			case prev != nil && rec.PrevHash != prev.Hash:
+				result.Reason = "previous hash mismatch"
+			case prev != nil && rec.Seq != prev.Seq+1:
+				result.Reason = "sequence gap"
+			default:
+				r := rec
+				prev = &r
+				return true
+			}
+			result.Valid = false
+			result.BrokenAt = rec.Seq
+			return false
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return result, err
+		}
+		if !result.Valid {
+			break
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// webhook delivers records to an HTTP endpoint in the background. Records are
+// dropped rather than blocking requests when the endpoint cannot keep up; the
+// file log remains the source of truth.
+type webhook struct {
+	url    string
+	client *http.Client
+	queue  chan Record
+}
+
+func newWebhook(url string) *webhook {
+	w := &webhook{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+		queue:  make(chan Record, 256),
+	}
+	go w.run()
+	return w
+}
+
+func (w *webhook) send(rec Record) {
+	select {
+	case w.queue <- rec:
+	default:
+		log.Printf("[DEBUG] Audit webhook queue full, dropping record %d", rec.Seq)
+	}
+}
+
+func (w *webhook) run() {
+	for rec := range w.queue {
+		body, err := json.Marshal(rec)
+		if err != nil {
+			continue
+		}
+
+		resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Printf("[DEBUG] Audit webhook delivery of record %d failed: %v", rec.Seq, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Printf("[DEBUG] Audit webhook returned status %d for record %d", resp.StatusCode, rec.Seq)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package bucketstats
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"minio-admin-panel/internal/services"
+	"minio-admin-panel/internal/session"
+)
+
+// forcedRefreshInterval is how long after a refresh of a cluster started
+// another one measures only the stale buckets even if forced, so that
+// repeated refresh requests cannot keep listing every bucket
+const forcedRefreshInterval = time.Minute
+
+// Options controls how bucket statistics are refreshed
+type Options struct {
+	Concurrency int           // buckets measured at the same time
+	Interval    time.Duration // age after which cached statistics are stale
+	Timeout     time.Duration // deadline for measuring one bucket
+}
+
+// Stats are the cached statistics of one bucket. Source is
+// services.StatsSourceScanner when the totals come from MinIO's data usage
+// scanner, recorded at ScannedAt, or services.StatsSourceListing when the
+// scanner had no data for the bucket and its objects were counted. Prefixes
+// always come from listing the bucket. Error is the reason the last listing
+// failed.
+type Stats struct {
+	Bucket string `json:"bucket"`
+	services.BucketStats
+	Source    string     `json:"source"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ScannedAt *time.Time `json:"scanned_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// Status describes the refresh state of a cluster's statistics
+type Status struct {
+	Refreshing bool       `json:"refreshing"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Pending    int        `json:"pending"`
+}
+
+type clusterCache struct {
+	stats      map[string]*Stats
+	refreshing bool
+	startedAt  time.Time
+	finishedAt time.Time
+	pending    int
+}
+
+// Worker measures buckets in the background and caches the results per
+// cluster, so that pages can show statistics without waiting for them
+type Worker struct {
+	backend  services.BucketBackend
+	opts     Options
+	mu       sync.Mutex
+	clusters map[string]*clusterCache
+}
+
+// New creates a worker measuring buckets through backend
+func New(backend services.BucketBackend, opts Options) *Worker {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	return &Worker{backend: backend, opts: opts, clusters: make(map[string]*clusterCache)}
+}
+
+// Interval returns the age after which cached statistics are stale
+func (w *Worker) Interval() time.Duration {
+	return w.opts.Interval
+}
+
+// cluster returns the cache of a cluster, creating it. Callers hold w.mu.
+func (w *Worker) cluster(name string) *clusterCache {
+	cache, ok := w.clusters[name]
+	if !ok {
+		cache = &clusterCache{stats: make(map[string]*Stats)}
+		w.clusters[name] = cache
+	}
+	return cache
+}
+
+// Get returns the cached statistics of a bucket
+func (w *Worker) Get(cluster, bucket string) (Stats, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	stats, ok := w.cluster(cluster).stats[bucket]
+	if !ok {
+		return Stats{}, false
+	}
+	return *stats, true
+}
+
+// Status returns the refresh state of a cluster
+func (w *Worker) Status(cluster string) Status {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	cache := w.cluster(cluster)
+	status := Status{Refreshing: cache.refreshing, Pending: cache.pending}
+	if !cache.startedAt.IsZero() {
+		startedAt := cache.startedAt
+		status.StartedAt = &startedAt
+	}
+	if !cache.finishedAt.IsZero() {
+		finishedAt := cache.finishedAt
+		status.FinishedAt = &finishedAt
+	}
+	return status
+}
+
+// Stale reports whether any of the buckets has no cached statistics or
+// statistics older than the refresh interval
+func (w *Worker) Stale(cluster string, buckets []string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	cache := w.cluster(cluster)
+	for _, bucket := range buckets {
+		if w.stale(cache.stats[bucket]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Worker) stale(stats *Stats) bool {
+	return stats == nil || time.Since(stats.UpdatedAt) >= w.opts.Interval
+}
+
+// Forget drops the cached statistics of a bucket, e.g. after it was deleted
+func (w *Worker) Forget(cluster, bucket string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.cluster(cluster).stats, bucket)
+	log.Printf("[DEBUG] Forgot cached statistics of bucket '%s' on cluster '%s'", bucket, cluster)
+}
+
+// Refresh measures the buckets visible to creds in the background: the stale
+// ones, or all of them when force is set and no refresh of the cluster
+// started within the last minute. It returns false without doing anything if
+// a refresh of the cluster is already running.
+func (w *Worker) Refresh(creds session.Credentials, force bool) bool {
+	w.mu.Lock()
+	cache := w.cluster(creds.Cluster)
+	if cache.refreshing {
+		w.mu.Unlock()
+		log.Printf("[DEBUG] Statistics of cluster '%s' are already being refreshed", creds.Cluster)
+		return false
+	}
+	if force && !cache.startedAt.IsZero() && time.Since(cache.startedAt) < forcedRefreshInterval {
+		log.Printf("[DEBUG] Statistics of cluster '%s' were refreshed %s ago, refreshing only stale buckets", creds.Cluster, time.Since(cache.startedAt).Round(time.Second))
+		force = false
+	}
+	cache.refreshing = true
+	cache.startedAt = time.Now()
+	cache.pending = 0
+	w.mu.Unlock()
+
+	go w.refresh(creds, force)
+	return true
+}
+
+func (w *Worker) refresh(creds session.Credentials, force bool) {
+	defer func() {
+		w.mu.Lock()
+		cache := w.cluster(creds.Cluster)
+		cache.refreshing = false
+		cache.finishedAt = time.Now()
+		cache.pending = 0
+		w.mu.Unlock()
+	}()
+
+	ctx := services.WithSessionToken(services.WithCluster(context.Background(), creds.Cluster), creds.SessionToken)
+	log.Printf("[DEBUG] Refreshing bucket statistics of cluster '%s' as '%s' (force: %t)", creds.Cluster, creds.AccessKey, force)
+
+	listCtx, cancel := context.WithTimeout(ctx, w.opts.Timeout)
+	buckets, err := w.backend.ListBucketsQuick(listCtx, creds.AccessKey, creds.SecretKey)
+	cancel()
+	if err != nil {
+		log.Printf("[DEBUG] Listing buckets for statistics of cluster '%s' failed: %v", creds.Cluster, services.Classify(err))
+		return
+	}
+
+	var names []string
+	w.mu.Lock()
+	cache := w.cluster(creds.Cluster)
+	for _, bucket := range buckets {
+		if force || w.stale(cache.stats[bucket.Name]) {
+			names = append(names, bucket.Name)
+		}
+	}
+	cache.pending = len(names)
+	w.mu.Unlock()
+	if len(names) == 0 {
+		log.Printf("[DEBUG] Statistics of all %d buckets of cluster '%s' are fresh", len(buckets), creds.Cluster)
+		return
+	}
+
+	// Take the totals from MinIO's scanner where it has them, so they show up
+	// at once and stay right for buckets too large to list in time
+	usageCtx, cancel := context.WithTimeout(ctx, w.opts.Timeout)
+	usage, err := w.backend.GetDataUsage(usageCtx, creds.AccessKey, creds.SecretKey)
+	cancel()
+	if err != nil {
+		log.Printf("[DEBUG] Scanner data of cluster '%s' unavailable, listing all buckets: %v", creds.Cluster, services.Classify(err))
+	}
+	w.applyUsage(creds.Cluster, names, usage)
+
+	sem := make(chan struct{}, w.opts.Concurrency)
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(name string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			w.measure(ctx, creds, name, usage)
+		}(name)
+	}
+	wg.Wait()
+	log.Printf("[DEBUG] Refreshed statistics of %d buckets of cluster '%s'", len(names), creds.Cluster)
+}
+
+// applyUsage caches the scanner's totals of the named buckets it covers,
+// keeping the prefix breakdown of their last listing
+func (w *Worker) applyUsage(cluster string, names []string, usage *services.DataUsage) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	cache := w.cluster(cluster)
+	scanned := 0
+	for _, name := range names {
+		bucketUsage, ok := usage.Bucket(name)
+		if !ok {
+			continue
+		}
+		stats := &Stats{
+			Bucket:      name,
+			BucketStats: services.BucketStats{Size: bucketUsage.Size, ObjectCount: bucketUsage.ObjectCount, VersionCount: bucketUsage.VersionCount},
+			Source:      services.StatsSourceScanner,
+			UpdatedAt:   time.Now(),
+			ScannedAt:   &usage.UpdatedAt,
+		}
+		if previous := cache.stats[name]; previous != nil {
+			stats.Prefixes = previous.Prefixes
+		}
+		cache.stats[name] = stats
+		scanned++
+	}
+	log.Printf("[DEBUG] Scanner data covers %d of %d buckets of cluster '%s'", scanned, len(names), cluster)
+}
+
+// measure lists the objects of one bucket for its prefix breakdown, and for
+// its totals if the scanner has no data for it
+func (w *Worker) measure(ctx context.Context, creds session.Credentials, bucket string, usage *services.DataUsage) {
+	ctx, cancel := context.WithTimeout(ctx, w.opts.Timeout)
+	defer cancel()
+
+	measured, err := w.backend.MeasureBucket(ctx, creds.AccessKey, creds.SecretKey, bucket)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	cache := w.cluster(creds.Cluster)
+	cache.pending--
+	_, scanned := usage.Bucket(bucket)
+	if err == nil {
+		if current := cache.stats[bucket]; scanned && current != nil {
+			current.Prefixes = measured.Prefixes
+			current.Error = ""
+			return
+		}
+		cache.stats[bucket] = &Stats{Bucket: bucket, BucketStats: *measured, Source: services.StatsSourceListing, UpdatedAt: time.Now()}
+		return
+	}
+
+	err = services.Classify(err)
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		delete(cache.stats, bucket)
+		return
+	case errors.Is(err, services.ErrAccessDenied) && cache.stats[bucket] != nil:
+		// Another user may list the bucket; keep what they measured
+		log.Printf("[DEBUG] User '%s' may not measure bucket '%s', keeping cached statistics", creds.AccessKey, bucket)
+		return
+	}
+
+	log.Printf("[DEBUG] Measuring bucket '%s' on cluster '%s' failed: %v", bucket, creds.Cluster, err)
+	if current := cache.stats[bucket]; scanned && current != nil {
+		// The scanner's totals stand; only the prefix breakdown may be dated
+		current.Error = err.Error()
+		return
+	}
+	stats := &Stats{Bucket: bucket, UpdatedAt: time.Now(), Error: err.Error()}
+	if previous := cache.stats[bucket]; previous != nil {
+		stats.BucketStats = previous.BucketStats
+		stats.Source = previous.Source
+	} else {
+		stats.BucketStats = services.BucketStats{Size: -1, ObjectCount: -1, VersionCount: -1}
+	}
+	cache.stats[bucket] = stats
+}
+
+// Run refreshes the statistics of the named clusters every interval with
+// the given credentials until ctx is done
+func (w *Worker) Run(ctx context.Context, clusters []string, accessKey, secretKey string) {
+	log.Printf("[DEBUG] Refreshing bucket statistics of %d clusters every %s", len(clusters), w.opts.Interval)
+
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+	for {
+		for _, name := range clusters {
+			w.Refresh(session.Credentials{Cluster: name, AccessKey: accessKey, SecretKey: secretKey}, true)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+
+	"minio-admin-panel/internal/config"
+	"minio-admin-panel/internal/services"
+)
+
+// DefaultName names the only cluster when no cluster file is configured
+const DefaultName = "default"
+
+// validName restricts cluster names to what can appear in a URL path segment
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Cluster is a MinIO deployment managed by the panel
+type Cluster struct {
+	Name    string           `json:"name"`
+	Label   string           `json:"label,omitempty"`
+	Host    string           `json:"host"`
+	Port    int              `json:"port"`
+	UseSSL  bool             `json:"use_ssl"`
+	Backend services.Backend `json:"-"`
+}
+
+// DisplayName returns the label of the cluster, or its name if it has none
+func (c *Cluster) DisplayName() string {
+	if c.Label != "" {
+		return c.Label
+	}
+	return c.Name
+}
+
+// Endpoint returns the URL of the cluster's S3 API
+func (c *Cluster) Endpoint() string {
+	scheme := "http"
+	if c.UseSSL {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, c.Host, c.Port)
+}
+
+// File is the cluster configuration file
+type File struct {
+	// Default is the cluster preselected on the login page; the first one if empty
+	Default  string     `json:"default"`
+	Clusters []*Cluster `json:"clusters"`
+}
+
+// Factory builds the backend of a cluster from the configuration with the
+// cluster's endpoint filled in
+type Factory func(cfg *config.Config) services.Backend
+
+// Registry holds the configured clusters in file order
+type Registry struct {
+	clusters    []*Cluster
+	byName      map[string]*Cluster
+	defaultName string
+}
+
+// Load reads the cluster file and builds a backend for every cluster. All
+// other MinIO settings, such as timeouts and the client cache, come from base.
+func Load(path string, base *config.Config, factory Factory) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster configuration: %v", err)
+	}
+
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid cluster configuration: %v", err)
+	}
+	if len(file.Clusters) == 0 {
+		return nil, fmt.Errorf("cluster configuration lists no clusters")
+	}
+
+	r := &Registry{byName: make(map[string]*Cluster), defaultName: file.Default}
+	for i, c := range file.Clusters {
+		if !validName.MatchString(c.Name) {
+			return nil, fmt.Errorf("cluster %d: invalid name %q (use lowercase letters, digits and dashes)", i, c.Name)
+		}
+		if r.byName[c.Name] != nil {
+			return nil, fmt.Errorf("cluster %d: duplicate name %q", i, c.Name)
+		}
+		if c.Host == "" || c.Port <= 0 {
+			return nil, fmt.Errorf("cluster %q: host and port are required", c.Name)
+		}
+		r.add(c, base, factory)
+	}
+
+	if r.defaultName == "" {
+		r.defaultName = r.clusters[0].Name
+	} else if r.byName[r.defaultName] == nil {
+		return nil, fmt.Errorf("unknown default cluster %q", r.defaultName)
+	}
+
+	log.Printf("[DEBUG] Loaded %d clusters from '%s' (default: %s)", len(r.clusters), path, r.defaultName)
+	return r, nil
+}
+
+// Single returns a registry with the one cluster configured by MINIO_HOST,
+// MINIO_PORT and MINIO_USE_SSL
+func Single(base *config.Config, factory Factory) *Registry {
+	r := &Registry{byName: make(map[string]*Cluster), defaultName: DefaultName}
+	r.add(&Cluster{
+		Name:   DefaultName,
+		Host:   base.MinIOHost,
+		Port:   base.MinIOPort,
+		UseSSL: base.MinIOUseSSL,
+	}, base, factory)
+	return r
+}
+
+// add builds the backend of a cluster and registers it
+func (r *Registry) add(c *Cluster, base *config.Config, factory Factory) {
+	cfg := *base
+	cfg.MinIOHost = c.Host
+	cfg.MinIOPort = c.Port
+	cfg.MinIOUseSSL = c.UseSSL
+	c.Backend = factory(&cfg)
+
+	r.clusters = append(r.clusters, c)
+	r.byName[c.Name] = c
+}
+
+// Get returns the named cluster, the default cluster for an empty name, or
+// nil if there is no such cluster
+func (r *Registry) Get(name string) *Cluster {
+	if name == "" {
+		name = r.defaultName
+	}
+	return r.byName[name]
+}
+
+// Default returns the cluster used when none is named
+func (r *Registry) Default() *Cluster {
+	return r.byName[r.defaultName]
+}
+
+// All returns the clusters in configuration order
+func (r *Registry) All() []*Cluster {
+	return r.clusters
+}
+
+// Multiple reports whether more than one cluster is configured
+func (r *Registry) Multiple() bool {
+	return len(r.clusters) > 1
+}
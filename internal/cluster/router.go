@@ -0,0 +1,539 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"minio-admin-panel/internal/permissions"
+	"minio-admin-panel/internal/services"
+)
+
+// Backend returns a backend that passes every call on to the cluster named in
+// its context (see services.WithCluster), or to the default cluster
+func (r *Registry) Backend() services.Backend {
+	return router{registry: r}
+}
+
+// router implements services.Backend on top of the backends of all clusters
+type router struct {
+	registry *Registry
+}
+
+var _ services.Backend = router{}
+
+// backend returns the backend of the cluster named in ctx, or of the default
+// cluster if none is named. Calls naming a cluster that is not configured
+// fail with services.ErrNotFound rather than reaching another deployment.
+func (rt router) backend(ctx context.Context) (services.Backend, error) {
+	name := services.ClusterFromContext(ctx)
+	if c := rt.registry.Get(name); c != nil {
+		return c.Backend, nil
+	}
+	log.Printf("[DEBUG] Rejecting MinIO call for unknown cluster '%s'", name)
+	return nil, &services.Error{Kind: services.ErrNotFound, Code: "UnknownCluster", Err: fmt.Errorf("unknown cluster '%s'", name)}
+}
+
+func (rt router) ValidateCredentials(ctx context.Context, username, password string) (*services.UserInfo, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.ValidateCredentials(ctx, username, password)
+}
+
+func (rt router) GetUserPermissions(ctx context.Context, username, password string) *permissions.Set {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		set, _ := permissions.NewSet(nil)
+		return set
+	}
+	return b.GetUserPermissions(ctx, username, password)
+}
+
+func (rt router) AssumeRoleWithWebIdentity(ctx context.Context, idToken, roleARN string, duration time.Duration) (*services.STSCredentials, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.AssumeRoleWithWebIdentity(ctx, idToken, roleARN, duration)
+}
+
+func (rt router) AssumeRoleWithLDAPIdentity(ctx context.Context, ldapUsername, ldapPassword string, duration time.Duration) (*services.STSCredentials, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.AssumeRoleWithLDAPIdentity(ctx, ldapUsername, ldapPassword, duration)
+}
+
+// LDAPEnabled reports the setting shared by all clusters
+func (rt router) LDAPEnabled() bool {
+	return rt.registry.Default().Backend.LDAPEnabled()
+}
+
+// LDAPSTSDuration reports the setting shared by all clusters
+func (rt router) LDAPSTSDuration() time.Duration {
+	return rt.registry.Default().Backend.LDAPSTSDuration()
+}
+
+func (rt router) ListBucketsQuick(ctx context.Context, username, password string) ([]services.BucketInfo, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.ListBucketsQuick(ctx, username, password)
+}
+
+func (rt router) GetBucketStatsQuick(ctx context.Context, username, password, bucketName string) (int64, int64) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return -1, -1
+	}
+	return b.GetBucketStatsQuick(ctx, username, password, bucketName)
+}
+
+func (rt router) GetDataUsage(ctx context.Context, username, password string) (*services.DataUsage, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetDataUsage(ctx, username, password)
+}
+
+func (rt router) MeasureBucket(ctx context.Context, username, password, bucketName string) (*services.BucketStats, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.MeasureBucket(ctx, username, password, bucketName)
+}
+
+func (rt router) CreateBucket(ctx context.Context, bucketName string, opts services.BucketOptions, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.CreateBucket(ctx, bucketName, opts, username, password)
+}
+
+func (rt router) DeleteBucket(ctx context.Context, bucketName, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.DeleteBucket(ctx, bucketName, username, password)
+}
+
+func (rt router) GetBucketPolicy(ctx context.Context, bucketName, username, password string) (string, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return "", err
+	}
+	return b.GetBucketPolicy(ctx, bucketName, username, password)
+}
+
+func (rt router) SetBucketPolicy(ctx context.Context, bucketName, policy, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.SetBucketPolicy(ctx, bucketName, policy, username, password)
+}
+
+func (rt router) GetBucketVersioning(ctx context.Context, bucketName, username, password string) (*services.BucketVersioning, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetBucketVersioning(ctx, bucketName, username, password)
+}
+
+func (rt router) SetBucketVersioning(ctx context.Context, bucketName string, versioning services.BucketVersioning, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.SetBucketVersioning(ctx, bucketName, versioning, username, password)
+}
+
+func (rt router) GetBucketObjectLock(ctx context.Context, bucketName, username, password string) (*services.BucketObjectLock, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetBucketObjectLock(ctx, bucketName, username, password)
+}
+
+func (rt router) SetBucketRetention(ctx context.Context, bucketName string, lock services.BucketObjectLock, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.SetBucketRetention(ctx, bucketName, lock, username, password)
+}
+
+func (rt router) GetBucketLifecycle(ctx context.Context, bucketName, username, password string) ([]services.LifecycleRule, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetBucketLifecycle(ctx, bucketName, username, password)
+}
+
+func (rt router) SetBucketLifecycle(ctx context.Context, bucketName string, rules []services.LifecycleRule, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.SetBucketLifecycle(ctx, bucketName, rules, username, password)
+}
+
+func (rt router) ListTiers(ctx context.Context, username, password string) ([]string, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.ListTiers(ctx, username, password)
+}
+
+func (rt router) ListReplicationTargets(ctx context.Context, bucketName, username, password string) ([]services.ReplicationTarget, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.ListReplicationTargets(ctx, bucketName, username, password)
+}
+
+func (rt router) AddReplicationTarget(ctx context.Context, bucketName string, target services.ReplicationTarget, username, password string) (string, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return "", err
+	}
+	return b.AddReplicationTarget(ctx, bucketName, target, username, password)
+}
+
+func (rt router) RemoveReplicationTarget(ctx context.Context, bucketName, arn, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.RemoveReplicationTarget(ctx, bucketName, arn, username, password)
+}
+
+func (rt router) GetBucketReplication(ctx context.Context, bucketName, username, password string) ([]services.ReplicationRule, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetBucketReplication(ctx, bucketName, username, password)
+}
+
+func (rt router) SetBucketReplication(ctx context.Context, bucketName string, rules []services.ReplicationRule, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.SetBucketReplication(ctx, bucketName, rules, username, password)
+}
+
+func (rt router) GetReplicationMetrics(ctx context.Context, bucketName, username, password string) (*services.ReplicationMetrics, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetReplicationMetrics(ctx, bucketName, username, password)
+}
+
+func (rt router) ResyncReplication(ctx context.Context, bucketName, arn, username, password string) (*services.ReplicationResync, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.ResyncReplication(ctx, bucketName, arn, username, password)
+}
+
+func (rt router) GetReplicationResyncStatus(ctx context.Context, bucketName, username, password string) ([]services.ReplicationResync, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetReplicationResyncStatus(ctx, bucketName, username, password)
+}
+
+func (rt router) GetBucketNotification(ctx context.Context, bucketName, username, password string) ([]services.NotificationRule, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetBucketNotification(ctx, bucketName, username, password)
+}
+
+func (rt router) SetBucketNotification(ctx context.Context, bucketName string, rules []services.NotificationRule, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.SetBucketNotification(ctx, bucketName, rules, username, password)
+}
+
+func (rt router) ListNotificationTargets(ctx context.Context, username, password string) ([]services.NotificationTarget, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.ListNotificationTargets(ctx, username, password)
+}
+
+func (rt router) ListUsers(ctx context.Context, username, password string) ([]services.UserInfo, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.ListUsers(ctx, username, password)
+}
+
+func (rt router) CreateUser(ctx context.Context, accessKey, secretKey, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.CreateUser(ctx, accessKey, secretKey, username, password)
+}
+
+func (rt router) DeleteUser(ctx context.Context, accessKey, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.DeleteUser(ctx, accessKey, username, password)
+}
+
+func (rt router) GetUser(ctx context.Context, accessKey, username, password string) (*services.UserInfo, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetUser(ctx, accessKey, username, password)
+}
+
+func (rt router) UpdateUserCredentials(ctx context.Context, accessKey, newSecretKey, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.UpdateUserCredentials(ctx, accessKey, newSecretKey, username, password)
+}
+
+func (rt router) SetUserStatus(ctx context.Context, accessKey string, enabled bool, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.SetUserStatus(ctx, accessKey, enabled, username, password)
+}
+
+func (rt router) SetUserPolicy(ctx context.Context, accessKey, policyName, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.SetUserPolicy(ctx, accessKey, policyName, username, password)
+}
+
+func (rt router) GetUserPolicy(ctx context.Context, accessKey, username, password string) (string, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return "", err
+	}
+	return b.GetUserPolicy(ctx, accessKey, username, password)
+}
+
+func (rt router) GetUserDetails(ctx context.Context, accessKey, username, password string) (map[string]interface{}, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetUserDetails(ctx, accessKey, username, password)
+}
+
+func (rt router) GetUserCredentials(ctx context.Context, accessKey, username, password string) (map[string]interface{}, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetUserCredentials(ctx, accessKey, username, password)
+}
+
+func (rt router) ListGroups(ctx context.Context, username, password string) ([]string, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.ListGroups(ctx, username, password)
+}
+
+func (rt router) CreateGroup(ctx context.Context, groupName string, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.CreateGroup(ctx, groupName, username, password)
+}
+
+func (rt router) DeleteGroup(ctx context.Context, groupName string, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.DeleteGroup(ctx, groupName, username, password)
+}
+
+func (rt router) GetGroupInfo(ctx context.Context, groupName string, username, password string) (map[string]interface{}, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetGroupInfo(ctx, groupName, username, password)
+}
+
+func (rt router) AddUsersToGroup(ctx context.Context, groupName string, usernames []string, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.AddUsersToGroup(ctx, groupName, usernames, username, password)
+}
+
+func (rt router) RemoveUsersFromGroup(ctx context.Context, groupName string, usernames []string, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.RemoveUsersFromGroup(ctx, groupName, usernames, username, password)
+}
+
+func (rt router) SetGroupPolicy(ctx context.Context, groupName, policyName string, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.SetGroupPolicy(ctx, groupName, policyName, username, password)
+}
+
+func (rt router) ListPolicies(ctx context.Context, username, password string) (map[string]json.RawMessage, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.ListPolicies(ctx, username, password)
+}
+
+func (rt router) GetPolicyDocument(ctx context.Context, policyName, username, password string) (string, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return "", err
+	}
+	return b.GetPolicyDocument(ctx, policyName, username, password)
+}
+
+func (rt router) CreateOrUpdatePolicyDocument(ctx context.Context, policyName, policyDocument, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.CreateOrUpdatePolicyDocument(ctx, policyName, policyDocument, username, password)
+}
+
+func (rt router) DeletePolicyDocument(ctx context.Context, policyName, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.DeletePolicyDocument(ctx, policyName, username, password)
+}
+
+func (rt router) GetUserServiceAccounts(ctx context.Context, username, password string) ([]map[string]interface{}, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetUserServiceAccounts(ctx, username, password)
+}
+
+func (rt router) ListServiceAccounts(ctx context.Context, targetUser, username, password string) ([]map[string]interface{}, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.ListServiceAccounts(ctx, targetUser, username, password)
+}
+
+func (rt router) CreateServiceAccount(ctx context.Context, targetUser, name, description, username, password string) (map[string]interface{}, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.CreateServiceAccount(ctx, targetUser, name, description, username, password)
+}
+
+func (rt router) DeleteServiceAccount(ctx context.Context, serviceAccountKey, username, password string) error {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return b.DeleteServiceAccount(ctx, serviceAccountKey, username, password)
+}
+
+func (rt router) GetServiceAccountInfo(ctx context.Context, serviceAccountKey, username, password string) (map[string]interface{}, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetServiceAccountInfo(ctx, serviceAccountKey, username, password)
+}
+
+func (rt router) GetServerInfo(ctx context.Context, username, password string) (map[string]interface{}, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetServerInfo(ctx, username, password)
+}
+
+func (rt router) GetMetrics(ctx context.Context, username, password string) (map[string]interface{}, error) {
+	b, err := rt.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetMetrics(ctx, username, password)
+}
+
+// ClientCacheStats adds up the client caches of all clusters
+func (rt router) ClientCacheStats() services.CacheStats {
+	var total services.CacheStats
+	for _, c := range rt.registry.All() {
+		stats := c.Backend.ClientCacheStats()
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+		total.Evictions += stats.Evictions
+		total.Entries += stats.Entries
+		total.MaxSize += stats.MaxSize
+		total.TTL = stats.TTL
+	}
+	return total
+}
+
+// EvictClients drops the cached clients of an access key on every cluster
+func (rt router) EvictClients(accessKey string) {
+	for _, c := range rt.registry.All() {
+		c.Backend.EvictClients(accessKey)
+	}
+}
+
+// Timeouts reports the deadlines shared by all clusters
+func (rt router) Timeouts() services.Timeouts {
+	return rt.registry.Default().Backend.Timeouts()
+}
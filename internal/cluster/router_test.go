@@ -0,0 +1,99 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"minio-admin-panel/internal/config"
+	"minio-admin-panel/internal/services"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// newTestRegistry loads two clusters, each with its own in-memory backend
+func newTestRegistry(t *testing.T) (*Registry, map[string]*services.MemoryBackend) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "clusters.json")
+	file := `{"default":"primary","clusters":[
+		{"name":"primary","host":"primary.example.com","port":9000},
+		{"name":"backup","host":"backup.example.com","port":9000}]}`
+	if err := os.WriteFile(path, []byte(file), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	backends := make(map[string]*services.MemoryBackend)
+	registry, err := Load(path, &config.Config{}, func(cfg *config.Config) services.Backend {
+		backend := services.NewMemoryBackend("admin", "adminsecret")
+		backends[cfg.MinIOHost] = backend
+		return backend
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return registry, backends
+}
+
+func TestRouterBackend(t *testing.T) {
+	tests := []struct {
+		name    string
+		cluster string // cluster named in the context, if any
+		host    string // cluster expected to get the bucket, "" if none
+	}{
+		{"default cluster", "", "primary.example.com"},
+		{"named default cluster", "primary", "primary.example.com"},
+		{"other cluster", "backup", "backup.example.com"},
+		{"unknown cluster", "staging", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, backends := newTestRegistry(t)
+			ctx := context.Background()
+			if tt.cluster != "" {
+				ctx = services.WithCluster(ctx, tt.cluster)
+			}
+
+			err := registry.Backend().CreateBucket(ctx, "photos", services.BucketOptions{}, "admin", "adminsecret")
+			if tt.host == "" {
+				if !errors.Is(err, services.ErrNotFound) {
+					t.Fatalf("got error %v, want %v", err, services.ErrNotFound)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			for host, backend := range backends {
+				buckets, err := backend.ListBucketsQuick(context.Background(), "admin", "adminsecret")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if created := len(buckets) == 1; created != (host == tt.host) {
+					t.Errorf("bucket created on %s: %t", host, created)
+				}
+			}
+		})
+	}
+}
+
+func TestRouterUnknownClusterFailsClosed(t *testing.T) {
+	registry, _ := newTestRegistry(t)
+	backend := registry.Backend()
+	ctx := services.WithCluster(context.Background(), "staging")
+
+	if _, err := backend.ValidateCredentials(ctx, "admin", "adminsecret"); !errors.Is(err, services.ErrNotFound) {
+		t.Errorf("ValidateCredentials: got error %v, want %v", err, services.ErrNotFound)
+	}
+	if access := backend.GetUserPermissions(ctx, "admin", "adminsecret"); access.Has("isAdmin") || access.Has("canListBuckets") {
+		t.Errorf("GetUserPermissions granted %v on an unknown cluster", access.Flags)
+	}
+	if objects, size := backend.GetBucketStatsQuick(ctx, "admin", "adminsecret", "photos"); objects != -1 || size != -1 {
+		t.Errorf("GetBucketStatsQuick = %d, %d, want -1, -1", objects, size)
+	}
+}
@@ -2,30 +2,285 @@ package config
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"strconv"
+	"strings"
 )
 
+// DefaultJWTSecret is the placeholder secret used when JWT_SECRET is not set
+const DefaultJWTSecret = "your-secret-key"
+
 type Config struct {
-	MinIOHost      string
-	MinIOPort      int
-	MinIOUseSSL    bool
-	JWTSecret      string
-	SessionTimeout int // in minutes
+	MinIOHost          string
+	MinIOPort          int
+	MinIOUseSSL        bool
+	JWTSecret          string
+	SessionTimeout     int // idle timeout in minutes
+	SessionMaxLifetime int // absolute timeout in minutes, 0 to disable
+	DevMode            bool
+
+	// MinIOBackend selects where the panel keeps its data: "minio" talks to the
+	// configured server, "memory" keeps everything in process (development only)
+	MinIOBackend string
+
+	// Deadlines of MinIO operations in seconds
+	MinIOReadTimeout       int
+	MinIOWriteTimeout      int
+	MinIOListTimeout       int
+	MinIOQuickStatsTimeout int
+
+	// MinIO client cache
+	MinIOClientCacheTTL  int // in seconds, 0 to disable
+	MinIOClientCacheSize int // maximum number of cached credentials
+
+	// Background bucket statistics
+	StatsRefreshInterval int // in minutes; cached statistics older than this are refreshed
+	StatsConcurrency     int // buckets measured at the same time
+	StatsBucketTimeout   int // in seconds, per bucket
+	StatsAccessKey       string
+	StatsSecretKey       string
+
+	// Retired JWT secrets that are still accepted for verification during rotation
+	JWTPreviousSecrets []string
+
+	// Cookie attributes
+	CookieSameSite string // "lax", "strict" or "none"
+	CookieSecure   bool
+
+	// Reverse proxies whose X-Forwarded-For header is trusted for the client IP
+	TrustedProxies []string
+
+	// Login throttling
+	LoginMaxAttempts    int
+	LoginBackoffSeconds int
+	LoginLockoutMinutes int
+	LoginAllowList      []string
+
+	// Session storage
+	SessionStore         string // "memory" or "file"
+	SessionStorePath     string
+	SessionEncryptionKey string
+
+	// TOTP second factor for password and LDAP logins
+	MFAEnabled       bool
+	MFAStorePath     string
+	MFAEncryptionKey string
+	MFARequiredRoles []string // panel roles that must use TOTP; "*" for everyone
+	MFAIssuer        string
+
+	// Personal API tokens
+	APITokensEnabled        bool
+	APITokenStorePath       string
+	APITokenEncryptionKey   string
+	APITokenMaxLifetimeDays int
+
+	// OpenID Connect single sign-on
+	OIDCIssuer        string
+	OIDCClientID      string
+	OIDCClientSecret  string
+	OIDCRedirectURL   string
+	OIDCScopes        []string
+	OIDCUsernameClaim string
+	OIDCRoleClaim     string            // claim whose values select a MinIO role
+	OIDCRoleMap       map[string]string // claim value -> MinIO role ARN
+	OIDCRoleARN       string            // role ARN used when no mapping matches
+
+	// LDAP login via MinIO's AssumeRoleWithLDAPIdentity STS API
+	LDAPEnabled     bool
+	LDAPSTSDuration int // in minutes
+
+	// Panel roles mapping file; empty disables panel RBAC
+	RBACConfigPath string
+
+	// Cluster registry file; empty manages the single MINIO_HOST cluster
+	ClustersConfigPath string
+
+	// Audit log
+	AuditLogPath    string
+	AuditMaxSizeMB  int
+	AuditMaxFiles   int
+	AuditWebhookURL string
 }
 
 func Load() *Config {
 	port, _ := strconv.Atoi(getEnv("MINIO_PORT", "9000"))
 
 	return &Config{
-		MinIOHost:      getEnv("MINIO_HOST", "localhost"),
-		MinIOPort:      port,
-		MinIOUseSSL:    getEnv("MINIO_USE_SSL", "false") == "true",
-		JWTSecret:      getEnv("JWT_SECRET", "your-secret-key"),
-		SessionTimeout: 60, // 1 hour
+		MinIOHost:   getEnv("MINIO_HOST", "localhost"),
+		MinIOPort:   port,
+		MinIOUseSSL: getEnv("MINIO_USE_SSL", "false") == "true",
+		JWTSecret:   getEnv("JWT_SECRET", DefaultJWTSecret),
+		DevMode:     getEnv("DEV_MODE", "false") == "true",
+
+		MinIOBackend: strings.ToLower(getEnv("MINIO_BACKEND", "minio")),
+
+		SessionTimeout:     getEnvInt("SESSION_TIMEOUT", 60),
+		SessionMaxLifetime: getEnvInt("SESSION_MAX_LIFETIME", 720),
+
+		MinIOReadTimeout:       getEnvInt("MINIO_READ_TIMEOUT", 30),
+		MinIOWriteTimeout:      getEnvInt("MINIO_WRITE_TIMEOUT", 60),
+		MinIOListTimeout:       getEnvInt("MINIO_LIST_TIMEOUT", 120),
+		MinIOQuickStatsTimeout: getEnvInt("MINIO_QUICK_STATS_TIMEOUT", 5),
+
+		MinIOClientCacheTTL:  getEnvInt("MINIO_CLIENT_CACHE_TTL", 300),
+		MinIOClientCacheSize: getEnvInt("MINIO_CLIENT_CACHE_SIZE", 256),
+
+		StatsRefreshInterval: getEnvInt("STATS_REFRESH_INTERVAL", 15),
+		StatsConcurrency:     getEnvInt("STATS_CONCURRENCY", 4),
+		StatsBucketTimeout:   getEnvInt("STATS_BUCKET_TIMEOUT", 300),
+		StatsAccessKey:       getEnv("STATS_ACCESS_KEY", ""),
+		StatsSecretKey:       getEnv("STATS_SECRET_KEY", ""),
+
+		JWTPreviousSecrets: getEnvList("JWT_PREVIOUS_SECRETS"),
+
+		CookieSameSite: strings.ToLower(getEnv("COOKIE_SAMESITE", "lax")),
+		CookieSecure:   getEnv("COOKIE_SECURE", "false") == "true",
+
+		TrustedProxies: getEnvList("TRUSTED_PROXIES"),
+
+		LoginMaxAttempts:    getEnvInt("LOGIN_MAX_ATTEMPTS", 5),
+		LoginBackoffSeconds: getEnvInt("LOGIN_BACKOFF_SECONDS", 1),
+		LoginLockoutMinutes: getEnvInt("LOGIN_LOCKOUT_MINUTES", 15),
+		LoginAllowList:      getEnvList("LOGIN_ALLOWLIST"),
+
+		SessionStore:         getEnv("SESSION_STORE", "memory"),
+		SessionStorePath:     getEnv("SESSION_STORE_PATH", "data/sessions"),
+		SessionEncryptionKey: getEnv("SESSION_ENCRYPTION_KEY", ""),
+
+		MFAEnabled:       getEnv("MFA_ENABLED", "false") == "true",
+		MFAStorePath:     getEnv("MFA_STORE_PATH", "data/mfa"),
+		MFAEncryptionKey: getEnv("MFA_ENCRYPTION_KEY", getEnv("SESSION_ENCRYPTION_KEY", "")),
+		MFARequiredRoles: getEnvList("MFA_REQUIRED_ROLES"),
+		MFAIssuer:        getEnv("MFA_ISSUER", "MinIO Admin Panel"),
+
+		APITokensEnabled:        getEnv("API_TOKENS_ENABLED", "false") == "true",
+		APITokenStorePath:       getEnv("API_TOKEN_STORE_PATH", "data/tokens"),
+		APITokenEncryptionKey:   getEnv("API_TOKEN_ENCRYPTION_KEY", getEnv("SESSION_ENCRYPTION_KEY", "")),
+		APITokenMaxLifetimeDays: getEnvInt("API_TOKEN_MAX_LIFETIME_DAYS", 365),
+
+		OIDCIssuer:        getEnv("OIDC_ISSUER", ""),
+		OIDCClientID:      getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:  getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:   getEnv("OIDC_REDIRECT_URL", "http://localhost:8080/auth/oidc/callback"),
+		OIDCScopes:        getEnvList("OIDC_SCOPES"),
+		OIDCUsernameClaim: getEnv("OIDC_USERNAME_CLAIM", "preferred_username"),
+		OIDCRoleClaim:     getEnv("OIDC_ROLE_CLAIM", "groups"),
+		OIDCRoleMap:       getEnvMap("OIDC_ROLE_MAP"),
+		OIDCRoleARN:       getEnv("OIDC_ROLE_ARN", ""),
+
+		LDAPEnabled:     getEnv("LDAP_ENABLED", "false") == "true",
+		LDAPSTSDuration: getEnvInt("LDAP_STS_DURATION", 60),
+
+		RBACConfigPath: getEnv("RBAC_CONFIG", ""),
+
+		ClustersConfigPath: getEnv("CLUSTERS_CONFIG", ""),
+
+		AuditLogPath:    getEnv("AUDIT_LOG_PATH", "data/audit/audit.log"),
+		AuditMaxSizeMB:  getEnvInt("AUDIT_MAX_SIZE_MB", 10),
+		AuditMaxFiles:   getEnvInt("AUDIT_MAX_FILES", 10),
+		AuditWebhookURL: getEnv("AUDIT_WEBHOOK_URL", ""),
 	}
 }
 
+// Validate checks the configuration for settings that are unsafe in production
+func (c *Config) Validate() error {
+	if c.JWTSecret == DefaultJWTSecret && !c.DevMode {
+		return fmt.Errorf("JWT_SECRET must be set to a non-default value (set DEV_MODE=true to allow the default)")
+	}
+	if c.MFAEnabled && c.MFAEncryptionKey == "" && !c.DevMode {
+		return fmt.Errorf("MFA_ENCRYPTION_KEY or SESSION_ENCRYPTION_KEY must be set when MFA_ENABLED=true")
+	}
+	if c.APITokensEnabled && c.APITokenEncryptionKey == "" && !c.DevMode {
+		return fmt.Errorf("API_TOKEN_ENCRYPTION_KEY or SESSION_ENCRYPTION_KEY must be set when API_TOKENS_ENABLED=true")
+	}
+	if c.APITokensEnabled && c.APITokenMaxLifetimeDays <= 0 {
+		return fmt.Errorf("API_TOKEN_MAX_LIFETIME_DAYS must be a positive number of days")
+	}
+	if c.SessionTimeout <= 0 {
+		return fmt.Errorf("SESSION_TIMEOUT must be a positive number of minutes")
+	}
+	if c.SessionMaxLifetime < 0 {
+		return fmt.Errorf("SESSION_MAX_LIFETIME must not be negative")
+	}
+	switch c.SessionStore {
+	case "memory":
+	case "file":
+		// A random key would make every stored session unreadable after a restart
+		if c.SessionEncryptionKey == "" && !c.DevMode {
+			return fmt.Errorf("SESSION_ENCRYPTION_KEY must be set when SESSION_STORE=file")
+		}
+	default:
+		return fmt.Errorf("SESSION_STORE must be \"memory\" or \"file\"")
+	}
+	switch c.MinIOBackend {
+	case "minio":
+	case "memory":
+		if !c.DevMode {
+			return fmt.Errorf("MINIO_BACKEND=memory is only allowed with DEV_MODE=true")
+		}
+	default:
+		return fmt.Errorf("MINIO_BACKEND must be \"minio\" or \"memory\"")
+	}
+	for _, timeout := range []struct {
+		name    string
+		seconds int
+	}{
+		{"MINIO_READ_TIMEOUT", c.MinIOReadTimeout},
+		{"MINIO_WRITE_TIMEOUT", c.MinIOWriteTimeout},
+		{"MINIO_LIST_TIMEOUT", c.MinIOListTimeout},
+		{"MINIO_QUICK_STATS_TIMEOUT", c.MinIOQuickStatsTimeout},
+	} {
+		if timeout.seconds <= 0 {
+			return fmt.Errorf("%s must be a positive number of seconds", timeout.name)
+		}
+	}
+	if c.MinIOClientCacheTTL < 0 {
+		return fmt.Errorf("MINIO_CLIENT_CACHE_TTL must not be negative")
+	}
+	if c.MinIOClientCacheSize <= 0 {
+		return fmt.Errorf("MINIO_CLIENT_CACHE_SIZE must be a positive number")
+	}
+	if c.StatsRefreshInterval <= 0 {
+		return fmt.Errorf("STATS_REFRESH_INTERVAL must be a positive number of minutes")
+	}
+	if c.StatsConcurrency <= 0 {
+		return fmt.Errorf("STATS_CONCURRENCY must be a positive number")
+	}
+	if c.StatsBucketTimeout <= 0 {
+		return fmt.Errorf("STATS_BUCKET_TIMEOUT must be a positive number of seconds")
+	}
+	if (c.StatsAccessKey == "") != (c.StatsSecretKey == "") {
+		return fmt.Errorf("STATS_ACCESS_KEY and STATS_SECRET_KEY must be set together")
+	}
+	sameSite, err := c.CookieSameSiteMode()
+	if err != nil {
+		return err
+	}
+	if sameSite == http.SameSiteNoneMode && !c.CookieSecure {
+		return fmt.Errorf("COOKIE_SAMESITE=none requires COOKIE_SECURE=true")
+	}
+	return nil
+}
+
+// CookieSameSiteMode returns the SameSite attribute configured for cookies
+func (c *Config) CookieSameSiteMode() (http.SameSite, error) {
+	switch c.CookieSameSite {
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return 0, fmt.Errorf("COOKIE_SAMESITE must be one of lax, strict or none, got %q", c.CookieSameSite)
+}
+
+// OIDCEnabled reports whether OpenID Connect login is configured
+func (c *Config) OIDCEnabled() bool {
+	return c.OIDCIssuer != "" && c.OIDCClientID != ""
+}
+
 // GetMinIOEndpoint returns the complete MinIO endpoint
 func (c *Config) GetMinIOEndpoint() string {
 	return fmt.Sprintf("%s:%d", c.MinIOHost, c.MinIOPort)
@@ -37,3 +292,34 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvInt reads an integer environment variable, falling back to the default
+// when it is unset or invalid
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvList reads a comma-separated environment variable, dropping empty entries
+func getEnvList(key string) []string {
+	var result []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// getEnvMap reads a comma-separated list of key=value pairs
+func getEnvMap(key string) map[string]string {
+	result := make(map[string]string)
+	for _, entry := range getEnvList(key) {
+		if k, v, ok := strings.Cut(entry, "="); ok {
+			result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return result
+}
@@ -1,23 +1,29 @@
 package handlers
 
 import (
-	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
+	"minio-admin-panel/internal/bucketstats"
 	"minio-admin-panel/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
+// statsSourceCache marks storage usage totalled from the background worker's cache
+const statsSourceCache = "cache"
+
 type APIHandler struct {
-	minioService *services.MinIOService
+	minioService services.Backend
+	stats        *bucketstats.Worker
 }
 
-func NewAPIHandler(minioService *services.MinIOService) *APIHandler {
+func NewAPIHandler(minioService services.Backend, stats *bucketstats.Worker) *APIHandler {
 	return &APIHandler{
 		minioService: minioService,
+		stats:        stats,
 	}
 }
 
@@ -45,10 +51,10 @@ func (h *APIHandler) GetServerInfo(c *gin.Context) {
 	}
 
 	log.Printf("[DEBUG] Getting server info for user '%s'", username)
-	info, err := h.minioService.GetServerInfo(context.Background(), username, password)
+	info, err := h.minioService.GetServerInfo(minioContext(c), username, password)
 	if err != nil {
 		log.Printf("[DEBUG] GetServerInfo failed: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -68,10 +74,10 @@ func (h *APIHandler) GetMetrics(c *gin.Context) {
 	}
 
 	log.Printf("[DEBUG] Getting metrics for user '%s'", username)
-	metrics, err := h.minioService.GetMetrics(context.Background(), username, password)
+	metrics, err := h.minioService.GetMetrics(minioContext(c), username, password)
 	if err != nil {
 		log.Printf("[DEBUG] GetMetrics failed: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -79,6 +85,11 @@ func (h *APIHandler) GetMetrics(c *gin.Context) {
 	c.JSON(http.StatusOK, metrics)
 }
 
+// GetClientCacheStats returns the hit and miss counters of the MinIO client cache
+func (h *APIHandler) GetClientCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"client_cache": h.minioService.ClientCacheStats()})
+}
+
 // GetStorageUsage returns storage usage statistics
 func (h *APIHandler) GetStorageUsage(c *gin.Context) {
 	log.Printf("[DEBUG] GetStorageUsage API request")
@@ -91,23 +102,53 @@ func (h *APIHandler) GetStorageUsage(c *gin.Context) {
 	}
 
 	log.Printf("[DEBUG] Getting storage usage for user '%s'", username)
+	ctx := minioContextFor(c, services.OpList)
 
 	// Get bucket statistics to calculate total storage usage
-	buckets, err := h.minioService.ListBucketsQuick(context.Background(), username, password)
+	buckets, err := h.minioService.ListBucketsQuick(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to list buckets for storage usage: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
+		return
+	}
+
+	// Serve the background worker's statistics once it has measured every
+	// bucket, refreshing them if they are outdated
+	cluster := c.GetString("cluster")
+	names := bucketNames(buckets)
+	if h.stats.Stale(cluster, names) {
+		h.stats.Refresh(requestCredentials(c), false)
+	}
+	if response, ok := h.cachedStorageUsage(cluster, names); ok {
+		c.JSON(http.StatusOK, response)
 		return
 	}
 
-	// Calculate storage usage by iterating through buckets and getting their stats
+	// Until then prefer the sizes recorded by MinIO's scanner; count objects
+	// only for buckets it has no data for
+	usage, err := h.minioService.GetDataUsage(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Data usage unavailable for user '%s', listing objects instead: %v", username, err)
+	}
+
 	var totalSize int64
 	var totalObjects int64
 	bucketsWithStats := 0
+	bucketsListed := 0
 
 	for _, bucket := range buckets {
-		log.Printf("[DEBUG] Calculating stats for bucket '%s'", bucket.Name)
-		size, objectCount := h.minioService.GetBucketStatsQuick(context.Background(), username, password, bucket.Name)
+		var size, objectCount int64
+		if stats, ok := usage.Bucket(bucket.Name); ok {
+			size, objectCount = stats.Size, stats.ObjectCount
+		} else {
+			log.Printf("[DEBUG] Calculating stats for bucket '%s' by listing", bucket.Name)
+			size, objectCount = h.minioService.GetBucketStatsQuick(ctx, username, password, bucket.Name)
+			if err := ctx.Err(); err != nil {
+				respondMinIOError(c, err)
+				return
+			}
+			bucketsListed++
+		}
 		if size >= 0 && objectCount >= 0 { // Valid stats (not timeout)
 			totalSize += size
 			totalObjects += objectCount
@@ -115,16 +156,63 @@ func (h *APIHandler) GetStorageUsage(c *gin.Context) {
 		}
 	}
 
-	log.Printf("[DEBUG] Storage usage calculation complete: %d bytes across %d objects in %d buckets",
-		totalSize, totalObjects, bucketsWithStats)
+	log.Printf("[DEBUG] Storage usage calculation complete: %d bytes across %d objects in %d buckets (%d listed)",
+		totalSize, totalObjects, bucketsWithStats, bucketsListed)
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"total_size":         totalSize,
 		"total_objects":      totalObjects,
 		"total_buckets":      len(buckets),
 		"buckets_with_stats": bucketsWithStats,
+		"buckets_listed":     bucketsListed,
 		"formatted_size":     formatBytes(totalSize),
-	})
+		"stats_source":       services.StatsSourceListing,
+		"refreshing":         h.stats.Status(cluster).Refreshing,
+	}
+	if usage != nil {
+		response["stats_source"] = services.StatsSourceScanner
+		response["updated_at"] = usage.UpdatedAt
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// cachedStorageUsage totals the cached statistics of the buckets. It reports
+// false if any bucket has not been measured yet.
+func (h *APIHandler) cachedStorageUsage(cluster string, buckets []string) (gin.H, bool) {
+	var totalSize, totalObjects int64
+	var updatedAt time.Time
+	bucketsWithStats := 0
+	for _, name := range buckets {
+		stats, ok := h.stats.Get(cluster, name)
+		if !ok {
+			return nil, false
+		}
+		if stats.Size >= 0 && stats.ObjectCount >= 0 {
+			totalSize += stats.Size
+			totalObjects += stats.ObjectCount
+			bucketsWithStats++
+		}
+		// Report the age of the oldest figures
+		if updatedAt.IsZero() || stats.UpdatedAt.Before(updatedAt) {
+			updatedAt = stats.UpdatedAt
+		}
+	}
+
+	log.Printf("[DEBUG] Storage usage from cache: %d bytes across %d objects in %d buckets", totalSize, totalObjects, bucketsWithStats)
+	response := gin.H{
+		"total_size":         totalSize,
+		"total_objects":      totalObjects,
+		"total_buckets":      len(buckets),
+		"buckets_with_stats": bucketsWithStats,
+		"buckets_listed":     0,
+		"formatted_size":     formatBytes(totalSize),
+		"stats_source":       statsSourceCache,
+		"refreshing":         h.stats.Status(cluster).Refreshing,
+	}
+	if !updatedAt.IsZero() {
+		response["updated_at"] = updatedAt
+	}
+	return response, true
 }
 
 // formatBytes converts bytes to human readable format (same as main.go)
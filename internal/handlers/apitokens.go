@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"minio-admin-panel/internal/apitoken"
+	"minio-admin-panel/internal/middleware"
+	"minio-admin-panel/internal/services"
+	"minio-admin-panel/internal/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxTokenNameLength limits the label users give their tokens
+const maxTokenNameLength = 64
+
+// defaultTokenLifetimeDays is preselected in the token form
+const defaultTokenLifetimeDays = 90
+
+type APITokenHandler struct {
+	tokens          *apitoken.Store
+	minioService    services.AuthBackend
+	maxLifetimeDays int
+}
+
+func NewAPITokenHandler(tokens *apitoken.Store, minioService services.AuthBackend, maxLifetimeDays int) *APITokenHandler {
+	return &APITokenHandler{
+		tokens:          tokens,
+		minioService:    minioService,
+		maxLifetimeDays: maxLifetimeDays,
+	}
+}
+
+// ShowTokens handles GET /account/tokens
+func (h *APITokenHandler) ShowTokens(c *gin.Context) {
+	h.render(c, gin.H{})
+}
+
+// CreateToken handles POST /account/tokens. The token value is shown once.
+func (h *APITokenHandler) CreateToken(c *gin.Context) {
+	owner := c.GetString("display_name")
+
+	token, errorKey := h.tokenFromForm(c)
+	if errorKey != "" {
+		c.Status(http.StatusBadRequest)
+		h.render(c, gin.H{"error": errorKey})
+		return
+	}
+
+	raw, err := h.tokens.Create(token)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create API token for user '%s': %v", owner, err)
+		c.Status(http.StatusInternalServerError)
+		h.render(c, gin.H{"error": "tokens.error.failed"})
+		return
+	}
+
+	middleware.SetAuditTarget(c, token.Name)
+	middleware.AddAuditDetail(c, "token_id", token.ID)
+	middleware.AddAuditDetail(c, "access_key", token.Credentials.AccessKey)
+	h.render(c, gin.H{"newToken": raw, "success": "tokens.created_success"})
+}
+
+// DeleteToken handles POST /account/tokens/:id/delete
+func (h *APITokenHandler) DeleteToken(c *gin.Context) {
+	token, err := h.tokens.Delete(c.Param("id"), c.GetString("display_name"))
+	if err != nil {
+		log.Printf("[DEBUG] Failed to delete API token: %v", err)
+		c.Status(http.StatusNotFound)
+		h.render(c, gin.H{"error": "tokens.error.not_found"})
+		return
+	}
+
+	middleware.SetAuditTarget(c, token.Name)
+	h.render(c, gin.H{"success": "tokens.deleted_success"})
+}
+
+// ListAllTokens handles GET /api/tokens, listing the tokens of every user
+func (h *APITokenHandler) ListAllTokens(c *gin.Context) {
+	tokens, err := h.tokens.List("")
+	if err != nil {
+		log.Printf("[DEBUG] Failed to list API tokens: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	infos := make([]apitoken.Info, 0, len(tokens))
+	for _, token := range tokens {
+		infos = append(infos, token.Info())
+	}
+	c.JSON(http.StatusOK, gin.H{"tokens": infos, "count": len(infos)})
+}
+
+// RevokeToken handles DELETE /api/tokens/:id, revoking a token of any user
+func (h *APITokenHandler) RevokeToken(c *gin.Context) {
+	token, err := h.tokens.Delete(c.Param("id"), "")
+	if err != nil {
+		if errors.Is(err, apitoken.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "tokens.error.not_found")})
+			return
+		}
+		log.Printf("[DEBUG] Failed to revoke API token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	middleware.SetAuditTarget(c, token.Owner)
+	middleware.AddAuditDetail(c, "token_id", token.ID)
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "tokens.deleted_success")})
+}
+
+// tokenFromForm validates the token form and resolves the MinIO credential the
+// token is bound to. It returns a translation key when the form is invalid.
+func (h *APITokenHandler) tokenFromForm(c *gin.Context) (*apitoken.Token, string) {
+	name := strings.TrimSpace(c.PostForm("name"))
+	if name == "" || len(name) > maxTokenNameLength {
+		return nil, "tokens.error.name"
+	}
+
+	var scopes []string
+	for _, area := range apitoken.Areas {
+		if level := c.PostForm("scope_" + area); level != "" {
+			scope := area + ":" + level
+			if !apitoken.ValidScope(scope) {
+				return nil, "tokens.error.scopes"
+			}
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, "tokens.error.scopes"
+	}
+
+	days, err := strconv.Atoi(c.PostForm("expires_days"))
+	if err != nil || days < 1 || days > h.maxLifetimeDays {
+		return nil, "tokens.error.lifetime"
+	}
+
+	var creds session.Credentials
+	switch binding := c.PostForm("binding"); binding {
+	case apitoken.BindingUser:
+		// Temporary STS credentials from SSO or LDAP logins expire with the session
+		if c.GetString("session_token") != "" {
+			return nil, "tokens.error.temporary_credentials"
+		}
+		creds = session.Credentials{AccessKey: c.GetString("username"), SecretKey: c.GetString("password")}
+	case apitoken.BindingServiceAccount:
+		creds = session.Credentials{
+			AccessKey: strings.TrimSpace(c.PostForm("access_key")),
+			SecretKey: c.PostForm("secret_key"),
+		}
+		if creds.AccessKey == "" || creds.SecretKey == "" {
+			return nil, "tokens.error.service_account"
+		}
+	default:
+		return nil, "tokens.error.binding"
+	}
+
+	// Both bindings are static credentials without an STS session token
+	ctx := services.WithSessionToken(minioContext(c), "")
+	// Tokens act on the cluster the session logged in to
+	creds.Cluster = c.GetString("cluster")
+	userInfo, err := h.minioService.ValidateCredentials(ctx, creds.AccessKey, creds.SecretKey)
+	if err != nil {
+		log.Printf("[DEBUG] API token credentials for '%s' rejected: %v", creds.AccessKey, err)
+		return nil, "tokens.error.service_account"
+	}
+	access := h.minioService.GetUserPermissions(ctx, creds.AccessKey, creds.SecretKey)
+
+	return &apitoken.Token{
+		Name:        name,
+		Owner:       c.GetString("display_name"),
+		Scopes:      scopes,
+		Binding:     c.PostForm("binding"),
+		PolicyName:  userInfo.PolicyName,
+		Permissions: access.Flags,
+		Policy:      access.PolicyJSON(),
+		Role:        panelRole(c),
+		Credentials: creds,
+		ExpiresAt:   time.Now().UTC().AddDate(0, 0, days),
+	}, ""
+}
+
+// render renders the token page for the current user
+func (h *APITokenHandler) render(c *gin.Context, data gin.H) {
+	tokens, err := h.tokens.List(c.GetString("display_name"))
+	if err != nil {
+		log.Printf("[DEBUG] Failed to list API tokens: %v", err)
+		data["error"] = "tokens.error.failed"
+	}
+
+	infos := make([]apitoken.Info, 0, len(tokens))
+	for _, token := range tokens {
+		infos = append(infos, token.Info())
+	}
+
+	data["title"] = "tokens.title"
+	data["tokens"] = infos
+	data["areas"] = apitoken.Areas
+	data["maxLifetimeDays"] = h.maxLifetimeDays
+	data["defaultLifetimeDays"] = min(defaultTokenLifetimeDays, h.maxLifetimeDays)
+	data["temporaryCredentials"] = c.GetString("session_token") != ""
+	RenderWithTranslations(c, "account_tokens.html", data)
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"minio-admin-panel/internal/audit"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultAuditLimit = 200
+	maxAuditLimit     = 5000
+)
+
+type AuditHandler struct {
+	logger *audit.Logger
+}
+
+func NewAuditHandler(logger *audit.Logger) *AuditHandler {
+	return &AuditHandler{
+		logger: logger,
+	}
+}
+
+// ShowAuditLog handles GET /audit
+func (h *AuditHandler) ShowAuditLog(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		log.Printf("[DEBUG] Invalid audit filter: %v", err)
+		RenderWithTranslations(c, "audit.html", gin.H{
+			"title":  "audit.title",
+			"error":  "audit.invalid_filter",
+			"filter": c.Request.URL.Query(),
+		})
+		return
+	}
+
+	records, err := h.logger.Query(filter)
+	if err != nil {
+		log.Printf("[DEBUG] Audit query failed: %v", err)
+		RenderWithTranslations(c, "audit.html", gin.H{
+			"title":  "audit.title",
+			"error":  "audit.load_failed",
+			"filter": c.Request.URL.Query(),
+		})
+		return
+	}
+
+	RenderWithTranslations(c, "audit.html", gin.H{
+		"title":   "audit.title",
+		"records": records,
+		"filter":  c.Request.URL.Query(),
+	})
+}
+
+// ListAuditRecords handles GET /api/audit
+func (h *AuditHandler) ListAuditRecords(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	records, err := h.logger.Query(filter)
+	if err != nil {
+		log.Printf("[DEBUG] Audit query failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"records": records, "count": len(records)})
+}
+
+// VerifyAuditLog handles GET /api/audit/verify
+func (h *AuditHandler) VerifyAuditLog(c *gin.Context) {
+	result, err := h.logger.Verify()
+	if err != nil {
+		log.Printf("[DEBUG] Audit verification failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("[DEBUG] Audit chain verified: %+v", result)
+	c.JSON(http.StatusOK, result)
+}
+
+// parseAuditFilter reads actor, target, action, from, to and limit query parameters
+func parseAuditFilter(c *gin.Context) (audit.Filter, error) {
+	filter := audit.Filter{
+		Actor:  c.Query("actor"),
+		Target: c.Query("target"),
+		Action: c.Query("action"),
+		Limit:  defaultAuditLimit,
+	}
+
+	var err error
+	if filter.From, err = parseAuditTime(c.Query("from")); err != nil {
+		return filter, fmt.Errorf("invalid 'from' time: %v", err)
+	}
+	if filter.To, err = parseAuditTime(c.Query("to")); err != nil {
+		return filter, fmt.Errorf("invalid 'to' time: %v", err)
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return filter, fmt.Errorf("invalid limit %q", limit)
+		}
+		if n > maxAuditLimit {
+			n = maxAuditLimit
+		}
+		filter.Limit = n
+	}
+
+	return filter, nil
+}
+
+// parseAuditTime accepts RFC 3339 timestamps as well as the values produced by
+// HTML date and datetime-local inputs
+func parseAuditTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported time format %q", value)
+}
@@ -1,36 +1,122 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
+	"minio-admin-panel/internal/audit"
+	"minio-admin-panel/internal/loginlimit"
+	"minio-admin-panel/internal/mfa"
 	"minio-admin-panel/internal/middleware"
+	"minio-admin-panel/internal/oidc"
+	"minio-admin-panel/internal/rbac"
 	"minio-admin-panel/internal/services"
+	"minio-admin-panel/internal/session"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthHandler struct {
-	minioService *services.MinIOService
+	minioService services.Backend
+	sessions     *session.Manager
+	keys         *middleware.KeyRing
+	oidc         *oidc.Provider // nil when single sign-on is disabled
+	roles        *rbac.Registry // nil when panel roles are disabled
+	audit        *audit.Logger
+	limiter      *loginlimit.Limiter
+	mfa          *mfa.Store // nil when two-factor authentication is disabled
+	mfaEnforce   mfa.Enforcement
 }
 
-func NewAuthHandler(minioService *services.MinIOService) *AuthHandler {
+// errNoRole is returned when panel roles are enabled and none applies to the user
+var errNoRole = errors.New("no panel role assigned")
+
+func NewAuthHandler(minioService services.Backend, sessions *session.Manager, keys *middleware.KeyRing, oidcProvider *oidc.Provider, roles *rbac.Registry, auditLog *audit.Logger, limiter *loginlimit.Limiter) *AuthHandler {
 	return &AuthHandler{
 		minioService: minioService,
+		sessions:     sessions,
+		keys:         keys,
+		oidc:         oidcProvider,
+		roles:        roles,
+		audit:        auditLog,
+		limiter:      limiter,
 	}
 }
 
-// LoginPage renders the login form
+// SetMFA enables TOTP as a second factor for password and LDAP logins
+func (h *AuthHandler) SetMFA(store *mfa.Store, enforce mfa.Enforcement) {
+	h.mfa = store
+	h.mfaEnforce = enforce
+}
+
+// loginNextCookie remembers where to go after login while the user signs in
+const loginNextCookie = "login_next"
+
+// LoginPage renders the login form. A next parameter, set when a session
+// expired, is kept so the user returns to that page after signing in.
 func (h *AuthHandler) LoginPage(c *gin.Context) {
+	next := middleware.SafeReturnPath(c.Query("next"))
+
 	// Check if user is already authenticated
-	if token, err := c.Cookie("token"); err == nil && token != "" {
-		c.Redirect(http.StatusFound, "/dashboard")
+	if _, err := middleware.CurrentSession(c, h.keys, h.sessions); err == nil {
+		if next == "" {
+			next = "/dashboard"
+		}
+		c.Redirect(http.StatusFound, next)
 		return
 	}
 
-	RenderWithTranslations(c, "login.html", gin.H{
-		"title": "login.title",
-	})
+	data := h.loginData()
+	if next != "" {
+		middleware.SetCookie(c, loginNextCookie, next, int(loginNextMaxAge.Seconds()), "/", true)
+		data["info"] = "session.expired_notice"
+	} else if c.Query("reason") == "revoked" {
+		data["info"] = "session.revoked"
+	}
+	RenderWithTranslations(c, "login.html", data)
+}
+
+// renderLogin renders the login form with an optional error translation key
+func (h *AuthHandler) renderLogin(c *gin.Context, errorKey string) {
+	data := h.loginData()
+	if errorKey != "" {
+		data["error"] = errorKey
+	}
+	RenderWithTranslations(c, "login.html", data)
+}
+
+// loginData returns the template data of the login form
+func (h *AuthHandler) loginData() gin.H {
+	data := gin.H{
+		"title":       "login.title",
+		"oidcEnabled": h.oidc != nil,
+		"ldapEnabled": h.minioService.LDAPEnabled(),
+	}
+	if clusters.Multiple() {
+		data["loginClusters"] = clusters.All()
+		data["defaultCluster"] = clusters.Default().Name
+	}
+	return data
+}
+
+// loginCluster selects the cluster named on the login form for the MinIO
+// calls of the request. It renders the login form and returns false if there
+// is no such cluster.
+func (h *AuthHandler) loginCluster(c *gin.Context, name string) bool {
+	target := clusters.Get(name)
+	if target == nil {
+		log.Printf("[DEBUG] Login attempt for unknown cluster '%s'", name)
+		c.Status(http.StatusBadRequest)
+		h.renderLogin(c, "clusters.error.unknown")
+		return false
+	}
+	c.Set("cluster", target.Name)
+	return true
 }
 
 // Login handles authentication
@@ -38,58 +124,367 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	var loginData struct {
 		Username string `form:"username" json:"username" binding:"required"`
 		Password string `form:"password" json:"password" binding:"required"`
+		Mode     string `form:"mode" json:"mode"`
+		Cluster  string `form:"cluster" json:"cluster"`
 	}
 
 	if err := c.ShouldBind(&loginData); err != nil {
 		log.Printf("[DEBUG] Login form validation failed: %v", err)
-		RenderWithTranslations(c, "login.html", gin.H{
-			"title": "login.title",
-			"error": "login.error.missing_credentials",
-		})
+		h.renderLogin(c, "login.error.missing_credentials")
+		return
+	}
+
+	if wait := h.limiter.Check(c.ClientIP(), loginData.Username); wait > 0 {
+		h.loginBlocked(c, loginData.Username, wait)
+		return
+	}
+	defer h.limiter.Release(c.ClientIP(), loginData.Username)
+
+	if !h.loginCluster(c, loginData.Cluster) {
+		return
+	}
+
+	if loginData.Mode == "ldap" && h.minioService.LDAPEnabled() {
+		h.loginLDAP(c, loginData.Username, loginData.Password)
 		return
 	}
 
 	log.Printf("[DEBUG] Login attempt for user '%s' from IP %s", loginData.Username, c.ClientIP())
 
 	// Validate credentials with admin panel credentials
-	userInfo, err := h.minioService.ValidateCredentials(loginData.Username, loginData.Password)
+	ctx := minioContext(c)
+	userInfo, err := h.minioService.ValidateCredentials(ctx, loginData.Username, loginData.Password)
 	if err != nil {
 		log.Printf("[DEBUG] Login failed for user '%s': %v", loginData.Username, err)
-		RenderWithTranslations(c, "login.html", gin.H{
-			"title": "login.title",
-			"error": "login.error.invalid_credentials",
-		})
+		h.auditLogin(c, loginData.Username, "static", err)
+		if h.minioUnavailable(c, err) {
+			return
+		}
+		h.loginFailed(c, loginData.Username)
+		h.renderLogin(c, "login.error.invalid_credentials")
 		return
 	}
 
 	log.Printf("[DEBUG] Credential validation successful for user '%s'", loginData.Username)
 
 	// Get user permissions
-	permissions := h.minioService.GetUserPermissions(loginData.Username, loginData.Password)
-	log.Printf("[DEBUG] Retrieved permissions for user '%s': %+v", loginData.Username, permissions)
+	permissions := h.minioService.GetUserPermissions(ctx, loginData.Username, loginData.Password)
+	log.Printf("[DEBUG] Retrieved permissions for user '%s': %+v", loginData.Username, permissions.Flags)
+
+	sess := &session.Session{
+		Username:    loginData.Username,
+		Cluster:     c.GetString("cluster"),
+		PolicyName:  userInfo.PolicyName,
+		Permissions: permissions.Flags,
+		Policy:      permissions.PolicyJSON(),
+	}
+	err = h.startSession(c, sess, session.Credentials{
+		Cluster:   sess.Cluster,
+		AccessKey: loginData.Username,
+		SecretKey: loginData.Password,
+	}, rbac.Identity{
+		Username: loginData.Username,
+		Groups:   h.userGroups(ctx, loginData.Username, loginData.Password),
+	}, true)
+	if err != nil {
+		log.Printf("[DEBUG] Session creation failed for user '%s': %v", loginData.Username, err)
+		h.auditLogin(c, loginData.Username, "static", err)
+		h.renderLogin(c, sessionErrorKey(err))
+		return
+	}
+
+	h.auditLogin(c, loginData.Username, "static", nil)
+	log.Printf("[DEBUG] Login successful for user '%s'", loginData.Username)
+	h.finishLogin(c, sess)
+}
+
+// loginLDAP authenticates against the directory configured in MinIO and keeps
+// the temporary STS credentials, renewing them from the stored LDAP credentials
+func (h *AuthHandler) loginLDAP(c *gin.Context, ldapUsername, ldapPassword string) {
+	log.Printf("[DEBUG] LDAP login attempt for user '%s' from IP %s", ldapUsername, c.ClientIP())
+
+	stsCreds, err := h.minioService.AssumeRoleWithLDAPIdentity(minioContext(c), ldapUsername, ldapPassword, h.minioService.LDAPSTSDuration())
+	if err != nil {
+		log.Printf("[DEBUG] LDAP login failed for user '%s': %v", ldapUsername, err)
+		h.auditLogin(c, ldapUsername, "ldap", err)
+		h.loginFailed(c, ldapUsername)
+		h.renderLogin(c, "login.error.invalid_credentials")
+		return
+	}
+
+	ctx := services.WithSessionToken(minioContext(c), stsCreds.SessionToken)
+	userInfo, err := h.minioService.ValidateCredentials(ctx, stsCreds.AccessKey, stsCreds.SecretKey)
+	if err != nil {
+		log.Printf("[DEBUG] LDAP user '%s' lacks panel access: %v", ldapUsername, err)
+		h.auditLogin(c, ldapUsername, "ldap", err)
+		if h.minioUnavailable(c, err) {
+			return
+		}
+		h.loginFailed(c, ldapUsername)
+		h.renderLogin(c, "login.error.invalid_credentials")
+		return
+	}
 
-	// Generate JWT token with user info
-	token, err := middleware.GenerateJWTWithUserInfo(loginData.Username, loginData.Password, userInfo.PolicyName, permissions)
+	permissions := h.minioService.GetUserPermissions(ctx, stsCreds.AccessKey, stsCreds.SecretKey)
+	log.Printf("[DEBUG] Retrieved permissions for LDAP user '%s': %+v", ldapUsername, permissions.Flags)
+
+	sess := &session.Session{
+		Username:    ldapUsername,
+		Cluster:     c.GetString("cluster"),
+		PolicyName:  userInfo.PolicyName,
+		Permissions: permissions.Flags,
+		Policy:      permissions.PolicyJSON(),
+	}
+	err = h.startSession(c, sess, session.Credentials{
+		Cluster:      sess.Cluster,
+		AccessKey:    stsCreds.AccessKey,
+		SecretKey:    stsCreds.SecretKey,
+		SessionToken: stsCreds.SessionToken,
+		Expiration:   stsCreds.Expiration,
+		LDAPUsername: ldapUsername,
+		LDAPPassword: ldapPassword,
+	}, rbac.Identity{
+		Username: ldapUsername,
+	}, true)
 	if err != nil {
-		log.Printf("[DEBUG] JWT token generation failed for user '%s': %v", loginData.Username, err)
-		RenderWithTranslations(c, "login.html", gin.H{
-			"title": "login.title",
-			"error": "login.error.token_generation",
-		})
+		log.Printf("[DEBUG] Session creation failed for LDAP user '%s': %v", ldapUsername, err)
+		h.auditLogin(c, ldapUsername, "ldap", err)
+		h.renderLogin(c, sessionErrorKey(err))
 		return
 	}
 
-	log.Printf("[DEBUG] JWT token generated successfully for user '%s'", loginData.Username)
+	h.auditLogin(c, ldapUsername, "ldap", nil)
+	log.Printf("[DEBUG] LDAP login successful for user '%s'", ldapUsername)
+	h.finishLogin(c, sess)
+}
+
+// startSession stores the credentials server-side and sets the session cookie;
+// the cookie only carries a signed reference to the session. When panel roles
+// are enabled, the identity must resolve to a role. With secondFactor set and
+// TOTP enabled, the session waits for a code or enrollment before it is usable.
+func (h *AuthHandler) startSession(c *gin.Context, sess *session.Session, creds session.Credentials, identity rbac.Identity, secondFactor bool) error {
+	if h.roles.Enabled() {
+		sess.Role = h.roles.Resolve(identity)
+		if sess.Role == "" {
+			return errNoRole
+		}
+	}
+
+	if secondFactor && h.mfa != nil {
+		switch {
+		case h.mfa.Enrolled(sess.Username):
+			sess.MFAState = session.MFAPending
+		case h.mfaEnforce.Requires(sess.Role):
+			sess.MFAState = session.MFAEnroll
+		}
+	}
+
+	sess.ClientIP = c.ClientIP()
+	sess.UserAgent = c.Request.UserAgent()
 
-	// Set cookie
-	c.SetCookie("token", token, 3600, "/", "", false, true)
+	sess, err := h.sessions.Create(sess, creds)
+	if err != nil {
+		return err
+	}
 
-	log.Printf("[DEBUG] Login successful for user '%s', redirecting to dashboard", loginData.Username)
-	c.Redirect(http.StatusFound, "/dashboard")
+	// Set a cookie with a JWT referencing the session
+	if err := middleware.IssueSessionCookie(c, h.keys, sess); err != nil {
+		_ = h.sessions.Delete(sess.ID)
+		return err
+	}
+
+	log.Printf("[DEBUG] JWT token generated successfully for user '%s', session expires %s", sess.Username, sess.ExpiresAt.Format(time.RFC3339))
+
+	// Issue a fresh CSRF token for the new session
+	if _, err := middleware.RotateCSRFToken(c); err != nil {
+		log.Printf("[DEBUG] Failed to rotate CSRF token on login: %v", err)
+	}
+	return nil
+}
+
+// finishLogin sends the user on to the page they came from or the dashboard,
+// or to the second factor when the session still needs one. Throttling
+// counters are only cleared once the login is complete so codes cannot be
+// guessed by logging in again.
+func (h *AuthHandler) finishLogin(c *gin.Context, sess *session.Session) {
+	if sess.MFAState != "" {
+		log.Printf("[DEBUG] User '%s' must complete second factor (%s)", sess.Username, sess.MFAState)
+		c.Redirect(http.StatusFound, "/login/mfa")
+		return
+	}
+
+	h.limiter.Success(c.ClientIP(), sess.Username)
+	redirectAfterLogin(c)
+}
+
+// loginNextMaxAge is how long the return URL is kept while the user signs in
+const loginNextMaxAge = 15 * time.Minute
+
+// redirectAfterLogin sends a signed-in user back to the page their previous
+// session expired on, or to the dashboard
+func redirectAfterLogin(c *gin.Context) {
+	c.Redirect(http.StatusFound, afterLoginURL(c))
+}
+
+// afterLoginURL returns and forgets the remembered return URL
+func afterLoginURL(c *gin.Context) string {
+	next, err := c.Cookie(loginNextCookie)
+	if err != nil {
+		return "/dashboard"
+	}
+	middleware.SetCookie(c, loginNextCookie, "", -1, "/", true)
+	if next = middleware.SafeReturnPath(next); next == "" {
+		return "/dashboard"
+	}
+	return next
+}
+
+// SessionStatus handles GET /api/session, reporting when the session expires
+// without counting as activity
+func (h *AuthHandler) SessionStatus(c *gin.Context) {
+	sess, err := middleware.CurrentSession(c, h.keys, h.sessions)
+	if err != nil {
+		middleware.RejectSession(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sessionExpiry(sess))
+}
+
+// RefreshSession handles POST /api/session/refresh. AuthRequired has already
+// extended the session; this reports the new expiry.
+func (h *AuthHandler) RefreshSession(c *gin.Context) {
+	sess := c.MustGet("session").(*session.Session)
+	c.JSON(http.StatusOK, sessionExpiry(sess))
+}
+
+// sessionExpiry describes when a session expires
+func sessionExpiry(sess *session.Session) gin.H {
+	data := gin.H{"expires_at": sess.ExpiresAt}
+	if !sess.AbsoluteExpiresAt.IsZero() {
+		data["absolute_expires_at"] = sess.AbsoluteExpiresAt
+	}
+	return data
+}
+
+// userGroups returns the MinIO groups of a user for role resolution. Identities
+// that are not IAM users, such as the root user, have no groups.
+func (h *AuthHandler) userGroups(ctx context.Context, accessKey, secretKey string) []string {
+	if !h.roles.Enabled() {
+		return nil
+	}
+
+	userInfo, err := h.minioService.GetUser(ctx, accessKey, accessKey, secretKey)
+	if err != nil {
+		log.Printf("[DEBUG] Could not look up groups for user '%s': %v", accessKey, err)
+		return nil
+	}
+	return userInfo.MemberOf
+}
+
+// auditLogin records a login attempt in the audit log
+func (h *AuthHandler) auditLogin(c *gin.Context, username, method string, err error) {
+	rec := audit.Record{
+		Actor:    username,
+		ClientIP: c.ClientIP(),
+		Action:   "auth.login",
+		Target:   username,
+		Details:  map[string]interface{}{"method": method},
+		Result:   audit.ResultSuccess,
+	}
+	if err != nil {
+		rec.Result = audit.ResultFailure
+		rec.Details["error"] = err.Error()
+	}
+	h.audit.Log(rec)
+}
+
+// loginBlocked rejects a throttled login attempt without contacting MinIO
+func (h *AuthHandler) loginBlocked(c *gin.Context, username string, wait time.Duration) {
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	h.audit.Log(audit.Record{
+		Actor:    username,
+		ClientIP: c.ClientIP(),
+		Action:   "auth.login_blocked",
+		Target:   username,
+		Details:  map[string]interface{}{"retry_after": retryAfter},
+		Result:   audit.ResultFailure,
+		Status:   http.StatusTooManyRequests,
+	})
+
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.Status(http.StatusTooManyRequests)
+	h.renderLogin(c, "login.error.too_many_attempts")
+}
+
+// loginFailed counts a failed credential check towards back-off and lockout
+func (h *AuthHandler) loginFailed(c *gin.Context, username string) {
+	if !h.limiter.Failure(c.ClientIP(), username) {
+		return
+	}
+	h.audit.Log(audit.Record{
+		Actor:    username,
+		ClientIP: c.ClientIP(),
+		Action:   "auth.lockout",
+		Target:   username,
+		Result:   audit.ResultFailure,
+	})
+}
+
+// LoginLimiterStatus handles GET /api/login-limiter, returning throttling
+// counters and the IPs and usernames that are currently blocked
+func (h *AuthHandler) LoginLimiterStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"stats": h.limiter.Stats(),
+		"locks": h.limiter.Locks(),
+	})
+}
+
+// minioUnavailable shows the login page with a notice when MinIO did not
+// answer or could not be reached, so the attempt does not count as a failed login
+func (h *AuthHandler) minioUnavailable(c *gin.Context, err error) bool {
+	switch {
+	case services.IsTimeout(err):
+		c.Status(http.StatusGatewayTimeout)
+		h.renderLogin(c, "errors.minio_timeout")
+	case errors.Is(services.Classify(err), services.ErrUnavailable):
+		c.Status(http.StatusServiceUnavailable)
+		h.renderLogin(c, "errors.minio_unavailable")
+	default:
+		return false
+	}
+	return true
+}
+
+// sessionErrorKey returns the login error shown when starting a session fails
+func sessionErrorKey(err error) string {
+	if errors.Is(err, errNoRole) {
+		return "login.error.no_role"
+	}
+	return "login.error.token_generation"
 }
 
 // Logout handles user logout
 func (h *AuthHandler) Logout(c *gin.Context) {
-	c.SetCookie("token", "", -1, "/", "", false, true)
+	if tokenString, err := c.Cookie("token"); err == nil && tokenString != "" {
+		if sessionID := middleware.SessionIDFromToken(h.keys, tokenString); sessionID != "" {
+			if sess, err := h.sessions.Get(sessionID); err == nil {
+				h.audit.Log(audit.Record{
+					Actor:    sess.Username,
+					ClientIP: c.ClientIP(),
+					Action:   "auth.logout",
+					Target:   sess.Username,
+					Result:   audit.ResultSuccess,
+				})
+			}
+			if err := h.sessions.Delete(sessionID); err != nil {
+				log.Printf("[DEBUG] Failed to delete session on logout: %v", err)
+			}
+		}
+	}
+
+	middleware.SetCookie(c, "token", "", -1, "/", true)
+	if _, err := middleware.RotateCSRFToken(c); err != nil {
+		log.Printf("[DEBUG] Failed to rotate CSRF token on logout: %v", err)
+	}
 	c.Redirect(http.StatusFound, "/")
 }
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"minio-admin-panel/internal/middleware"
+	"minio-admin-panel/internal/oidc"
+	"minio-admin-panel/internal/rbac"
+	"minio-admin-panel/internal/services"
+	"minio-admin-panel/internal/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oidcStateCookie = "oidc_state"
+	oidcStateMaxAge = 600 // seconds allowed to complete the IdP login
+	stsDuration     = time.Hour
+)
+
+// OIDCLogin handles GET /auth/oidc/login by redirecting to the identity provider
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	if h.oidc == nil {
+		c.Redirect(http.StatusFound, "/")
+		return
+	}
+	if !h.loginCluster(c, c.Query("cluster")) {
+		return
+	}
+
+	state, err1 := oidc.RandomString()
+	nonce, err2 := oidc.RandomString()
+	verifier, err3 := oidc.RandomString()
+	if err1 != nil || err2 != nil || err3 != nil {
+		log.Printf("[DEBUG] Failed to generate OIDC state values")
+		h.renderLogin(c, "login.error.sso_failed")
+		return
+	}
+
+	authURL, err := h.oidc.AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to build OIDC authorization URL: %v", err)
+		h.renderLogin(c, "login.error.sso_failed")
+		return
+	}
+
+	// Remember state, nonce, PKCE verifier and cluster until the IdP redirects back.
+	// The callback is a cross-site navigation, so the cookie must not be SameSite=Strict.
+	c.SetSameSite(http.SameSiteLaxMode)
+	middleware.SetCookie(c, oidcStateCookie, strings.Join([]string{state, nonce, verifier, c.GetString("cluster")}, "."), oidcStateMaxAge, "/auth/oidc", true)
+
+	log.Printf("[DEBUG] Redirecting to OIDC provider for login from IP %s", c.ClientIP())
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback handles GET /auth/oidc/callback, exchanging the authorization
+// code for an ID token and the ID token for MinIO STS credentials
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	if h.oidc == nil {
+		c.Redirect(http.StatusFound, "/")
+		return
+	}
+
+	stateCookie, _ := c.Cookie(oidcStateCookie)
+	middleware.SetCookie(c, oidcStateCookie, "", -1, "/auth/oidc", true)
+
+	if errParam := c.Query("error"); errParam != "" {
+		log.Printf("[DEBUG] OIDC provider returned error '%s': %s", errParam, c.Query("error_description"))
+		h.renderLogin(c, "login.error.sso_failed")
+		return
+	}
+
+	parts := strings.Split(stateCookie, ".")
+	if len(parts) != 4 || subtle.ConstantTimeCompare([]byte(parts[0]), []byte(c.Query("state"))) != 1 {
+		log.Printf("[DEBUG] OIDC state mismatch for callback from IP %s", c.ClientIP())
+		h.renderLogin(c, "login.error.sso_state")
+		return
+	}
+	nonce, verifier := parts[1], parts[2]
+	if !h.loginCluster(c, parts[3]) {
+		return
+	}
+
+	token, err := h.oidc.Exchange(c.Request.Context(), c.Query("code"), verifier)
+	if err != nil {
+		log.Printf("[DEBUG] OIDC code exchange failed: %v", err)
+		h.renderLogin(c, "login.error.sso_failed")
+		return
+	}
+
+	claims, err := h.oidc.VerifyIDToken(c.Request.Context(), token.IDToken, nonce)
+	if err != nil {
+		log.Printf("[DEBUG] OIDC ID token verification failed: %v", err)
+		h.renderLogin(c, "login.error.sso_failed")
+		return
+	}
+
+	displayName := claims.Username(h.oidc.UsernameClaim())
+	roleARN := h.oidc.RoleARN(claims)
+	log.Printf("[DEBUG] OIDC login for '%s' (role ARN: '%s')", displayName, roleARN)
+
+	stsCreds, err := h.minioService.AssumeRoleWithWebIdentity(minioContext(c), token.IDToken, roleARN, stsDuration)
+	if err != nil {
+		log.Printf("[DEBUG] STS exchange failed for '%s': %v", displayName, err)
+		h.auditLogin(c, displayName, "oidc", err)
+		h.renderLogin(c, "login.error.sso_failed")
+		return
+	}
+
+	ctx := services.WithSessionToken(minioContext(c), stsCreds.SessionToken)
+	userInfo, err := h.minioService.ValidateCredentials(ctx, stsCreds.AccessKey, stsCreds.SecretKey)
+	if err != nil {
+		log.Printf("[DEBUG] SSO user '%s' lacks panel access: %v", displayName, err)
+		h.auditLogin(c, displayName, "oidc", err)
+		if h.minioUnavailable(c, err) {
+			return
+		}
+		h.renderLogin(c, "login.error.invalid_credentials")
+		return
+	}
+
+	permissions := h.minioService.GetUserPermissions(ctx, stsCreds.AccessKey, stsCreds.SecretKey)
+	log.Printf("[DEBUG] Retrieved permissions for SSO user '%s': %+v", displayName, permissions.Flags)
+
+	err = h.startSession(c, &session.Session{
+		Username:    displayName,
+		Cluster:     c.GetString("cluster"),
+		PolicyName:  userInfo.PolicyName,
+		Permissions: permissions.Flags,
+		Policy:      permissions.PolicyJSON(),
+	}, session.Credentials{
+		Cluster:      c.GetString("cluster"),
+		AccessKey:    stsCreds.AccessKey,
+		SecretKey:    stsCreds.SecretKey,
+		SessionToken: stsCreds.SessionToken,
+		Expiration:   stsCreds.Expiration,
+	}, rbac.Identity{
+		Username: displayName,
+		Groups:   claims.Strings(h.oidc.RoleClaim()),
+		Claims:   claims.All(),
+	}, false)
+	if err != nil {
+		log.Printf("[DEBUG] Session creation failed for SSO user '%s': %v", displayName, err)
+		h.auditLogin(c, displayName, "oidc", err)
+		h.renderLogin(c, sessionErrorKey(err))
+		return
+	}
+
+	h.auditLogin(c, displayName, "oidc", nil)
+	log.Printf("[DEBUG] SSO login successful for '%s'", displayName)
+	redirectAfterLogin(c)
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"context"
+	"html"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"minio-admin-panel/internal/cluster"
+	"minio-admin-panel/internal/config"
+	"minio-admin-panel/internal/i18n"
+	"minio-admin-panel/internal/middleware"
+	"minio-admin-panel/internal/oidc"
+	"minio-admin-panel/internal/oidc/oidctest"
+	"minio-admin-panel/internal/services"
+	"minio-admin-panel/internal/session"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// stsBackend hands out the credentials of a MinIO user for any ID token,
+// standing in for MinIO's AssumeRoleWithWebIdentity
+type stsBackend struct {
+	services.Backend
+	accessKey, secretKey string
+	roleARN              *string
+}
+
+func (b stsBackend) AssumeRoleWithWebIdentity(ctx context.Context, idToken, roleARN string, duration time.Duration) (*services.STSCredentials, error) {
+	*b.roleARN = roleARN
+	return &services.STSCredentials{
+		AccessKey:    b.accessKey,
+		SecretKey:    b.secretKey,
+		SessionToken: "sts-" + idToken[:8],
+		Expiration:   time.Now().Add(duration),
+	}, nil
+}
+
+// oidcServer serves the OpenID Connect login routes of main.go against a
+// mock identity provider
+type oidcServer struct {
+	router   *gin.Engine
+	idp      *oidctest.IdP
+	sessions *session.Manager
+	roleARN  string
+}
+
+func newOIDCServer(t *testing.T) *oidcServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	idp, err := oidctest.NewIdP("panel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(idp.Close)
+
+	s := &oidcServer{idp: idp}
+	backend := services.NewMemoryBackend(testRootUser, testRootPassword)
+	ctx := context.Background()
+	if err := backend.CreateUser(ctx, "sso-alice", "ssosecret", testRootUser, testRootPassword); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.SetUserPolicy(ctx, "sso-alice", "readwrite", testRootUser, testRootPassword); err != nil {
+		t.Fatal(err)
+	}
+	registry := cluster.Single(&config.Config{MinIOHost: "localhost", MinIOPort: 9000}, func(*config.Config) services.Backend {
+		return stsBackend{Backend: backend, accessKey: "sso-alice", secretKey: "ssosecret", roleARN: &s.roleARN}
+	})
+	SetClusters(registry)
+
+	cipher, err := session.NewCipher("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.sessions = session.NewManager(session.NewMemoryStore(), cipher, time.Hour, 0)
+	keys, err := middleware.NewKeyRing("test-secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:      idp.URL,
+		ClientID:    "panel",
+		RedirectURL: "http://panel.example.com/auth/oidc/callback",
+		RoleMapping: oidc.RoleMapping{
+			Claim: "groups",
+			Roles: map[string]string{"admins": "arn:minio:iam:::role/admins"},
+		},
+	})
+	authHandler := NewAuthHandler(registry.Backend(), s.sessions, keys, provider, nil, nil, nil)
+
+	s.router = gin.New()
+	s.router.SetHTMLTemplate(template.Must(template.New("login.html").Parse(`{{.error}}`)))
+	s.router.GET("/auth/oidc/login", authHandler.OIDCLogin)
+	s.router.GET("/auth/oidc/callback", authHandler.OIDCCallback)
+	return s
+}
+
+// get sends a GET request with the given cookies
+func (s *oidcServer) get(target string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// responseCookie returns the cookie a response sets, or nil
+func responseCookie(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestOIDCLogin(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims // claims the IdP puts into the ID token
+		// tamper changes the state cookie parts (state, nonce, PKCE verifier,
+		// cluster) and the callback query before the callback is sent
+		tamper  func(parts []string, query url.Values) []string
+		wantErr string // login error shown, "" if the login succeeds
+	}{
+		{name: "valid"},
+		{
+			name: "state mismatch",
+			tamper: func(parts []string, query url.Values) []string {
+				query.Set("state", "forged")
+				return parts
+			},
+			wantErr: "login.error.sso_state",
+		},
+		{
+			name: "missing state cookie",
+			tamper: func(parts []string, query url.Values) []string {
+				return nil
+			},
+			wantErr: "login.error.sso_state",
+		},
+		{
+			name: "PKCE verifier mismatch",
+			tamper: func(parts []string, query url.Values) []string {
+				parts[2] = "forged-verifier"
+				return parts
+			},
+			wantErr: "login.error.sso_failed",
+		},
+		{
+			name: "nonce mismatch",
+			tamper: func(parts []string, query url.Values) []string {
+				parts[1] = "forged-nonce"
+				return parts
+			},
+			wantErr: "login.error.sso_failed",
+		},
+		{name: "replayed ID token nonce", claims: jwt.MapClaims{"nonce": "old-nonce"}, wantErr: "login.error.sso_failed"},
+		{name: "wrong issuer", claims: jwt.MapClaims{"iss": "https://evil.example.com"}, wantErr: "login.error.sso_failed"},
+		{name: "wrong audience", claims: jwt.MapClaims{"aud": "other-client"}, wantErr: "login.error.sso_failed"},
+		{name: "expired ID token", claims: jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}, wantErr: "login.error.sso_failed"},
+		{
+			name: "error from the IdP",
+			tamper: func(parts []string, query url.Values) []string {
+				query.Del("code")
+				query.Set("error", "access_denied")
+				return parts
+			},
+			wantErr: "login.error.sso_failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newOIDCServer(t)
+			for name, value := range tt.claims {
+				s.idp.Claims[name] = value
+			}
+
+			w := s.get("/auth/oidc/login")
+			if w.Code != http.StatusFound {
+				t.Fatalf("login: got status %d, want %d", w.Code, http.StatusFound)
+			}
+			stateCookie := responseCookie(w, oidcStateCookie)
+			if stateCookie == nil || stateCookie.Path != "/auth/oidc" || !stateCookie.HttpOnly {
+				t.Fatalf("login set state cookie %+v", stateCookie)
+			}
+			callback, err := s.idp.Authorize(w.Header().Get("Location"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parts := strings.Split(stateCookie.Value, ".")
+			query := callback.Query()
+			if tt.tamper != nil {
+				parts = tt.tamper(parts, query)
+			}
+			var cookies []*http.Cookie
+			if parts != nil {
+				cookies = append(cookies, &http.Cookie{Name: oidcStateCookie, Value: strings.Join(parts, ".")})
+			}
+			w = s.get(callback.Path+"?"+query.Encode(), cookies...)
+
+			if cleared := responseCookie(w, oidcStateCookie); cleared == nil || cleared.MaxAge >= 0 {
+				t.Error("callback did not clear the state cookie")
+			}
+			list, err := s.sessions.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantErr != "" {
+				if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), html.EscapeString(i18n.T("en", tt.wantErr))) {
+					t.Fatalf("callback: got status %d (%s), want the %s login error", w.Code, w.Body.String(), tt.wantErr)
+				}
+				if len(list) != 0 || responseCookie(w, "token") != nil {
+					t.Fatal("session started after a failed login")
+				}
+				return
+			}
+
+			if w.Code != http.StatusFound || w.Header().Get("Location") != "/dashboard" {
+				t.Fatalf("callback: got status %d to %q (%s), want a redirect to the dashboard", w.Code, w.Header().Get("Location"), w.Body.String())
+			}
+			if responseCookie(w, "token") == nil {
+				t.Fatal("no session cookie set")
+			}
+			if len(list) != 1 || list[0].Username != "alice" || list[0].Cluster != cluster.DefaultName {
+				t.Fatalf("sessions after login: %+v, want one of alice on the default cluster", list)
+			}
+			creds, err := s.sessions.Credentials(list[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if creds.AccessKey != "sso-alice" || creds.SessionToken == "" {
+				t.Errorf("session holds credentials of %q with session token %q, want the STS credentials", creds.AccessKey, creds.SessionToken)
+			}
+			if s.roleARN != "arn:minio:iam:::role/admins" {
+				t.Errorf("assumed role %q, want the role mapped to the admins group", s.roleARN)
+			}
+		})
+	}
+}
+
+func TestOIDCLoginUnknownCluster(t *testing.T) {
+	s := newOIDCServer(t)
+	w := s.get("/auth/oidc/login?cluster=other")
+	if w.Code != http.StatusBadRequest || responseCookie(w, oidcStateCookie) != nil {
+		t.Fatalf("got status %d, want %d without a state cookie", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"minio-admin-panel/internal/cluster"
+	"minio-admin-panel/internal/config"
+	"minio-admin-panel/internal/loginlimit"
+	"minio-admin-panel/internal/middleware"
+	"minio-admin-panel/internal/services"
+	"minio-admin-panel/internal/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLoginThrottling(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	backend := services.NewMemoryBackend(testRootUser, testRootPassword)
+	registry := cluster.Single(&config.Config{MinIOHost: "localhost", MinIOPort: 9000}, func(*config.Config) services.Backend {
+		return backend
+	})
+	SetClusters(registry)
+
+	cipher, err := session.NewCipher("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions := session.NewManager(session.NewMemoryStore(), cipher, time.Hour, 0)
+	keys, err := middleware.NewKeyRing("test-secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter, err := loginlimit.New(loginlimit.Config{MaxAttempts: 2, Lockout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewAuthHandler(registry.Backend(), sessions, keys, nil, nil, nil, limiter)
+
+	r := gin.New()
+	r.SetHTMLTemplate(template.Must(template.New("login.html").Parse(`{{.error}}`)))
+	r.POST("/login", h.Login)
+
+	tests := []struct {
+		name     string
+		password string
+		status   int
+		blocked  bool
+	}{
+		{"first wrong password", "guess-1", http.StatusOK, false},
+		{"second wrong password locks out", "guess-2", http.StatusOK, false},
+		{"right password while locked out", testRootPassword, http.StatusTooManyRequests, true},
+	}
+	for _, tt := range tests {
+		form := url.Values{"username": {testRootUser}, "password": {tt.password}}
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Fatalf("%s: got status %d, want %d (%s)", tt.name, w.Code, tt.status, w.Body.String())
+		}
+		if blocked := w.Header().Get("Retry-After") != ""; blocked != tt.blocked {
+			t.Fatalf("%s: Retry-After header set: %t, want %t", tt.name, blocked, tt.blocked)
+		}
+	}
+}
@@ -5,19 +5,32 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
+	"sync"
 
+	"minio-admin-panel/internal/bucketstats"
+	"minio-admin-panel/internal/middleware"
 	"minio-admin-panel/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
+// versioningOff is shown for buckets that never had versioning enabled
+const versioningOff = "Off"
+
+// settingsLookups bounds the versioning and object lock requests made for
+// the bucket list
+const settingsLookups = 8
+
 type BucketHandler struct {
-	minioService *services.MinIOService
+	minioService services.BucketBackend
+	stats        *bucketstats.Worker
 }
 
-func NewBucketHandler(minioService *services.MinIOService) *BucketHandler {
+func NewBucketHandler(minioService services.BucketBackend, stats *bucketstats.Worker) *BucketHandler {
 	return &BucketHandler{
 		minioService: minioService,
+		stats:        stats,
 	}
 }
 
@@ -47,19 +60,30 @@ func (h *BucketHandler) ListBuckets(c *gin.Context) {
 	}
 
 	log.Printf("[DEBUG] ListBuckets for user '%s'", username)
-	buckets, err := h.minioService.ListBuckets(context.Background(), username, password)
+	ctx := minioContextFor(c, services.OpList)
+	buckets, err := h.minioService.ListBucketsQuick(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] ListBuckets failed for user '%s': %v", username, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
 		return
 	}
+	h.applyBucketSettings(ctx, c, buckets, username, password)
 
 	log.Printf("[DEBUG] ListBuckets successful for user '%s', found %d buckets", username, len(buckets))
 
+	// Statistics come from the background worker; start it if they are outdated
+	cluster := c.GetString("cluster")
+	h.applyCachedStats(cluster, buckets)
+	if h.stats.Stale(cluster, bucketNames(buckets)) {
+		log.Printf("[DEBUG] Bucket statistics of cluster '%s' are outdated, refreshing", cluster)
+		h.stats.Refresh(requestCredentials(c), false)
+	}
+	status := h.stats.Status(cluster)
+
 	// Check if this is an API request
 	if c.GetHeader("Accept") == "application/json" {
 		log.Printf("[DEBUG] Returning JSON response with %d buckets", len(buckets))
-		c.JSON(http.StatusOK, gin.H{"buckets": buckets})
+		c.JSON(http.StatusOK, gin.H{"buckets": buckets, "stats": status})
 		return
 	}
 
@@ -70,12 +94,138 @@ func (h *BucketHandler) ListBuckets(c *gin.Context) {
 	RenderWithTranslations(c, "buckets.html", gin.H{
 		"title":       "buckets.title",
 		"buckets":     buckets,
+		"stats":       status,
+		"maxPrefixes": services.MaxBucketPrefixes,
 		"permissions": permissions,
 		"username":    username,
 		"policy_name": policyName,
 	})
 }
 
+// applyCachedStats fills in the cached statistics of the buckets
+func (h *BucketHandler) applyCachedStats(cluster string, buckets []services.BucketInfo) {
+	for i := range buckets {
+		stats, ok := h.stats.Get(cluster, buckets[i].Name)
+		if !ok {
+			continue
+		}
+		buckets[i].Size = stats.Size
+		buckets[i].ObjectCount = stats.ObjectCount
+		buckets[i].VersionCount = stats.VersionCount
+		buckets[i].StatsSource = stats.Source
+		updatedAt := stats.UpdatedAt
+		if stats.ScannedAt != nil {
+			updatedAt = *stats.ScannedAt
+		}
+		buckets[i].StatsUpdatedAt = &updatedAt
+	}
+}
+
+// applyBucketSettings fills in the versioning state and object locking of
+// the buckets whose configuration the user may read, fetching them
+// concurrently
+func (h *BucketHandler) applyBucketSettings(ctx context.Context, c *gin.Context, buckets []services.BucketInfo, username, password string) {
+	access := middleware.GetAccess(c)
+	sem := make(chan struct{}, settingsLookups)
+	var wg sync.WaitGroup
+	for i := range buckets {
+		readVersioning := access.CanBucket("s3:GetBucketVersioning", buckets[i].Name)
+		readObjectLock := access.CanBucket("s3:GetBucketObjectLockConfiguration", buckets[i].Name)
+		if !readVersioning && !readObjectLock {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(bucket *services.BucketInfo) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if readVersioning {
+				versioning, err := h.minioService.GetBucketVersioning(ctx, bucket.Name, username, password)
+				if err != nil {
+					log.Printf("[DEBUG] Versioning of bucket '%s' unavailable: %v", bucket.Name, err)
+				} else if bucket.Versioning = versioning.Status; bucket.Versioning == "" {
+					bucket.Versioning = versioningOff
+				}
+			}
+			if readObjectLock {
+				lock, err := h.minioService.GetBucketObjectLock(ctx, bucket.Name, username, password)
+				if err != nil {
+					log.Printf("[DEBUG] Object lock of bucket '%s' unavailable: %v", bucket.Name, err)
+				} else {
+					bucket.ObjectLocking = lock.Enabled
+				}
+			}
+		}(&buckets[i])
+	}
+	wg.Wait()
+}
+
+// bucketNames returns the names of the buckets
+func bucketNames(buckets []services.BucketInfo) []string {
+	names := make([]string, 0, len(buckets))
+	for _, bucket := range buckets {
+		names = append(names, bucket.Name)
+	}
+	return names
+}
+
+// GetBucketStats handles GET /api/bucket-stats. It returns the refresh state
+// and the cached statistics of the buckets the user can list, or of the one
+// named by ?bucket=. Prefix breakdowns are only included for buckets whose
+// objects the user may list.
+func (h *BucketHandler) GetBucketStats(c *gin.Context) {
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	buckets, err := h.minioService.ListBucketsQuick(minioContextFor(c, services.OpList), username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Listing buckets for statistics failed for user '%s': %v", username, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	cluster := c.GetString("cluster")
+	access := middleware.GetAccess(c)
+	only := c.Query("bucket")
+	result := make([]bucketstats.Stats, 0, len(buckets))
+	found := false
+	for _, bucket := range buckets {
+		if only != "" && bucket.Name != only {
+			continue
+		}
+		found = true
+		stats, ok := h.stats.Get(cluster, bucket.Name)
+		if !ok {
+			continue
+		}
+		if !access.CanBucket("s3:ListBucket", bucket.Name) {
+			stats.Prefixes = nil
+		}
+		result = append(result, stats)
+	}
+	if only != "" && !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "errors.not_found")})
+		return
+	}
+
+	log.Printf("[DEBUG] Returning cached statistics of %d buckets on cluster '%s'", len(result), cluster)
+	c.JSON(http.StatusOK, gin.H{"status": h.stats.Status(cluster), "buckets": result})
+}
+
+// RefreshBucketStats handles POST /api/bucket-stats/refresh, measuring all
+// buckets the user can list again in the background
+func (h *BucketHandler) RefreshBucketStats(c *gin.Context) {
+	creds := requestCredentials(c)
+	started := h.stats.Refresh(creds, true)
+	log.Printf("[DEBUG] Bucket statistics refresh of cluster '%s' requested by '%s' (started: %t)", creds.Cluster, creds.AccessKey, started)
+	c.JSON(http.StatusAccepted, gin.H{"started": started, "status": h.stats.Status(creds.Cluster)})
+}
+
 // CreateBucket handles POST /buckets
 func (h *BucketHandler) CreateBucket(c *gin.Context) {
 	username, password, err := h.getCredentials(c)
@@ -85,16 +235,27 @@ func (h *BucketHandler) CreateBucket(c *gin.Context) {
 	}
 
 	var req struct {
-		Name string `form:"name" json:"name" binding:"required"`
+		Name          string `form:"name" json:"name" binding:"required"`
+		ObjectLocking bool   `form:"object_locking" json:"object_locking"`
+		// Object locking cannot be undone, so it must be confirmed explicitly
+		ConfirmObjectLocking bool `form:"confirm_object_locking" json:"confirm_object_locking"`
 	}
 
 	if err := c.ShouldBind(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Bucket name is required"})
 		return
 	}
+	middleware.SetAuditTarget(c, req.Name)
+
+	if req.ObjectLocking && !req.ConfirmObjectLocking {
+		log.Printf("[DEBUG] Object locking of bucket '%s' requested without confirmation", req.Name)
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "buckets.object_lock.error.confirm")})
+		return
+	}
 
-	if err := h.minioService.CreateBucket(context.Background(), req.Name, username, password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	opts := services.BucketOptions{ObjectLocking: req.ObjectLocking}
+	if err := h.minioService.CreateBucket(minioContext(c), req.Name, opts, username, password); err != nil {
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -111,10 +272,11 @@ func (h *BucketHandler) DeleteBucket(c *gin.Context) {
 
 	bucketName := c.Param("name")
 
-	if err := h.minioService.DeleteBucket(context.Background(), bucketName, username, password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.minioService.DeleteBucket(minioContext(c), bucketName, username, password); err != nil {
+		respondMinIOError(c, err)
 		return
 	}
+	h.stats.Forget(c.GetString("cluster"), bucketName)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Bucket deleted successfully"})
 }
@@ -132,10 +294,10 @@ func (h *BucketHandler) GetBucketPolicy(c *gin.Context) {
 	}
 
 	log.Printf("[DEBUG] Getting policy for bucket '%s' by user '%s'", bucketName, username)
-	policy, err := h.minioService.GetBucketPolicy(context.Background(), bucketName, username, password)
+	policy, err := h.minioService.GetBucketPolicy(minioContext(c), bucketName, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] GetBucketPolicy failed for bucket '%s': %v", bucketName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -143,6 +305,187 @@ func (h *BucketHandler) GetBucketPolicy(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"policy": policy})
 }
 
+// GetBucketVersioning handles GET /buckets/:name/versioning
+func (h *BucketHandler) GetBucketVersioning(c *gin.Context) {
+	bucketName := c.Param("name")
+	log.Printf("[DEBUG] GetBucketVersioning request for bucket '%s'", bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in GetBucketVersioning: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	versioning, err := h.minioService.GetBucketVersioning(minioContext(c), bucketName, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] GetBucketVersioning failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"versioning": versioning})
+}
+
+// SetBucketVersioning handles PUT /buckets/:name/versioning
+func (h *BucketHandler) SetBucketVersioning(c *gin.Context) {
+	bucketName := c.Param("name")
+	log.Printf("[DEBUG] SetBucketVersioning request for bucket '%s'", bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in SetBucketVersioning: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	var req services.BucketVersioning
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[DEBUG] Failed to bind request in SetBucketVersioning: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if key := validateVersioning(&req); key != "" {
+		log.Printf("[DEBUG] Rejected versioning configuration for bucket '%s': %s", bucketName, key)
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, key)})
+		return
+	}
+
+	log.Printf("[DEBUG] Setting versioning of bucket '%s' to %q by user '%s' (%d excluded prefixes, exclude folders: %t)",
+		bucketName, req.Status, username, len(req.ExcludedPrefixes), req.ExcludeFolders)
+	if err := h.minioService.SetBucketVersioning(minioContext(c), bucketName, req, username, password); err != nil {
+		log.Printf("[DEBUG] SetBucketVersioning failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "buckets.versioning.updated"), "versioning": req})
+}
+
+// validateVersioning normalizes the excluded prefixes of a versioning
+// configuration and checks it the way MinIO does. It returns the translation
+// key of the first problem, or an empty string.
+func validateVersioning(v *services.BucketVersioning) string {
+	if v.Status != services.VersioningEnabled && v.Status != services.VersioningSuspended {
+		return "buckets.versioning.error.status"
+	}
+
+	prefixes := make([]string, 0, len(v.ExcludedPrefixes))
+	seen := make(map[string]bool)
+	for _, prefix := range v.ExcludedPrefixes {
+		prefix = strings.TrimSpace(prefix)
+		if prefix == "" || seen[prefix] {
+			continue
+		}
+		seen[prefix] = true
+		prefixes = append(prefixes, prefix)
+	}
+	v.ExcludedPrefixes = prefixes
+
+	if v.Status == services.VersioningSuspended && (len(v.ExcludedPrefixes) > 0 || v.ExcludeFolders) {
+		return "buckets.versioning.error.requires_enabled"
+	}
+	if len(v.ExcludedPrefixes) > services.MaxExcludedPrefixes {
+		return "buckets.versioning.error.too_many_prefixes"
+	}
+	return ""
+}
+
+// GetBucketObjectLock handles GET /buckets/:name/object-lock
+func (h *BucketHandler) GetBucketObjectLock(c *gin.Context) {
+	bucketName := c.Param("name")
+	log.Printf("[DEBUG] GetBucketObjectLock request for bucket '%s'", bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in GetBucketObjectLock: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	lock, err := h.minioService.GetBucketObjectLock(minioContext(c), bucketName, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] GetBucketObjectLock failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"object_lock": lock})
+}
+
+// SetBucketRetention handles PUT /buckets/:name/object-lock, setting or
+// removing the default retention of an object lock enabled bucket
+func (h *BucketHandler) SetBucketRetention(c *gin.Context) {
+	bucketName := c.Param("name")
+	log.Printf("[DEBUG] SetBucketRetention request for bucket '%s'", bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in SetBucketRetention: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	var req struct {
+		Mode     string `json:"mode"`
+		Validity uint   `json:"validity"`
+		Unit     string `json:"unit"`
+		// Compliance retention cannot be shortened by anyone, so it must be confirmed
+		Confirm bool `json:"confirm"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[DEBUG] Failed to bind request in SetBucketRetention: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	lock := services.BucketObjectLock{Enabled: true, Mode: strings.ToUpper(req.Mode), Validity: req.Validity, Unit: strings.ToUpper(req.Unit)}
+	if key := validateRetention(&lock); key != "" {
+		log.Printf("[DEBUG] Rejected default retention for bucket '%s': %s", bucketName, key)
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, key)})
+		return
+	}
+	if lock.Mode == services.RetentionCompliance && !req.Confirm {
+		log.Printf("[DEBUG] Compliance retention of bucket '%s' requested without confirmation", bucketName)
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "buckets.object_lock.error.confirm_compliance")})
+		return
+	}
+
+	if before, err := h.minioService.GetBucketObjectLock(minioContext(c), bucketName, username, password); err == nil {
+		middleware.AddAuditDetail(c, "before", gin.H{"mode": before.Mode, "validity": before.Validity, "unit": before.Unit})
+	}
+
+	if err := h.minioService.SetBucketRetention(minioContext(c), bucketName, lock, username, password); err != nil {
+		log.Printf("[DEBUG] SetBucketRetention failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	log.Printf("[DEBUG] Default retention of bucket '%s' set to %s %d %s by user '%s'", bucketName, lock.Mode, lock.Validity, lock.Unit, username)
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "buckets.object_lock.updated"), "object_lock": lock})
+}
+
+// validateRetention checks a default retention. An empty mode removes the
+// default retention and clears the validity. It returns the translation key
+// of the first problem, or an empty string.
+func validateRetention(lock *services.BucketObjectLock) string {
+	switch lock.Mode {
+	case "":
+		lock.Validity, lock.Unit = 0, ""
+		return ""
+	case services.RetentionGovernance, services.RetentionCompliance:
+	default:
+		return "buckets.object_lock.error.mode"
+	}
+	if lock.Unit != services.RetentionDays && lock.Unit != services.RetentionYears {
+		return "buckets.object_lock.error.unit"
+	}
+	if lock.Validity == 0 {
+		return "buckets.object_lock.error.validity"
+	}
+	return ""
+}
+
 // SetBucketPolicy handles PUT /buckets/:name/policy
 func (h *BucketHandler) SetBucketPolicy(c *gin.Context) {
 	bucketName := c.Param("name")
@@ -167,9 +510,9 @@ func (h *BucketHandler) SetBucketPolicy(c *gin.Context) {
 
 	log.Printf("[DEBUG] Setting policy for bucket '%s' by user '%s', policy length: %d", bucketName, username, len(req.Policy))
 
-	if err := h.minioService.SetBucketPolicy(context.Background(), bucketName, req.Policy, username, password); err != nil {
+	if err := h.minioService.SetBucketPolicy(minioContext(c), bucketName, req.Policy, username, password); err != nil {
 		log.Printf("[DEBUG] SetBucketPolicy failed for bucket '%s': %v", bucketName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
 		return
 	}
 
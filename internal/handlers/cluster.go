@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"minio-admin-panel/internal/cluster"
+	"minio-admin-panel/internal/loginlimit"
+	"minio-admin-panel/internal/middleware"
+	"minio-admin-panel/internal/permissions"
+	"minio-admin-panel/internal/rbac"
+	"minio-admin-panel/internal/services"
+	"minio-admin-panel/internal/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+// clusters holds the configured MinIO clusters
+var clusters *cluster.Registry
+
+// SetClusters configures the clusters offered on the login page and in the
+// cluster switcher
+func SetClusters(registry *cluster.Registry) {
+	clusters = registry
+}
+
+// clusterPrefix returns the path prefix of the cluster a page was opened
+// for, or "" for the session's own cluster
+func clusterPrefix(c *gin.Context) string {
+	if c.Param("cluster") == "" {
+		return ""
+	}
+	return "/c/" + c.GetString("cluster")
+}
+
+// ClusterInfo describes a cluster and the caller's access to it
+type ClusterInfo struct {
+	Name        string     `json:"name"`
+	Label       string     `json:"label"`
+	Endpoint    string     `json:"endpoint"`
+	Home        bool       `json:"home"`
+	Current     bool       `json:"current"`
+	Connected   bool       `json:"connected"`
+	AccessKey   string     `json:"access_key,omitempty"`
+	ConnectedAt *time.Time `json:"connected_at,omitempty"`
+	URL         string     `json:"url"`
+}
+
+// LookupResult is the outcome of looking for a resource on one cluster
+type LookupResult struct {
+	Cluster string `json:"cluster"`
+	Label   string `json:"label"`
+	Status  string `json:"status"` // found, not_found, forbidden, not_connected or error
+	Detail  string `json:"detail,omitempty"`
+}
+
+// lookupPermissions maps the kinds of cross-cluster lookups to the
+// permission they need on each cluster
+var lookupPermissions = map[string]string{
+	"user":   "canViewUsers",
+	"group":  "canViewGroups",
+	"policy": "canViewPolicies",
+	"bucket": "canListBuckets",
+}
+
+type ClusterHandler struct {
+	clusters     *cluster.Registry
+	minioService services.Backend
+	sessions     *session.Manager
+	limiter      *loginlimit.Limiter
+}
+
+func NewClusterHandler(clusters *cluster.Registry, minioService services.Backend, sessions *session.Manager, limiter *loginlimit.Limiter) *ClusterHandler {
+	return &ClusterHandler{
+		clusters:     clusters,
+		minioService: minioService,
+		sessions:     sessions,
+		limiter:      limiter,
+	}
+}
+
+// ShowClusters handles GET /clusters
+func (h *ClusterHandler) ShowClusters(c *gin.Context) {
+	data := gin.H{"connect": c.Query("connect"), "next": middleware.SafeReturnPath(c.Query("next"))}
+	// Only show messages this page knows about
+	if key := c.Query("error"); strings.HasPrefix(key, "clusters.error.") {
+		data["error"] = key
+	}
+	if name := c.Query("connected"); name != "" && h.clusters.Get(name) != nil {
+		data["connected"] = h.clusters.Get(name).DisplayName()
+	}
+	h.render(c, data)
+}
+
+// ListClusters handles GET /api/clusters
+func (h *ClusterHandler) ListClusters(c *gin.Context) {
+	list := listClusters(h.clusters, c)
+	c.JSON(http.StatusOK, gin.H{
+		"clusters": list,
+		"current":  c.GetString("cluster"),
+		"count":    len(list),
+	})
+}
+
+// Connect handles POST /clusters/:name/connect, checking the submitted
+// credentials against the cluster and keeping them in the session
+func (h *ClusterHandler) Connect(c *gin.Context) {
+	target := h.clusters.Get(c.Param("name"))
+	if target == nil || c.Param("name") == "" {
+		h.connectFailed(c, http.StatusNotFound, "clusters.error.unknown", "", "")
+		return
+	}
+	if c.GetString("api_token_id") != "" {
+		h.connectFailed(c, http.StatusForbidden, "clusters.error.token_cluster", "", "")
+		return
+	}
+	if target.Name == c.GetString("home_cluster") {
+		h.connectFailed(c, http.StatusBadRequest, "clusters.error.home_cluster", "", "")
+		return
+	}
+
+	next := middleware.SafeReturnPath(c.PostForm("next"))
+	accessKey := strings.TrimSpace(c.PostForm("access_key"))
+	secretKey := c.PostForm("secret_key")
+	if accessKey == "" || secretKey == "" {
+		h.connectFailed(c, http.StatusBadRequest, "clusters.error.missing_credentials", target.Name, next)
+		return
+	}
+	middleware.AddAuditDetail(c, "access_key", accessKey)
+
+	// Connecting checks credentials like a login, so it is throttled like one.
+	// Access keys are counted per cluster, the client IP together with logins.
+	limitKey := target.Name + "/" + accessKey
+	if wait := h.limiter.Check(c.ClientIP(), limitKey); wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		h.connectFailed(c, http.StatusTooManyRequests, "clusters.error.too_many_attempts", target.Name, next)
+		return
+	}
+	defer h.limiter.Release(c.ClientIP(), limitKey)
+
+	sess := c.MustGet("session").(*session.Session)
+	log.Printf("[DEBUG] User '%s' connecting to cluster '%s' as '%s'", sess.Username, target.Name, accessKey)
+
+	ctx := clusterContext(minioContext(c), target.Name, "")
+	userInfo, err := h.minioService.ValidateCredentials(ctx, accessKey, secretKey)
+	if err != nil {
+		log.Printf("[DEBUG] Connecting to cluster '%s' failed for '%s': %v", target.Name, accessKey, err)
+		if services.IsTimeout(err) || errors.Is(services.Classify(err), services.ErrUnavailable) {
+			h.connectFailed(c, http.StatusUnauthorized, "clusters.error.unavailable", target.Name, next)
+			return
+		}
+		if h.limiter.Failure(c.ClientIP(), limitKey) {
+			middleware.AddAuditDetail(c, "lockout", true)
+		}
+		h.connectFailed(c, http.StatusUnauthorized, "clusters.error.invalid_credentials", target.Name, next)
+		return
+	}
+	h.limiter.Success(c.ClientIP(), limitKey)
+
+	perms := h.minioService.GetUserPermissions(ctx, accessKey, secretKey)
+	err = h.sessions.Connect(sess, target.Name, &session.Connection{
+		AccessKey:   accessKey,
+		PolicyName:  userInfo.PolicyName,
+		Permissions: perms.Flags,
+		Policy:      perms.PolicyJSON(),
+	}, session.Credentials{
+		Cluster:   target.Name,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+	})
+	if err != nil {
+		log.Printf("[DEBUG] Failed to store connection to cluster '%s': %v", target.Name, err)
+		h.connectFailed(c, http.StatusInternalServerError, "clusters.error.connect_failed", target.Name, next)
+		return
+	}
+
+	if next == "" {
+		next = "/clusters?connected=" + url.QueryEscape(target.Name)
+	}
+	c.Redirect(http.StatusFound, next)
+}
+
+// Disconnect handles POST /clusters/:name/disconnect
+func (h *ClusterHandler) Disconnect(c *gin.Context) {
+	sess, ok := c.Get("session")
+	if !ok || c.GetString("api_token_id") != "" {
+		h.connectFailed(c, http.StatusForbidden, "clusters.error.token_cluster", "", "")
+		return
+	}
+
+	name := c.Param("name")
+	if err := h.sessions.Disconnect(sess.(*session.Session), name); err != nil {
+		log.Printf("[DEBUG] Failed to disconnect from cluster '%s': %v", name, err)
+		if errors.Is(err, session.ErrNotFound) {
+			h.connectFailed(c, http.StatusNotFound, "clusters.error.not_connected", "", "")
+			return
+		}
+		h.connectFailed(c, http.StatusInternalServerError, "clusters.error.connect_failed", "", "")
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/clusters")
+}
+
+// Lookup handles GET /api/clusters/lookup?kind=user&name=alice, looking for
+// a user, group, policy or bucket on every cluster the caller can reach
+func (h *ClusterHandler) Lookup(c *gin.Context) {
+	kind := c.Query("kind")
+	name := strings.TrimSpace(c.Query("name"))
+	flag, ok := lookupPermissions[kind]
+	if !ok || name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "clusters.error.invalid_lookup")})
+		return
+	}
+
+	log.Printf("[DEBUG] Looking up %s '%s' on %d clusters", kind, name, len(h.clusters.All()))
+
+	base := minioContext(c)
+	all := h.clusters.All()
+	results := make([]LookupResult, len(all))
+	var wg sync.WaitGroup
+	for i, target := range all {
+		results[i] = LookupResult{Cluster: target.Name, Label: target.DisplayName()}
+
+		creds, access, err := h.clusterAccess(c, target.Name)
+		if err != nil {
+			results[i].Status = "not_connected"
+			continue
+		}
+		if !access.Has(flag) {
+			results[i].Status = "forbidden"
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *LookupResult, target *cluster.Cluster, creds session.Credentials) {
+			defer wg.Done()
+			ctx := clusterContext(base, target.Name, creds.SessionToken)
+			h.lookup(ctx, result, kind, name, creds)
+		}(&results[i], target, creds)
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, gin.H{"kind": kind, "name": name, "results": results})
+}
+
+// lookup looks for one resource on one cluster and records the outcome
+func (h *ClusterHandler) lookup(ctx context.Context, result *LookupResult, kind, name string, creds session.Credentials) {
+	var err error
+	switch kind {
+	case "user":
+		var user *services.UserInfo
+		if user, err = h.minioService.GetUser(ctx, name, creds.AccessKey, creds.SecretKey); err == nil {
+			result.Detail = user.Status
+			if user.PolicyName != "" {
+				result.Detail += ", " + user.PolicyName
+			}
+		}
+	case "group":
+		_, err = h.minioService.GetGroupInfo(ctx, name, creds.AccessKey, creds.SecretKey)
+	case "policy":
+		_, err = h.minioService.GetPolicyDocument(ctx, name, creds.AccessKey, creds.SecretKey)
+	case "bucket":
+		var buckets []services.BucketInfo
+		if buckets, err = h.minioService.ListBucketsQuick(ctx, creds.AccessKey, creds.SecretKey); err == nil {
+			err = services.ErrNotFound
+			for _, bucket := range buckets {
+				if bucket.Name == name {
+					err = nil
+					break
+				}
+			}
+		}
+	}
+
+	switch {
+	case err == nil:
+		result.Status = "found"
+	case errors.Is(err, services.ErrNotFound), errors.Is(services.Classify(err), services.ErrNotFound):
+		result.Status = "not_found"
+		result.Detail = ""
+	case errors.Is(services.Classify(err), services.ErrAccessDenied):
+		result.Status = "forbidden"
+	default:
+		log.Printf("[DEBUG] Lookup of %s '%s' on cluster '%s' failed: %v", kind, name, result.Cluster, err)
+		result.Status = "error"
+		result.Detail = err.Error()
+	}
+}
+
+// clusterAccess returns the credentials and permissions the caller acts with
+// on a cluster: those of the request on its own cluster, those of the
+// session's connection on any other
+func (h *ClusterHandler) clusterAccess(c *gin.Context, name string) (session.Credentials, *permissions.Set, error) {
+	if name == c.GetString("home_cluster") {
+		return session.Credentials{
+			Cluster:      name,
+			AccessKey:    c.GetString("username"),
+			SecretKey:    c.GetString("password"),
+			SessionToken: c.GetString("session_token"),
+		}, middleware.GetAccess(c), nil
+	}
+
+	value, ok := c.Get("session")
+	if !ok {
+		return session.Credentials{}, nil, session.ErrNotFound
+	}
+	sess := value.(*session.Session)
+	creds, err := h.sessions.ConnectionCredentials(sess, name)
+	if err != nil {
+		return creds, nil, err
+	}
+	conn := sess.Connections[name]
+	access := permissions.Restore(conn.Permissions, conn.Policy)
+	if role, ok := c.Get("role"); ok {
+		access = access.Limit(role.(*rbac.Role))
+	}
+	return creds, access, nil
+}
+
+// clusterContext derives the context for MinIO calls on another cluster from
+// the MinIO context of a request
+func clusterContext(ctx context.Context, name, sessionToken string) context.Context {
+	ctx = services.WithCluster(ctx, name)
+	return services.WithSessionToken(ctx, sessionToken)
+}
+
+// listClusters describes every cluster with the caller's connection to it
+func listClusters(registry *cluster.Registry, c *gin.Context) []ClusterInfo {
+	var connections map[string]*session.Connection
+	if value, ok := c.Get("session"); ok {
+		connections = value.(*session.Session).Connections
+	}
+
+	home := c.GetString("home_cluster")
+	all := registry.All()
+	list := make([]ClusterInfo, 0, len(all))
+	for _, target := range all {
+		info := ClusterInfo{
+			Name:     target.Name,
+			Label:    target.DisplayName(),
+			Endpoint: target.Endpoint(),
+			Home:     target.Name == home,
+			Current:  target.Name == c.GetString("cluster"),
+			URL:      "/c/" + target.Name + "/dashboard",
+		}
+		if info.Home {
+			info.Connected = true
+			info.URL = "/dashboard"
+		} else if conn, ok := connections[target.Name]; ok {
+			info.Connected = true
+			info.AccessKey = conn.AccessKey
+			connectedAt := conn.ConnectedAt
+			info.ConnectedAt = &connectedAt
+		}
+		list = append(list, info)
+	}
+	return list
+}
+
+// render shows the cluster page with data added to the common page data
+func (h *ClusterHandler) render(c *gin.Context, data gin.H) {
+	data["title"] = "clusters.title"
+	data["clusterList"] = listClusters(h.clusters, c)
+	data["canConnect"] = c.GetString("api_token_id") == ""
+	RenderWithTranslations(c, "clusters.html", data)
+}
+
+// connectFailed answers a failed connect or disconnect with the cluster page,
+// reopening the connect form of connect if given
+func (h *ClusterHandler) connectFailed(c *gin.Context, status int, key, connect, next string) {
+	c.Status(status)
+	h.render(c, gin.H{"error": key, "connect": connect, "next": next})
+}
+
+// clusterMenu lists the clusters of the sidebar switcher
+func clusterMenu(c *gin.Context) []ClusterInfo {
+	if _, ok := c.Get("home_cluster"); !ok {
+		return nil
+	}
+	return listClusters(clusters, c)
+}
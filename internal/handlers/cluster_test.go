@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"minio-admin-panel/internal/cluster"
+	"minio-admin-panel/internal/config"
+	"minio-admin-panel/internal/loginlimit"
+	"minio-admin-panel/internal/services"
+	"minio-admin-panel/internal/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newClusterTestRouter serves the cluster connect route for a session on
+// the primary cluster, with a second cluster that has the user "backup-admin"
+func newClusterTestRouter(t *testing.T, limiter *loginlimit.Limiter) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	path := filepath.Join(t.TempDir(), "clusters.json")
+	file := `{"clusters":[{"name":"primary","host":"primary","port":9000},{"name":"backup","host":"backup","port":9000}]}`
+	if err := os.WriteFile(path, []byte(file), 0600); err != nil {
+		t.Fatal(err)
+	}
+	registry, err := cluster.Load(path, &config.Config{}, func(cfg *config.Config) services.Backend {
+		backend := services.NewMemoryBackend(testRootUser, testRootPassword)
+		if cfg.MinIOHost == "backup" {
+			if err := backend.CreateUser(context.Background(), "backup-admin", "backupsecret", testRootUser, testRootPassword); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return backend
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cipher, err := session.NewCipher("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions := session.NewManager(session.NewMemoryStore(), cipher, time.Hour, 0)
+	sess, err := sessions.Create(&session.Session{Username: "alice", Cluster: "primary"}, session.Credentials{Cluster: "primary", AccessKey: "alice", SecretKey: "alicesecret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := NewClusterHandler(registry, registry.Backend(), sessions, limiter)
+
+	r := gin.New()
+	r.SetHTMLTemplate(template.Must(template.New("clusters.html").Parse(`{{.error}}`)))
+	r.POST("/clusters/:name/connect", func(c *gin.Context) {
+		c.Set("session", sess)
+		c.Set("home_cluster", "primary")
+		c.Set("cluster", "primary")
+	}, handler.Connect)
+	return r
+}
+
+func TestClusterConnectIsThrottled(t *testing.T) {
+	limiter, err := loginlimit.New(loginlimit.Config{MaxAttempts: 3, Lockout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newClusterTestRouter(t, limiter)
+	connect := func(accessKey, secretKey string) *httptest.ResponseRecorder {
+		form := url.Values{"access_key": {accessKey}, "secret_key": {secretKey}}
+		req := httptest.NewRequest(http.MethodPost, "/clusters/backup/connect", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name      string
+		accessKey string
+		secretKey string
+		status    int
+	}{
+		{"first wrong secret", "backup-admin", "guess-1", http.StatusUnauthorized},
+		{"second wrong secret", "backup-admin", "guess-2", http.StatusUnauthorized},
+		{"third wrong secret locks out", "backup-admin", "guess-3", http.StatusUnauthorized},
+		{"right secret while locked out", "backup-admin", "backupsecret", http.StatusTooManyRequests},
+		{"other access key from the same IP", "root", "guess-4", http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		w := connect(tt.accessKey, tt.secretKey)
+		if w.Code != tt.status {
+			t.Fatalf("%s: got status %d, want %d (%s)", tt.name, w.Code, tt.status, w.Body.String())
+		}
+		if tt.status == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+			t.Fatalf("%s: no Retry-After header", tt.name)
+		}
+	}
+
+	locks := limiter.Locks()
+	var keys []string
+	for _, lock := range locks {
+		keys = append(keys, lock.Key)
+	}
+	if got := strings.Join(keys, ","); !strings.Contains(got, "user:backup/backup-admin") || !strings.Contains(got, "ip:192.0.2.1") {
+		t.Fatalf("locked keys %s, want the IP and the access key on the cluster", got)
+	}
+}
+
+func TestClusterConnect(t *testing.T) {
+	limiter, err := loginlimit.New(loginlimit.Config{MaxAttempts: 3, Lockout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newClusterTestRouter(t, limiter)
+
+	tests := []struct {
+		name     string
+		form     url.Values
+		status   int
+		location string
+	}{
+		{"connect", url.Values{"access_key": {"backup-admin"}, "secret_key": {"backupsecret"}}, http.StatusFound, "/clusters?connected=backup"},
+		{"connect and return", url.Values{"access_key": {"backup-admin"}, "secret_key": {"backupsecret"}, "next": {"/c/backup/buckets"}}, http.StatusFound, "/c/backup/buckets"},
+		{"unsafe return path", url.Values{"access_key": {"backup-admin"}, "secret_key": {"backupsecret"}, "next": {"/\t/evil.com"}}, http.StatusFound, "/clusters?connected=backup"},
+		{"missing secret", url.Values{"access_key": {"backup-admin"}}, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/clusters/backup/connect", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status || w.Header().Get("Location") != tt.location {
+				t.Fatalf("got status %d to %q, want %d to %q (%s)", w.Code, w.Header().Get("Location"), tt.status, tt.location, w.Body.String())
+			}
+		})
+	}
+}
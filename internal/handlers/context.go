@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"minio-admin-panel/internal/middleware"
+	"minio-admin-panel/internal/services"
+	"minio-admin-panel/internal/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusClientClosedRequest is recorded when the client goes away before
+// MinIO answers (the status nginx uses for the same case)
+const statusClientClosedRequest = 499
+
+// minioContextKey stores the MinIO context of a request in the gin context
+const minioContextKey = "minio_context"
+
+// minioTimeouts holds the deadlines of MinIO operations
+var minioTimeouts services.Timeouts
+
+// SetMinIOTimeouts configures the deadlines applied to MinIO calls
+func SetMinIOTimeouts(timeouts services.Timeouts) {
+	minioTimeouts = timeouts
+}
+
+// minioContext returns the context for MinIO calls made on behalf of the
+// request, carrying the cluster to act on and the STS session token of the
+// logged-in user if present.
+// It ends when the client goes away or when the read or write timeout of the
+// request passes.
+func minioContext(c *gin.Context) context.Context {
+	op := services.OpWrite
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead:
+		op = services.OpRead
+	}
+	return minioContextFor(c, op)
+}
+
+// minioContextFor returns the MinIO context of the request with the deadline
+// of op. All MinIO calls of a request share the deadline set by the first one.
+func minioContextFor(c *gin.Context, op services.Operation) context.Context {
+	if ctx, ok := c.Get(minioContextKey); ok {
+		return ctx.(context.Context)
+	}
+
+	ctx := c.Request.Context()
+	if timeout := minioTimeouts.For(op); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		// Release the timer once the request is done
+		context.AfterFunc(c.Request.Context(), cancel)
+	}
+	ctx = services.WithCluster(ctx, c.GetString("cluster"))
+	ctx = services.WithSessionToken(ctx, c.GetString("session_token"))
+
+	c.Set(minioContextKey, ctx)
+	return ctx
+}
+
+// requestCredentials returns the MinIO credentials the request acts with, for
+// work that continues after the request is done
+func requestCredentials(c *gin.Context) session.Credentials {
+	return session.Credentials{
+		Cluster:      c.GetString("cluster"),
+		AccessKey:    c.GetString("username"),
+		SecretKey:    c.GetString("password"),
+		SessionToken: c.GetString("session_token"),
+	}
+}
+
+// minioErrorResponses maps the kinds of service errors to their status and
+// translation key
+var minioErrorResponses = []struct {
+	kind   error
+	status int
+	key    string
+}{
+	{services.ErrNotFound, http.StatusNotFound, "errors.not_found"},
+	{services.ErrAlreadyExists, http.StatusConflict, "errors.already_exists"},
+	{services.ErrAccessDenied, http.StatusForbidden, "errors.access_denied"},
+	{services.ErrInvalid, http.StatusBadRequest, "errors.invalid_request"},
+	{services.ErrUnavailable, http.StatusServiceUnavailable, "errors.minio_unavailable"},
+}
+
+// respondMinIOError answers a request whose MinIO call failed. Timeouts get
+// 504 and classified errors their matching status, both with a translated
+// message and MinIO's own in "detail"; nothing is sent to clients that went
+// away. Other errors are answered with 500.
+func respondMinIOError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		log.Printf("[DEBUG] Client went away during %s %s", c.Request.Method, c.Request.URL.Path)
+		c.AbortWithStatus(statusClientClosedRequest)
+		return
+	case services.IsTimeout(err):
+		log.Printf("[DEBUG] MinIO did not respond in time for %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": middleware.T(c, "errors.minio_timeout")})
+		return
+	}
+
+	minioErr := services.Classify(err)
+	for _, resp := range minioErrorResponses {
+		if errors.Is(minioErr, resp.kind) {
+			log.Printf("[DEBUG] MinIO error for %s %s (code %q): %v", c.Request.Method, c.Request.URL.Path, minioErr.Code, err)
+			c.JSON(resp.status, gin.H{"error": middleware.T(c, resp.key), "detail": err.Error(), "code": minioErr.Code})
+			return
+		}
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -1,22 +1,22 @@
 package handlers
 
 import (
-	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
 
+	"minio-admin-panel/internal/middleware"
 	"minio-admin-panel/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 type GroupHandler struct {
-	minioService *services.MinIOService
+	minioService services.IAMBackend
 }
 
-func NewGroupHandler(minioService *services.MinIOService) *GroupHandler {
+func NewGroupHandler(minioService services.IAMBackend) *GroupHandler {
 	return &GroupHandler{
 		minioService: minioService,
 	}
@@ -46,10 +46,10 @@ func (h *GroupHandler) ListGroups(c *gin.Context) {
 	}
 
 	log.Printf("[DEBUG] ListGroups for admin '%s'", username)
-	groups, err := h.minioService.ListGroups(context.Background(), username, password)
+	groups, err := h.minioService.ListGroups(minioContext(c), username, password)
 	if err != nil {
 		log.Printf("[DEBUG] ListGroups failed for admin '%s': %v", username, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -93,8 +93,10 @@ func (h *GroupHandler) CreateGroup(c *gin.Context) {
 		return
 	}
 
-	if err := h.minioService.CreateGroup(context.Background(), req.Name, username, password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	middleware.SetAuditTarget(c, req.Name)
+
+	if err := h.minioService.CreateGroup(minioContext(c), req.Name, username, password); err != nil {
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -111,8 +113,8 @@ func (h *GroupHandler) DeleteGroup(c *gin.Context) {
 
 	groupName := c.Param("name")
 
-	if err := h.minioService.DeleteGroup(context.Background(), groupName, username, password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.minioService.DeleteGroup(minioContext(c), groupName, username, password); err != nil {
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -129,9 +131,9 @@ func (h *GroupHandler) GetGroupInfo(c *gin.Context) {
 
 	groupName := c.Param("name")
 
-	groupInfo, err := h.minioService.GetGroupInfo(context.Background(), groupName, username, password)
+	groupInfo, err := h.minioService.GetGroupInfo(minioContext(c), groupName, username, password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -157,8 +159,12 @@ func (h *GroupHandler) SetGroupPolicy(c *gin.Context) {
 		return
 	}
 
-	if err := h.minioService.SetGroupPolicy(context.Background(), groupName, req.PolicyName, username, password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if before, err := h.minioService.GetGroupInfo(minioContext(c), groupName, username, password); err == nil {
+		middleware.AddAuditDetail(c, "before", gin.H{"policy": before["policy"]})
+	}
+
+	if err := h.minioService.SetGroupPolicy(minioContext(c), groupName, req.PolicyName, username, password); err != nil {
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -187,16 +193,16 @@ func (h *GroupHandler) UpdateGroupMembers(c *gin.Context) {
 
 	// Add users to group
 	if len(req.AddUsers) > 0 {
-		if err := h.minioService.AddUsersToGroup(context.Background(), groupName, req.AddUsers, username, password); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to add users: %v", err)})
+		if err := h.minioService.AddUsersToGroup(minioContext(c), groupName, req.AddUsers, username, password); err != nil {
+			respondMinIOError(c, fmt.Errorf("Failed to add users: %w", err))
 			return
 		}
 	}
 
 	// Remove users from group
 	if len(req.RemoveUsers) > 0 {
-		if err := h.minioService.RemoveUsersFromGroup(context.Background(), groupName, req.RemoveUsers, username, password); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to remove users: %v", err)})
+		if err := h.minioService.RemoveUsersFromGroup(minioContext(c), groupName, req.RemoveUsers, username, password); err != nil {
+			respondMinIOError(c, fmt.Errorf("Failed to remove users: %w", err))
 			return
 		}
 	}
@@ -224,16 +230,16 @@ func (h *GroupHandler) SetUserGroups(c *gin.Context) {
 	}
 
 	// Get current user info to see existing groups
-	userInfo, err := h.minioService.GetUser(context.Background(), userName, username, password)
+	userInfo, err := h.minioService.GetUser(minioContext(c), userName, username, password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get user info: %v", err)})
+		respondMinIOError(c, fmt.Errorf("Failed to get user info: %w", err))
 		return
 	}
 
 	// Remove user from all current groups
 	if len(userInfo.MemberOf) > 0 {
 		for _, groupName := range userInfo.MemberOf {
-			if err := h.minioService.RemoveUsersFromGroup(context.Background(), groupName, []string{userName}, username, password); err != nil {
+			if err := h.minioService.RemoveUsersFromGroup(minioContext(c), groupName, []string{userName}, username, password); err != nil {
 				log.Printf("[DEBUG] Failed to remove user '%s' from group '%s': %v", userName, groupName, err)
 				// Continue to try removing from other groups
 			}
@@ -243,8 +249,8 @@ func (h *GroupHandler) SetUserGroups(c *gin.Context) {
 	// Add user to new groups
 	for _, groupName := range req.Groups {
 		if strings.TrimSpace(groupName) != "" {
-			if err := h.minioService.AddUsersToGroup(context.Background(), groupName, []string{userName}, username, password); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to add user to group '%s': %v", groupName, err)})
+			if err := h.minioService.AddUsersToGroup(minioContext(c), groupName, []string{userName}, username, password); err != nil {
+				respondMinIOError(c, fmt.Errorf("Failed to add user to group '%s': %w", groupName, err))
 				return
 			}
 		}
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"minio-admin-panel/internal/apitoken"
+	"minio-admin-panel/internal/audit"
+	"minio-admin-panel/internal/bucketstats"
+	"minio-admin-panel/internal/cluster"
+	"minio-admin-panel/internal/config"
+	"minio-admin-panel/internal/i18n"
+	"minio-admin-panel/internal/loginlimit"
+	"minio-admin-panel/internal/middleware"
+	"minio-admin-panel/internal/services"
+	"minio-admin-panel/internal/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	testRootUser     = "minioadmin"
+	testRootPassword = "minioadmin"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	i18n.Init("en")
+	if err := i18n.LoadDir("../../translations/i18n"); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// testServer serves the routes of the panel against an in-memory MinIO
+// backend
+type testServer struct {
+	t        *testing.T
+	router   *gin.Engine
+	backend  *services.MemoryBackend
+	sessions *session.Manager
+	keys     *middleware.KeyRing
+	tokens   *apitoken.Store
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	backend := services.NewMemoryBackend(testRootUser, testRootPassword)
+	registry := cluster.Single(&config.Config{MinIOHost: "localhost", MinIOPort: 9000}, func(*config.Config) services.Backend {
+		return backend
+	})
+	minioService := registry.Backend()
+
+	cipher, err := session.NewCipher("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions := session.NewManager(session.NewMemoryStore(), cipher, time.Hour, 0)
+	keys, err := middleware.NewKeyRing("test-secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := apitoken.NewStore(t.TempDir(), cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auditLog, err := audit.NewLogger(audit.Options{Path: filepath.Join(t.TempDir(), "audit.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { auditLog.Close() })
+	limiter, err := loginlimit.New(loginlimit.Config{MaxAttempts: 5, Lockout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := bucketstats.New(minioService, bucketstats.Options{Concurrency: 1, Interval: time.Hour, Timeout: time.Minute})
+	r := gin.New()
+	SetupRoutes(r, Routes{
+		Version:  "test",
+		JWTKeys:  keys,
+		Sessions: sessions,
+		Clusters: registry,
+		Tokens:   tokens,
+		AuditLog: auditLog,
+
+		AuthHandler:           NewAuthHandler(minioService, sessions, keys, nil, nil, auditLog, limiter),
+		BucketHandler:         NewBucketHandler(minioService, stats),
+		UserHandler:           NewUserHandler(minioService, tokens),
+		PolicyHandler:         NewPolicyHandler(minioService),
+		GroupHandler:          NewGroupHandler(minioService),
+		ServiceAccountHandler: NewServiceAccountHandler(minioService),
+		APIHandler:            NewAPIHandler(minioService, stats),
+		SettingsHandler:       NewSettingsHandler(minioService, "test", "none", "unknown", "test"),
+		AuditHandler:          NewAuditHandler(auditLog),
+		SessionHandler:        NewSessionHandler(sessions, tokens),
+		APITokenHandler:       NewAPITokenHandler(tokens, minioService, 30),
+		ClusterHandler:        NewClusterHandler(registry, minioService, sessions, limiter),
+	})
+
+	return &testServer{t: t, router: r, backend: backend, sessions: sessions, keys: keys, tokens: tokens}
+}
+
+// addUser creates a MinIO user with the given canned policy
+func (s *testServer) addUser(accessKey, secretKey, policy string) {
+	s.t.Helper()
+	ctx := context.Background()
+	if err := s.backend.CreateUser(ctx, accessKey, secretKey, testRootUser, testRootPassword); err != nil {
+		s.t.Fatal(err)
+	}
+	if err := s.backend.SetUserPolicy(ctx, accessKey, policy, testRootUser, testRootPassword); err != nil {
+		s.t.Fatal(err)
+	}
+}
+
+// addBucket creates a bucket as the root user
+func (s *testServer) addBucket(name string) {
+	s.t.Helper()
+	if err := s.backend.CreateBucket(context.Background(), name, services.BucketOptions{}, testRootUser, testRootPassword); err != nil {
+		s.t.Fatal(err)
+	}
+}
+
+// login starts a session the way AuthHandler.Login does and returns its
+// cookie
+func (s *testServer) login(accessKey, secretKey string) *http.Cookie {
+	s.t.Helper()
+	access := s.backend.GetUserPermissions(context.Background(), accessKey, secretKey)
+	sess, err := s.sessions.Create(&session.Session{
+		Username:    accessKey,
+		Permissions: access.Flags,
+		Policy:      access.PolicyJSON(),
+	}, session.Credentials{AccessKey: accessKey, SecretKey: secretKey})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	token, err := middleware.GenerateJWTWithSession(s.keys, sess.ID, sess.Username, sess.ExpiresAt)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return &http.Cookie{Name: "token", Value: token}
+}
+
+// createToken stores an API token of accessKey with the given scopes
+func (s *testServer) createToken(accessKey, secretKey string, scopes ...string) (*apitoken.Token, string) {
+	s.t.Helper()
+	access := s.backend.GetUserPermissions(context.Background(), accessKey, secretKey)
+	token := &apitoken.Token{
+		Name:        "test",
+		Owner:       accessKey,
+		Scopes:      scopes,
+		Binding:     apitoken.BindingUser,
+		Permissions: access.Flags,
+		Policy:      access.PolicyJSON(),
+		Credentials: session.Credentials{Cluster: cluster.DefaultName, AccessKey: accessKey, SecretKey: secretKey},
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	raw, err := s.tokens.Create(token)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return token, raw
+}
+
+// do sends a JSON request with the session cookie and a valid CSRF token
+func (s *testServer) do(method, path string, cookie *http.Cookie, body string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "test-csrf"})
+	req.Header.Set("X-CSRF-Token", "test-csrf")
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// doToken sends a request authenticated with an API token. Such requests
+// carry no cookies and need no CSRF token.
+func (s *testServer) doToken(method, path, token, body string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// decode unmarshals a JSON response body
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", w.Body.String(), err)
+	}
+}
+
+func TestBucketRoutes(t *testing.T) {
+	s := newTestServer(t)
+	s.addUser("reader", "readersecret", "readonly")
+	s.addUser("writer", "writersecret", "readwrite")
+	s.addBucket("existing")
+
+	root := s.login(testRootUser, testRootPassword)
+	reader := s.login("reader", "readersecret")
+	writer := s.login("writer", "writersecret")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		cookie *http.Cookie
+		body   string
+		status int
+	}{
+		{"anonymous list", http.MethodGet, "/buckets", nil, "", http.StatusUnauthorized},
+		{"root lists", http.MethodGet, "/buckets", root, "", http.StatusOK},
+		{"root lists on its cluster", http.MethodGet, "/c/default/buckets", root, "", http.StatusOK},
+		{"unknown cluster", http.MethodGet, "/c/other/buckets", root, "", http.StatusNotFound},
+		{"root creates", http.MethodPost, "/buckets", root, `{"name":"created"}`, http.StatusCreated},
+		{"create without name", http.MethodPost, "/buckets", root, `{}`, http.StatusBadRequest},
+		{"create duplicate", http.MethodPost, "/buckets", root, `{"name":"existing"}`, http.StatusConflict},
+		{"object lock without confirmation", http.MethodPost, "/buckets", root, `{"name":"locked","object_locking":true}`, http.StatusBadRequest},
+		{"reader may not create", http.MethodPost, "/buckets", reader, `{"name":"denied"}`, http.StatusForbidden},
+		{"writer creates", http.MethodPost, "/buckets", writer, `{"name":"written"}`, http.StatusCreated},
+		{"reader may not delete", http.MethodDelete, "/buckets/existing", reader, "", http.StatusForbidden},
+		{"delete missing bucket", http.MethodDelete, "/buckets/missing", root, "", http.StatusNotFound},
+		{"root deletes", http.MethodDelete, "/buckets/created", root, "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(tt.method, tt.path, tt.cookie, tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("%s %s: got status %d, want %d (%s)", tt.method, tt.path, w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+
+	var list struct {
+		Buckets []services.BucketInfo `json:"buckets"`
+	}
+	decode(t, s.do(http.MethodGet, "/buckets", root, ""), &list)
+	var names []string
+	for _, bucket := range list.Buckets {
+		names = append(names, bucket.Name)
+	}
+	if got := strings.Join(names, ","); got != "existing,written" {
+		t.Errorf("buckets after changes: got %s, want existing,written", got)
+	}
+}
+
+func TestBucketRoutesRejectMissingCSRFToken(t *testing.T) {
+	s := newTestServer(t)
+	root := s.login(testRootUser, testRootPassword)
+
+	req := httptest.NewRequest(http.MethodPost, "/buckets", strings.NewReader(`{"name":"forged"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(root)
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "test-csrf"})
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if _, err := s.backend.GetBucketPolicy(context.Background(), "forged", testRootUser, testRootPassword); err == nil {
+		t.Error("bucket was created without a CSRF token")
+	}
+}
+
+func TestRevokedSessionIsRejected(t *testing.T) {
+	s := newTestServer(t)
+	s.addUser("alice", "alicesecret", "readwrite")
+	root := s.login(testRootUser, testRootPassword)
+	alice := s.login("alice", "alicesecret")
+
+	if w := s.do(http.MethodGet, "/buckets", alice, ""); w.Code != http.StatusOK {
+		t.Fatalf("before revocation: got status %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := s.do(http.MethodDelete, "/sessions/users/alice", root, ""); w.Code != http.StatusOK {
+		t.Fatalf("revoking sessions: got status %d (%s)", w.Code, w.Body.String())
+	}
+	if w := s.do(http.MethodGet, "/buckets", alice, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("after revocation: got status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
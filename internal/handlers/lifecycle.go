@@ -0,0 +1,520 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"minio-admin-panel/internal/middleware"
+	"minio-admin-panel/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxLifecycleImport bounds the size of an imported lifecycle configuration
+const maxLifecycleImport = 1 << 20
+
+// maxRuleID is the longest lifecycle or replication rule ID S3 accepts
+const maxRuleID = 255
+
+// lifecycleRuleRequest is a rule as sent by clients. Enabled is optional:
+// new rules default to enabled, updated ones keep their status.
+type lifecycleRuleRequest struct {
+	services.LifecycleRule
+	Enabled *bool `json:"enabled"`
+}
+
+// GetBucketLifecycle handles GET /buckets/:name/lifecycle
+func (h *BucketHandler) GetBucketLifecycle(c *gin.Context) {
+	bucketName := c.Param("name")
+	log.Printf("[DEBUG] GetBucketLifecycle request for bucket '%s'", bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in GetBucketLifecycle: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	rules, err := h.minioService.GetBucketLifecycle(minioContext(c), bucketName, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] GetBucketLifecycle failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// DeleteBucketLifecycle handles DELETE /buckets/:name/lifecycle, removing
+// all lifecycle rules of a bucket
+func (h *BucketHandler) DeleteBucketLifecycle(c *gin.Context) {
+	bucketName := c.Param("name")
+	log.Printf("[DEBUG] DeleteBucketLifecycle request for bucket '%s'", bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in DeleteBucketLifecycle: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	if before, err := h.minioService.GetBucketLifecycle(minioContext(c), bucketName, username, password); err == nil {
+		middleware.AddAuditDetail(c, "before", gin.H{"rules": lifecycleRuleIDs(before)})
+	}
+
+	if err := h.minioService.SetBucketLifecycle(minioContext(c), bucketName, nil, username, password); err != nil {
+		log.Printf("[DEBUG] DeleteBucketLifecycle failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	log.Printf("[DEBUG] Lifecycle configuration of bucket '%s' removed by user '%s'", bucketName, username)
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "buckets.lifecycle.deleted_all")})
+}
+
+// CreateLifecycleRule handles POST /buckets/:name/lifecycle/rules. Rules
+// without an ID get a random one.
+func (h *BucketHandler) CreateLifecycleRule(c *gin.Context) {
+	bucketName := c.Param("name")
+	log.Printf("[DEBUG] CreateLifecycleRule request for bucket '%s'", bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in CreateLifecycleRule: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	var req lifecycleRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[DEBUG] Failed to bind request in CreateLifecycleRule: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	rule := req.LifecycleRule
+	rule.Enabled = req.Enabled == nil || *req.Enabled
+	normalizeLifecycleRule(&rule)
+	if rule.ID == "" {
+		if rule.ID, err = newRuleID(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "errors.generic")})
+			return
+		}
+	}
+
+	rules, err := h.minioService.GetBucketLifecycle(minioContext(c), bucketName, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Reading lifecycle of bucket '%s' failed in CreateLifecycleRule: %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+	if lifecycleRuleIndex(rules, rule.ID) >= 0 {
+		log.Printf("[DEBUG] Lifecycle rule '%s' already exists on bucket '%s'", rule.ID, bucketName)
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "buckets.lifecycle.error.duplicate_id"), "rule": rule.ID})
+		return
+	}
+
+	if !h.saveLifecycle(c, bucketName, append(rules, rule), username, password) {
+		return
+	}
+
+	log.Printf("[DEBUG] Lifecycle rule '%s' added to bucket '%s' by user '%s'", rule.ID, bucketName, username)
+	c.JSON(http.StatusCreated, gin.H{"message": middleware.T(c, "buckets.lifecycle.created"), "rule": rule})
+}
+
+// UpdateLifecycleRule handles PUT /buckets/:name/lifecycle/rules/*rule,
+// replacing a rule. The rule may be renamed by sending another ID.
+func (h *BucketHandler) UpdateLifecycleRule(c *gin.Context) {
+	bucketName := c.Param("name")
+	ruleID := strings.TrimPrefix(c.Param("rule"), "/")
+	log.Printf("[DEBUG] UpdateLifecycleRule request for rule '%s' of bucket '%s'", ruleID, bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in UpdateLifecycleRule: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	var req lifecycleRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[DEBUG] Failed to bind request in UpdateLifecycleRule: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	rules, err := h.minioService.GetBucketLifecycle(minioContext(c), bucketName, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Reading lifecycle of bucket '%s' failed in UpdateLifecycleRule: %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+	index := lifecycleRuleIndex(rules, ruleID)
+	if index < 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "buckets.lifecycle.error.rule_not_found"), "rule": ruleID})
+		return
+	}
+
+	rule := req.LifecycleRule
+	rule.Enabled = rules[index].Enabled
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	normalizeLifecycleRule(&rule)
+	if rule.ID == "" {
+		rule.ID = ruleID
+	}
+	if rule.ID != ruleID && lifecycleRuleIndex(rules, rule.ID) >= 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "buckets.lifecycle.error.duplicate_id"), "rule": rule.ID})
+		return
+	}
+
+	middleware.AddAuditDetail(c, "before", rules[index])
+	rules[index] = rule
+	if !h.saveLifecycle(c, bucketName, rules, username, password) {
+		return
+	}
+
+	log.Printf("[DEBUG] Lifecycle rule '%s' of bucket '%s' updated by user '%s'", ruleID, bucketName, username)
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "buckets.lifecycle.updated"), "rule": rule})
+}
+
+// DeleteLifecycleRule handles DELETE /buckets/:name/lifecycle/rules/*rule
+func (h *BucketHandler) DeleteLifecycleRule(c *gin.Context) {
+	bucketName := c.Param("name")
+	ruleID := strings.TrimPrefix(c.Param("rule"), "/")
+	log.Printf("[DEBUG] DeleteLifecycleRule request for rule '%s' of bucket '%s'", ruleID, bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in DeleteLifecycleRule: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	rules, err := h.minioService.GetBucketLifecycle(minioContext(c), bucketName, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Reading lifecycle of bucket '%s' failed in DeleteLifecycleRule: %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+	index := lifecycleRuleIndex(rules, ruleID)
+	if index < 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "buckets.lifecycle.error.rule_not_found"), "rule": ruleID})
+		return
+	}
+
+	middleware.AddAuditDetail(c, "before", rules[index])
+	if err := h.minioService.SetBucketLifecycle(minioContext(c), bucketName, append(rules[:index], rules[index+1:]...), username, password); err != nil {
+		log.Printf("[DEBUG] DeleteLifecycleRule failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	log.Printf("[DEBUG] Lifecycle rule '%s' of bucket '%s' deleted by user '%s'", ruleID, bucketName, username)
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "buckets.lifecycle.deleted")})
+}
+
+// ExportBucketLifecycle handles GET /buckets/:name/lifecycle/export, sending
+// the lifecycle configuration as a download in ?format=xml (the default) or
+// json
+func (h *BucketHandler) ExportBucketLifecycle(c *gin.Context) {
+	bucketName := c.Param("name")
+	format := c.DefaultQuery("format", services.LifecycleFormatXML)
+	log.Printf("[DEBUG] ExportBucketLifecycle request for bucket '%s' as %s", bucketName, format)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in ExportBucketLifecycle: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	contentType := map[string]string{
+		services.LifecycleFormatXML:  "application/xml",
+		services.LifecycleFormatJSON: "application/json",
+	}[format]
+	if contentType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "buckets.lifecycle.error.format")})
+		return
+	}
+
+	rules, err := h.minioService.GetBucketLifecycle(minioContext(c), bucketName, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] ExportBucketLifecycle failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+	data, err := services.ExportLifecycle(rules, format)
+	if err != nil {
+		log.Printf("[DEBUG] Encoding lifecycle of bucket '%s' failed: %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	log.Printf("[DEBUG] Exporting %d lifecycle rules of bucket '%s' as %s", len(rules), bucketName, format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", bucketName+"-lifecycle."+format))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// ImportBucketLifecycle handles POST /buckets/:name/lifecycle/import. The
+// body is a lifecycle configuration as exported, in XML or JSON; it replaces
+// all rules of the bucket after passing the same checks as edited rules.
+func (h *BucketHandler) ImportBucketLifecycle(c *gin.Context) {
+	bucketName := c.Param("name")
+	log.Printf("[DEBUG] ImportBucketLifecycle request for bucket '%s'", bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in ImportBucketLifecycle: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxLifecycleImport+1))
+	if err != nil || len(data) > maxLifecycleImport {
+		log.Printf("[DEBUG] Lifecycle import for bucket '%s' unreadable or too large (%d bytes): %v", bucketName, len(data), err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "buckets.lifecycle.error.import_size")})
+		return
+	}
+	rules, err := services.ParseLifecycle(data)
+	if err != nil {
+		log.Printf("[DEBUG] Lifecycle import for bucket '%s' malformed: %v", bucketName, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "buckets.lifecycle.error.import_malformed"), "detail": err.Error()})
+		return
+	}
+	if len(rules) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "buckets.lifecycle.error.import_empty")})
+		return
+	}
+	for i := range rules {
+		normalizeLifecycleRule(&rules[i])
+		if rules[i].ID == "" {
+			if rules[i].ID, err = newRuleID(); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "errors.generic")})
+				return
+			}
+		}
+	}
+
+	if before, err := h.minioService.GetBucketLifecycle(minioContext(c), bucketName, username, password); err == nil {
+		middleware.AddAuditDetail(c, "before", gin.H{"rules": lifecycleRuleIDs(before)})
+	}
+	middleware.AddAuditDetail(c, "after", gin.H{"rules": lifecycleRuleIDs(rules)})
+	if !h.saveLifecycle(c, bucketName, rules, username, password) {
+		return
+	}
+
+	log.Printf("[DEBUG] Imported %d lifecycle rules into bucket '%s' by user '%s'", len(rules), bucketName, username)
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "buckets.lifecycle.imported"), "rules": rules})
+}
+
+// ListTiers handles GET /api/tiers, listing the remote tiers lifecycle
+// rules can transition objects to
+func (h *BucketHandler) ListTiers(c *gin.Context) {
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	tiers, err := h.minioService.ListTiers(minioContext(c), username, password)
+	if err != nil {
+		log.Printf("[DEBUG] ListTiers failed for user '%s': %v", username, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tiers": tiers})
+}
+
+// saveLifecycle validates rules and stores them as the lifecycle
+// configuration of a bucket. It responds and returns false if that fails.
+func (h *BucketHandler) saveLifecycle(c *gin.Context, bucketName string, rules []services.LifecycleRule, username, password string) bool {
+	// Check transitions against the known tiers if the user may list them;
+	// MinIO rejects unknown ones either way
+	tiers, err := h.minioService.ListTiers(minioContext(c), username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Tiers unavailable to user '%s', not checking transitions: %v", username, err)
+		tiers = nil
+	}
+
+	if ruleID, key := validateLifecycle(rules, tiers); key != "" {
+		log.Printf("[DEBUG] Rejected lifecycle rule '%s' for bucket '%s': %s", ruleID, bucketName, key)
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, key), "rule": ruleID})
+		return false
+	}
+
+	if err := h.minioService.SetBucketLifecycle(minioContext(c), bucketName, rules, username, password); err != nil {
+		log.Printf("[DEBUG] SetBucketLifecycle failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return false
+	}
+	return true
+}
+
+// normalizeLifecycleRule trims the ID, tag keys and tier names of a rule
+func normalizeLifecycleRule(rule *services.LifecycleRule) {
+	rule.ID = strings.TrimSpace(rule.ID)
+	rule.ExpirationDate = strings.TrimSpace(rule.ExpirationDate)
+	rule.TransitionDate = strings.TrimSpace(rule.TransitionDate)
+	rule.TransitionTier = strings.TrimSpace(rule.TransitionTier)
+	rule.NoncurrentTransitionTier = strings.TrimSpace(rule.NoncurrentTransitionTier)
+	if len(rule.Tags) == 0 {
+		rule.Tags = nil
+		return
+	}
+	tags := make(map[string]string, len(rule.Tags))
+	for key, value := range rule.Tags {
+		if key = strings.TrimSpace(key); key != "" {
+			tags[key] = value
+		}
+	}
+	rule.Tags = tags
+}
+
+// validateLifecycle checks lifecycle rules before they are sent to MinIO.
+// Transitions are checked against tiers unless it is nil. It returns the ID
+// of the first offending rule and the translation key of its problem, or
+// empty strings.
+func validateLifecycle(rules []services.LifecycleRule, tiers []string) (string, string) {
+	if len(rules) > services.MaxLifecycleRules {
+		return "", "buckets.lifecycle.error.too_many_rules"
+	}
+
+	known := make(map[string]bool, len(tiers))
+	for _, tier := range tiers {
+		known[tier] = true
+	}
+	ids := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if ids[rule.ID] {
+			return rule.ID, "buckets.lifecycle.error.duplicate_id"
+		}
+		ids[rule.ID] = true
+
+		if key := validateLifecycleRule(rule); key != "" {
+			return rule.ID, key
+		}
+		if tiers == nil {
+			continue
+		}
+		for _, tier := range []string{rule.TransitionTier, rule.NoncurrentTransitionTier} {
+			if tier != "" && !known[tier] {
+				return rule.ID, "buckets.lifecycle.error.unknown_tier"
+			}
+		}
+	}
+	return "", ""
+}
+
+// validateLifecycleRule checks one rule the way S3 and MinIO do. It returns
+// the translation key of the first problem, or an empty string.
+func validateLifecycleRule(rule services.LifecycleRule) string {
+	if rule.ID == "" {
+		return "buckets.lifecycle.error.id_required"
+	}
+	if len(rule.ID) > maxRuleID {
+		return "buckets.lifecycle.error.id_too_long"
+	}
+
+	for _, n := range []int64{
+		rule.ObjectSizeGreaterThan, rule.ObjectSizeLessThan,
+		int64(rule.ExpirationDays), int64(rule.DeleteMarkerExpirationDays),
+		int64(rule.NoncurrentExpirationDays), int64(rule.NewerNoncurrentVersions),
+		int64(rule.TransitionDays), int64(rule.NoncurrentTransitionDays),
+		int64(rule.AbortIncompleteUploadDays),
+	} {
+		if n < 0 {
+			return "buckets.lifecycle.error.negative"
+		}
+	}
+	for _, date := range []string{rule.ExpirationDate, rule.TransitionDate} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(services.LifecycleDateLayout, date); err != nil {
+			return "buckets.lifecycle.error.date"
+		}
+	}
+	if !rule.HasAction() {
+		return "buckets.lifecycle.error.no_action"
+	}
+
+	if rule.ObjectSizeGreaterThan > 0 && rule.ObjectSizeLessThan > 0 && rule.ObjectSizeLessThan <= rule.ObjectSizeGreaterThan {
+		return "buckets.lifecycle.error.size_range"
+	}
+
+	// Expiration
+	if rule.ExpirationDays > 0 && rule.ExpirationDate != "" {
+		return "buckets.lifecycle.error.expiration_days_and_date"
+	}
+	if rule.ExpireDeleteMarker && (rule.ExpirationDays > 0 || rule.ExpirationDate != "") {
+		return "buckets.lifecycle.error.delete_marker_with_expiration"
+	}
+	if (rule.ExpireDeleteMarker || rule.DeleteMarkerExpirationDays > 0) && len(rule.Tags) > 0 {
+		return "buckets.lifecycle.error.delete_marker_with_tags"
+	}
+	if rule.ExpireAllVersions && rule.ExpirationDays == 0 {
+		return "buckets.lifecycle.error.all_versions_needs_days"
+	}
+
+	// Transitions
+	if rule.TransitionDays > 0 && rule.TransitionDate != "" {
+		return "buckets.lifecycle.error.transition_days_and_date"
+	}
+	if rule.TransitionTier == "" && (rule.TransitionDays > 0 || rule.TransitionDate != "") {
+		return "buckets.lifecycle.error.transition_needs_tier"
+	}
+	if rule.TransitionTier != "" && rule.TransitionDays == 0 && rule.TransitionDate == "" {
+		return "buckets.lifecycle.error.transition_needs_time"
+	}
+	if (rule.NoncurrentTransitionTier == "") != (rule.NoncurrentTransitionDays == 0) {
+		return "buckets.lifecycle.error.noncurrent_transition"
+	}
+	if (rule.ExpirationDays > 0 && rule.TransitionDays >= rule.ExpirationDays) ||
+		(rule.NoncurrentExpirationDays > 0 && rule.NoncurrentTransitionDays >= rule.NoncurrentExpirationDays) {
+		return "buckets.lifecycle.error.transition_after_expiration"
+	}
+
+	if rule.AbortIncompleteUploadDays > 0 && len(rule.Tags) > 0 {
+		return "buckets.lifecycle.error.abort_with_tags"
+	}
+	return ""
+}
+
+// lifecycleRuleIndex returns the position of the rule with the given ID, or -1
+func lifecycleRuleIndex(rules []services.LifecycleRule, id string) int {
+	for i, rule := range rules {
+		if rule.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// lifecycleRuleIDs returns the IDs of rules, e.g. for audit records
+func lifecycleRuleIDs(rules []services.LifecycleRule) []string {
+	ids := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		ids = append(ids, rule.ID)
+	}
+	return ids
+}
+
+// newRuleID returns a random rule ID like the ones mc generates
+func newRuleID() (string, error) {
+	const alphabet = "0123456789abcdefghijklmnopqrstuv"
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating rule ID: %w", err)
+	}
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b), nil
+}
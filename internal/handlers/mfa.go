@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"minio-admin-panel/internal/audit"
+	"minio-admin-panel/internal/loginlimit"
+	"minio-admin-panel/internal/mfa"
+	"minio-admin-panel/internal/middleware"
+	"minio-admin-panel/internal/rbac"
+	"minio-admin-panel/internal/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mfaPendingTimeout is how long a password-verified session may wait for its
+// second factor
+const mfaPendingTimeout = 10 * time.Minute
+
+// MFAHandler serves TOTP verification during login and the self-service
+// enrollment page
+type MFAHandler struct {
+	store    *mfa.Store
+	enforce  mfa.Enforcement
+	sessions *session.Manager
+	limiter  *loginlimit.Limiter
+	audit    *audit.Logger
+	issuer   string
+}
+
+func NewMFAHandler(store *mfa.Store, enforce mfa.Enforcement, sessions *session.Manager, limiter *loginlimit.Limiter, auditLog *audit.Logger, issuer string) *MFAHandler {
+	return &MFAHandler{
+		store:    store,
+		enforce:  enforce,
+		sessions: sessions,
+		limiter:  limiter,
+		audit:    auditLog,
+		issuer:   issuer,
+	}
+}
+
+// ShowVerify handles GET /login/mfa
+func (h *MFAHandler) ShowVerify(c *gin.Context) {
+	sess := c.MustGet("session").(*session.Session)
+	if h.expired(c, sess) {
+		return
+	}
+	if sess.MFAState == session.MFAEnroll {
+		c.Redirect(http.StatusFound, "/login/mfa/setup")
+		return
+	}
+
+	h.renderLoginMFA(c, "verify", gin.H{})
+}
+
+// Verify handles POST /login/mfa, accepting a TOTP or recovery code
+func (h *MFAHandler) Verify(c *gin.Context) {
+	sess := c.MustGet("session").(*session.Session)
+	if h.expired(c, sess) {
+		return
+	}
+	if sess.MFAState != session.MFAPending {
+		c.Redirect(http.StatusFound, "/login/mfa")
+		return
+	}
+
+	if wait := h.limiter.Check(c.ClientIP(), sess.Username); wait > 0 {
+		h.blocked(c, "auth.mfa.verify", wait)
+		h.renderLoginMFA(c, "verify", gin.H{"error": "login.error.too_many_attempts"})
+		return
+	}
+	defer h.limiter.Release(c.ClientIP(), sess.Username)
+
+	recovery, err := h.store.Verify(sess.Username, c.PostForm("code"))
+	if err != nil {
+		log.Printf("[DEBUG] Second factor failed for user '%s': %v", sess.Username, err)
+		h.limiter.Failure(c.ClientIP(), sess.Username)
+		h.auditEvent(c, "auth.mfa.verify", nil, err)
+		h.renderLoginMFA(c, "verify", gin.H{"error": "mfa.error.invalid_code"})
+		return
+	}
+
+	if !h.completeMFA(c, sess) {
+		return
+	}
+
+	h.limiter.Success(c.ClientIP(), sess.Username)
+	h.auditEvent(c, "auth.mfa.verify", map[string]interface{}{"recovery_code": recovery}, nil)
+	redirectAfterLogin(c)
+}
+
+// ShowEnroll handles GET /login/mfa/setup for users whose role requires TOTP
+func (h *MFAHandler) ShowEnroll(c *gin.Context) {
+	sess := c.MustGet("session").(*session.Session)
+	if h.expired(c, sess) {
+		return
+	}
+	if sess.MFAState != session.MFAEnroll {
+		c.Redirect(http.StatusFound, "/login/mfa")
+		return
+	}
+
+	data, err := h.setupData(sess.Username, "/login/mfa/setup")
+	if err != nil {
+		log.Printf("[DEBUG] Failed to start TOTP enrollment for user '%s': %v", sess.Username, err)
+		h.renderLoginMFA(c, "setup", gin.H{"error": "mfa.error.failed"})
+		return
+	}
+	h.renderLoginMFA(c, "setup", data)
+}
+
+// Enroll handles POST /login/mfa/setup, confirming the enrollment and
+// completing the login
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	sess := c.MustGet("session").(*session.Session)
+	if h.expired(c, sess) {
+		return
+	}
+	if sess.MFAState != session.MFAEnroll {
+		c.Redirect(http.StatusFound, "/login/mfa")
+		return
+	}
+
+	var (
+		codes    []string
+		err      error
+		errorKey string
+	)
+	if wait := h.limiter.Check(c.ClientIP(), sess.Username); wait > 0 {
+		h.blocked(c, "auth.mfa.enroll", wait)
+		errorKey = "login.error.too_many_attempts"
+	} else {
+		defer h.limiter.Release(c.ClientIP(), sess.Username)
+		if codes, err = h.store.Confirm(sess.Username, c.PostForm("code")); err != nil {
+			log.Printf("[DEBUG] TOTP enrollment failed for user '%s': %v", sess.Username, err)
+			h.limiter.Failure(c.ClientIP(), sess.Username)
+			h.auditEvent(c, "auth.mfa.enroll", nil, err)
+			errorKey = mfaErrorKey(err)
+		}
+	}
+	if errorKey != "" {
+		data, err := h.setupData(sess.Username, "/login/mfa/setup")
+		if err != nil {
+			data = gin.H{}
+		}
+		data["error"] = errorKey
+		h.renderLoginMFA(c, "setup", data)
+		return
+	}
+
+	if !h.completeMFA(c, sess) {
+		return
+	}
+
+	h.limiter.Success(c.ClientIP(), sess.Username)
+	h.auditEvent(c, "auth.mfa.enroll", nil, nil)
+	h.renderLoginMFA(c, "recovery", gin.H{"recoveryCodes": codes, "continueURL": afterLoginURL(c)})
+}
+
+// ShowAccount handles GET /account/mfa
+func (h *MFAHandler) ShowAccount(c *gin.Context) {
+	h.renderAccount(c, gin.H{})
+}
+
+// AccountEnroll handles POST /account/mfa, confirming a self-service enrollment
+func (h *MFAHandler) AccountEnroll(c *gin.Context) {
+	username := c.GetString("display_name")
+
+	var codes []string
+	if !h.checkAccountCode(c, "auth.mfa.enroll", username, func() (err error) {
+		codes, err = h.store.Confirm(username, c.PostForm("code"))
+		return err
+	}) {
+		return
+	}
+
+	h.auditEvent(c, "auth.mfa.enroll", nil, nil)
+	h.renderAccount(c, gin.H{"recoveryCodes": codes, "success": "mfa.enabled_success"})
+}
+
+// AccountDisable handles POST /account/mfa/disable. A current code is required,
+// and users whose role requires TOTP cannot disable it.
+func (h *MFAHandler) AccountDisable(c *gin.Context) {
+	username := c.GetString("display_name")
+
+	if h.enforce.Requires(panelRole(c)) {
+		h.renderAccount(c, gin.H{"error": "mfa.error.required"})
+		return
+	}
+
+	if !h.checkAccountCode(c, "auth.mfa.disable", username, h.verifyCode(c, username)) {
+		return
+	}
+
+	if err := h.store.Delete(username); err != nil {
+		log.Printf("[DEBUG] Failed to disable TOTP for user '%s': %v", username, err)
+		h.auditEvent(c, "auth.mfa.disable", nil, err)
+		h.renderAccount(c, gin.H{"error": "mfa.error.failed"})
+		return
+	}
+
+	h.auditEvent(c, "auth.mfa.disable", nil, nil)
+	h.renderAccount(c, gin.H{"success": "mfa.disabled_success"})
+}
+
+// AccountRecoveryCodes handles POST /account/mfa/recovery-codes, replacing all
+// recovery codes after checking a current code
+func (h *MFAHandler) AccountRecoveryCodes(c *gin.Context) {
+	username := c.GetString("display_name")
+
+	if !h.checkAccountCode(c, "auth.mfa.recovery_codes", username, h.verifyCode(c, username)) {
+		return
+	}
+
+	codes, err := h.store.RegenerateRecoveryCodes(username)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to regenerate recovery codes for user '%s': %v", username, err)
+		h.renderAccount(c, gin.H{"error": "mfa.error.failed"})
+		return
+	}
+
+	h.auditEvent(c, "auth.mfa.recovery_codes", nil, nil)
+	h.renderAccount(c, gin.H{"recoveryCodes": codes})
+}
+
+// checkAccountCode runs check on a code entered on the account page. Like
+// logins, wrong codes count towards the back-off and lockout of the user, so
+// a stolen session cannot be used to guess codes. It renders the page with
+// the error and returns false if the attempt is blocked or the check fails.
+func (h *MFAHandler) checkAccountCode(c *gin.Context, action, username string, check func() error) bool {
+	if wait := h.limiter.Check(c.ClientIP(), username); wait > 0 {
+		h.blocked(c, action, wait)
+		h.renderAccount(c, gin.H{"error": "login.error.too_many_attempts"})
+		return false
+	}
+	defer h.limiter.Release(c.ClientIP(), username)
+
+	if err := check(); err != nil {
+		log.Printf("[DEBUG] Rejected code for '%s' of user '%s': %v", action, username, err)
+		if errors.Is(err, mfa.ErrInvalidCode) {
+			h.limiter.Failure(c.ClientIP(), username)
+		}
+		h.auditEvent(c, action, nil, err)
+		h.renderAccount(c, gin.H{"error": mfaErrorKey(err)})
+		return false
+	}
+
+	h.limiter.Success(c.ClientIP(), username)
+	return true
+}
+
+// verifyCode returns a check of the TOTP or recovery code in the form
+func (h *MFAHandler) verifyCode(c *gin.Context, username string) func() error {
+	return func() error {
+		_, err := h.store.Verify(username, c.PostForm("code"))
+		return err
+	}
+}
+
+// blocked records an attempt refused by the login limiter and sets the
+// status and Retry-After header of the response
+func (h *MFAHandler) blocked(c *gin.Context, action string, wait time.Duration) {
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	h.auditEvent(c, action, map[string]interface{}{"blocked": true, "retry_after": retryAfter}, errors.New("too many attempts"))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.Status(http.StatusTooManyRequests)
+}
+
+// renderAccount renders the account TOTP page for the current user
+func (h *MFAHandler) renderAccount(c *gin.Context, data gin.H) {
+	username := c.GetString("display_name")
+
+	data["title"] = "mfa.title"
+	data["required"] = h.enforce.Requires(panelRole(c))
+	data["enrolled"] = h.store.Enrolled(username)
+
+	if data["enrolled"] == true {
+		data["recoveryCodesLeft"] = h.store.RecoveryCodesLeft(username)
+	} else {
+		setup, err := h.setupData(username, "/account/mfa")
+		if err != nil {
+			log.Printf("[DEBUG] Failed to start TOTP enrollment for user '%s': %v", username, err)
+			data["error"] = "mfa.error.failed"
+		}
+		for key, value := range setup {
+			data[key] = value
+		}
+	}
+
+	RenderWithTranslations(c, "account_mfa.html", data)
+}
+
+// renderLoginMFA renders the second-factor step of the login
+func (h *MFAHandler) renderLoginMFA(c *gin.Context, mode string, data gin.H) {
+	data["title"] = "mfa.title"
+	data["mode"] = mode
+	RenderWithTranslations(c, "login_mfa.html", data)
+}
+
+// setupData starts or resumes an enrollment and returns the values the setup
+// form needs
+func (h *MFAHandler) setupData(username, action string) (gin.H, error) {
+	enrollment, err := h.store.Begin(username)
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"secret":      enrollment.Secret,
+		"otpauthURI":  mfa.URI(h.issuer, username, enrollment.Secret),
+		"setupAction": action,
+	}, nil
+}
+
+// expired ends sessions that waited too long for their second factor
+func (h *MFAHandler) expired(c *gin.Context, sess *session.Session) bool {
+	if time.Since(sess.CreatedAt) < mfaPendingTimeout {
+		return false
+	}
+
+	log.Printf("[DEBUG] Second factor for user '%s' timed out", sess.Username)
+	if err := h.sessions.Delete(sess.ID); err != nil {
+		log.Printf("[DEBUG] Failed to delete timed out session: %v", err)
+	}
+	middleware.SetCookie(c, "token", "", -1, "/", true)
+	h.renderLoginMFA(c, "expired", gin.H{"error": "mfa.error.expired"})
+	return true
+}
+
+// completeMFA marks the second factor of a session as verified. It responds
+// and returns false if that fails, signing the user out if the session was
+// revoked or removed meanwhile.
+func (h *MFAHandler) completeMFA(c *gin.Context, sess *session.Session) bool {
+	err := h.sessions.CompleteMFA(sess)
+	if err == nil {
+		return true
+	}
+
+	log.Printf("[DEBUG] Failed to complete second factor for user '%s': %v", sess.Username, err)
+	if errors.Is(err, session.ErrNotFound) || errors.Is(err, session.ErrRevoked) {
+		middleware.SetCookie(c, "token", "", -1, "/", true)
+		h.renderLoginMFA(c, "expired", gin.H{"error": "mfa.error.expired"})
+		return false
+	}
+	h.renderLoginMFA(c, "verify", gin.H{"error": "mfa.error.failed"})
+	return false
+}
+
+// auditEvent records a second-factor event for the current user
+func (h *MFAHandler) auditEvent(c *gin.Context, action string, details map[string]interface{}, err error) {
+	username := c.GetString("display_name")
+	rec := audit.Record{
+		Actor:    username,
+		ClientIP: c.ClientIP(),
+		Action:   action,
+		Target:   username,
+		Details:  details,
+		Result:   audit.ResultSuccess,
+	}
+	if err != nil {
+		rec.Result = audit.ResultFailure
+		if rec.Details == nil {
+			rec.Details = map[string]interface{}{}
+		}
+		rec.Details["error"] = err.Error()
+	}
+	h.audit.Log(rec)
+}
+
+// panelRole returns the panel role of the current user, if roles are enabled
+func panelRole(c *gin.Context) string {
+	if role, ok := c.Get("role"); ok {
+		return role.(*rbac.Role).Name
+	}
+	return ""
+}
+
+// mfaErrorKey returns the translation key for an MFA store error
+func mfaErrorKey(err error) string {
+	switch {
+	case errors.Is(err, mfa.ErrInvalidCode):
+		return "mfa.error.invalid_code"
+	case errors.Is(err, mfa.ErrNotEnrolled):
+		return "mfa.error.not_enrolled"
+	case errors.Is(err, mfa.ErrAlreadyEnrolled):
+		return "mfa.error.already_enrolled"
+	}
+	return "mfa.error.failed"
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"minio-admin-panel/internal/loginlimit"
+	"minio-admin-panel/internal/mfa"
+	"minio-admin-panel/internal/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newMFATestRouter serves the account TOTP routes for "alice", who has
+// enrolled, and returns the router and her TOTP secret
+func newMFATestRouter(t *testing.T, limiter *loginlimit.Limiter) (*gin.Engine, *mfa.Store, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cipher, err := session.NewCipher("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := mfa.NewStore(t.TempDir(), cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enrollment, err := store.Begin("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := mfa.Code(enrollment.Secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Confirm("alice", code); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions := session.NewManager(session.NewMemoryStore(), cipher, time.Hour, 0)
+	handler := NewMFAHandler(store, nil, sessions, limiter, nil, "MinIO Admin Panel")
+
+	r := gin.New()
+	r.SetHTMLTemplate(template.Must(template.New("account_mfa.html").Parse(`{{.error}}`)))
+	account := r.Group("/account/mfa", func(c *gin.Context) { c.Set("display_name", "alice") })
+	account.POST("/disable", handler.AccountDisable)
+	account.POST("/recovery-codes", handler.AccountRecoveryCodes)
+	return r, store, enrollment.Secret
+}
+
+// postCode submits a code to an account TOTP route
+func postCode(r *gin.Engine, path, code string) *httptest.ResponseRecorder {
+	form := url.Values{"code": {code}}
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAccountMFAIsThrottled(t *testing.T) {
+	limiter, err := loginlimit.New(loginlimit.Config{MaxAttempts: 3, Lockout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, store, secret := newMFATestRouter(t, limiter)
+	next, err := mfa.Code(secret, time.Now().Add(30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		code   string
+		status int
+	}{
+		{"first wrong code", "/account/mfa/disable", "000000", http.StatusOK},
+		{"wrong recovery code", "/account/mfa/recovery-codes", "00000-00000", http.StatusOK},
+		{"third wrong code locks out", "/account/mfa/disable", "111111", http.StatusOK},
+		{"right code while locked out", "/account/mfa/disable", next, http.StatusTooManyRequests},
+		{"other endpoint while locked out", "/account/mfa/recovery-codes", next, http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		w := postCode(r, tt.path, tt.code)
+		if w.Code != tt.status {
+			t.Fatalf("%s: got status %d, want %d (%s)", tt.name, w.Code, tt.status, w.Body.String())
+		}
+		if blocked := w.Header().Get("Retry-After") != ""; blocked != (tt.status == http.StatusTooManyRequests) {
+			t.Fatalf("%s: Retry-After header set: %t", tt.name, blocked)
+		}
+	}
+
+	if !store.Enrolled("alice") {
+		t.Error("TOTP was disabled during the lockout")
+	}
+	if locks := limiter.Locks(); len(locks) == 0 {
+		t.Error("no lockout was recorded")
+	}
+}
+
+func TestAccountMFADisable(t *testing.T) {
+	limiter, err := loginlimit.New(loginlimit.Config{MaxAttempts: 3, Lockout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, store, secret := newMFATestRouter(t, limiter)
+	next, err := mfa.Code(secret, time.Now().Add(30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w := postCode(r, "/account/mfa/disable", "000000"); w.Code != http.StatusOK || !store.Enrolled("alice") {
+		t.Fatalf("wrong code: got status %d, enrolled %t", w.Code, store.Enrolled("alice"))
+	}
+	if w := postCode(r, "/account/mfa/disable", next); w.Code != http.StatusOK || store.Enrolled("alice") {
+		t.Fatalf("right code: got status %d, enrolled %t (%s)", w.Code, store.Enrolled("alice"), w.Body.String())
+	}
+	if stats := limiter.Stats(); stats.Tracked != 0 {
+		t.Errorf("limiter still tracks %d keys after the right code", stats.Tracked)
+	}
+}
@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"minio-admin-panel/internal/middleware"
+	"minio-admin-panel/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxNotificationFilter is the longest prefix or suffix filter MinIO accepts
+const maxNotificationFilter = 1024
+
+// GetBucketNotification handles GET /buckets/:name/notifications, listing
+// the notification rules of a bucket and the event types rules can use
+func (h *BucketHandler) GetBucketNotification(c *gin.Context) {
+	bucketName := c.Param("name")
+	log.Printf("[DEBUG] GetBucketNotification request for bucket '%s'", bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in GetBucketNotification: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	rules, err := h.minioService.GetBucketNotification(minioContext(c), bucketName, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] GetBucketNotification failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules, "events": services.NotificationEvents})
+}
+
+// DeleteBucketNotification handles DELETE /buckets/:name/notifications,
+// removing all notification rules of a bucket
+func (h *BucketHandler) DeleteBucketNotification(c *gin.Context) {
+	bucketName := c.Param("name")
+	log.Printf("[DEBUG] DeleteBucketNotification request for bucket '%s'", bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in DeleteBucketNotification: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	if before, err := h.minioService.GetBucketNotification(minioContext(c), bucketName, username, password); err == nil {
+		middleware.AddAuditDetail(c, "before", gin.H{"rules": before})
+	}
+
+	if err := h.minioService.SetBucketNotification(minioContext(c), bucketName, nil, username, password); err != nil {
+		log.Printf("[DEBUG] DeleteBucketNotification failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	log.Printf("[DEBUG] Notification configuration of bucket '%s' removed by user '%s'", bucketName, username)
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "buckets.notifications.deleted_all")})
+}
+
+// CreateNotificationRule handles POST /buckets/:name/notifications/rules.
+// Rules without an ID get a random one.
+func (h *BucketHandler) CreateNotificationRule(c *gin.Context) {
+	bucketName := c.Param("name")
+	log.Printf("[DEBUG] CreateNotificationRule request for bucket '%s'", bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in CreateNotificationRule: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	var rule services.NotificationRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		log.Printf("[DEBUG] Failed to bind request in CreateNotificationRule: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	normalizeNotificationRule(&rule)
+	if rule.ID == "" {
+		if rule.ID, err = newRuleID(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "errors.generic")})
+			return
+		}
+	}
+
+	rules, err := h.minioService.GetBucketNotification(minioContext(c), bucketName, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Reading notifications of bucket '%s' failed in CreateNotificationRule: %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	// Check the target against the configured ones if the user may list
+	// them; MinIO rejects unknown ones either way
+	targets, err := h.minioService.ListNotificationTargets(minioContext(c), username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Notification targets unavailable to user '%s', not checking ARNs: %v", username, err)
+		targets = nil
+	}
+
+	rules = append(rules, rule)
+	if key := validateNotification(rules, len(rules)-1, targets); key != "" {
+		log.Printf("[DEBUG] Rejected notification rule '%s' for bucket '%s': %s", rule.ID, bucketName, key)
+		status := http.StatusBadRequest
+		if key == "buckets.notifications.error.duplicate_id" || key == "buckets.notifications.error.overlap" {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": middleware.T(c, key), "rule": rule.ID})
+		return
+	}
+
+	if err := h.minioService.SetBucketNotification(minioContext(c), bucketName, rules, username, password); err != nil {
+		log.Printf("[DEBUG] CreateNotificationRule failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	log.Printf("[DEBUG] Notification rule '%s' to '%s' added to bucket '%s' by user '%s'", rule.ID, rule.ARN, bucketName, username)
+	c.JSON(http.StatusCreated, gin.H{"message": middleware.T(c, "buckets.notifications.created"), "rule": rule})
+}
+
+// DeleteNotificationRule handles DELETE /buckets/:name/notifications/rules/*rule
+func (h *BucketHandler) DeleteNotificationRule(c *gin.Context) {
+	bucketName := c.Param("name")
+	ruleID := strings.TrimPrefix(c.Param("rule"), "/")
+	log.Printf("[DEBUG] DeleteNotificationRule request for rule '%s' of bucket '%s'", ruleID, bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in DeleteNotificationRule: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	rules, err := h.minioService.GetBucketNotification(minioContext(c), bucketName, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Reading notifications of bucket '%s' failed in DeleteNotificationRule: %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+	index := notificationRuleIndex(rules, ruleID)
+	if index < 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "buckets.notifications.error.rule_not_found"), "rule": ruleID})
+		return
+	}
+
+	middleware.AddAuditDetail(c, "before", rules[index])
+	if err := h.minioService.SetBucketNotification(minioContext(c), bucketName, append(rules[:index], rules[index+1:]...), username, password); err != nil {
+		log.Printf("[DEBUG] DeleteNotificationRule failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	log.Printf("[DEBUG] Notification rule '%s' of bucket '%s' deleted by user '%s'", ruleID, bucketName, username)
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "buckets.notifications.deleted")})
+}
+
+// ListNotificationTargets handles GET /api/notification-targets, listing the
+// targets configured on the server that notification rules can send to
+func (h *BucketHandler) ListNotificationTargets(c *gin.Context) {
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	targets, err := h.minioService.ListNotificationTargets(minioContext(c), username, password)
+	if err != nil {
+		log.Printf("[DEBUG] ListNotificationTargets failed for user '%s': %v", username, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"targets": targets})
+}
+
+// normalizeNotificationRule trims the ID and ARN of a rule and drops
+// repeated events. Filters are kept as sent since keys may contain spaces.
+func normalizeNotificationRule(rule *services.NotificationRule) {
+	rule.ID = strings.TrimSpace(rule.ID)
+	rule.ARN = strings.TrimSpace(rule.ARN)
+	events := make([]string, 0, len(rule.Events))
+	seen := make(map[string]bool, len(rule.Events))
+	for _, event := range rule.Events {
+		if event = strings.TrimSpace(event); event != "" && !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	rule.Events = events
+}
+
+// validateNotification checks the rule at index against the other rules
+// before they are sent to MinIO. The ARN is checked against targets unless
+// it is nil. It returns the translation key of the problem, or an empty
+// string.
+func validateNotification(rules []services.NotificationRule, index int, targets []services.NotificationTarget) string {
+	rule := rules[index]
+	if len(rule.ID) > maxRuleID {
+		return "buckets.notifications.error.id_too_long"
+	}
+	if rule.ARN == "" {
+		return "buckets.notifications.error.arn_required"
+	}
+	if _, err := services.ParseNotificationTarget(rule.ARN); err != nil {
+		return "buckets.notifications.error.arn_invalid"
+	}
+	if targets != nil && notificationTargetIndex(targets, rule.ARN) < 0 {
+		return "buckets.notifications.error.unknown_target"
+	}
+
+	if len(rule.Events) == 0 {
+		return "buckets.notifications.error.events_required"
+	}
+	known := make(map[string]bool, len(services.NotificationEvents))
+	for _, event := range services.NotificationEvents {
+		known[event] = true
+	}
+	for _, event := range rule.Events {
+		if !known[event] {
+			return "buckets.notifications.error.unknown_event"
+		}
+	}
+
+	for _, filter := range []string{rule.Prefix, rule.Suffix} {
+		if !validNotificationFilter(filter) {
+			return "buckets.notifications.error.filter"
+		}
+	}
+
+	for i, other := range rules {
+		if i == index {
+			continue
+		}
+		if other.ID == rule.ID {
+			return "buckets.notifications.error.duplicate_id"
+		}
+		// MinIO would deliver the same event twice to the target
+		if other.ARN == rule.ARN && other.Prefix == rule.Prefix && other.Suffix == rule.Suffix && notificationEventsOverlap(other.Events, rule.Events) {
+			return "buckets.notifications.error.overlap"
+		}
+	}
+	return ""
+}
+
+// validNotificationFilter reports whether MinIO accepts a prefix or suffix
+// filter: valid UTF-8 of at most 1024 bytes without "." or ".." segments
+func validNotificationFilter(filter string) bool {
+	if filter == "" {
+		return true
+	}
+	if len(filter) > maxNotificationFilter || !utf8.ValidString(filter) {
+		return false
+	}
+	for _, segment := range strings.Split(filter, "/") {
+		if segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// notificationEventsOverlap reports whether two event lists share an event,
+// counting s3:ObjectCreated:* as sharing every s3:ObjectCreated event
+func notificationEventsOverlap(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y || notificationEventCovers(x, y) || notificationEventCovers(y, x) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// notificationEventCovers reports whether the wildcard event pattern
+// includes event
+func notificationEventCovers(pattern, event string) bool {
+	prefix, ok := strings.CutSuffix(pattern, "*")
+	return ok && strings.HasPrefix(event, prefix)
+}
+
+// notificationRuleIndex returns the position of the rule with the given ID, or -1
+func notificationRuleIndex(rules []services.NotificationRule, id string) int {
+	for i, rule := range rules {
+		if rule.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// notificationTargetIndex returns the position of the target with the given
+// ARN, or -1
+func notificationTargetIndex(targets []services.NotificationTarget, arn string) int {
+	for i, target := range targets {
+		if target.ARN == arn {
+			return i
+		}
+	}
+	return -1
+}
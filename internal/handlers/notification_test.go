@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"minio-admin-panel/internal/services"
+)
+
+func TestNotificationRuleRoutes(t *testing.T) {
+	const webhook = "arn:minio:sqs::primary:webhook"
+
+	s := newTestServer(t)
+	s.backend.AddNotificationTarget(webhook)
+	s.addBucket("photos")
+	s.addUser("reader", "readersecret", "readonly")
+	root := s.login(testRootUser, testRootPassword)
+	reader := s.login("reader", "readersecret")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		cookie *http.Cookie
+		body   string
+		status int
+	}{
+		{"create", http.MethodPost, "/buckets/photos/notifications/rules", root, `{"id":"jpg","arn":"` + webhook + `","events":["s3:ObjectCreated:*"],"suffix":".jpg"}`, http.StatusCreated},
+		{"duplicate ID", http.MethodPost, "/buckets/photos/notifications/rules", root, `{"id":"jpg","arn":"` + webhook + `","events":["s3:ObjectRemoved:*"]}`, http.StatusConflict},
+		{"overlapping events", http.MethodPost, "/buckets/photos/notifications/rules", root, `{"id":"put","arn":"` + webhook + `","events":["s3:ObjectCreated:Put"],"suffix":".jpg"}`, http.StatusConflict},
+		{"unknown target", http.MethodPost, "/buckets/photos/notifications/rules", root, `{"arn":"arn:minio:sqs::other:kafka","events":["s3:ObjectCreated:*"]}`, http.StatusBadRequest},
+		{"malformed ARN", http.MethodPost, "/buckets/photos/notifications/rules", root, `{"arn":"webhook","events":["s3:ObjectCreated:*"]}`, http.StatusBadRequest},
+		{"unknown event", http.MethodPost, "/buckets/photos/notifications/rules", root, `{"arn":"` + webhook + `","events":["s3:Everything"]}`, http.StatusBadRequest},
+		{"no events", http.MethodPost, "/buckets/photos/notifications/rules", root, `{"arn":"` + webhook + `","events":[]}`, http.StatusBadRequest},
+		{"dot segment in prefix", http.MethodPost, "/buckets/photos/notifications/rules", root, `{"arn":"` + webhook + `","events":["s3:ObjectRemoved:*"],"prefix":"a/../b"}`, http.StatusBadRequest},
+		{"missing bucket", http.MethodPost, "/buckets/missing/notifications/rules", root, `{"arn":"` + webhook + `","events":["s3:ObjectCreated:*"]}`, http.StatusNotFound},
+		{"reader may not create", http.MethodPost, "/buckets/photos/notifications/rules", reader, `{"arn":"` + webhook + `","events":["s3:ObjectCreated:*"]}`, http.StatusForbidden},
+		{"reader may not read", http.MethodGet, "/buckets/photos/notifications", reader, "", http.StatusForbidden},
+		{"delete unknown rule", http.MethodDelete, "/buckets/photos/notifications/rules/missing", root, "", http.StatusNotFound},
+		{"delete", http.MethodDelete, "/buckets/photos/notifications/rules/jpg", root, "", http.StatusOK},
+		{"delete again", http.MethodDelete, "/buckets/photos/notifications/rules/jpg", root, "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(tt.method, tt.path, tt.cookie, tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("%s %s: got status %d, want %d (%s)", tt.method, tt.path, w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestNotificationRuleWithoutID(t *testing.T) {
+	const webhook = "arn:minio:sqs::primary:webhook"
+
+	s := newTestServer(t)
+	s.backend.AddNotificationTarget(webhook)
+	s.addBucket("photos")
+	root := s.login(testRootUser, testRootPassword)
+
+	w := s.do(http.MethodPost, "/buckets/photos/notifications/rules", root, `{"arn":"`+webhook+`","events":["s3:ObjectCreated:*"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: got status %d (%s)", w.Code, w.Body.String())
+	}
+	var created struct {
+		Rule services.NotificationRule `json:"rule"`
+	}
+	decode(t, w, &created)
+	if created.Rule.ID == "" {
+		t.Fatal("rule created without an ID")
+	}
+
+	var listed struct {
+		Rules []services.NotificationRule `json:"rules"`
+	}
+	decode(t, s.do(http.MethodGet, "/buckets/photos/notifications", root, ""), &listed)
+	if len(listed.Rules) != 1 || listed.Rules[0].ID != created.Rule.ID {
+		t.Fatalf("listed rules %+v, want the created rule %q", listed.Rules, created.Rule.ID)
+	}
+
+	if w := s.do(http.MethodDelete, "/buckets/photos/notifications/rules/"+created.Rule.ID, root, ""); w.Code != http.StatusOK {
+		t.Fatalf("delete: got status %d (%s)", w.Code, w.Body.String())
+	}
+}
+
+func TestValidNotificationFilter(t *testing.T) {
+	tests := []struct {
+		filter string
+		valid  bool
+	}{
+		{"", true},
+		{"images/", true},
+		{".jpg", true},
+		{"a/./b", false},
+		{"../a", false},
+		{"a/..", false},
+		{string([]byte{0xff}), false},
+	}
+	for _, tt := range tests {
+		if got := validNotificationFilter(tt.filter); got != tt.valid {
+			t.Errorf("validNotificationFilter(%q) = %t, want %t", tt.filter, got, tt.valid)
+		}
+	}
+}
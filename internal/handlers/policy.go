@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -13,10 +12,10 @@ import (
 )
 
 type PolicyHandler struct {
-	minioService *services.MinIOService
+	minioService services.PolicyBackend
 }
 
-func NewPolicyHandler(minioService *services.MinIOService) *PolicyHandler {
+func NewPolicyHandler(minioService services.PolicyBackend) *PolicyHandler {
 	return &PolicyHandler{
 		minioService: minioService,
 	}
@@ -46,10 +45,10 @@ func (h *PolicyHandler) ListPolicies(c *gin.Context) {
 	}
 
 	log.Printf("[DEBUG] ListPolicies for admin '%s'", username)
-	policies, err := h.minioService.ListPolicies(context.Background(), username, password)
+	policies, err := h.minioService.ListPolicies(minioContext(c), username, password)
 	if err != nil {
 		log.Printf("[DEBUG] ListPolicies failed for admin '%s': %v", username, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -103,10 +102,10 @@ func (h *PolicyHandler) GetPolicyDocument(c *gin.Context) {
 
 	log.Printf("[DEBUG] Getting policy document for '%s' by admin '%s'", policyName, username)
 
-	policyDocument, err := h.minioService.GetPolicyDocument(context.Background(), policyName, username, password)
+	policyDocument, err := h.minioService.GetPolicyDocument(minioContext(c), policyName, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] GetPolicyDocument failed for '%s': %v", policyName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -146,9 +145,9 @@ func (h *PolicyHandler) CreateOrUpdatePolicy(c *gin.Context) {
 
 	log.Printf("[DEBUG] Creating/updating policy '%s' by admin '%s', policy length: %d", policyName, username, len(req.Policy))
 
-	if err := h.minioService.CreateOrUpdatePolicyDocument(context.Background(), policyName, req.Policy, username, password); err != nil {
+	if err := h.minioService.CreateOrUpdatePolicyDocument(minioContext(c), policyName, req.Policy, username, password); err != nil {
 		log.Printf("[DEBUG] CreateOrUpdatePolicy failed for '%s': %v", policyName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -170,9 +169,9 @@ func (h *PolicyHandler) DeletePolicy(c *gin.Context) {
 
 	log.Printf("[DEBUG] Deleting policy '%s' by admin '%s'", policyName, username)
 
-	if err := h.minioService.DeletePolicyDocument(context.Background(), policyName, username, password); err != nil {
+	if err := h.minioService.DeletePolicyDocument(minioContext(c), policyName, username, password); err != nil {
 		log.Printf("[DEBUG] DeletePolicy failed for '%s': %v", policyName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -0,0 +1,591 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"minio-admin-panel/internal/middleware"
+	"minio-admin-panel/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7/pkg/s3utils"
+)
+
+// replicationTargetRequest is a target as sent by clients, who also send
+// its secret key
+type replicationTargetRequest struct {
+	services.ReplicationTarget
+	SecretKey string `json:"secret_key"`
+}
+
+// replicationRuleRequest is a rule as sent by clients. Enabled and Priority
+// are optional: new rules default to enabled with a priority above all
+// others, updated ones keep theirs.
+type replicationRuleRequest struct {
+	services.ReplicationRule
+	Enabled  *bool `json:"enabled"`
+	Priority *int  `json:"priority"`
+}
+
+// resyncRequest names the target to resync
+type resyncRequest struct {
+	ARN string `json:"arn" binding:"required"`
+}
+
+// ListReplicationTargets handles GET /buckets/:name/replication/targets
+func (h *BucketHandler) ListReplicationTargets(c *gin.Context) {
+	bucketName := c.Param("name")
+	log.Printf("[DEBUG] ListReplicationTargets request for bucket '%s'", bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in ListReplicationTargets: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	targets, err := h.minioService.ListReplicationTargets(minioContext(c), bucketName, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] ListReplicationTargets failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"targets": targets})
+}
+
+// AddReplicationTarget handles POST /buckets/:name/replication/targets. The
+// endpoint may be given as a URL, whose scheme then sets Secure.
+func (h *BucketHandler) AddReplicationTarget(c *gin.Context) {
+	bucketName := c.Param("name")
+	log.Printf("[DEBUG] AddReplicationTarget request for bucket '%s'", bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in AddReplicationTarget: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	var req replicationTargetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[DEBUG] Failed to bind request in AddReplicationTarget: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	target := req.ReplicationTarget
+	target.SecretKey = req.SecretKey
+	if key := normalizeReplicationTarget(&target); key != "" {
+		log.Printf("[DEBUG] Rejected replication target for bucket '%s': %s", bucketName, key)
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, key)})
+		return
+	}
+	if !h.requireVersioning(c, bucketName, username, password) {
+		return
+	}
+
+	arn, err := h.minioService.AddReplicationTarget(minioContext(c), bucketName, target, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] AddReplicationTarget failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	log.Printf("[DEBUG] Replication target '%s' added to bucket '%s' by user '%s'", arn, bucketName, username)
+	c.JSON(http.StatusCreated, gin.H{"message": middleware.T(c, "buckets.replication.target_added"), "arn": arn})
+}
+
+// RemoveReplicationTarget handles DELETE /buckets/:name/replication/targets/:arn
+func (h *BucketHandler) RemoveReplicationTarget(c *gin.Context) {
+	bucketName := c.Param("name")
+	arn := c.Param("arn")
+	log.Printf("[DEBUG] RemoveReplicationTarget request for target '%s' of bucket '%s'", arn, bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in RemoveReplicationTarget: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	// MinIO refuses to remove a target in use; say which rules use it
+	if rules, err := h.minioService.GetBucketReplication(minioContext(c), bucketName, username, password); err == nil {
+		var using []string
+		for _, rule := range rules {
+			if rule.TargetARN == arn {
+				using = append(using, rule.ID)
+			}
+		}
+		if len(using) > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "buckets.replication.error.target_in_use"), "rules": using})
+			return
+		}
+	}
+	if targets, err := h.minioService.ListReplicationTargets(minioContext(c), bucketName, username, password); err == nil {
+		if index := replicationTargetIndex(targets, arn); index >= 0 {
+			middleware.AddAuditDetail(c, "before", targets[index])
+		}
+	}
+
+	if err := h.minioService.RemoveReplicationTarget(minioContext(c), bucketName, arn, username, password); err != nil {
+		log.Printf("[DEBUG] RemoveReplicationTarget failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	log.Printf("[DEBUG] Replication target '%s' of bucket '%s' removed by user '%s'", arn, bucketName, username)
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "buckets.replication.target_removed")})
+}
+
+// GetBucketReplication handles GET /buckets/:name/replication
+func (h *BucketHandler) GetBucketReplication(c *gin.Context) {
+	bucketName := c.Param("name")
+	log.Printf("[DEBUG] GetBucketReplication request for bucket '%s'", bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in GetBucketReplication: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	rules, err := h.minioService.GetBucketReplication(minioContext(c), bucketName, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] GetBucketReplication failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// DeleteBucketReplication handles DELETE /buckets/:name/replication,
+// removing all replication rules of a bucket
+func (h *BucketHandler) DeleteBucketReplication(c *gin.Context) {
+	bucketName := c.Param("name")
+	log.Printf("[DEBUG] DeleteBucketReplication request for bucket '%s'", bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in DeleteBucketReplication: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	if before, err := h.minioService.GetBucketReplication(minioContext(c), bucketName, username, password); err == nil {
+		middleware.AddAuditDetail(c, "before", gin.H{"rules": replicationRuleIDs(before)})
+	}
+
+	if err := h.minioService.SetBucketReplication(minioContext(c), bucketName, nil, username, password); err != nil {
+		log.Printf("[DEBUG] DeleteBucketReplication failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	log.Printf("[DEBUG] Replication configuration of bucket '%s' removed by user '%s'", bucketName, username)
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "buckets.replication.deleted_all")})
+}
+
+// CreateReplicationRule handles POST /buckets/:name/replication/rules.
+// Rules without an ID get a random one.
+func (h *BucketHandler) CreateReplicationRule(c *gin.Context) {
+	bucketName := c.Param("name")
+	log.Printf("[DEBUG] CreateReplicationRule request for bucket '%s'", bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in CreateReplicationRule: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	var req replicationRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[DEBUG] Failed to bind request in CreateReplicationRule: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	rules, err := h.minioService.GetBucketReplication(minioContext(c), bucketName, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Reading replication of bucket '%s' failed in CreateReplicationRule: %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	rule := req.ReplicationRule
+	rule.Enabled = req.Enabled == nil || *req.Enabled
+	if req.Priority != nil {
+		rule.Priority = *req.Priority
+	} else {
+		rule.Priority = nextReplicationPriority(rules)
+	}
+	normalizeReplicationRule(&rule)
+	if rule.ID == "" {
+		if rule.ID, err = newRuleID(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "errors.generic")})
+			return
+		}
+	}
+	if replicationRuleIndex(rules, rule.ID) >= 0 {
+		log.Printf("[DEBUG] Replication rule '%s' already exists on bucket '%s'", rule.ID, bucketName)
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "buckets.replication.error.duplicate_id"), "rule": rule.ID})
+		return
+	}
+
+	if !h.saveReplication(c, bucketName, append(rules, rule), username, password) {
+		return
+	}
+
+	log.Printf("[DEBUG] Replication rule '%s' added to bucket '%s' by user '%s'", rule.ID, bucketName, username)
+	c.JSON(http.StatusCreated, gin.H{"message": middleware.T(c, "buckets.replication.rule_created"), "rule": rule})
+}
+
+// UpdateReplicationRule handles PUT /buckets/:name/replication/rules/*rule,
+// replacing a rule. The rule may be renamed by sending another ID.
+func (h *BucketHandler) UpdateReplicationRule(c *gin.Context) {
+	bucketName := c.Param("name")
+	ruleID := strings.TrimPrefix(c.Param("rule"), "/")
+	log.Printf("[DEBUG] UpdateReplicationRule request for rule '%s' of bucket '%s'", ruleID, bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in UpdateReplicationRule: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	var req replicationRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[DEBUG] Failed to bind request in UpdateReplicationRule: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	rules, err := h.minioService.GetBucketReplication(minioContext(c), bucketName, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Reading replication of bucket '%s' failed in UpdateReplicationRule: %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+	index := replicationRuleIndex(rules, ruleID)
+	if index < 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "buckets.replication.error.rule_not_found"), "rule": ruleID})
+		return
+	}
+
+	rule := req.ReplicationRule
+	rule.Enabled = rules[index].Enabled
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	rule.Priority = rules[index].Priority
+	if req.Priority != nil {
+		rule.Priority = *req.Priority
+	}
+	normalizeReplicationRule(&rule)
+	if rule.ID == "" {
+		rule.ID = ruleID
+	}
+	if rule.ID != ruleID && replicationRuleIndex(rules, rule.ID) >= 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "buckets.replication.error.duplicate_id"), "rule": rule.ID})
+		return
+	}
+
+	middleware.AddAuditDetail(c, "before", rules[index])
+	rules[index] = rule
+	if !h.saveReplication(c, bucketName, rules, username, password) {
+		return
+	}
+
+	log.Printf("[DEBUG] Replication rule '%s' of bucket '%s' updated by user '%s'", ruleID, bucketName, username)
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "buckets.replication.rule_updated"), "rule": rule})
+}
+
+// DeleteReplicationRule handles DELETE /buckets/:name/replication/rules/*rule
+func (h *BucketHandler) DeleteReplicationRule(c *gin.Context) {
+	bucketName := c.Param("name")
+	ruleID := strings.TrimPrefix(c.Param("rule"), "/")
+	log.Printf("[DEBUG] DeleteReplicationRule request for rule '%s' of bucket '%s'", ruleID, bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in DeleteReplicationRule: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	rules, err := h.minioService.GetBucketReplication(minioContext(c), bucketName, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Reading replication of bucket '%s' failed in DeleteReplicationRule: %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+	index := replicationRuleIndex(rules, ruleID)
+	if index < 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "buckets.replication.error.rule_not_found"), "rule": ruleID})
+		return
+	}
+
+	middleware.AddAuditDetail(c, "before", rules[index])
+	if err := h.minioService.SetBucketReplication(minioContext(c), bucketName, append(rules[:index], rules[index+1:]...), username, password); err != nil {
+		log.Printf("[DEBUG] DeleteReplicationRule failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	log.Printf("[DEBUG] Replication rule '%s' of bucket '%s' deleted by user '%s'", ruleID, bucketName, username)
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "buckets.replication.rule_deleted")})
+}
+
+// GetReplicationMetrics handles GET /buckets/:name/replication/metrics
+func (h *BucketHandler) GetReplicationMetrics(c *gin.Context) {
+	bucketName := c.Param("name")
+	log.Printf("[DEBUG] GetReplicationMetrics request for bucket '%s'", bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in GetReplicationMetrics: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	metrics, err := h.minioService.GetReplicationMetrics(minioContext(c), bucketName, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] GetReplicationMetrics failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"metrics": metrics})
+}
+
+// GetReplicationResyncStatus handles GET /buckets/:name/replication/resync
+func (h *BucketHandler) GetReplicationResyncStatus(c *gin.Context) {
+	bucketName := c.Param("name")
+	log.Printf("[DEBUG] GetReplicationResyncStatus request for bucket '%s'", bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in GetReplicationResyncStatus: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	resyncs, err := h.minioService.GetReplicationResyncStatus(minioContext(c), bucketName, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] GetReplicationResyncStatus failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"resyncs": resyncs})
+}
+
+// ResyncReplication handles POST /buckets/:name/replication/resync,
+// replicating every existing object to a target again, e.g. after the
+// target lost data
+func (h *BucketHandler) ResyncReplication(c *gin.Context) {
+	bucketName := c.Param("name")
+	log.Printf("[DEBUG] ResyncReplication request for bucket '%s'", bucketName)
+
+	username, password, err := h.getCredentials(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to get credentials in ResyncReplication: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing credentials"})
+		return
+	}
+
+	var req resyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[DEBUG] Failed to bind request in ResyncReplication: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	// Only rules replicating existing objects are resynced
+	if rules, err := h.minioService.GetBucketReplication(minioContext(c), bucketName, username, password); err == nil {
+		existing := false
+		for _, rule := range rules {
+			existing = existing || (rule.TargetARN == req.ARN && rule.Enabled && rule.ExistingObjects)
+		}
+		if !existing {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "buckets.replication.error.resync_needs_existing")})
+			return
+		}
+	}
+
+	resync, err := h.minioService.ResyncReplication(minioContext(c), bucketName, req.ARN, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] ResyncReplication failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return
+	}
+
+	log.Printf("[DEBUG] Resync '%s' of bucket '%s' to '%s' started by user '%s'", resync.ResetID, bucketName, req.ARN, username)
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "buckets.replication.resync_started"), "resync": resync})
+}
+
+// requireVersioning responds and returns false if the bucket is known not
+// to be versioned, which replication requires. If versioning cannot be
+// read, MinIO is left to decide.
+func (h *BucketHandler) requireVersioning(c *gin.Context, bucketName, username, password string) bool {
+	versioning, err := h.minioService.GetBucketVersioning(minioContext(c), bucketName, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Versioning of bucket '%s' unavailable to user '%s', not checking it: %v", bucketName, username, err)
+		return true
+	}
+	if versioning.Status != services.VersioningEnabled {
+		log.Printf("[DEBUG] Bucket '%s' is not versioned, refusing replication", bucketName)
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "buckets.replication.error.versioning_required")})
+		return false
+	}
+	return true
+}
+
+// saveReplication validates rules and stores them as the replication
+// configuration of a bucket. It responds and returns false if that fails.
+func (h *BucketHandler) saveReplication(c *gin.Context, bucketName string, rules []services.ReplicationRule, username, password string) bool {
+	// Check targets if the user may list them; MinIO rejects unknown ones
+	// either way
+	targets, err := h.minioService.ListReplicationTargets(minioContext(c), bucketName, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Replication targets unavailable to user '%s', not checking rules against them: %v", username, err)
+		targets = nil
+	}
+
+	if ruleID, key := validateReplication(rules, targets); key != "" {
+		log.Printf("[DEBUG] Rejected replication rule '%s' for bucket '%s': %s", ruleID, bucketName, key)
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, key), "rule": ruleID})
+		return false
+	}
+	if len(rules) > 0 && !h.requireVersioning(c, bucketName, username, password) {
+		return false
+	}
+
+	if err := h.minioService.SetBucketReplication(minioContext(c), bucketName, rules, username, password); err != nil {
+		log.Printf("[DEBUG] SetBucketReplication failed for bucket '%s': %v", bucketName, err)
+		respondMinIOError(c, err)
+		return false
+	}
+	return true
+}
+
+// normalizeReplicationTarget trims a target and splits a URL endpoint into
+// host and scheme. It returns the translation key of the first problem, or
+// an empty string.
+func normalizeReplicationTarget(target *services.ReplicationTarget) string {
+	target.Endpoint = strings.TrimSpace(target.Endpoint)
+	target.TargetBucket = strings.TrimSpace(target.TargetBucket)
+	target.Region = strings.TrimSpace(target.Region)
+	target.AccessKey = strings.TrimSpace(target.AccessKey)
+
+	if strings.Contains(target.Endpoint, "://") {
+		parsed, err := url.Parse(target.Endpoint)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || strings.Trim(parsed.Path, "/") != "" {
+			return "buckets.replication.error.endpoint_invalid"
+		}
+		target.Endpoint = parsed.Host
+		target.Secure = parsed.Scheme == "https"
+	}
+	switch {
+	case target.Endpoint == "" || strings.ContainsAny(target.Endpoint, "/ "):
+		return "buckets.replication.error.endpoint_invalid"
+	case s3utils.CheckValidBucketNameStrict(target.TargetBucket) != nil:
+		return "buckets.replication.error.target_bucket_invalid"
+	case target.AccessKey == "" || target.SecretKey == "":
+		return "buckets.replication.error.credentials_required"
+	case target.BandwidthLimit < 0 || target.HealthCheckSeconds < 0:
+		return "buckets.replication.error.negative"
+	}
+	return ""
+}
+
+// normalizeReplicationRule trims the ID, target and tag keys of a rule
+func normalizeReplicationRule(rule *services.ReplicationRule) {
+	rule.ID = strings.TrimSpace(rule.ID)
+	rule.TargetARN = strings.TrimSpace(rule.TargetARN)
+	rule.StorageClass = strings.TrimSpace(rule.StorageClass)
+	if len(rule.Tags) == 0 {
+		rule.Tags = nil
+		return
+	}
+	tags := make(map[string]string, len(rule.Tags))
+	for key, value := range rule.Tags {
+		if key = strings.TrimSpace(key); key != "" {
+			tags[key] = value
+		}
+	}
+	rule.Tags = tags
+}
+
+// validateReplication checks replication rules before they are sent to
+// MinIO. Targets are checked against targets unless it is nil. It returns
+// the ID of the first offending rule and the translation key of its
+// problem, or empty strings.
+func validateReplication(rules []services.ReplicationRule, targets []services.ReplicationTarget) (string, string) {
+	ids := make(map[string]bool, len(rules))
+	priorities := make(map[int]bool, len(rules))
+	for _, rule := range rules {
+		switch {
+		case rule.ID == "":
+			return rule.ID, "buckets.replication.error.id_required"
+		case len(rule.ID) > maxRuleID:
+			return rule.ID, "buckets.replication.error.id_too_long"
+		case ids[rule.ID]:
+			return rule.ID, "buckets.replication.error.duplicate_id"
+		case rule.Priority < 0:
+			return rule.ID, "buckets.replication.error.negative"
+		case priorities[rule.Priority]:
+			return rule.ID, "buckets.replication.error.duplicate_priority"
+		case rule.TargetARN == "":
+			return rule.ID, "buckets.replication.error.target_required"
+		case targets != nil && replicationTargetIndex(targets, rule.TargetARN) < 0:
+			return rule.ID, "buckets.replication.error.unknown_target"
+		}
+		ids[rule.ID], priorities[rule.Priority] = true, true
+	}
+	return "", ""
+}
+
+// nextReplicationPriority returns a priority above that of every rule
+func nextReplicationPriority(rules []services.ReplicationRule) int {
+	next := 1
+	for _, rule := range rules {
+		if rule.Priority >= next {
+			next = rule.Priority + 1
+		}
+	}
+	return next
+}
+
+// replicationRuleIndex returns the position of the rule with the given ID, or -1
+func replicationRuleIndex(rules []services.ReplicationRule, id string) int {
+	for i, rule := range rules {
+		if rule.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// replicationTargetIndex returns the position of the target with the given ARN, or -1
+func replicationTargetIndex(targets []services.ReplicationTarget, arn string) int {
+	for i, target := range targets {
+		if target.ARN == arn {
+			return i
+		}
+	}
+	return -1
+}
+
+// replicationRuleIDs returns the IDs of rules, e.g. for audit records
+func replicationRuleIDs(rules []services.ReplicationRule) []string {
+	ids := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		ids = append(ids, rule.ID)
+	}
+	return ids
+}
@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"minio-admin-panel/internal/apitoken"
+	"minio-admin-panel/internal/audit"
+	"minio-admin-panel/internal/cluster"
+	"minio-admin-panel/internal/i18n"
+	"minio-admin-panel/internal/middleware"
+	"minio-admin-panel/internal/rbac"
+	"minio-admin-panel/internal/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Routes holds the handlers and services the panel's routes are served with.
+// MFAHandler, APITokenHandler, Roles and Tokens are nil when the feature they
+// belong to is disabled.
+type Routes struct {
+	Version  string
+	Cookies  middleware.CookiePolicy
+	JWTKeys  *middleware.KeyRing
+	Sessions *session.Manager
+	Clusters *cluster.Registry
+	Roles    *rbac.Registry
+	Tokens   *apitoken.Store
+	AuditLog *audit.Logger
+
+	AuthHandler           *AuthHandler
+	BucketHandler         *BucketHandler
+	UserHandler           *UserHandler
+	PolicyHandler         *PolicyHandler
+	GroupHandler          *GroupHandler
+	ServiceAccountHandler *ServiceAccountHandler
+	APIHandler            *APIHandler
+	SettingsHandler       *SettingsHandler
+	AuditHandler          *AuditHandler
+	SessionHandler        *SessionHandler
+	APITokenHandler       *APITokenHandler
+	MFAHandler            *MFAHandler
+	ClusterHandler        *ClusterHandler
+}
+
+// SetupRoutes installs the panel's middleware and routes on r
+func SetupRoutes(r *gin.Engine, rt Routes) {
+	// Apply the cookie policy before any middleware sets cookies
+	r.Use(middleware.Cookies(rt.Cookies))
+
+	// Add language middleware
+	r.Use(middleware.LanguageMiddleware())
+
+	// Require a CSRF token on every state-changing request
+	r.Use(middleware.CSRF())
+
+	// Health check endpoint
+	r.GET("/health", func(c *gin.Context) {
+		c.JSON(200, gin.H{"status": "ok", "version": rt.Version})
+	})
+
+	// Auth routes
+	r.GET("/", rt.AuthHandler.LoginPage)
+	r.POST("/login", rt.AuthHandler.Login)
+	r.POST("/logout", rt.AuthHandler.Logout)
+	r.GET("/auth/oidc/login", rt.AuthHandler.OIDCLogin)
+	r.GET("/auth/oidc/callback", rt.AuthHandler.OIDCCallback)
+
+	// Session expiry status; does not extend the session
+	r.GET("/api/session", rt.AuthHandler.SessionStatus)
+
+	// Second factor for sessions that passed the password check
+	if rt.MFAHandler != nil {
+		mfaRoutes := r.Group("/login/mfa")
+		mfaRoutes.Use(middleware.MFAPending(rt.JWTKeys, rt.Sessions))
+		{
+			mfaRoutes.GET("", rt.MFAHandler.ShowVerify)
+			mfaRoutes.POST("", rt.MFAHandler.Verify)
+			mfaRoutes.GET("/setup", rt.MFAHandler.ShowEnroll)
+			mfaRoutes.POST("/setup", rt.MFAHandler.Enroll)
+		}
+	}
+
+	// Language switching endpoint
+	r.POST("/set-language", func(c *gin.Context) {
+		var langData struct {
+			Language string `json:"language" form:"language"`
+		}
+
+		if err := c.ShouldBind(&langData); err != nil {
+			c.JSON(400, gin.H{"error": "Invalid language parameter"})
+			return
+		}
+
+		// Validate the language
+		supportedLanguages := i18n.GetAvailableLanguages()
+		isValid := false
+		for _, lang := range supportedLanguages {
+			if langData.Language == lang {
+				isValid = true
+				break
+			}
+		}
+
+		if !isValid {
+			c.JSON(400, gin.H{"error": "Unsupported language"})
+			return
+		}
+
+		// Set the language cookie
+		middleware.SetCookie(c, "language", langData.Language, 86400*30, "/", false) // 30 days
+
+		// If it's a JSON request, return JSON response
+		if c.GetHeader("Content-Type") == "application/json" || c.GetHeader("Accept") == "application/json" {
+			c.JSON(200, gin.H{"success": true, "language": langData.Language})
+			return
+		}
+
+		// For form submissions, redirect back to the referring page
+		referer := c.GetHeader("Referer")
+		if referer == "" {
+			referer = "/"
+		}
+		c.Redirect(302, referer)
+	})
+
+	// track records an administrative action in the audit log
+	track := func(action string) gin.HandlerFunc {
+		return middleware.Audit(rt.AuditLog, action)
+	}
+
+	// clusterRoutes registers the pages and API routes that act on one MinIO
+	// cluster. They are served both for the session's own cluster and under
+	// /c/:cluster for any configured cluster.
+	clusterRoutes := func(g *gin.RouterGroup) {
+		// Dashboard - accessible to all authenticated users
+		g.GET("/dashboard", func(c *gin.Context) {
+			permissions := middleware.GetUserPermissions(c)
+			username, _ := c.Get("username")
+			policyName, _ := c.Get("policy_name")
+
+			// Use the helper for consistent translation handling
+			RenderWithTranslations(c, "dashboard.html", gin.H{
+				"title":       "MinIO Admin Panel",
+				"username":    username,
+				"policy_name": policyName,
+				"permissions": permissions,
+			})
+		})
+
+		// Bucket management - require bucket list permission
+		bucketRoutes := g.Group("/buckets")
+		bucketRoutes.Use(middleware.RequirePermission("canListBuckets"))
+		{
+			bucketRoutes.GET("", rt.BucketHandler.ListBuckets)
+			bucketRoutes.POST("", track("bucket.create"), middleware.RequirePermission("canCreateBuckets"), rt.BucketHandler.CreateBucket)
+			bucketRoutes.DELETE("/:name", track("bucket.delete"), middleware.RequireBucketPermission("s3:DeleteBucket", "name"), rt.BucketHandler.DeleteBucket)
+			bucketRoutes.GET("/:name/policy", middleware.RequireBucketPermission("s3:GetBucketPolicy", "name"), rt.BucketHandler.GetBucketPolicy)
+			bucketRoutes.PUT("/:name/policy", track("bucket.policy.set"), middleware.RequireBucketPermission("s3:PutBucketPolicy", "name"), rt.BucketHandler.SetBucketPolicy)
+			bucketRoutes.GET("/:name/versioning", middleware.RequireBucketPermission("s3:GetBucketVersioning", "name"), rt.BucketHandler.GetBucketVersioning)
+			bucketRoutes.PUT("/:name/versioning", track("bucket.versioning.set"), middleware.RequireBucketPermission("s3:PutBucketVersioning", "name"), rt.BucketHandler.SetBucketVersioning)
+			bucketRoutes.GET("/:name/object-lock", middleware.RequireBucketPermission("s3:GetBucketObjectLockConfiguration", "name"), rt.BucketHandler.GetBucketObjectLock)
+			bucketRoutes.PUT("/:name/object-lock", track("bucket.retention.set"), middleware.RequireBucketPermission("s3:PutBucketObjectLockConfiguration", "name"), rt.BucketHandler.SetBucketRetention)
+			bucketRoutes.GET("/:name/lifecycle", middleware.RequireBucketPermission("s3:GetLifecycleConfiguration", "name"), rt.BucketHandler.GetBucketLifecycle)
+			bucketRoutes.DELETE("/:name/lifecycle", track("bucket.lifecycle.delete"), middleware.RequireBucketPermission("s3:PutLifecycleConfiguration", "name"), rt.BucketHandler.DeleteBucketLifecycle)
+			bucketRoutes.POST("/:name/lifecycle/rules", track("bucket.lifecycle.rule.create"), middleware.RequireBucketPermission("s3:PutLifecycleConfiguration", "name"), rt.BucketHandler.CreateLifecycleRule)
+			bucketRoutes.PUT("/:name/lifecycle/rules/*rule", track("bucket.lifecycle.rule.update"), middleware.RequireBucketPermission("s3:PutLifecycleConfiguration", "name"), rt.BucketHandler.UpdateLifecycleRule)
+			bucketRoutes.DELETE("/:name/lifecycle/rules/*rule", track("bucket.lifecycle.rule.delete"), middleware.RequireBucketPermission("s3:PutLifecycleConfiguration", "name"), rt.BucketHandler.DeleteLifecycleRule)
+			bucketRoutes.GET("/:name/lifecycle/export", middleware.RequireBucketPermission("s3:GetLifecycleConfiguration", "name"), rt.BucketHandler.ExportBucketLifecycle)
+			bucketRoutes.POST("/:name/lifecycle/import", track("bucket.lifecycle.import"), middleware.RequireBucketPermission("s3:PutLifecycleConfiguration", "name"), rt.BucketHandler.ImportBucketLifecycle)
+			bucketRoutes.GET("/:name/replication", middleware.RequireBucketPermission("s3:GetReplicationConfiguration", "name"), rt.BucketHandler.GetBucketReplication)
+			bucketRoutes.DELETE("/:name/replication", track("bucket.replication.delete"), middleware.RequireBucketPermission("s3:PutReplicationConfiguration", "name"), rt.BucketHandler.DeleteBucketReplication)
+			bucketRoutes.POST("/:name/replication/rules", track("bucket.replication.rule.create"), middleware.RequireBucketPermission("s3:PutReplicationConfiguration", "name"), rt.BucketHandler.CreateReplicationRule)
+			bucketRoutes.PUT("/:name/replication/rules/*rule", track("bucket.replication.rule.update"), middleware.RequireBucketPermission("s3:PutReplicationConfiguration", "name"), rt.BucketHandler.UpdateReplicationRule)
+			bucketRoutes.DELETE("/:name/replication/rules/*rule", track("bucket.replication.rule.delete"), middleware.RequireBucketPermission("s3:PutReplicationConfiguration", "name"), rt.BucketHandler.DeleteReplicationRule)
+			bucketRoutes.GET("/:name/replication/targets", middleware.RequirePermission("admin:GetBucketTarget"), rt.BucketHandler.ListReplicationTargets)
+			bucketRoutes.POST("/:name/replication/targets", track("bucket.replication.target.add"), middleware.RequirePermission("admin:SetBucketTarget"), rt.BucketHandler.AddReplicationTarget)
+			bucketRoutes.DELETE("/:name/replication/targets/:arn", track("bucket.replication.target.remove"), middleware.RequirePermission("admin:SetBucketTarget"), rt.BucketHandler.RemoveReplicationTarget)
+			bucketRoutes.GET("/:name/replication/metrics", middleware.RequireBucketPermission("s3:GetReplicationConfiguration", "name"), rt.BucketHandler.GetReplicationMetrics)
+			bucketRoutes.GET("/:name/replication/resync", middleware.RequireBucketPermission("s3:ResetBucketReplicationState", "name"), rt.BucketHandler.GetReplicationResyncStatus)
+			bucketRoutes.POST("/:name/replication/resync", track("bucket.replication.resync"), middleware.RequireBucketPermission("s3:ResetBucketReplicationState", "name"), rt.BucketHandler.ResyncReplication)
+			bucketRoutes.GET("/:name/notifications", middleware.RequireBucketPermission("s3:GetBucketNotification", "name"), rt.BucketHandler.GetBucketNotification)
+			bucketRoutes.DELETE("/:name/notifications", track("bucket.notification.delete"), middleware.RequireBucketPermission("s3:PutBucketNotification", "name"), rt.BucketHandler.DeleteBucketNotification)
+			bucketRoutes.POST("/:name/notifications/rules", track("bucket.notification.rule.create"), middleware.RequireBucketPermission("s3:PutBucketNotification", "name"), rt.BucketHandler.CreateNotificationRule)
+			bucketRoutes.DELETE("/:name/notifications/rules/*rule", track("bucket.notification.rule.delete"), middleware.RequireBucketPermission("s3:PutBucketNotification", "name"), rt.BucketHandler.DeleteNotificationRule)
+		}
+
+		// User management - listing requires view permission, changes require manage permission
+		userRoutes := g.Group("/users")
+		userRoutes.Use(middleware.RequirePermission("canViewUsers"))
+		{
+			userRoutes.GET("", rt.UserHandler.ListUsers)
+			userRoutes.POST("", track("user.create"), middleware.RequirePermission("canManageUsers"), rt.UserHandler.CreateUser)
+			userRoutes.GET("/:name", rt.UserHandler.GetUser)
+			userRoutes.GET("/:name/details", rt.UserHandler.GetUserDetails)
+			userRoutes.GET("/:name/credentials", middleware.RequirePermission("canManageUsers"), rt.UserHandler.GetUserCredentials)
+			userRoutes.DELETE("/:name", track("user.delete"), middleware.RequirePermission("admin:DeleteUser"), rt.UserHandler.DeleteUser)
+			userRoutes.PUT("/:name/credentials", track("user.credentials.update"), middleware.RequirePermission("canManageUsers"), rt.UserHandler.UpdateUserCredentials)
+			userRoutes.PUT("/:name/status", track("user.status.set"), middleware.RequirePermission("admin:EnableUser"), rt.UserHandler.SetUserStatus)
+			userRoutes.GET("/:name/policy", rt.UserHandler.GetUserPolicy)
+			userRoutes.PUT("/:name/policy", track("user.policy.set"), middleware.RequirePermission("canAttachPolicies"), rt.UserHandler.SetUserPolicy)
+			userRoutes.PUT("/:name/groups", track("user.groups.set"), middleware.RequirePermission("canManageGroups"), rt.GroupHandler.SetUserGroups)
+		}
+
+		// Group management - listing requires view permission, changes require manage permission
+		groupRoutes := g.Group("/groups")
+		groupRoutes.Use(middleware.RequirePermission("canViewGroups"))
+		{
+			groupRoutes.GET("", rt.GroupHandler.ListGroups)
+			groupRoutes.POST("", track("group.create"), middleware.RequirePermission("canManageGroups"), rt.GroupHandler.CreateGroup)
+			groupRoutes.GET("/:name", rt.GroupHandler.GetGroupInfo)
+			groupRoutes.DELETE("/:name", track("group.delete"), middleware.RequirePermission("admin:RemoveUserFromGroup"), rt.GroupHandler.DeleteGroup)
+			groupRoutes.PUT("/:name/members", track("group.members.update"), middleware.RequirePermission("canManageGroups"), rt.GroupHandler.UpdateGroupMembers)
+			groupRoutes.PUT("/:name/policy", track("group.policy.set"), middleware.RequirePermission("canAttachPolicies"), rt.GroupHandler.SetGroupPolicy)
+		}
+
+		// Service Account management
+		serviceAccountRoutes := g.Group("/service-accounts")
+		serviceAccountRoutes.Use(middleware.RequirePermission("canViewServiceAccounts"))
+		{
+			serviceAccountRoutes.GET("", rt.ServiceAccountHandler.ListServiceAccounts)
+			serviceAccountRoutes.POST("", track("service_account.create"), middleware.RequirePermission("canManageServiceAccounts"), rt.ServiceAccountHandler.CreateServiceAccount)
+			serviceAccountRoutes.GET("/:accessKey", rt.ServiceAccountHandler.GetServiceAccountInfo)
+			serviceAccountRoutes.DELETE("/:accessKey", track("service_account.delete"), middleware.RequirePermission("admin:RemoveServiceAccount"), rt.ServiceAccountHandler.DeleteServiceAccount)
+		}
+
+		// Policy management - listing requires view permission, changes require manage permission
+		policyRoutes := g.Group("/policies")
+		policyRoutes.Use(middleware.RequirePermission("canViewPolicies"))
+		{
+			policyRoutes.GET("", rt.PolicyHandler.ListPolicies)
+			policyRoutes.GET("/:name", rt.PolicyHandler.GetPolicyDocument)
+			policyRoutes.POST("/:name", track("policy.create"), middleware.RequirePermission("canManagePolicies"), rt.PolicyHandler.CreateOrUpdatePolicy)
+			policyRoutes.PUT("/:name", track("policy.update"), middleware.RequirePermission("canManagePolicies"), rt.PolicyHandler.CreateOrUpdatePolicy)
+			policyRoutes.DELETE("/:name", track("policy.delete"), middleware.RequirePermission("admin:DeletePolicy"), rt.PolicyHandler.DeletePolicy)
+		}
+
+		// Settings - require admin permissions
+		g.GET("/settings", middleware.RequireRole(rbac.RoleSuperAdmin), middleware.RequirePermission("isAdmin"), rt.SettingsHandler.ShowSettings)
+
+		// API routes for AJAX that read the selected cluster
+		api := g.Group("/api")
+		{
+			api.GET("/server-info", middleware.RequirePermission("canViewServerInfo"), rt.APIHandler.GetServerInfo)
+			api.GET("/metrics", rt.APIHandler.GetMetrics)
+			api.GET("/storage-usage", rt.APIHandler.GetStorageUsage)
+			api.GET("/bucket-stats", middleware.RequirePermission("canListBuckets"), rt.BucketHandler.GetBucketStats)
+			api.POST("/bucket-stats/refresh", track("bucket.stats.refresh"), middleware.RequirePermission("canListBuckets"), rt.BucketHandler.RefreshBucketStats)
+			api.GET("/tiers", middleware.RequirePermission("canListBuckets"), rt.BucketHandler.ListTiers)
+			api.GET("/notification-targets", middleware.RequirePermission("canListBuckets"), rt.BucketHandler.ListNotificationTargets)
+			api.GET("/policies", middleware.RequirePermission("canViewPolicies"), rt.UserHandler.ListPolicies)
+			api.GET("/groups", middleware.RequirePermission("canViewGroups"), func(c *gin.Context) {
+				// Forward to group handler with JSON accept header
+				c.Request.Header.Set("Accept", "application/json")
+				rt.GroupHandler.ListGroups(c)
+			})
+			api.GET("/service-accounts", middleware.RequirePermission("canViewServiceAccounts"), rt.ServiceAccountHandler.ListServiceAccounts)
+			api.POST("/service-accounts", track("service_account.create"), middleware.RequirePermission("canManageServiceAccounts"), rt.ServiceAccountHandler.CreateServiceAccount)
+			api.GET("/service-accounts/:accessKey", middleware.RequirePermission("canViewServiceAccounts"), rt.ServiceAccountHandler.GetServiceAccountInfo)
+			api.DELETE("/service-accounts/:accessKey", track("service_account.delete"), middleware.RequirePermission("admin:RemoveServiceAccount"), rt.ServiceAccountHandler.DeleteServiceAccount)
+		}
+	}
+
+	// Protected routes
+	protected := r.Group("/")
+	protected.Use(middleware.AuthRequired(rt.JWTKeys, rt.Sessions, rt.Roles, rt.Tokens), middleware.SelectCluster(rt.Sessions, rt.Clusters))
+	{
+		clusterRoutes(protected)
+		clusterRoutes(protected.Group("/c/:cluster"))
+
+		// Clusters and the session's connections to them
+		protected.GET("/clusters", rt.ClusterHandler.ShowClusters)
+		protected.POST("/clusters/:name/connect", track("cluster.connect"), rt.ClusterHandler.Connect)
+		protected.POST("/clusters/:name/disconnect", track("cluster.disconnect"), rt.ClusterHandler.Disconnect)
+
+		// Audit log - require admin permissions
+		protected.GET("/audit", middleware.RequirePermission("isAdmin"), rt.AuditHandler.ShowAuditLog)
+
+		// Active sessions - require admin permissions
+		sessionRoutes := protected.Group("/sessions")
+		sessionRoutes.Use(middleware.RequirePermission("isAdmin"))
+		{
+			sessionRoutes.GET("", rt.SessionHandler.ShowSessions)
+			sessionRoutes.DELETE("/:id", track("session.revoke"), rt.SessionHandler.RevokeSession)
+			sessionRoutes.DELETE("/users/:name", track("session.revoke_user"), rt.SessionHandler.RevokeUserSessions)
+		}
+
+		// Two-factor authentication for the current user
+		if rt.MFAHandler != nil {
+			protected.GET("/account/mfa", rt.MFAHandler.ShowAccount)
+			protected.POST("/account/mfa", rt.MFAHandler.AccountEnroll)
+			protected.POST("/account/mfa/disable", rt.MFAHandler.AccountDisable)
+			protected.POST("/account/mfa/recovery-codes", rt.MFAHandler.AccountRecoveryCodes)
+		}
+
+		// Personal API tokens of the current user
+		if rt.APITokenHandler != nil {
+			protected.GET("/account/tokens", rt.APITokenHandler.ShowTokens)
+			protected.POST("/account/tokens", track("api_token.create"), rt.APITokenHandler.CreateToken)
+			protected.POST("/account/tokens/:id/delete", track("api_token.delete"), rt.APITokenHandler.DeleteToken)
+		}
+
+		// API routes for AJAX
+		api := protected.Group("/api")
+		{
+			api.POST("/session/refresh", rt.AuthHandler.RefreshSession)
+			api.GET("/audit", middleware.RequirePermission("isAdmin"), rt.AuditHandler.ListAuditRecords)
+			api.GET("/audit/verify", middleware.RequirePermission("isAdmin"), rt.AuditHandler.VerifyAuditLog)
+			api.GET("/login-limiter", middleware.RequirePermission("isAdmin"), rt.AuthHandler.LoginLimiterStatus)
+			api.GET("/sessions", middleware.RequirePermission("isAdmin"), rt.SessionHandler.ListSessions)
+			api.GET("/client-cache", middleware.RequirePermission("isAdmin"), rt.APIHandler.GetClientCacheStats)
+			api.GET("/clusters", rt.ClusterHandler.ListClusters)
+			api.GET("/clusters/lookup", rt.ClusterHandler.Lookup)
+			if rt.APITokenHandler != nil {
+				api.GET("/tokens", middleware.RequirePermission("isAdmin"), rt.APITokenHandler.ListAllTokens)
+				api.DELETE("/tokens/:id", track("api_token.revoke"), middleware.RequirePermission("isAdmin"), rt.APITokenHandler.RevokeToken)
+			}
+		}
+	}
+}
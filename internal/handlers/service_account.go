@@ -1,21 +1,21 @@
 package handlers
 
 import (
-	"context"
 	"fmt"
 	"log"
 	"net/http"
 
+	"minio-admin-panel/internal/middleware"
 	"minio-admin-panel/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 type ServiceAccountHandler struct {
-	minioService *services.MinIOService
+	minioService services.ServiceAccountBackend
 }
 
-func NewServiceAccountHandler(minioService *services.MinIOService) *ServiceAccountHandler {
+func NewServiceAccountHandler(minioService services.ServiceAccountBackend) *ServiceAccountHandler {
 	return &ServiceAccountHandler{
 		minioService: minioService,
 	}
@@ -52,10 +52,10 @@ func (h *ServiceAccountHandler) ListServiceAccounts(c *gin.Context) {
 	}
 
 	log.Printf("[DEBUG] ListServiceAccounts for target user '%s' by admin '%s'", targetUser, username)
-	serviceAccounts, err := h.minioService.ListServiceAccounts(context.Background(), targetUser, username, password)
+	serviceAccounts, err := h.minioService.ListServiceAccounts(minioContext(c), targetUser, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to list service accounts for user '%s': %v", targetUser, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to list service accounts: %v", err)})
+		respondMinIOError(c, fmt.Errorf("Failed to list service accounts: %w", err))
 		return
 	}
 
@@ -86,15 +86,18 @@ func (h *ServiceAccountHandler) CreateServiceAccount(c *gin.Context) {
 		return
 	}
 
+	middleware.SetAuditTarget(c, req.TargetUser)
+
 	log.Printf("[DEBUG] CreateServiceAccount for target user '%s' with name '%s' by admin '%s'", req.TargetUser, req.Name, username)
-	serviceAccount, err := h.minioService.CreateServiceAccount(context.Background(), req.TargetUser, req.Name, req.Description, username, password)
+	serviceAccount, err := h.minioService.CreateServiceAccount(minioContext(c), req.TargetUser, req.Name, req.Description, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create service account for user '%s': %v", req.TargetUser, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create service account: %v", err)})
+		respondMinIOError(c, fmt.Errorf("Failed to create service account: %w", err))
 		return
 	}
 
 	log.Printf("[DEBUG] CreateServiceAccount successful for user '%s', access key: '%s'", req.TargetUser, serviceAccount["access_key"])
+	middleware.AddAuditDetail(c, "created_access_key", serviceAccount["access_key"])
 	c.JSON(http.StatusOK, gin.H{"service_account": serviceAccount})
 }
 
@@ -117,10 +120,10 @@ func (h *ServiceAccountHandler) DeleteServiceAccount(c *gin.Context) {
 	}
 
 	log.Printf("[DEBUG] DeleteServiceAccount for access key '%s' by admin '%s'", accessKey, username)
-	err = h.minioService.DeleteServiceAccount(context.Background(), accessKey, username, password)
+	err = h.minioService.DeleteServiceAccount(minioContext(c), accessKey, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to delete service account '%s': %v", accessKey, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete service account: %v", err)})
+		respondMinIOError(c, fmt.Errorf("Failed to delete service account: %w", err))
 		return
 	}
 
@@ -147,10 +150,10 @@ func (h *ServiceAccountHandler) GetServiceAccountInfo(c *gin.Context) {
 	}
 
 	log.Printf("[DEBUG] GetServiceAccountInfo for access key '%s' by admin '%s'", accessKey, username)
-	serviceAccount, err := h.minioService.GetServiceAccountInfo(context.Background(), accessKey, username, password)
+	serviceAccount, err := h.minioService.GetServiceAccountInfo(minioContext(c), accessKey, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to get service account info '%s': %v", accessKey, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get service account info: %v", err)})
+		respondMinIOError(c, fmt.Errorf("Failed to get service account info: %w", err))
 		return
 	}
 
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"minio-admin-panel/internal/apitoken"
+	"minio-admin-panel/internal/middleware"
+	"minio-admin-panel/internal/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionInfo describes an active panel session without its credentials
+type SessionInfo struct {
+	ID         string    `json:"id"`
+	Username   string    `json:"username"`
+	Cluster    string    `json:"cluster,omitempty"`
+	Role       string    `json:"role,omitempty"`
+	ClientIP   string    `json:"client_ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	MFAPending bool      `json:"mfa_pending"`
+	Current    bool      `json:"current"`
+}
+
+type SessionHandler struct {
+	sessions *session.Manager
+	tokens   *apitoken.Store // nil when API tokens are disabled
+}
+
+func NewSessionHandler(sessions *session.Manager, tokens *apitoken.Store) *SessionHandler {
+	return &SessionHandler{
+		sessions: sessions,
+		tokens:   tokens,
+	}
+}
+
+// ShowSessions handles GET /sessions
+func (h *SessionHandler) ShowSessions(c *gin.Context) {
+	list, err := h.list(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to list sessions: %v", err)
+		RenderWithTranslations(c, "sessions.html", gin.H{
+			"title": "sessions.title",
+			"error": "sessions.load_failed",
+		})
+		return
+	}
+
+	RenderWithTranslations(c, "sessions.html", gin.H{
+		"title":    "sessions.title",
+		"sessions": list,
+	})
+}
+
+// ListSessions handles GET /api/sessions
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	list, err := h.list(c)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to list sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": list, "count": len(list)})
+}
+
+// RevokeSession handles DELETE /sessions/:id
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	id := c.Param("id")
+	if id == c.GetString("session_id") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "sessions.error.own_session")})
+		return
+	}
+
+	sess, err := h.sessions.Revoke(id, c.GetString("display_name"))
+	if err != nil {
+		log.Printf("[DEBUG] Failed to revoke session: %v", err)
+		if errors.Is(err, session.ErrNotFound) || errors.Is(err, session.ErrRevoked) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "sessions.error.not_found")})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	middleware.SetAuditTarget(c, sess.Username)
+	middleware.AddAuditDetail(c, "client_ip", sess.ClientIP)
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "sessions.revoked_success")})
+}
+
+// RevokeUserSessions handles DELETE /sessions/users/:name, signing the user
+// out everywhere and revoking their API tokens. The caller's own session and
+// token are kept.
+func (h *SessionHandler) RevokeUserSessions(c *gin.Context) {
+	username := c.Param("name")
+
+	count, err := h.sessions.RevokeUser(username, c.GetString("display_name"), c.GetString("session_id"))
+	if err != nil {
+		log.Printf("[DEBUG] Failed to revoke sessions of user '%s': %v", username, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.tokens.RevokeOwner(username, c.GetString("api_token_id"))
+	if err != nil {
+		log.Printf("[DEBUG] Failed to revoke API tokens of user '%s': %v", username, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("[DEBUG] Revoked %d sessions and %d API tokens of user '%s'", count, tokens, username)
+	middleware.AddAuditDetail(c, "revoked", count)
+	if tokens > 0 {
+		middleware.AddAuditDetail(c, "revoked_tokens", tokens)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":        middleware.T(c, "sessions.revoked_user_success"),
+		"revoked":        count,
+		"revoked_tokens": tokens,
+	})
+}
+
+// list returns the active sessions, marking the caller's own
+func (h *SessionHandler) list(c *gin.Context) ([]SessionInfo, error) {
+	sessions, err := h.sessions.List()
+	if err != nil {
+		return nil, err
+	}
+
+	current := c.GetString("session_id")
+	list := make([]SessionInfo, 0, len(sessions))
+	for _, sess := range sessions {
+		list = append(list, SessionInfo{
+			ID:         sess.ID,
+			Username:   sess.Username,
+			Cluster:    sess.Cluster,
+			Role:       sess.Role,
+			ClientIP:   sess.ClientIP,
+			UserAgent:  sess.UserAgent,
+			CreatedAt:  sess.CreatedAt,
+			LastSeenAt: sess.LastSeenAt,
+			ExpiresAt:  sess.ExpiresAt,
+			MFAPending: sess.MFAState != "",
+			Current:    sess.ID == current,
+		})
+	}
+	return list, nil
+}
@@ -7,14 +7,14 @@ import (
 )
 
 type SettingsHandler struct {
-	minioService *services.MinIOService
+	minioService services.Backend
 	version      string
 	commit       string
 	date         string
 	builtBy      string
 }
 
-func NewSettingsHandler(minioService *services.MinIOService, version, commit, date, builtBy string) *SettingsHandler {
+func NewSettingsHandler(minioService services.Backend, version, commit, date, builtBy string) *SettingsHandler {
 	return &SettingsHandler{
 		minioService: minioService,
 		version:      version,
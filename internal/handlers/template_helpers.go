@@ -6,6 +6,7 @@ import (
 	"strings"
 	"minio-admin-panel/internal/i18n"
 	"minio-admin-panel/internal/middleware"
+	"minio-admin-panel/internal/rbac"
 
 	"github.com/gin-gonic/gin"
 )
@@ -53,6 +54,14 @@ func NewTemplateData(c *gin.Context, title string) TemplateData {
 	}
 }
 
+// templateDefaults holds application-wide values available to every template
+var templateDefaults = gin.H{}
+
+// SetTemplateDefault makes a value available to every rendered template
+func SetTemplateDefault(key string, value interface{}) {
+	templateDefaults[key] = value
+}
+
 // RenderWithTranslations renders template with translation support
 func RenderWithTranslations(c *gin.Context, templateName string, data gin.H) {
 	lang := middleware.GetLanguage(c)
@@ -85,6 +94,14 @@ func RenderWithTranslations(c *gin.Context, templateName string, data gin.H) {
 			data["currentPage"] = "buckets"
 		case strings.Contains(templateName, "settings"):
 			data["currentPage"] = "settings"
+		case strings.Contains(templateName, "audit"):
+			data["currentPage"] = "audit"
+		case strings.Contains(templateName, "sessions"):
+			data["currentPage"] = "sessions"
+		case strings.Contains(templateName, "account"):
+			data["currentPage"] = "account"
+		case strings.Contains(templateName, "clusters"):
+			data["currentPage"] = "clusters"
 		default:
 			data["currentPage"] = ""
 		}
@@ -159,6 +176,9 @@ func RenderWithTranslations(c *gin.Context, templateName string, data gin.H) {
 		username, _ := c.Get("username")
 		translatedData["username"] = username
 	}
+	if _, exists := translatedData["display_name"]; !exists {
+		translatedData["display_name"] = c.GetString("display_name")
+	}
 	if _, exists := translatedData["policy_name"]; !exists {
 		policyName, _ := c.Get("policy_name")
 		translatedData["policy_name"] = policyName
@@ -166,6 +186,31 @@ func RenderWithTranslations(c *gin.Context, templateName string, data gin.H) {
 	if _, exists := translatedData["permissions"]; !exists {
 		translatedData["permissions"] = middleware.GetUserPermissions(c)
 	}
+	if _, exists := translatedData["access"]; !exists {
+		translatedData["access"] = middleware.GetAccess(c)
+	}
+	for key, value := range templateDefaults {
+		if _, exists := translatedData[key]; !exists {
+			translatedData[key] = value
+		}
+	}
+	if _, exists := translatedData["csrf_token"]; !exists {
+		translatedData["csrf_token"] = middleware.CSRFToken(c)
+	}
+	if _, exists := translatedData["panel_role"]; !exists {
+		if role, ok := c.Get("role"); ok {
+			translatedData["panel_role"] = role.(*rbac.Role).Name
+		}
+	}
+	if _, exists := translatedData["clusterPrefix"]; !exists {
+		translatedData["clusterPrefix"] = clusterPrefix(c)
+	}
+	if clusters != nil && clusters.Multiple() {
+		if current := clusters.Get(c.GetString("cluster")); current != nil {
+			translatedData["currentCluster"] = current.DisplayName()
+		}
+		translatedData["clusterMenu"] = clusterMenu(c)
+	}
 
 	log.Printf("[DEBUG i18n] Final template data keys: ")
 	for key := range translatedData {
@@ -174,5 +219,6 @@ func RenderWithTranslations(c *gin.Context, templateName string, data gin.H) {
 	fmt.Printf("\n")
 	log.Printf("[DEBUG i18n] Calling c.HTML with template: %s", templateName)
 
-	c.HTML(200, templateName, translatedData)
+	// Keep an error status the handler set with c.Status, so failures are audited as such
+	c.HTML(c.Writer.Status(), templateName, translatedData)
 }
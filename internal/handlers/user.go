@@ -1,23 +1,26 @@
 package handlers
 
 import (
-	"context"
 	"fmt"
 	"log"
 	"net/http"
 
+	"minio-admin-panel/internal/apitoken"
+	"minio-admin-panel/internal/middleware"
 	"minio-admin-panel/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 type UserHandler struct {
-	minioService *services.MinIOService
+	minioService services.IAMBackend
+	tokens       *apitoken.Store // nil when API tokens are disabled
 }
 
-func NewUserHandler(minioService *services.MinIOService) *UserHandler {
+func NewUserHandler(minioService services.IAMBackend, tokens *apitoken.Store) *UserHandler {
 	return &UserHandler{
 		minioService: minioService,
+		tokens:       tokens,
 	}
 }
 
@@ -46,10 +49,10 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 	}
 
 	log.Printf("[DEBUG] ListUsers for user '%s'", username)
-	users, err := h.minioService.ListUsers(context.Background(), username, password)
+	users, err := h.minioService.ListUsers(minioContext(c), username, password)
 	if err != nil {
 		log.Printf("[DEBUG] ListUsers failed for user '%s': %v", username, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -93,9 +96,10 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Access key and secret key are required"})
 		return
 	}
+	middleware.SetAuditTarget(c, req.AccessKey)
 
-	if err := h.minioService.CreateUser(context.Background(), req.AccessKey, req.SecretKey, username, password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.minioService.CreateUser(minioContext(c), req.AccessKey, req.SecretKey, username, password); err != nil {
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -112,8 +116,11 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 
 	accessKey := c.Param("name")
 
-	if err := h.minioService.DeleteUser(context.Background(), accessKey, username, password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.minioService.DeleteUser(minioContext(c), accessKey, username, password); err != nil {
+		respondMinIOError(c, err)
+		return
+	}
+	if !h.revokeTokens(c, accessKey) {
 		return
 	}
 
@@ -143,10 +150,14 @@ func (h *UserHandler) SetUserPolicy(c *gin.Context) {
 		return
 	}
 
+	if before, err := h.minioService.GetUserPolicy(minioContext(c), accessKey, username, password); err == nil {
+		middleware.AddAuditDetail(c, "before", gin.H{"policy": before})
+	}
+
 	log.Printf("[DEBUG] Setting policy '%s' for user '%s' by admin '%s'", req.Policy, accessKey, username)
-	if err := h.minioService.SetUserPolicy(context.Background(), accessKey, req.Policy, username, password); err != nil {
+	if err := h.minioService.SetUserPolicy(minioContext(c), accessKey, req.Policy, username, password); err != nil {
 		log.Printf("[DEBUG] SetUserPolicy failed for '%s': %v", accessKey, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -168,15 +179,15 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 	accessKey := c.Param("name")
 	log.Printf("[DEBUG] Getting user details for '%s' by admin '%s'", accessKey, username)
 
-	user, err := h.minioService.GetUser(context.Background(), accessKey, username, password)
+	user, err := h.minioService.GetUser(minioContext(c), accessKey, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] GetUser failed for '%s': %v", accessKey, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
 		return
 	}
 
 	// Also get user policy
-	policy, err := h.minioService.GetUserPolicy(context.Background(), accessKey, username, password)
+	policy, err := h.minioService.GetUserPolicy(minioContext(c), accessKey, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] GetUserPolicy failed for '%s': %v", accessKey, err)
 		// Don't fail the request, just leave policy empty
@@ -212,9 +223,9 @@ func (h *UserHandler) UpdateUserCredentials(c *gin.Context) {
 	}
 
 	log.Printf("[DEBUG] Updating credentials for user '%s' by admin '%s'", accessKey, username)
-	if err := h.minioService.UpdateUserCredentials(context.Background(), accessKey, req.SecretKey, username, password); err != nil {
+	if err := h.minioService.UpdateUserCredentials(minioContext(c), accessKey, req.SecretKey, username, password); err != nil {
 		log.Printf("[DEBUG] UpdateUserCredentials failed for '%s': %v", accessKey, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -245,10 +256,20 @@ func (h *UserHandler) SetUserStatus(c *gin.Context) {
 		return
 	}
 
+	if before, err := h.minioService.GetUser(minioContext(c), accessKey, username, password); err == nil {
+		middleware.AddAuditDetail(c, "before", gin.H{"status": before.Status})
+	}
+
 	log.Printf("[DEBUG] Setting status for user '%s' to enabled=%t by admin '%s'", accessKey, req.Enabled, username)
-	if err := h.minioService.SetUserStatus(context.Background(), accessKey, req.Enabled, username, password); err != nil {
+	if err := h.minioService.SetUserStatus(minioContext(c), accessKey, req.Enabled, username, password); err != nil {
 		log.Printf("[DEBUG] SetUserStatus failed for '%s': %v", accessKey, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
+		return
+	}
+
+	// A disabled user must not keep working through tokens that carry the
+	// role and permissions they had when the token was created
+	if !req.Enabled && !h.revokeTokens(c, accessKey) {
 		return
 	}
 
@@ -256,6 +277,22 @@ func (h *UserHandler) SetUserStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "User status updated successfully"})
 }
 
+// revokeTokens revokes the API tokens of a user that was disabled or
+// deleted, responding with an error if that fails
+func (h *UserHandler) revokeTokens(c *gin.Context, accessKey string) bool {
+	revoked, err := h.tokens.RevokeOwner(accessKey, "")
+	if err != nil {
+		log.Printf("[DEBUG] Failed to revoke API tokens of user '%s': %v", accessKey, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if revoked > 0 {
+		log.Printf("[DEBUG] Revoked %d API tokens of user '%s'", revoked, accessKey)
+		middleware.AddAuditDetail(c, "revoked_tokens", revoked)
+	}
+	return true
+}
+
 // GetUserPolicy handles GET /users/:name/policy
 func (h *UserHandler) GetUserPolicy(c *gin.Context) {
 	log.Printf("[DEBUG] GetUserPolicy request for access key '%s'", c.Param("name"))
@@ -270,10 +307,10 @@ func (h *UserHandler) GetUserPolicy(c *gin.Context) {
 	accessKey := c.Param("name")
 	log.Printf("[DEBUG] Getting policy for user '%s' by admin '%s'", accessKey, username)
 
-	policy, err := h.minioService.GetUserPolicy(context.Background(), accessKey, username, password)
+	policy, err := h.minioService.GetUserPolicy(minioContext(c), accessKey, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] GetUserPolicy failed for '%s': %v", accessKey, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -293,10 +330,10 @@ func (h *UserHandler) ListPolicies(c *gin.Context) {
 	}
 
 	log.Printf("[DEBUG] Listing policies by admin '%s'", username)
-	policies, err := h.minioService.ListPolicies(context.Background(), username, password)
+	policies, err := h.minioService.ListPolicies(minioContext(c), username, password)
 	if err != nil {
 		log.Printf("[DEBUG] ListPolicies failed: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -324,10 +361,10 @@ func (h *UserHandler) GetUserDetails(c *gin.Context) {
 	accessKey := c.Param("name")
 	log.Printf("[DEBUG] Getting detailed user info for '%s' by admin '%s'", accessKey, username)
 
-	details, err := h.minioService.GetUserDetails(context.Background(), accessKey, username, password)
+	details, err := h.minioService.GetUserDetails(minioContext(c), accessKey, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] GetUserDetails failed for '%s': %v", accessKey, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -349,10 +386,10 @@ func (h *UserHandler) GetUserCredentials(c *gin.Context) {
 
 	log.Printf("[DEBUG] Getting credentials for user '%s' by admin '%s'", accessKey, username)
 
-	credentials, err := h.minioService.GetUserCredentials(context.Background(), accessKey, username, password)
+	credentials, err := h.minioService.GetUserCredentials(minioContext(c), accessKey, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] GetUserCredentials failed for '%s': %v", accessKey, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMinIOError(c, err)
 		return
 	}
 
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"minio-admin-panel/internal/apitoken"
+)
+
+func TestUserChangesRevokeAPITokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		revoked bool
+	}{
+		{"disable", http.MethodPut, "/users/alice/status", `{"enabled":false}`, true},
+		{"enable", http.MethodPut, "/users/alice/status", `{"enabled":true}`, false},
+		{"delete", http.MethodDelete, "/users/alice", "", true},
+		{"sign out everywhere", http.MethodDelete, "/sessions/users/alice", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			s.addUser("alice", "alicesecret", "readwrite")
+			s.addUser("bob", "bobsecret12", "readwrite")
+			_, aliceToken := s.createToken("alice", "alicesecret", "buckets:read")
+			_, bobToken := s.createToken("bob", "bobsecret12", "buckets:read")
+			root := s.login(testRootUser, testRootPassword)
+
+			if w := s.do(tt.method, tt.path, root, tt.body); w.Code != http.StatusOK {
+				t.Fatalf("%s %s: got status %d (%s)", tt.method, tt.path, w.Code, w.Body.String())
+			}
+
+			_, err := s.tokens.Authenticate(aliceToken)
+			if revoked := errors.Is(err, apitoken.ErrInvalid); revoked != tt.revoked {
+				t.Errorf("token of alice revoked: %t, want %t (err: %v)", revoked, tt.revoked, err)
+			}
+			if _, err := s.tokens.Authenticate(bobToken); err != nil {
+				t.Errorf("token of bob no longer works: %v", err)
+			}
+		})
+	}
+}
+
+func TestAPITokenRoutes(t *testing.T) {
+	s := newTestServer(t)
+	s.addBucket("photos")
+	s.addUser("alice", "alicesecret", "readonly")
+	_, readToken := s.createToken(testRootUser, testRootPassword, "buckets:read")
+	_, writeToken := s.createToken(testRootUser, testRootPassword, "buckets:write")
+	_, aliceToken := s.createToken("alice", "alicesecret", "buckets:write")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		status int
+	}{
+		{"read scope lists", http.MethodGet, "/buckets", readToken, "", http.StatusOK},
+		{"read scope lists on its cluster", http.MethodGet, "/c/default/buckets", readToken, "", http.StatusOK},
+		{"read scope may not create", http.MethodPost, "/buckets", readToken, `{"name":"denied"}`, http.StatusForbidden},
+		{"write scope creates", http.MethodPost, "/buckets", writeToken, `{"name":"created"}`, http.StatusCreated},
+		{"write scope creates on its cluster", http.MethodPost, "/c/default/buckets", writeToken, `{"name":"prefixed"}`, http.StatusCreated},
+		{"bucket scope may not list users", http.MethodGet, "/users", writeToken, "", http.StatusForbidden},
+		{"policy still applies", http.MethodPost, "/buckets", aliceToken, `{"name":"alice"}`, http.StatusForbidden},
+		{"unknown token", http.MethodGet, "/buckets", apitoken.Prefix + "0123456789abcdef_secret", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.doToken(tt.method, tt.path, tt.token, tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("%s %s: got status %d, want %d (%s)", tt.method, tt.path, w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package loginlimit
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config controls how failed logins are throttled
+type Config struct {
+	MaxAttempts int           // failures before a key is locked out
+	BaseDelay   time.Duration // back-off after the first failure, doubled for each further one
+	Lockout     time.Duration // lockout length once MaxAttempts is reached
+	AllowList   []string      // IP addresses or CIDR ranges that are never throttled
+}
+
+// Stats are counters for the login limiter since start
+type Stats struct {
+	Failures int64 `json:"failures"`
+	Blocked  int64 `json:"blocked"`
+	Lockouts int64 `json:"lockouts"`
+	Tracked  int   `json:"tracked"`
+}
+
+// Lock describes a key that is currently throttled
+type Lock struct {
+	Key      string    `json:"key"`
+	Failures int       `json:"failures"`
+	Until    time.Time `json:"until"`
+	Lockout  bool      `json:"lockout"`
+}
+
+// attemptTimeout is how long an attempt that was never released counts as
+// in progress
+const attemptTimeout = 5 * time.Minute
+
+type entry struct {
+	failures    int
+	lastFailure time.Time
+	blocked     time.Time
+	pending     int       // attempts that passed Check and are not released yet
+	reserved    time.Time // when the latest of them passed Check
+}
+
+// inFlight returns the attempts in progress, forgetting stale ones
+func (e *entry) inFlight(now time.Time) int {
+	if e.pending > 0 && now.Sub(e.reserved) > attemptTimeout {
+		e.pending = 0
+	}
+	return e.pending
+}
+
+// Limiter throttles login attempts per client IP and per username with
+// exponential back-off and a temporary lockout
+type Limiter struct {
+	cfg       Config
+	allow     []*net.IPNet
+	mu        sync.Mutex
+	entries   map[string]*entry
+	stats     Stats
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// New creates a limiter, parsing the allow-list
+func New(cfg Config) (*Limiter, error) {
+	l := &Limiter{cfg: cfg, entries: make(map[string]*entry), now: time.Now}
+	for _, value := range cfg.AllowList {
+		if !strings.Contains(value, "/") {
+			if ip := net.ParseIP(value); ip != nil && ip.To4() != nil {
+				value += "/32"
+			} else {
+				value += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid login allow-list entry %q: %v", value, err)
+		}
+		l.allow = append(l.allow, network)
+	}
+	return l, nil
+}
+
+// Check reports how long a login from ip for username must wait. Zero means
+// the attempt may proceed; it then counts towards the limit until the caller
+// passes it to Release, so parallel attempts cannot all pass before the
+// first failure is recorded.
+func (l *Limiter) Check(ip, username string) time.Duration {
+	if l == nil || l.allowed(ip) {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	var wait time.Duration
+	for _, key := range keys(ip, username) {
+		e, ok := l.entries[key]
+		if !ok {
+			continue
+		}
+		// A lockout that has run out starts the count afresh
+		if l.lockedOut(e) && !e.blocked.After(now) {
+			e.failures = 0
+		}
+		if e.blocked.After(now) {
+			if d := e.blocked.Sub(now); d > wait {
+				wait = d
+			}
+		}
+		// Attempts in progress could use up the remaining ones
+		if l.cfg.MaxAttempts > 0 && e.failures+e.inFlight(now) >= l.cfg.MaxAttempts {
+			if d := max(l.cfg.BaseDelay, time.Second); d > wait {
+				wait = d
+			}
+		}
+	}
+	if wait > 0 {
+		l.stats.Blocked++
+		log.Printf("[DEBUG] Login attempt for user '%s' from IP %s blocked for %s", username, ip, wait.Round(time.Second))
+		return wait
+	}
+
+	for _, key := range keys(ip, username) {
+		e, ok := l.entries[key]
+		if !ok {
+			e = &entry{}
+			l.entries[key] = e
+		}
+		e.pending++
+		e.reserved = now
+	}
+	return 0
+}
+
+// Release ends an attempt that passed Check, whatever its outcome. Failed
+// attempts must be recorded with Failure before.
+func (l *Limiter) Release(ip, username string) {
+	if l == nil || l.allowed(ip) {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys(ip, username) {
+		e, ok := l.entries[key]
+		if !ok {
+			continue
+		}
+		if e.pending > 0 {
+			e.pending--
+		}
+		if e.pending == 0 && e.failures == 0 {
+			delete(l.entries, key)
+		}
+	}
+}
+
+// Failure records a failed login and returns whether it triggered a lockout
+func (l *Limiter) Failure(ip, username string) bool {
+	if l == nil || l.allowed(ip) {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+	l.stats.Failures++
+
+	locked := false
+	for _, key := range keys(ip, username) {
+		e, ok := l.entries[key]
+		if !ok {
+			e = &entry{}
+			l.entries[key] = e
+		}
+		e.failures++
+		e.lastFailure = now
+
+		if l.lockedOut(e) {
+			e.blocked = now.Add(l.cfg.Lockout)
+			l.stats.Lockouts++
+			locked = true
+			log.Printf("[DEBUG] Locked out '%s' for %s after %d failed logins", key, l.cfg.Lockout, e.failures)
+		} else {
+			e.blocked = now.Add(l.backoff(e.failures))
+		}
+	}
+	return locked
+}
+
+// Success clears the failure history of the IP and username. Attempts still
+// in progress keep counting until they are released.
+func (l *Limiter) Success(ip, username string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for _, key := range keys(ip, username) {
+		e, ok := l.entries[key]
+		if !ok {
+			continue
+		}
+		if e.inFlight(now) == 0 {
+			delete(l.entries, key)
+			continue
+		}
+		e.failures = 0
+		e.blocked = time.Time{}
+	}
+}
+
+// Stats returns the limiter counters
+func (l *Limiter) Stats() Stats {
+	if l == nil {
+		return Stats{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := l.stats
+	stats.Tracked = len(l.entries)
+	return stats
+}
+
+// Locks lists the keys that are currently throttled, longest wait first
+func (l *Limiter) Locks() []Lock {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	locks := []Lock{}
+	for key, e := range l.entries {
+		if e.blocked.After(now) {
+			locks = append(locks, Lock{
+				Key:      key,
+				Failures: e.failures,
+				Until:    e.blocked,
+				Lockout:  l.lockedOut(e),
+			})
+		}
+	}
+	sort.Slice(locks, func(i, j int) bool { return locks[i].Until.After(locks[j].Until) })
+	return locks
+}
+
+// lockedOut reports whether the failures of e reached the lockout limit
+func (l *Limiter) lockedOut(e *entry) bool {
+	return l.cfg.MaxAttempts > 0 && e.failures >= l.cfg.MaxAttempts
+}
+
+// backoff returns the delay after the given number of consecutive failures
+func (l *Limiter) backoff(failures int) time.Duration {
+	delay := l.cfg.BaseDelay
+	for i := 1; i < failures && delay < l.cfg.Lockout; i++ {
+		delay *= 2
+	}
+	if l.cfg.Lockout > 0 && delay > l.cfg.Lockout {
+		delay = l.cfg.Lockout
+	}
+	return delay
+}
+
+// prune forgets keys whose last failure is older than the lockout period
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Minute {
+		return
+	}
+	l.lastPrune = now
+
+	for key, e := range l.entries {
+		if e.blocked.Before(now) && now.Sub(e.lastFailure) > l.cfg.Lockout && e.inFlight(now) == 0 {
+			delete(l.entries, key)
+		}
+	}
+}
+
+// allowed reports whether ip is on the allow-list
+func (l *Limiter) allowed(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range l.allow {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// keys returns the limiter keys of a login attempt
+func keys(ip, username string) []string {
+	return []string{"ip:" + ip, "user:" + strings.ToLower(username)}
+}
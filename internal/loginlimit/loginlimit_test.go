@@ -0,0 +1,185 @@
+package loginlimit
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// clock is a manually advanced time source
+type clock struct{ now time.Time }
+
+func newClock() *clock {
+	return &clock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *clock) Now() time.Time { return c.now }
+
+func (c *clock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// limiter creates a limiter that runs on the clock
+func (c *clock) limiter(t *testing.T, cfg Config) *Limiter {
+	t.Helper()
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.now = c.Now
+	return l
+}
+
+var testConfig = Config{MaxAttempts: 3, BaseDelay: time.Second, Lockout: time.Minute}
+
+// fail runs a failed login attempt through the limiter
+func fail(t *testing.T, l *Limiter, ip, username string) {
+	t.Helper()
+	if wait := l.Check(ip, username); wait > 0 {
+		t.Fatalf("attempt for %s from %s blocked for %s", username, ip, wait)
+	}
+	l.Failure(ip, username)
+	l.Release(ip, username)
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int           // failures recorded at once
+		after    time.Duration // time since the failures
+		wait     time.Duration // expected wait, 0 if allowed
+	}{
+		{"no failures", 0, 0, 0},
+		{"back-off after one failure", 1, 0, time.Second},
+		{"back-off over", 1, time.Second, 0},
+		{"back-off doubles", 2, 0, 2 * time.Second},
+		{"doubled back-off running", 2, time.Second, time.Second},
+		{"doubled back-off over", 2, 2 * time.Second, 0},
+		{"lockout", 3, 30 * time.Second, 30 * time.Second},
+		{"lockout over", 3, time.Minute, 0},
+		{"lockout long over", 3, time.Hour, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newClock()
+			l := c.limiter(t, testConfig)
+			for i := 0; i < tt.failures; i++ {
+				l.Failure("10.0.0.1", "alice")
+			}
+			c.Advance(tt.after)
+
+			if got := l.Check("10.0.0.1", "alice"); got != tt.wait {
+				t.Fatalf("Check waits %s, want %s", got, tt.wait)
+			}
+		})
+	}
+}
+
+func TestLockoutEndsWithoutOtherLogins(t *testing.T) {
+	c := newClock()
+	l := c.limiter(t, testConfig)
+	for i := 1; i <= testConfig.MaxAttempts; i++ {
+		fail(t, l, "10.0.0.1", "alice")
+		if i < testConfig.MaxAttempts {
+			c.Advance(l.backoff(i))
+		}
+	}
+	if l.Check("10.0.0.1", "alice") == 0 {
+		t.Fatal("not locked out")
+	}
+
+	c.Advance(testConfig.Lockout)
+	if wait := l.Check("10.0.0.1", "alice"); wait > 0 {
+		t.Fatalf("still blocked for %s after the lockout ended", wait)
+	}
+	l.Failure("10.0.0.1", "alice")
+	l.Release("10.0.0.1", "alice")
+	if locks := l.Locks(); len(locks) != 2 || locks[0].Lockout || locks[0].Failures != 1 {
+		t.Fatalf("failure after the lockout counts from %+v, want a fresh back-off", locks)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		ip      string
+		user    string
+		blocked bool
+	}{
+		{"same IP and user", "10.0.0.1", "alice", true},
+		{"same user from another IP", "10.0.0.2", "alice", true},
+		{"username case is ignored", "10.0.0.2", "ALICE", true},
+		{"same IP for another user", "10.0.0.1", "bob", true},
+		{"other IP and user", "10.0.0.2", "bob", false},
+		{"allow-listed IP", "192.168.1.7", "alice", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newClock()
+			cfg := testConfig
+			cfg.AllowList = []string{"192.168.1.0/24", "::1"}
+			l := c.limiter(t, cfg)
+			fail(t, l, "10.0.0.1", "alice")
+
+			if blocked := l.Check(tt.ip, tt.user) > 0; blocked != tt.blocked {
+				t.Fatalf("blocked: %t, want %t", blocked, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestParallelAttempts(t *testing.T) {
+	c := newClock()
+	l := c.limiter(t, Config{MaxAttempts: 2, BaseDelay: time.Second, Lockout: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		if wait := l.Check("10.0.0.1", "alice"); wait > 0 {
+			t.Fatalf("attempt %d blocked for %s", i+1, wait)
+		}
+	}
+	if l.Check("10.0.0.1", "alice") == 0 {
+		t.Fatal("more attempts in progress than the limit allows")
+	}
+
+	l.Release("10.0.0.1", "alice")
+	if wait := l.Check("10.0.0.1", "alice"); wait > 0 {
+		t.Fatalf("released attempt still counts, blocked for %s", wait)
+	}
+
+	// Attempts that are never released expire
+	c.Advance(attemptTimeout + time.Second)
+	if wait := l.Check("10.0.0.1", "alice"); wait > 0 {
+		t.Fatalf("stale attempts still count, blocked for %s", wait)
+	}
+}
+
+func TestSuccess(t *testing.T) {
+	c := newClock()
+	l := c.limiter(t, testConfig)
+	fail(t, l, "10.0.0.1", "alice")
+	c.Advance(time.Second)
+	fail(t, l, "10.0.0.1", "alice")
+
+	l.Success("10.0.0.1", "alice")
+	if wait := l.Check("10.0.0.1", "alice"); wait > 0 {
+		t.Fatalf("blocked for %s after a successful login", wait)
+	}
+	l.Release("10.0.0.1", "alice")
+	if stats := l.Stats(); stats.Tracked != 0 || stats.Failures != 2 {
+		t.Fatalf("stats after success %+v, want 2 failures and no tracked keys", stats)
+	}
+}
+
+func TestNewRejectsInvalidAllowList(t *testing.T) {
+	if _, err := New(Config{AllowList: []string{"10.0.0.0/33"}}); err == nil {
+		t.Fatal("invalid CIDR accepted")
+	}
+	if _, err := New(Config{AllowList: []string{"not-an-ip"}}); err == nil {
+		t.Fatal("invalid IP accepted")
+	}
+}
@@ -0,0 +1,15 @@
+package mfa
+
+// Enforcement lists the panel roles that must use TOTP. The entry "*" applies
+// to every user, including when panel roles are disabled.
+type Enforcement []string
+
+// Requires reports whether users with the given panel role must use TOTP
+func (e Enforcement) Requires(role string) bool {
+	for _, required := range e {
+		if required == "*" || (role != "" && required == role) {
+			return true
+		}
+	}
+	return false
+}
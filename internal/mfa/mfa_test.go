@@ -0,0 +1,320 @@
+package mfa
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"minio-admin-panel/internal/session"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// rfcSecret is the RFC 6238 test key "12345678901234567890" in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	cipher, err := session.NewCipher("test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewStore(t.TempDir(), cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// enroll confirms a TOTP enrollment for username and returns its secret and
+// recovery codes
+func enroll(t *testing.T, store *Store, username string) (string, []string) {
+	t.Helper()
+	e, err := store.Begin(username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, err := store.Confirm(username, mustCode(t, e.Secret, time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e.Secret, codes
+}
+
+func mustCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	code, err := Code(secret, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		if got := mustCode(t, rfcSecret, time.Unix(tt.unix, 0)); got != tt.code {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+
+	if got := mustCode(t, strings.ToLower(rfcSecret), time.Unix(59, 0)); got != "287082" {
+		t.Errorf("lower-case secret: got %s, want 287082", got)
+	}
+	if _, err := Code("not base32!", time.Now()); err == nil {
+		t.Error("invalid secret was accepted")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := now.Unix() / 30
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		step   int64 // accepted step, 0 if rejected
+	}{
+		{"current step", rfcSecret, mustCode(t, rfcSecret, now), step},
+		{"previous step", rfcSecret, mustCode(t, rfcSecret, now.Add(-30*time.Second)), step - 1},
+		{"next step", rfcSecret, mustCode(t, rfcSecret, now.Add(30*time.Second)), step + 1},
+		{"two steps ago", rfcSecret, mustCode(t, rfcSecret, now.Add(-time.Minute)), 0},
+		{"two steps ahead", rfcSecret, mustCode(t, rfcSecret, now.Add(time.Minute)), 0},
+		{"spaces", rfcSecret, " 005 924 ", step},
+		{"too short", rfcSecret, "05924", 0},
+		{"wrong code", rfcSecret, "123456", 0},
+		{"invalid secret", "not base32!", "005924", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Verify(tt.secret, tt.code, now)
+			if ok != (tt.step != 0) || got != tt.step {
+				t.Fatalf("Verify(%q) = %d, %t, want step %d", tt.code, got, ok, tt.step)
+			}
+		})
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri, err := url.Parse(URI("MinIO Admin Panel", "alice@example.com", rfcSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/MinIO Admin Panel:alice@example.com" {
+		t.Errorf("unexpected URI %s", uri)
+	}
+	params := uri.Query()
+	for key, want := range map[string]string{"secret": rfcSecret, "issuer": "MinIO Admin Panel", "digits": "6", "period": "30", "algorithm": "SHA1"} {
+		if got := params.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestEnforcement(t *testing.T) {
+	tests := []struct {
+		enforce Enforcement
+		role    string
+		want    bool
+	}{
+		{nil, "super-admin", false},
+		{Enforcement{"super-admin"}, "super-admin", true},
+		{Enforcement{"super-admin"}, "viewer", false},
+		{Enforcement{"super-admin"}, "", false},
+		{Enforcement{"viewer", "*"}, "operator", true},
+		{Enforcement{"*"}, "", true},
+	}
+	for _, tt := range tests {
+		if got := tt.enforce.Requires(tt.role); got != tt.want {
+			t.Errorf("%v.Requires(%q) = %t, want %t", tt.enforce, tt.role, got, tt.want)
+		}
+	}
+}
+
+func TestEnrollment(t *testing.T) {
+	store := newTestStore(t)
+
+	first, err := store.Begin("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := store.Begin("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Secret != first.Secret {
+		t.Error("a pending enrollment got a new secret")
+	}
+	if store.Enrolled("alice") {
+		t.Error("user is enrolled before confirming")
+	}
+	if _, err := store.Verify("alice", mustCode(t, first.Secret, time.Now())); !errors.Is(err, ErrNotEnrolled) {
+		t.Errorf("Verify before confirming: got error %v, want %v", err, ErrNotEnrolled)
+	}
+
+	if _, err := store.Confirm("alice", "000000"); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("Confirm with a wrong code: got error %v, want %v", err, ErrInvalidCode)
+	}
+	codes, err := store.Confirm("alice", mustCode(t, first.Secret, time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount || store.RecoveryCodesLeft("alice") != recoveryCodeCount {
+		t.Errorf("got %d recovery codes, %d left, want %d", len(codes), store.RecoveryCodesLeft("alice"), recoveryCodeCount)
+	}
+	if !store.Enrolled("alice") {
+		t.Error("user is not enrolled after confirming")
+	}
+
+	if _, err := store.Begin("alice"); !errors.Is(err, ErrAlreadyEnrolled) {
+		t.Errorf("Begin after confirming: got error %v, want %v", err, ErrAlreadyEnrolled)
+	}
+	if _, err := store.Confirm("alice", mustCode(t, first.Secret, time.Now())); !errors.Is(err, ErrAlreadyEnrolled) {
+		t.Errorf("second Confirm: got error %v, want %v", err, ErrAlreadyEnrolled)
+	}
+
+	if err := store.Delete("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if store.Enrolled("alice") {
+		t.Error("user is enrolled after deleting the enrollment")
+	}
+	if err := store.Delete("alice"); err != nil {
+		t.Errorf("deleting a missing enrollment: %v", err)
+	}
+}
+
+func TestTOTPReplay(t *testing.T) {
+	store := newTestStore(t)
+	now := time.Now()
+	e, err := store.Begin("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := e.Secret
+	if _, err := store.Confirm("alice", mustCode(t, secret, now)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		code string
+		err  error
+	}{
+		{"code used to confirm", mustCode(t, secret, now), ErrInvalidCode},
+		{"earlier code", mustCode(t, secret, now.Add(-30*time.Second)), ErrInvalidCode},
+		{"next code", mustCode(t, secret, now.Add(30*time.Second)), nil},
+		{"next code again", mustCode(t, secret, now.Add(30*time.Second)), ErrInvalidCode},
+		{"code before it", mustCode(t, secret, now), ErrInvalidCode},
+	}
+	for _, tt := range tests {
+		recovery, err := store.Verify("alice", tt.code)
+		if !errors.Is(err, tt.err) || recovery {
+			t.Fatalf("%s: got recovery %t, error %v, want error %v", tt.name, recovery, err, tt.err)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	store := newTestStore(t)
+	_, codes := enroll(t, store, "alice")
+
+	tests := []struct {
+		name string
+		code string
+		err  error
+		left int
+	}{
+		{"as issued", codes[0], nil, 9},
+		{"used twice", codes[0], ErrInvalidCode, 9},
+		{"without dash and in upper case", strings.ToUpper(strings.ReplaceAll(codes[1], "-", "")), nil, 8},
+		{"with spaces", strings.ReplaceAll(codes[2], "-", " "), nil, 7},
+		{"unknown", "00000-00000", ErrInvalidCode, 7},
+	}
+	for _, tt := range tests {
+		recovery, err := store.Verify("alice", tt.code)
+		if !errors.Is(err, tt.err) || recovery != (tt.err == nil) {
+			t.Fatalf("%s: got recovery %t, error %v, want error %v", tt.name, recovery, err, tt.err)
+		}
+		if left := store.RecoveryCodesLeft("alice"); left != tt.left {
+			t.Fatalf("%s: %d codes left, want %d", tt.name, left, tt.left)
+		}
+	}
+
+	renewed, err := store.RegenerateRecoveryCodes("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.RecoveryCodesLeft("alice") != recoveryCodeCount {
+		t.Errorf("%d codes left after regenerating, want %d", store.RecoveryCodesLeft("alice"), recoveryCodeCount)
+	}
+	if _, err := store.Verify("alice", codes[3]); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("replaced code: got error %v, want %v", err, ErrInvalidCode)
+	}
+	if recovery, err := store.Verify("alice", renewed[0]); err != nil || !recovery {
+		t.Errorf("new code: got recovery %t, error %v", recovery, err)
+	}
+
+	if _, err := store.RegenerateRecoveryCodes("bob"); !errors.Is(err, ErrNotEnrolled) {
+		t.Errorf("regenerating for an unenrolled user: got error %v, want %v", err, ErrNotEnrolled)
+	}
+}
+
+func TestStoreIsEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	cipher, err := session.NewCipher("test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewStore(dir, cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, _ := enroll(t, store, "../alice")
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("got files %v, want one enrollment file in the store directory", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), secret) || strings.Contains(string(data), "alice") {
+		t.Error("enrollment file is not encrypted")
+	}
+
+	other, err := session.NewCipher("other-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := NewStore(dir, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Enrolled("../alice") {
+		t.Error("enrollment was read with the wrong key")
+	}
+}
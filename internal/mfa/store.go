@@ -0,0 +1,283 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"minio-admin-panel/internal/session"
+)
+
+// recoveryCodeCount is the number of recovery codes issued at a time
+const recoveryCodeCount = 10
+
+var (
+	// ErrNotEnrolled is returned for users without a confirmed TOTP enrollment
+	ErrNotEnrolled = errors.New("two-factor authentication is not enabled")
+	// ErrAlreadyEnrolled is returned when starting enrollment for an enrolled user
+	ErrAlreadyEnrolled = errors.New("two-factor authentication is already enabled")
+	// ErrInvalidCode is returned for wrong, expired or replayed codes
+	ErrInvalidCode = errors.New("invalid verification code")
+)
+
+// Enrollment is the TOTP state of a panel user. Recovery codes are only kept
+// as SHA-256 hashes and removed once used.
+type Enrollment struct {
+	Username      string    `json:"username"`
+	Secret        string    `json:"secret"`
+	Confirmed     bool      `json:"confirmed"`
+	RecoveryCodes []string  `json:"recovery_codes,omitempty"`
+	LastStep      int64     `json:"last_step"`
+	CreatedAt     time.Time `json:"created_at"`
+	ConfirmedAt   time.Time `json:"confirmed_at,omitempty"`
+}
+
+// Store keeps TOTP enrollments as encrypted files, one per user
+type Store struct {
+	mu     sync.Mutex
+	dir    string
+	cipher *session.Cipher
+}
+
+// NewStore creates an enrollment store rooted at dir
+func NewStore(dir string, cipher *session.Cipher) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create MFA directory: %v", err)
+	}
+	return &Store{dir: dir, cipher: cipher}, nil
+}
+
+// Enrolled reports whether the user has confirmed a TOTP enrollment
+func (s *Store) Enrolled(username string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.load(username)
+	return err == nil && e.Confirmed
+}
+
+// Begin returns the pending enrollment of a user, creating a new secret when
+// there is none yet
+func (s *Store) Begin(username string) (*Enrollment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.load(username)
+	if err == nil {
+		if e.Confirmed {
+			return nil, ErrAlreadyEnrolled
+		}
+		return e, nil
+	}
+	if !errors.Is(err, ErrNotEnrolled) {
+		return nil, err
+	}
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	e = &Enrollment{Username: username, Secret: secret, CreatedAt: time.Now().UTC()}
+	if err := s.save(e); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[DEBUG] Started TOTP enrollment for user '%s'", username)
+	return e, nil
+}
+
+// Confirm completes a pending enrollment with a code from the authenticator
+// app and returns the user's recovery codes
+func (s *Store) Confirm(username, code string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.load(username)
+	if err != nil {
+		return nil, err
+	}
+	if e.Confirmed {
+		return nil, ErrAlreadyEnrolled
+	}
+
+	step, ok := Verify(e.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	e.Confirmed = true
+	e.ConfirmedAt = time.Now().UTC()
+	e.LastStep = step
+	e.RecoveryCodes = hashes
+	if err := s.save(e); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[DEBUG] Confirmed TOTP enrollment for user '%s'", username)
+	return codes, nil
+}
+
+// Verify checks a TOTP code or a recovery code. Each TOTP code is accepted
+// once and each recovery code is consumed on use.
+func (s *Store) Verify(username, code string) (recovery bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.load(username)
+	if err != nil {
+		return false, err
+	}
+	if !e.Confirmed {
+		return false, ErrNotEnrolled
+	}
+
+	if step, ok := Verify(e.Secret, code, time.Now()); ok {
+		if step <= e.LastStep {
+			log.Printf("[DEBUG] Rejected replayed TOTP code for user '%s'", username)
+			return false, ErrInvalidCode
+		}
+		e.LastStep = step
+		return false, s.save(e)
+	}
+
+	hash := hashRecoveryCode(code)
+	for i, stored := range e.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(hash)) == 1 {
+			e.RecoveryCodes = append(e.RecoveryCodes[:i], e.RecoveryCodes[i+1:]...)
+			log.Printf("[DEBUG] User '%s' used a recovery code (%d left)", username, len(e.RecoveryCodes))
+			return true, s.save(e)
+		}
+	}
+
+	return false, ErrInvalidCode
+}
+
+// RecoveryCodesLeft returns the number of unused recovery codes
+func (s *Store) RecoveryCodesLeft(username string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.load(username)
+	if err != nil {
+		return 0
+	}
+	return len(e.RecoveryCodes)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of an enrolled user
+func (s *Store) RegenerateRecoveryCodes(username string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.load(username)
+	if err != nil {
+		return nil, err
+	}
+	if !e.Confirmed {
+		return nil, ErrNotEnrolled
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	e.RecoveryCodes = hashes
+	return codes, s.save(e)
+}
+
+// Delete removes the enrollment of a user
+func (s *Store) Delete(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.path(username)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	log.Printf("[DEBUG] Removed TOTP enrollment for user '%s'", username)
+	return nil
+}
+
+func (s *Store) load(username string) (*Enrollment, error) {
+	data, err := os.ReadFile(s.path(username))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotEnrolled
+		}
+		return nil, err
+	}
+
+	plaintext, err := s.cipher.Decrypt(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt MFA enrollment: %v", err)
+	}
+
+	var e Enrollment
+	if err := json.Unmarshal(plaintext, &e); err != nil {
+		return nil, fmt.Errorf("corrupt MFA enrollment: %v", err)
+	}
+	return &e, nil
+}
+
+// save writes the enrollment to disk atomically
+func (s *Store) save(e *Enrollment) error {
+	plaintext, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	data, err := s.cipher.Encrypt(plaintext)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt MFA enrollment: %v", err)
+	}
+
+	path := s.path(e.Username)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// path maps a username to its file; usernames are hashed so they can never
+// escape the store directory
+func (s *Store) path(username string) string {
+	sum := sha256.Sum256([]byte(username))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".enc")
+}
+
+// newRecoveryCodes returns fresh recovery codes and their hashes
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := hex.EncodeToString(buf)
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode normalizes and hashes a recovery code
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"minio-admin-panel/internal/apitoken"
+	"minio-admin-panel/internal/permissions"
+	"minio-admin-panel/internal/rbac"
+	"minio-admin-panel/internal/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAPITokenAuthentication(t *testing.T) {
+	cipher, err := session.NewCipher("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := apitoken.NewStore(t.TempDir(), cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := NewKeyRing("test-secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions := session.NewManager(session.NewMemoryStore(), cipher, time.Hour, 0)
+
+	path := filepath.Join(t.TempDir(), "roles.json")
+	if err := os.WriteFile(path, []byte(`{"default_role":"viewer"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	roles, err := rbac.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	access := newAccess(t, adminPolicy, nil)
+	create := func(scopes []string, role string, expiresAt time.Time) string {
+		t.Helper()
+		raw, err := tokens.Create(&apitoken.Token{
+			Name:        "test",
+			Owner:       "alice",
+			Scopes:      scopes,
+			Binding:     apitoken.BindingUser,
+			Permissions: access.Flags,
+			Policy:      access.PolicyJSON(),
+			Role:        role,
+			Credentials: session.Credentials{Cluster: "default", AccessKey: "alice", SecretKey: "alicesecret"},
+			ExpiresAt:   expiresAt,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	later := time.Now().Add(time.Hour)
+	read := create([]string{"buckets:read"}, rbac.RoleSuperAdmin, later)
+	write := create([]string{"buckets:write", "users:read"}, rbac.RoleSuperAdmin, later)
+	viewer := create([]string{"buckets:write"}, rbac.RoleViewer, later)
+	unknownRole := create([]string{"buckets:write"}, "nobody", later)
+	expired := create([]string{"buckets:write"}, rbac.RoleSuperAdmin, time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name   string
+		tokens *apitoken.Store
+		method string
+		path   string
+		token  string
+		status int
+		flag   string // permission flag the request must end up with, if any
+	}{
+		{"read scope reads", tokens, http.MethodGet, "/buckets", read, http.StatusNoContent, "canCreateBuckets"},
+		{"read scope reads under a cluster", tokens, http.MethodGet, "/c/default/buckets/photos", read, http.StatusNoContent, ""},
+		{"read scope may not write", tokens, http.MethodPost, "/buckets", read, http.StatusForbidden, ""},
+		{"read scope may not write under a cluster", tokens, http.MethodDelete, "/c/default/buckets/photos", read, http.StatusForbidden, ""},
+		{"write scope writes", tokens, http.MethodPost, "/buckets", write, http.StatusNoContent, ""},
+		{"write scope writes under a cluster", tokens, http.MethodPost, "/c/default/buckets", write, http.StatusNoContent, ""},
+		{"other area", tokens, http.MethodGet, "/policies", write, http.StatusForbidden, ""},
+		{"other area under a cluster", tokens, http.MethodGet, "/c/default/policies", write, http.StatusForbidden, ""},
+		{"second scope", tokens, http.MethodGet, "/users", write, http.StatusNoContent, ""},
+		{"token management", tokens, http.MethodGet, "/api/tokens", write, http.StatusForbidden, ""},
+		{"cluster root", tokens, http.MethodGet, "/c/default", write, http.StatusForbidden, ""},
+		{"role limits permissions", tokens, http.MethodPost, "/buckets", viewer, http.StatusNoContent, "!canCreateBuckets"},
+		{"unknown role", tokens, http.MethodGet, "/buckets", unknownRole, http.StatusForbidden, ""},
+		{"expired", tokens, http.MethodGet, "/buckets", expired, http.StatusUnauthorized, ""},
+		{"wrong secret", tokens, http.MethodGet, "/buckets", read[:len(read)-1] + "x", http.StatusUnauthorized, ""},
+		{"unknown token", tokens, http.MethodGet, "/buckets", apitoken.Prefix + "0123456789abcdef_secret", http.StatusUnauthorized, ""},
+		{"tokens disabled", nil, http.MethodGet, "/buckets", read, http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *permissions.Set
+			var username, tokenID string
+			r := gin.New()
+			r.Use(AuthRequired(keys, sessions, roles, tt.tokens))
+			r.NoRoute(func(c *gin.Context) {
+				got = GetAccess(c)
+				username = c.GetString("username")
+				tokenID = c.GetString("api_token_id")
+				c.Status(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("got status %d, want %d (%s)", w.Code, tt.status, w.Body.String())
+			}
+			if w.Code != http.StatusNoContent {
+				return
+			}
+			if username != "alice" || tokenID == "" {
+				t.Errorf("request acts as %q with token %q, want alice's token", username, tokenID)
+			}
+			switch {
+			case tt.flag == "":
+			case tt.flag[0] == '!' && got.Has(tt.flag[1:]):
+				t.Errorf("permission %s kept", tt.flag[1:])
+			case tt.flag[0] != '!' && !got.Has(tt.flag):
+				t.Errorf("permission %s missing", tt.flag)
+			}
+		})
+	}
+}
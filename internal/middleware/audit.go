@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"minio-admin-panel/internal/audit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAuditBody is the largest request body summarized in audit records
+const maxAuditBody = 64 * 1024
+
+// Audit middleware records the outcome of an administrative action. The
+// target defaults to the first route parameter other than the cluster; the
+// request parameters are kept as the "after" summary with secrets redacted.
+func Audit(logger *audit.Logger, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		after := requestSummary(c)
+
+		c.Next()
+
+		target := c.GetString("audit_target")
+		for _, param := range c.Params {
+			if target == "" && param.Key != "cluster" {
+				target = param.Value
+			}
+		}
+
+		details := map[string]interface{}{}
+		if existing, ok := c.Get("audit_details"); ok {
+			details = existing.(map[string]interface{})
+		}
+		if len(after) > 0 {
+			if _, set := details["after"]; !set {
+				details["after"] = after
+			}
+		}
+		if tokenID := c.GetString("api_token_id"); tokenID != "" {
+			details["api_token"] = tokenID
+		}
+		if cluster := c.GetString("cluster"); cluster != "" {
+			details["cluster"] = cluster
+		}
+
+		result := audit.ResultSuccess
+		if c.Writer.Status() >= http.StatusBadRequest {
+			result = audit.ResultFailure
+		}
+
+		logger.Log(audit.Record{
+			Actor:    actorName(c),
+			ClientIP: c.ClientIP(),
+			Action:   action,
+			Target:   target,
+			Details:  details,
+			Result:   result,
+			Status:   c.Writer.Status(),
+		})
+	}
+}
+
+// SetAuditTarget names the object an audited action applies to, for actions
+// whose target is not a route parameter
+func SetAuditTarget(c *gin.Context, target string) {
+	c.Set("audit_target", target)
+}
+
+// AddAuditDetail attaches a detail such as a "before" summary to the audit record
+func AddAuditDetail(c *gin.Context, key string, value interface{}) {
+	details, ok := c.Get("audit_details")
+	if !ok {
+		details = map[string]interface{}{}
+		c.Set("audit_details", details)
+	}
+	details.(map[string]interface{})[key] = value
+}
+
+// actorName returns the panel user responsible for the request
+func actorName(c *gin.Context) string {
+	if name := c.GetString("display_name"); name != "" {
+		return name
+	}
+	return c.GetString("username")
+}
+
+// requestSummary returns the query and body parameters of a request with
+// secrets redacted and long values replaced by a digest. The body is restored
+// so handlers can still bind it.
+func requestSummary(c *gin.Context) map[string]interface{} {
+	summary := map[string]interface{}{}
+	for key, values := range c.Request.URL.Query() {
+		summary[key] = strings.Join(values, ",")
+	}
+	// Form bodies already parsed by earlier middleware, such as the CSRF check
+	for key, values := range c.Request.PostForm {
+		summary[key] = strings.Join(values, ",")
+	}
+
+	if c.Request.Body == nil || c.Request.ContentLength > maxAuditBody {
+		return summarize(summary)
+	}
+
+	original := c.Request.Body
+	body, err := io.ReadAll(io.LimitReader(original, maxAuditBody+1))
+	if len(body) > maxAuditBody {
+		// Too large to summarize; hand the full body on untouched
+		c.Request.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), original), original}
+		return summarize(summary)
+	}
+	original.Close()
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil || len(body) == 0 {
+		return summarize(summary)
+	}
+
+	if strings.HasPrefix(c.ContentType(), "application/json") {
+		var fields map[string]interface{}
+		if json.Unmarshal(body, &fields) == nil {
+			for key, value := range fields {
+				summary[key] = value
+			}
+		}
+	} else if form, err := url.ParseQuery(string(body)); err == nil {
+		for key, values := range form {
+			summary[key] = strings.Join(values, ",")
+		}
+	}
+
+	return summarize(summary)
+}
+
+// summarize redacts secrets and shortens long values in place
+func summarize(fields map[string]interface{}) map[string]interface{} {
+	for key, value := range fields {
+		lower := strings.ToLower(key)
+		if strings.Contains(lower, "secret") || strings.Contains(lower, "password") || strings.Contains(lower, "token") {
+			fields[key] = "[redacted]"
+			continue
+		}
+
+		var text string
+		switch v := value.(type) {
+		case string:
+			text = v
+		case map[string]interface{}, []interface{}:
+			encoded, _ := json.Marshal(v)
+			text = string(encoded)
+		default:
+			continue
+		}
+		if len(text) > 200 {
+			sum := sha256.Sum256([]byte(text))
+			fields[key] = fmt.Sprintf("sha256:%s (%d bytes)", hex.EncodeToString(sum[:8]), len(text))
+		} else if _, isString := value.(string); !isString {
+			fields[key] = text
+		}
+	}
+	return fields
+}
@@ -6,103 +6,221 @@ import (
 	"strings"
 	"time"
 
+	"minio-admin-panel/internal/apitoken"
+	"minio-admin-panel/internal/permissions"
+	"minio-admin-panel/internal/rbac"
+	"minio-admin-panel/internal/session"
+
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Claims only references a server-side session; credentials never leave the server
 type Claims struct {
-	Username    string          `json:"username"`
-	Password    string          `json:"password"` // Store encrypted password
-	PolicyName  string          `json:"policy_name"`
-	Permissions map[string]bool `json:"permissions"`
+	Username  string `json:"username"`
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-const JWTSecret = "your-secret-key" // Should come from config
-
-// AuthRequired middleware checks for valid JWT token
-func AuthRequired() gin.HandlerFunc {
+// AuthRequired middleware checks for valid JWT token and loads the session it
+// references. Each request extends the session's idle timeout, re-issuing the
+// cookie when the expiry moves. Personal API tokens are accepted as bearer
+// tokens when a token store is given. When panel roles are enabled,
+// permissions are narrowed to the session's role.
+func AuthRequired(keys *KeyRing, sessions *session.Manager, roles *rbac.Registry, tokens *apitoken.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log.Printf("[DEBUG] Auth middleware checking token for %s %s", c.Request.Method, c.Request.URL.Path)
 
-		// Check for token in cookie first
-		tokenString, err := c.Cookie("token")
+		if raw, ok := bearerAPIToken(c); ok {
+			authenticateAPIToken(c, tokens, roles, raw)
+			if !c.IsAborted() {
+				c.Next()
+			}
+			return
+		}
+
+		sess, claims, err := loadSession(c, keys, sessions)
 		if err != nil {
-			log.Printf("[DEBUG] No token found in cookie, checking Authorization header")
-			// Check Authorization header
-			authHeader := c.GetHeader("Authorization")
-			if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-				log.Printf("[DEBUG] No valid authorization found, redirecting to login")
-				c.Redirect(http.StatusFound, "/")
-				c.Abort()
+			rejectSession(c, err)
+			return
+		}
+
+		// Sessions that still need a second factor may only reach the MFA pages
+		if sess.MFAState != "" {
+			log.Printf("[DEBUG] Session for user '%s' awaits second factor (%s)", claims.Username, sess.MFAState)
+			c.Redirect(http.StatusFound, "/login/mfa")
+			c.Abort()
+			return
+		}
+
+		creds, err := sessions.Credentials(sess)
+		if err != nil {
+			log.Printf("[DEBUG] Failed to load credentials for user '%s': %v", claims.Username, err)
+			rejectSession(c, err)
+			return
+		}
+
+		if extended, err := sessions.Touch(sess); err != nil {
+			log.Printf("[DEBUG] Failed to extend session for user '%s': %v", claims.Username, err)
+		} else if extended && tokenFromCookie(c) {
+			if err := IssueSessionCookie(c, keys, sess); err != nil {
+				log.Printf("[DEBUG] Failed to re-issue session cookie for user '%s': %v", claims.Username, err)
+			} else {
+				log.Printf("[DEBUG] Session for user '%s' extended until %s", claims.Username, sess.ExpiresAt.Format(time.RFC3339))
+			}
+		}
+
+		access := permissions.Restore(sess.Permissions, sess.Policy)
+		if roles.Enabled() {
+			role := roles.Role(sess.Role)
+			if role == nil {
+				log.Printf("[DEBUG] Session for user '%s' has no valid panel role '%s'", claims.Username, sess.Role)
+				rejectSession(c, session.ErrNotFound)
 				return
 			}
-			tokenString = strings.TrimPrefix(authHeader, "Bearer ")
-			log.Printf("[DEBUG] Found token in Authorization header")
-		} else {
-			log.Printf("[DEBUG] Found token in cookie")
+			access = access.Limit(role)
+			c.Set("role", role)
 		}
 
-		// Parse and validate token
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(JWTSecret), nil
-		})
+		log.Printf("[DEBUG] Token validated successfully for user '%s'", claims.Username)
+		c.Set("username", creds.AccessKey)
+		c.Set("password", creds.SecretKey)
+		c.Set("session_token", creds.SessionToken)
+		c.Set("cluster", sess.Cluster)
+		c.Set("display_name", sess.Username)
+		c.Set("policy_name", sess.PolicyName)
+		c.Set("permissions", access.Flags)
+		c.Set("access", access)
+		c.Set("session_id", sess.ID)
+		c.Set("session", sess)
+		c.Set("user_claims", claims)
+
+		c.Next()
+	}
+}
+
+// CurrentSession returns the session of the request without extending it, for
+// status checks that must not count as activity
+func CurrentSession(c *gin.Context, keys *KeyRing, sessions *session.Manager) (*session.Session, error) {
+	sess, _, err := loadSession(c, keys, sessions)
+	if err != nil {
+		return nil, err
+	}
+	if sess.MFAState != "" {
+		return nil, session.ErrNotFound
+	}
+	return sess, nil
+}
+
+// RejectSession ends the request the same way AuthRequired does when the
+// session has expired or was revoked
+func RejectSession(c *gin.Context, err error) {
+	rejectSession(c, err)
+}
 
-		if err != nil || !token.Valid {
-			log.Printf("[DEBUG] Token validation failed: %v", err)
+// MFAPending middleware admits only sessions that have passed the password
+// check but still need their second factor verified or enrolled
+func MFAPending(keys *KeyRing, sessions *session.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sess, claims, err := loadSession(c, keys, sessions)
+		if err != nil {
 			c.Redirect(http.StatusFound, "/")
 			c.Abort()
 			return
 		}
-
-		// Extract claims
-		if claims, ok := token.Claims.(*Claims); ok {
-			log.Printf("[DEBUG] Token validated successfully for user '%s'", claims.Username)
-			c.Set("username", claims.Username)
-			c.Set("password", claims.Password)
-			c.Set("policy_name", claims.PolicyName)
-			c.Set("permissions", claims.Permissions)
-			c.Set("user_claims", claims)
-		} else {
-			log.Printf("[DEBUG] Failed to extract claims from token")
-			c.Redirect(http.StatusFound, "/")
+		if sess.MFAState == "" {
+			c.Redirect(http.StatusFound, "/dashboard")
 			c.Abort()
 			return
 		}
 
+		c.Set("session", sess)
+		c.Set("display_name", sess.Username)
+		c.Set("session_id", sess.ID)
+		c.Set("user_claims", claims)
+
 		c.Next()
 	}
 }
 
-// GenerateJWT creates a JWT token for authenticated user
-func GenerateJWT(username string) (string, error) {
-	claims := Claims{
-		Username: username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 1)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+// loadSession validates the JWT from the cookie or Authorization header and
+// returns the session it references
+func loadSession(c *gin.Context, keys *KeyRing, sessions *session.Manager) (*session.Session, *Claims, error) {
+	// Check for token in cookie first
+	tokenString, err := c.Cookie("token")
+	if err != nil {
+		log.Printf("[DEBUG] No token found in cookie, checking Authorization header")
+		// Check Authorization header
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+			log.Printf("[DEBUG] No valid authorization found, redirecting to login")
+			return nil, nil, session.ErrNotFound
+		}
+		tokenString = strings.TrimPrefix(authHeader, "Bearer ")
+		log.Printf("[DEBUG] Found token in Authorization header")
+	} else {
+		log.Printf("[DEBUG] Found token in cookie")
+	}
+
+	// Parse and validate token
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keys.Keyfunc)
+
+	if err != nil || !token.Valid {
+		log.Printf("[DEBUG] Token validation failed: %v", err)
+		return nil, nil, session.ErrNotFound
+	}
+
+	// Extract claims
+	claims, ok := token.Claims.(*Claims)
+	if !ok || claims.SessionID == "" {
+		log.Printf("[DEBUG] Failed to extract session from token")
+		return nil, nil, session.ErrNotFound
+	}
+
+	sess, err := sessions.Get(claims.SessionID)
+	if err != nil {
+		log.Printf("[DEBUG] Session lookup failed for user '%s': %v", claims.Username, err)
+		return nil, nil, err
 	}
+	if sess.Username != claims.Username {
+		log.Printf("[DEBUG] Session does not belong to user '%s'", claims.Username)
+		return nil, nil, session.ErrNotFound
+	}
+
+	return sess, claims, nil
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(JWTSecret))
+// tokenFromCookie reports whether the request authenticated with the session
+// cookie rather than an Authorization header
+func tokenFromCookie(c *gin.Context) bool {
+	token, err := c.Cookie("token")
+	return err == nil && token != ""
 }
 
-// GenerateJWTWithUserInfo creates a JWT token with user information and permissions
-func GenerateJWTWithUserInfo(username, password, policyName string, permissions map[string]bool) (string, error) {
+// GenerateJWTWithSession creates a JWT token referencing a server-side session
+// that expires together with it
+func GenerateJWTWithSession(keys *KeyRing, sessionID, username string, expiresAt time.Time) (string, error) {
 	claims := Claims{
-		Username:    username,
-		Password:    password, // In production, this should be encrypted
-		PolicyName:  policyName,
-		Permissions: permissions,
+		Username:  username,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 1)),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(JWTSecret))
+	return keys.Sign(claims)
+}
+
+// SessionIDFromToken returns the session referenced by a signed token, even if the
+// token itself has already expired. An empty string is returned for invalid tokens.
+func SessionIDFromToken(keys *KeyRing, tokenString string) string {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, keys.Keyfunc, jwt.WithoutClaimsValidation())
+	if err != nil {
+		return ""
+	}
+	return claims.SessionID
 }
 
 // GetUserPermissions extracts user permissions from context
@@ -115,8 +233,23 @@ func GetUserPermissions(c *gin.Context) map[string]bool {
 	return map[string]bool{}
 }
 
-// CheckPermission checks if user has specific permission
+// GetAccess returns the fine-grained permission set of the current session
+func GetAccess(c *gin.Context) *permissions.Set {
+	if access, exists := c.Get("access"); exists {
+		if set, ok := access.(*permissions.Set); ok {
+			return set
+		}
+	}
+	return nil
+}
+
+// CheckPermission checks if user has specific permission. The permission is
+// either a coarse flag such as "canCreateBuckets" or an IAM action such as
+// "s3:PutBucketVersioning", which must be allowed on at least one resource.
 func CheckPermission(c *gin.Context, permission string) bool {
+	if strings.Contains(permission, ":") {
+		return GetAccess(c).Can(permission)
+	}
 	permissions := GetUserPermissions(c)
 	return permissions[permission]
 }
@@ -125,6 +258,43 @@ func CheckPermission(c *gin.Context, permission string) bool {
 func RequirePermission(permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if !CheckPermission(c, permission) {
+			log.Printf("[DEBUG] Permission '%s' denied for %s %s", permission, c.Request.Method, c.Request.URL.Path)
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Insufficient permissions",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole middleware that requires at least the given panel role. It
+// passes when panel roles are disabled.
+func RequireRole(minimum string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if value, exists := c.Get("role"); exists {
+			role, _ := value.(*rbac.Role)
+			if role == nil || !role.AtLeast(minimum) {
+				log.Printf("[DEBUG] Role '%s' required for %s %s", minimum, c.Request.Method, c.Request.URL.Path)
+				c.JSON(http.StatusForbidden, gin.H{
+					"error": "Insufficient permissions",
+				})
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireBucketPermission middleware that requires an IAM action to be allowed
+// on the bucket named by the given route parameter
+func RequireBucketPermission(action, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bucket := c.Param(param)
+		if !GetAccess(c).CanBucket(action, bucket) {
+			log.Printf("[DEBUG] Action '%s' denied on bucket '%s'", action, bucket)
 			c.JSON(http.StatusForbidden, gin.H{
 				"error": "Insufficient permissions",
 			})
@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"minio-admin-panel/internal/i18n"
+	"minio-admin-panel/internal/permissions"
+	"minio-admin-panel/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	gin.SetMode(gin.TestMode)
+	i18n.Init("en")
+	if err := i18n.LoadDir("../../translations/i18n"); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// Policies used by the permission tests
+const (
+	adminPolicy = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["admin:*"]},{"Effect":"Allow","Action":["s3:*"],"Resource":["arn:aws:s3:::*"]}]}`
+	readPolicy  = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["s3:ListAllMyBuckets","s3:GetObject"],"Resource":["arn:aws:s3:::*"]}]}`
+	logsPolicy  = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["s3:ListAllMyBuckets"],"Resource":["arn:aws:s3:::*"]},{"Effect":"Allow","Action":["s3:DeleteBucket","s3:PutObject"],"Resource":["arn:aws:s3:::logs-*"]}]}`
+	denyPolicy  = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["s3:*"],"Resource":["arn:aws:s3:::*"]},{"Effect":"Deny","Action":["s3:DeleteBucket"],"Resource":["arn:aws:s3:::protected"]}]}`
+	usersPolicy = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["admin:ListUsers"]}]}`
+)
+
+// newAccess derives a permission set from a policy, optionally limited by a
+// panel role
+func newAccess(t *testing.T, policy string, role *rbac.Role) *permissions.Set {
+	t.Helper()
+	access, err := permissions.NewSet([]byte(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role != nil {
+		access = access.Limit(role)
+	}
+	return access
+}
+
+// viewerRole loads the built-in viewer role through a role mapping file
+func viewerRole(t *testing.T) *rbac.Role {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "roles.json")
+	if err := os.WriteFile(path, []byte(`{"default_role":"viewer"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	roles, err := rbac.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return roles.Role(rbac.RoleViewer)
+}
+
+// serveWithAccess runs the guard on a request to path, as AuthRequired would
+// have left it with access, and returns the response
+func serveWithAccess(access *permissions.Set, role *rbac.Role, route, path string, guard gin.HandlerFunc) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("permissions", access.Flags)
+		c.Set("access", access)
+		if role != nil {
+			c.Set("role", role)
+		}
+	})
+	r.GET(route, guard, func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestRequirePermission(t *testing.T) {
+	viewer := viewerRole(t)
+
+	tests := []struct {
+		name       string
+		policy     string
+		role       *rbac.Role
+		permission string
+		allowed    bool
+	}{
+		{"flag granted", readPolicy, nil, "canListBuckets", true},
+		{"flag missing", readPolicy, nil, "canCreateBuckets", false},
+		{"unknown flag", adminPolicy, nil, "canDoAnything", false},
+		{"admin flag", adminPolicy, nil, "isAdmin", true},
+		{"admin flag without admin:*", usersPolicy, nil, "isAdmin", false},
+		{"action granted", adminPolicy, nil, "admin:DeleteUser", true},
+		{"action missing", usersPolicy, nil, "admin:DeleteUser", false},
+		{"action on some bucket", logsPolicy, nil, "s3:DeleteBucket", true},
+		{"role keeps reads", adminPolicy, viewer, "canViewUsers", true},
+		{"role removes flag", adminPolicy, viewer, "canCreateBuckets", false},
+		{"role removes action", adminPolicy, viewer, "admin:DeleteUser", false},
+		{"role removes admin flag", adminPolicy, viewer, "isAdmin", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			access := newAccess(t, tt.policy, tt.role)
+			w := serveWithAccess(access, tt.role, "/", "/", RequirePermission(tt.permission))
+			if allowed := w.Code == http.StatusNoContent; allowed != tt.allowed {
+				t.Fatalf("%s allowed: %t, want %t (status %d)", tt.permission, allowed, tt.allowed, w.Code)
+			}
+			if !tt.allowed && w.Code != http.StatusForbidden {
+				t.Fatalf("denied with status %d, want %d", w.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestRequireBucketPermission(t *testing.T) {
+	viewer := viewerRole(t)
+
+	tests := []struct {
+		name    string
+		policy  string
+		role    *rbac.Role
+		action  string
+		bucket  string
+		allowed bool
+	}{
+		{"admin deletes", adminPolicy, nil, "s3:DeleteBucket", "photos", true},
+		{"matching bucket", logsPolicy, nil, "s3:DeleteBucket", "logs-2024", true},
+		{"other bucket", logsPolicy, nil, "s3:DeleteBucket", "photos", false},
+		{"object action on matching bucket", logsPolicy, nil, "s3:PutObject", "logs-2024", true},
+		{"object action on other bucket", logsPolicy, nil, "s3:PutObject", "photos", false},
+		{"read-only policy", readPolicy, nil, "s3:DeleteBucket", "photos", false},
+		{"explicit deny", denyPolicy, nil, "s3:DeleteBucket", "protected", false},
+		{"explicit deny of another bucket", denyPolicy, nil, "s3:DeleteBucket", "photos", true},
+		{"role limits bucket action", adminPolicy, viewer, "s3:DeleteBucket", "photos", false},
+		{"role allows read", adminPolicy, viewer, "s3:GetBucketPolicy", "photos", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			access := newAccess(t, tt.policy, tt.role)
+			w := serveWithAccess(access, tt.role, "/buckets/:name", "/buckets/"+tt.bucket, RequireBucketPermission(tt.action, "name"))
+			if allowed := w.Code == http.StatusNoContent; allowed != tt.allowed {
+				t.Fatalf("%s on %s allowed: %t, want %t (status %d)", tt.action, tt.bucket, allowed, tt.allowed, w.Code)
+			}
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	viewer := viewerRole(t)
+
+	tests := []struct {
+		name    string
+		role    *rbac.Role
+		minimum string
+		allowed bool
+	}{
+		{"roles disabled", nil, rbac.RoleSuperAdmin, true},
+		{"same role", viewer, rbac.RoleViewer, true},
+		{"higher role required", viewer, rbac.RoleSuperAdmin, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveWithAccess(newAccess(t, adminPolicy, nil), tt.role, "/", "/", RequireRole(tt.minimum))
+			if allowed := w.Code == http.StatusNoContent; allowed != tt.allowed {
+				t.Fatalf("allowed: %t, want %t (status %d)", allowed, tt.allowed, w.Code)
+			}
+		})
+	}
+}
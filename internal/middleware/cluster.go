@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+
+	"minio-admin-panel/internal/cluster"
+	"minio-admin-panel/internal/permissions"
+	"minio-admin-panel/internal/rbac"
+	"minio-admin-panel/internal/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SelectCluster middleware picks the MinIO cluster a request acts on: the one
+// named by the :cluster route parameter, or the one the session logged in to
+// (or the API token is bound to). On another cluster than that, the request
+// acts with the credentials and permissions of the session's connection to
+// it; sessions without one are sent to the cluster page to connect first.
+func SelectCluster(sessions *session.Manager, clusters *cluster.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		home := clusters.Get(c.GetString("cluster"))
+		if home == nil {
+			log.Printf("[DEBUG] Credentials of user '%s' belong to unknown cluster '%s'", c.GetString("display_name"), c.GetString("cluster"))
+			if c.GetString("api_token_id") != "" {
+				abortAPIToken(c, http.StatusUnauthorized, "tokens.error.invalid")
+			} else {
+				rejectSession(c, session.ErrNotFound)
+			}
+			return
+		}
+
+		target := home
+		if name := c.Param("cluster"); name != "" {
+			if target = clusters.Get(name); target == nil {
+				log.Printf("[DEBUG] Unknown cluster '%s' requested by user '%s'", name, c.GetString("display_name"))
+				abortCluster(c, http.StatusNotFound, "clusters.error.unknown", "")
+				return
+			}
+		}
+		c.Set("home_cluster", home.Name)
+		c.Set("cluster", target.Name)
+
+		if target == home {
+			c.Next()
+			return
+		}
+
+		if c.GetString("api_token_id") != "" {
+			log.Printf("[DEBUG] API token %s is bound to cluster '%s', not '%s'", c.GetString("api_token_id"), home.Name, target.Name)
+			abortAPIToken(c, http.StatusForbidden, "clusters.error.token_cluster")
+			return
+		}
+
+		sess := c.MustGet("session").(*session.Session)
+		conn := sess.Connections[target.Name]
+		creds, err := sessions.ConnectionCredentials(sess, target.Name)
+		if err != nil {
+			log.Printf("[DEBUG] User '%s' is not connected to cluster '%s': %v", sess.Username, target.Name, err)
+			abortCluster(c, http.StatusForbidden, "clusters.error.not_connected", target.Name)
+			return
+		}
+
+		access := permissions.Restore(conn.Permissions, conn.Policy)
+		if value, ok := c.Get("role"); ok {
+			access = access.Limit(value.(*rbac.Role))
+		}
+
+		log.Printf("[DEBUG] User '%s' acting on cluster '%s' as '%s'", sess.Username, target.Name, creds.AccessKey)
+		c.Set("username", creds.AccessKey)
+		c.Set("password", creds.SecretKey)
+		c.Set("session_token", creds.SessionToken)
+		c.Set("policy_name", conn.PolicyName)
+		c.Set("permissions", access.Flags)
+		c.Set("access", access)
+
+		c.Next()
+	}
+}
+
+// abortCluster ends a request for a cluster that cannot be used. Page loads
+// go to the cluster page, opening the connect form of connect if given.
+func abortCluster(c *gin.Context, status int, key, connect string) {
+	if wantsJSON(c) {
+		c.AbortWithStatusJSON(status, gin.H{"error": T(c, key)})
+		return
+	}
+
+	target := "/clusters?error=" + url.QueryEscape(key)
+	if connect != "" {
+		target += "&connect=" + url.QueryEscape(connect) + "&next=" + url.QueryEscape(c.Request.URL.RequestURI())
+	}
+	c.Redirect(http.StatusFound, target)
+	c.Abort()
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CookiePolicy holds the attributes applied to every cookie the panel sets
+type CookiePolicy struct {
+	SameSite http.SameSite
+	Secure   bool
+}
+
+// Cookies middleware applies the cookie policy to the cookies set while
+// handling the request
+func Cookies(policy CookiePolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.SetSameSite(policy.SameSite)
+		c.Set("cookie_policy", policy)
+		c.Next()
+	}
+}
+
+// SetCookie sets a cookie using the configured SameSite and Secure attributes
+func SetCookie(c *gin.Context, name, value string, maxAge int, path string, httpOnly bool) {
+	secure := false
+	if policy, ok := c.Get("cookie_policy"); ok {
+		secure = policy.(CookiePolicy).Secure
+	}
+	c.SetCookie(name, value, maxAge, path, "", secure, httpOnly)
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCSRF(t *testing.T) {
+	const token = "csrf-test-token"
+
+	tests := []struct {
+		name    string
+		method  string
+		cookie  string // CSRF cookie, if any
+		header  string // X-CSRF-Token header, if any
+		form    string // csrf_token form field, if any
+		auth    string // Authorization header, if any
+		session bool   // send the session cookie
+		allowed bool
+	}{
+		{"safe method without token", http.MethodGet, "", "", "", "", true, true},
+		{"head without token", http.MethodHead, "", "", "", "", true, true},
+		{"post without token", http.MethodPost, token, "", "", "", true, false},
+		{"post without cookie", http.MethodPost, "", token, "", "", true, false},
+		{"post with matching header", http.MethodPost, token, token, "", "", true, true},
+		{"delete with matching header", http.MethodDelete, token, token, "", "", true, true},
+		{"post with matching form field", http.MethodPost, token, "", token, "", true, true},
+		{"post with wrong header", http.MethodPost, token, "other", "", "", true, false},
+		{"post with empty cookie", http.MethodPost, "", "", "", "", true, false},
+		{"bearer request without token", http.MethodPost, "", "", "", "Bearer mpat_0123456789abcdef_secret", false, true},
+		{"bearer request with session cookie", http.MethodPost, token, "", "", "Bearer mpat_0123456789abcdef_secret", true, false},
+		{"basic auth without token", http.MethodPost, "", "", "", "Basic dXNlcjpwYXNz", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(CSRF())
+			r.Handle(tt.method, "/", func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			})
+
+			body := ""
+			if tt.form != "" {
+				body = url.Values{csrfField: {tt.form}}.Encode()
+			}
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(body))
+			if tt.form != "" {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: csrfCookie, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set(csrfHeader, tt.header)
+			}
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			if tt.session {
+				req.AddCookie(&http.Cookie{Name: "token", Value: "session"})
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if allowed := w.Code == http.StatusNoContent; allowed != tt.allowed {
+				t.Fatalf("allowed: %t, want %t (status %d: %s)", allowed, tt.allowed, w.Code, w.Body.String())
+			}
+			if !tt.allowed && w.Code != http.StatusForbidden {
+				t.Fatalf("rejected with status %d, want %d", w.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestCSRFIssuesToken(t *testing.T) {
+	r := gin.New()
+	r.Use(CSRF())
+	var rendered string
+	r.GET("/", func(c *gin.Context) {
+		rendered = CSRFToken(c)
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var issued *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == csrfCookie {
+			issued = cookie
+		}
+	}
+	if issued == nil || issued.Value == "" {
+		t.Fatal("no CSRF cookie issued")
+	}
+	if !issued.HttpOnly {
+		t.Error("CSRF cookie is readable by scripts")
+	}
+	if rendered != issued.Value {
+		t.Errorf("page token %q does not match cookie %q", rendered, issued.Value)
+	}
+}
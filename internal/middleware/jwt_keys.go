@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyRing holds the JWT signing key plus any retired keys that are still
+// accepted for verification. Each key is identified by a "kid" header derived
+// from the secret, so rotating JWT_SECRET does not invalidate existing tokens
+// as long as the old secret is listed in JWT_PREVIOUS_SECRETS.
+type KeyRing struct {
+	signingKID string
+	keys       map[string][]byte
+}
+
+// NewKeyRing creates a key ring that signs with signingSecret and verifies with
+// signingSecret and every entry of previousSecrets
+func NewKeyRing(signingSecret string, previousSecrets []string) (*KeyRing, error) {
+	if signingSecret == "" {
+		return nil, fmt.Errorf("JWT signing secret must not be empty")
+	}
+
+	ring := &KeyRing{
+		signingKID: keyID(signingSecret),
+		keys:       make(map[string][]byte),
+	}
+	ring.keys[ring.signingKID] = []byte(signingSecret)
+
+	for _, secret := range previousSecrets {
+		ring.keys[keyID(secret)] = []byte(secret)
+	}
+
+	return ring, nil
+}
+
+// Sign signs the claims with the current signing key
+func (k *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = k.signingKID
+	return token.SignedString(k.keys[k.signingKID])
+}
+
+// Keyfunc resolves the verification key for a token from its "kid" header.
+// Tokens without a kid were issued before rotation support and are checked
+// against the current signing key.
+func (k *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return k.keys[k.signingKID], nil
+	}
+
+	key, exists := k.keys[kid]
+	if !exists {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// keyID derives a stable, non-reversible identifier for a secret
+func keyID(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:8])
+}
@@ -18,8 +18,8 @@ func LanguageMiddleware() gin.HandlerFunc {
 		// Always ensure the cookie is set or updated
 		if lang != "" {
 			// Set/update the language cookie with each request to refresh expiry
-			// Secure: false to allow HTTP testing, HttpOnly: false to allow JS access
-			c.SetCookie("language", lang, 86400*30, "/", "", false, false) // 30 days
+			// HttpOnly: false to allow JS access
+			SetCookie(c, "language", lang, 86400*30, "/", false) // 30 days
 			fmt.Printf("[DEBUG] Set language cookie: %s\n", lang)
 		}
 
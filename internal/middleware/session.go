@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"minio-admin-panel/internal/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IssueSessionCookie signs a token for the session and sets it as the session
+// cookie. Token and cookie expire together with the session's idle timeout.
+func IssueSessionCookie(c *gin.Context, keys *KeyRing, sess *session.Session) error {
+	token, err := GenerateJWTWithSession(keys, sess.ID, sess.Username, sess.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	maxAge := int(time.Until(sess.ExpiresAt).Seconds())
+	if maxAge <= 0 {
+		maxAge = -1
+	}
+	SetCookie(c, "token", token, maxAge, "/", true)
+	return nil
+}
+
+// rejectSession ends a request without a usable session. Page loads are sent
+// to the login page with a return URL; AJAX requests get a 401 with the same
+// URL so the page can keep unsaved input before it navigates away. Users whose
+// session was revoked are told so instead.
+func rejectSession(c *gin.Context, err error) {
+	if _, err := c.Cookie("token"); err == nil {
+		SetCookie(c, "token", "", -1, "/", true)
+	}
+
+	loginURL, message := LoginURL(returnPath(c)), "session.expired"
+	if errors.Is(err, session.ErrRevoked) {
+		loginURL, message = "/?reason=revoked", "session.revoked"
+	}
+
+	if wantsJSON(c) {
+		c.Header("X-Session-Expired", "true")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error":    T(c, message),
+			"notice":   T(c, "session.draft_restored"),
+			"redirect": loginURL,
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, loginURL)
+	c.Abort()
+}
+
+// LoginURL returns the login page URL that returns to next after signing in
+func LoginURL(next string) string {
+	if next = SafeReturnPath(next); next == "" {
+		return "/"
+	}
+	return "/?next=" + url.QueryEscape(next)
+}
+
+// SafeReturnPath returns next if it is a local path that is safe to redirect
+// to after login, and an empty string otherwise. Browsers drop control
+// characters and read backslashes as slashes in URLs, so "/\t/host" or
+// "/\\host" would lead to another site; any of them rejects the path.
+func SafeReturnPath(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") {
+		return ""
+	}
+	if strings.ContainsFunc(next, func(r rune) bool { return r == '\\' || unicode.IsControl(r) }) {
+		return ""
+	}
+	switch path := strings.SplitN(next, "?", 2)[0]; {
+	case path == "/", path == "/login", path == "/logout", strings.HasPrefix(path, "/login/"),
+		strings.HasPrefix(path, "/api/"), strings.HasPrefix(path, "/auth/"):
+		return ""
+	}
+	return next
+}
+
+// returnPath is the page a user should come back to after signing in again.
+// For page loads it is the requested URL; for form posts and AJAX requests it
+// is the same-origin page that sent them.
+func returnPath(c *gin.Context) string {
+	if c.Request.Method == http.MethodGet && !wantsJSON(c) {
+		return c.Request.URL.RequestURI()
+	}
+
+	referer, err := url.Parse(c.GetHeader("Referer"))
+	if err != nil || referer.Host != c.Request.Host {
+		return ""
+	}
+	return referer.RequestURI()
+}
+
+// wantsJSON reports whether the request comes from a script or API client
+// rather than a page load
+func wantsJSON(c *gin.Context) bool {
+	return c.GetHeader("X-Requested-With") == "XMLHttpRequest" ||
+		strings.Contains(c.GetHeader("Accept"), "application/json") ||
+		strings.HasPrefix(c.ContentType(), "application/json") ||
+		strings.HasPrefix(c.GetHeader("Authorization"), "Bearer ")
+}
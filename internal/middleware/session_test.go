@@ -0,0 +1,60 @@
+package middleware
+
+import "testing"
+
+func TestSafeReturnPath(t *testing.T) {
+	tests := []struct {
+		next string
+		safe bool
+	}{
+		{"/buckets", true},
+		{"/buckets/photos?tab=versioning", true},
+		{"/c/backup/users", true},
+		{"/buckets?next=//evil.com", true},
+		{"", false},
+		{"buckets", false},
+		{"https://evil.com", false},
+		{"//evil.com", false},
+		{"/\\evil.com", false},
+		{"/\\/evil.com", false},
+		{"/buckets\\..\\..\\evil.com", false},
+		{"/\t/evil.com", false},
+		{"/\n/evil.com", false},
+		{"/\r\n/evil.com", false},
+		{"/\x00/evil.com", false},
+		{"/\x7f/evil.com", false},
+		{"/\u0085/evil.com", false},
+		{"/", false},
+		{"/login", false},
+		{"/login/mfa", false},
+		{"/logout", false},
+		{"/api/buckets", false},
+		{"/auth/oidc/callback", false},
+	}
+	for _, tt := range tests {
+		want := ""
+		if tt.safe {
+			want = tt.next
+		}
+		if got := SafeReturnPath(tt.next); got != want {
+			t.Errorf("SafeReturnPath(%q) = %q, want %q", tt.next, got, want)
+		}
+	}
+}
+
+func TestLoginURL(t *testing.T) {
+	tests := []struct {
+		next string
+		want string
+	}{
+		{"/buckets?tab=a&b=c", "/?next=%2Fbuckets%3Ftab%3Da%26b%3Dc"},
+		{"/\t/evil.com", "/"},
+		{"//evil.com", "/"},
+		{"", "/"},
+	}
+	for _, tt := range tests {
+		if got := LoginURL(tt.next); got != tt.want {
+			t.Errorf("LoginURL(%q) = %q, want %q", tt.next, got, tt.want)
+		}
+	}
+}
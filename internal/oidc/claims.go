@@ -0,0 +1,82 @@
+package oidc
+
+import "fmt"
+
+// Claims holds the verified claims of an ID token
+type Claims map[string]interface{}
+
+// String returns a claim as a string, or "" if it is missing
+func (c Claims) String(name string) string {
+	switch v := c[name].(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Strings returns a claim as a list of strings. Single values are returned as
+// a one-element list so "groups": "admins" and "groups": ["admins"] behave alike.
+func (c Claims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var result []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// All returns every claim that has string values, as lists of strings
+func (c Claims) All() map[string][]string {
+	result := make(map[string][]string, len(c))
+	for name := range c {
+		if values := c.Strings(name); len(values) > 0 {
+			result[name] = values
+		}
+	}
+	return result
+}
+
+// Username returns the first non-empty claim out of claimName, preferred_username,
+// email and sub
+func (c Claims) Username(claimName string) string {
+	for _, name := range []string{claimName, "preferred_username", "email", "sub"} {
+		if name == "" {
+			continue
+		}
+		if value := c.String(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// RoleMapping maps values of a claim (typically "groups") to MinIO role ARNs
+type RoleMapping struct {
+	Claim   string
+	Roles   map[string]string
+	Default string
+}
+
+// RoleARN returns the role for the first claim value with a mapping, falling
+// back to the default role. An empty result lets MinIO apply claim-based policies.
+func (m RoleMapping) RoleARN(claims Claims) string {
+	if m.Claim != "" {
+		for _, value := range claims.Strings(m.Claim) {
+			if roleARN, ok := m.Roles[value]; ok {
+				return roleARN
+			}
+		}
+	}
+	return m.Default
+}
@@ -0,0 +1,238 @@
+package oidc
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"minio-admin-panel/internal/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID    = "panel"
+	testRedirectURL = "https://panel.example.com/auth/oidc/callback"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func newIdP(t *testing.T) *oidctest.IdP {
+	t.Helper()
+	idp, err := oidctest.NewIdP(testClientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+func newTestProvider(issuer string) *Provider {
+	return NewProvider(Config{
+		Issuer:      issuer,
+		ClientID:    testClientID,
+		RedirectURL: testRedirectURL,
+		RoleMapping: RoleMapping{
+			Claim:   "groups",
+			Roles:   map[string]string{"admins": "arn:minio:iam:::role/admins"},
+			Default: "arn:minio:iam:::role/default",
+		},
+	})
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	idp := newIdP(t)
+	p := newTestProvider(idp.URL)
+
+	authURL, err := p.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          testRedirectURL,
+		"scope":                 "openid profile email",
+		"state":                 "state",
+		"nonce":                 "nonce",
+		"code_challenge":        "iMnq5o6zALKXGivsnlom_0F5_WYda32GHkxlV7mq7hQ", // S256 of "verifier"
+		"code_challenge_method": "S256",
+	}
+	for name, value := range want {
+		if got := u.Query().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	if !strings.HasPrefix(authURL, idp.URL+"/authorize?") {
+		t.Errorf("authorization URL %s does not point at the authorization endpoint", authURL)
+	}
+}
+
+func TestCodeFlow(t *testing.T) {
+	tests := []struct {
+		name     string
+		claims   jwt.MapClaims // claims the IdP puts into the ID token
+		verifier string        // PKCE verifier sent with the code, if not the original
+		nonce    string        // nonce expected by the panel, if not the original
+		wantErr  string
+	}{
+		{name: "valid"},
+		{name: "PKCE verifier mismatch", verifier: "other-verifier", wantErr: "status 400"},
+		{name: "nonce mismatch", nonce: "other-nonce", wantErr: "nonce mismatch"},
+		{name: "nonce missing", claims: jwt.MapClaims{"nonce": nil}, wantErr: "nonce mismatch"},
+		{name: "wrong issuer", claims: jwt.MapClaims{"iss": "https://evil.example.com"}, wantErr: "invalid issuer"},
+		{name: "wrong audience", claims: jwt.MapClaims{"aud": "other-client"}, wantErr: "invalid audience"},
+		{name: "audience list without client", claims: jwt.MapClaims{"aud": []string{"a", "b"}}, wantErr: "invalid audience"},
+		{name: "audience list with client", claims: jwt.MapClaims{"aud": []string{"a", testClientID}}},
+		{name: "expired", claims: jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}, wantErr: "expired"},
+		{name: "no expiry", claims: jwt.MapClaims{"exp": nil}, wantErr: "exp claim is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newIdP(t)
+			for name, value := range tt.claims {
+				idp.Claims[name] = value
+			}
+			p := newTestProvider(idp.URL)
+			ctx := context.Background()
+
+			const state, nonce, verifier = "state-value", "nonce-value", "verifier-value"
+			authURL, err := p.AuthCodeURL(ctx, state, nonce, verifier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			callback, err := idp.Authorize(authURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := callback.Query().Get("state"); got != state {
+				t.Fatalf("IdP returned state %q, want %q", got, state)
+			}
+
+			sentVerifier := verifier
+			if tt.verifier != "" {
+				sentVerifier = tt.verifier
+			}
+			expectedNonce := nonce
+			if tt.nonce != "" {
+				expectedNonce = tt.nonce
+			}
+
+			var claims Claims
+			token, err := p.Exchange(ctx, callback.Query().Get("code"), sentVerifier)
+			if err == nil {
+				claims, err = p.VerifyIDToken(ctx, token.IDToken, expectedNonce)
+			}
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := claims.Username(p.UsernameClaim()); got != "alice" {
+				t.Errorf("username %q, want alice", got)
+			}
+			if got := p.RoleARN(claims); got != "arn:minio:iam:::role/admins" {
+				t.Errorf("role ARN %q, want the admins role", got)
+			}
+		})
+	}
+}
+
+func TestCodeIsRedeemedOnce(t *testing.T) {
+	idp := newIdP(t)
+	p := newTestProvider(idp.URL)
+	ctx := context.Background()
+
+	authURL, err := p.AuthCodeURL(ctx, "state", "nonce", "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback, err := idp.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := callback.Query().Get("code")
+
+	if _, err := p.Exchange(ctx, code, "verifier"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Exchange(ctx, code, "verifier"); err == nil {
+		t.Fatal("code was redeemed twice")
+	}
+}
+
+func TestDiscovery(t *testing.T) {
+	tests := []struct {
+		name            string
+		issuer          string // configured issuer, relative to the IdP URL
+		discoveryIssuer string // issuer announced by the IdP, if not its URL
+		wantErr         string
+	}{
+		{name: "matching issuer"},
+		{name: "trailing slash", issuer: "/"},
+		{name: "issuer mismatch", discoveryIssuer: "https://evil.example.com", wantErr: "issuer mismatch"},
+		{name: "no discovery document", issuer: "/missing", wantErr: "status 404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newIdP(t)
+			idp.DiscoveryIssuer = tt.discoveryIssuer
+			p := newTestProvider(idp.URL + tt.issuer)
+
+			_, err := p.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRejectsForeignSignature(t *testing.T) {
+	idp := newIdP(t)
+	other := newIdP(t)
+	other.Claims["iss"] = idp.URL
+	ctx := context.Background()
+
+	// A token issued by another IdP that claims to come from ours
+	foreign := newTestProvider(other.URL)
+	authURL, err := foreign.AuthCodeURL(ctx, "state", "nonce", "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback, err := other.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := foreign.Exchange(ctx, callback.Query().Get("code"), "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newTestProvider(idp.URL).VerifyIDToken(ctx, token.IDToken, "nonce"); err == nil {
+		t.Fatal("ID token signed by another key was accepted")
+	}
+}
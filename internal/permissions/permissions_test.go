@@ -0,0 +1,249 @@
+package permissions
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+)
+
+// Policies used by the tests
+const (
+	adminPolicy = `{"Version":"2012-10-17","Statement":[
+		{"Effect":"Allow","Action":["admin:*"]},
+		{"Effect":"Allow","Action":["s3:*"],"Resource":["arn:aws:s3:::*"]}]}`
+	photosPolicy = `{"Version":"2012-10-17","Statement":[
+		{"Effect":"Allow","Action":"s3:ListAllMyBuckets","Resource":"arn:aws:s3:::*"},
+		{"Effect":"Allow","Action":["s3:*"],"Resource":["arn:aws:s3:::photos","arn:aws:s3:::photos/*"]},
+		{"Effect":"Deny","Action":["s3:DeleteBucket","s3:DeleteObject"],"Resource":["arn:aws:s3:::photos"]}]}`
+	notAdminPolicy = `{"Version":"2012-10-17","Statement":[
+		{"Effect":"Allow","NotAction":["admin:*"],"Resource":["*"]},
+		{"Effect":"deny","Action":["s3:CreateBucket"]}]}`
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func mustParse(t *testing.T, doc string) *Policy {
+	t.Helper()
+	policy, err := ParsePolicy([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return policy
+}
+
+// allowList is a limiter that allows the actions matching its patterns
+type allowList []string
+
+func (l allowList) Allows(action string) bool {
+	return matchAny(l, action)
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		match   bool
+	}{
+		{"*", "anything", true},
+		{"s3:*", "s3:GetObject", true},
+		{"s3:*", "admin:ServerInfo", false},
+		{"s3:Get*", "s3:GetObject", true},
+		{"s3:Get*", "s3:PutObject", false},
+		{"arn:aws:s3:::photos/*", "arn:aws:s3:::photos/2024/a.jpg", true},
+		{"arn:aws:s3:::photos/*", "arn:aws:s3:::photos", false},
+		{"arn:aws:s3:::photos*", "arn:aws:s3:::photos-old", true},
+		{"arn:aws:s3:::*/reports/*", "arn:aws:s3:::finance/reports/q1.csv", true},
+		{"arn:aws:s3:::*/reports/*", "arn:aws:s3:::finance/q1.csv", false},
+		{"arn:aws:s3:::log?", "arn:aws:s3:::logs", true},
+		{"arn:aws:s3:::log?", "arn:aws:s3:::log", false},
+		{"arn:aws:s3:::log?/*", "arn:aws:s3:::logs/a", true},
+		{"s3:GetObject", "s3:GetObjectTagging", false},
+	}
+	for _, tt := range tests {
+		if got := MatchPattern(tt.pattern, tt.value); got != tt.match {
+			t.Errorf("MatchPattern(%q, %q) = %t, want %t", tt.pattern, tt.value, got, tt.match)
+		}
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		doc        string
+		statements int
+		valid      bool
+	}{
+		{"empty document", "", 0, true},
+		{"single strings", `{"Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"arn:aws:s3:::a/*"}]}`, 1, true},
+		{"lists", photosPolicy, 3, true},
+		{"not JSON", `{"Statement":`, 0, false},
+		{"action is a number", `{"Statement":[{"Effect":"Allow","Action":1}]}`, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ParsePolicy([]byte(tt.doc))
+			if !tt.valid {
+				if err == nil {
+					t.Fatal("invalid policy was accepted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(policy.Statements) != tt.statements {
+				t.Fatalf("got %d statements, want %d", len(policy.Statements), tt.statements)
+			}
+		})
+	}
+}
+
+func TestIsAllowed(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		action   string
+		resource string
+		allowed  bool
+	}{
+		{"admin action ignores resource", adminPolicy, "admin:CreateUser", "", true},
+		{"admin wildcard", adminPolicy, "admin:*", "", true},
+		{"object in bucket", photosPolicy, "s3:GetObject", "arn:aws:s3:::photos/a.jpg", true},
+		{"object in other bucket", photosPolicy, "s3:GetObject", "arn:aws:s3:::finance/a.jpg", false},
+		{"deny on bucket wins", photosPolicy, "s3:DeleteBucket", "arn:aws:s3:::photos", false},
+		{"deny on bucket leaves objects", photosPolicy, "s3:DeleteObject", "arn:aws:s3:::photos/a.jpg", true},
+		{"not granted at all", photosPolicy, "admin:ServerInfo", "", false},
+		{"NotAction grants other actions", notAdminPolicy, "s3:PutObject", "arn:aws:s3:::any/a", true},
+		{"NotAction excludes its actions", notAdminPolicy, "admin:ServerInfo", "", false},
+		{"deny effect is case-insensitive", notAdminPolicy, "s3:CreateBucket", "arn:aws:s3:::new", false},
+		{"empty policy", "", "s3:GetObject", "arn:aws:s3:::photos/a", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := mustParse(t, tt.policy)
+			if got := policy.IsAllowed(tt.action, tt.resource); got != tt.allowed {
+				t.Fatalf("IsAllowed(%q, %q) = %t, want %t", tt.action, tt.resource, got, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestIsAllowedAnywhere(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		action  string
+		allowed bool
+	}{
+		{"granted on one bucket", photosPolicy, "s3:PutObject", true},
+		{"denied on one bucket only", `{"Statement":[
+			{"Effect":"Allow","Action":"s3:*","Resource":"*"},
+			{"Effect":"Deny","Action":"s3:DeleteBucket","Resource":"arn:aws:s3:::photos"}]}`, "s3:DeleteBucket", true},
+		{"denied on every bucket", `{"Statement":[
+			{"Effect":"Allow","Action":"s3:*","Resource":"*"},
+			{"Effect":"Deny","Action":"s3:DeleteBucket","Resource":"arn:aws:s3:::*"}]}`, "s3:DeleteBucket", false},
+		{"denied without resource", notAdminPolicy, "s3:CreateBucket", false},
+		{"admin action denied on a resource", `{"Statement":[
+			{"Effect":"Allow","Action":"admin:*"},
+			{"Effect":"Deny","Action":"admin:CreateUser","Resource":"arn:aws:s3:::photos"}]}`, "admin:CreateUser", false},
+		{"not granted", photosPolicy, "admin:ListUsers", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := mustParse(t, tt.policy)
+			if got := policy.IsAllowedAnywhere(tt.action); got != tt.allowed {
+				t.Fatalf("IsAllowedAnywhere(%q) = %t, want %t", tt.action, got, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		limiter Limiter
+		flags   map[string]bool
+		buckets map[string]bool // s3:PutObject per bucket
+	}{
+		{
+			name:    "admin",
+			policy:  adminPolicy,
+			flags:   map[string]bool{"isAdmin": true, "canListBuckets": true, "canDeleteBuckets": true, "canManageUsers": true},
+			buckets: map[string]bool{"photos": true, "finance": true},
+		},
+		{
+			name:    "one bucket",
+			policy:  photosPolicy,
+			flags:   map[string]bool{"isAdmin": false, "canListBuckets": true, "canCreateBuckets": true, "canDeleteBuckets": true, "canViewUsers": false},
+			buckets: map[string]bool{"photos": true, "finance": false},
+		},
+		{
+			name:    "admin limited to reading",
+			policy:  adminPolicy,
+			limiter: allowList{"s3:List*", "s3:Get*", "admin:List*"},
+			flags:   map[string]bool{"isAdmin": false, "canListBuckets": true, "canDeleteBuckets": false, "canViewUsers": true, "canManageUsers": false},
+			buckets: map[string]bool{"photos": false, "finance": false},
+		},
+		{
+			name:    "no policy",
+			policy:  "",
+			flags:   map[string]bool{"isAdmin": false, "canListBuckets": false},
+			buckets: map[string]bool{"photos": false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := NewSet([]byte(tt.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.limiter != nil {
+				set = set.Limit(tt.limiter)
+			}
+
+			for flag, want := range tt.flags {
+				if got := set.Has(flag); got != want {
+					t.Errorf("Has(%q) = %t, want %t", flag, got, want)
+				}
+			}
+			for bucket, want := range tt.buckets {
+				if got := set.CanBucket("s3:PutObject", bucket); got != want {
+					t.Errorf("CanBucket(s3:PutObject, %q) = %t, want %t", bucket, got, want)
+				}
+			}
+
+			// A set restored from a session answers like the one it was saved from
+			restored := Restore(set.Flags, set.PolicyJSON())
+			if tt.limiter != nil {
+				restored = restored.Limit(tt.limiter)
+			}
+			for bucket, want := range tt.buckets {
+				if got := restored.CanBucket("s3:PutObject", bucket); got != want {
+					t.Errorf("restored CanBucket(s3:PutObject, %q) = %t, want %t", bucket, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNilSet(t *testing.T) {
+	var set *Set
+	if set.Has("isAdmin") || set.Can("s3:GetObject") || set.CanBucket("s3:GetObject", "photos") || set.PolicyJSON() != nil {
+		t.Fatal("a nil set granted a permission")
+	}
+}
+
+func TestRestoreIgnoresUnparsablePolicy(t *testing.T) {
+	set := Restore(map[string]bool{"canListBuckets": true}, []byte(`{"Statement":`))
+	if !set.Has("canListBuckets") {
+		t.Error("flags were dropped")
+	}
+	if set.Can("s3:ListAllMyBuckets") {
+		t.Error("unparsable policy granted an action")
+	}
+}
@@ -0,0 +1,180 @@
+package permissions
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// BucketARN returns the resource name of a bucket
+func BucketARN(bucket string) string {
+	return "arn:aws:s3:::" + bucket
+}
+
+// Policy is an IAM policy document as returned by MinIO
+type Policy struct {
+	Version    string      `json:"Version"`
+	Statements []Statement `json:"Statement"`
+}
+
+// Statement is a single policy statement. Conditions are not evaluated: the
+// panel only uses the policy to decide what to show, MinIO still enforces it.
+type Statement struct {
+	Effect    string          `json:"Effect"`
+	Actions   stringList      `json:"Action"`
+	NotAction stringList      `json:"NotAction,omitempty"`
+	Resources stringList      `json:"Resource,omitempty"`
+	Condition json.RawMessage `json:"Condition,omitempty"`
+}
+
+// stringList accepts both a single string and a list of strings
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = stringList{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("expected string or list of strings: %v", err)
+	}
+	*l = list
+	return nil
+}
+
+// ParsePolicy parses a policy document. An empty document yields an empty policy.
+func ParsePolicy(doc []byte) (*Policy, error) {
+	policy := &Policy{}
+	if len(doc) == 0 {
+		return policy, nil
+	}
+	if err := json.Unmarshal(doc, policy); err != nil {
+		return nil, fmt.Errorf("invalid policy document: %v", err)
+	}
+	return policy, nil
+}
+
+// IsAllowed reports whether the policy allows action on resource. An explicit
+// Deny always wins. Admin actions are not resource-scoped.
+func (p *Policy) IsAllowed(action, resource string) bool {
+	allowed := false
+	for _, st := range p.Statements {
+		if !st.matchesAction(action) || !st.matchesResource(action, resource) {
+			continue
+		}
+		if strings.EqualFold(st.Effect, "Deny") {
+			return false
+		}
+		allowed = true
+	}
+	return allowed
+}
+
+// IsAllowedAnywhere reports whether action is allowed on at least some
+// resource, i.e. whether the action should be offered in the UI at all
+func (p *Policy) IsAllowedAnywhere(action string) bool {
+	allowed := false
+	for _, st := range p.Statements {
+		if !st.matchesAction(action) {
+			continue
+		}
+		if strings.EqualFold(st.Effect, "Deny") {
+			// Only a deny on every resource rules the action out entirely
+			if strings.HasPrefix(action, "admin:") || len(st.Resources) == 0 || containsWildcardAll(st.Resources) {
+				return false
+			}
+			continue
+		}
+		allowed = true
+	}
+	return allowed
+}
+
+func (st Statement) matchesAction(action string) bool {
+	if len(st.NotAction) > 0 {
+		return !matchAny(st.NotAction, action)
+	}
+	return matchAny(st.Actions, action)
+}
+
+func (st Statement) matchesResource(action, resource string) bool {
+	if strings.HasPrefix(action, "admin:") || len(st.Resources) == 0 {
+		return true
+	}
+	return matchAny(st.Resources, resource)
+}
+
+func containsWildcardAll(resources []string) bool {
+	for _, r := range resources {
+		if r == "*" || r == "arn:aws:s3:::*" {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPattern matches a value against an IAM-style pattern with * and ? wildcards
+func MatchPattern(pattern, value string) bool {
+	return wildcardMatch(pattern, value)
+}
+
+// matchAny matches value against a list of patterns with * and ? wildcards
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if wildcardMatch(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardMatch matches like IAM: * spans any characters including "/"
+func wildcardMatch(pattern, value string) bool {
+	if pattern == "*" {
+		return true
+	}
+	if !strings.Contains(pattern, "*") {
+		return matchPart(pattern, value, false)
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !matchPart(parts[0], value, true) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	for i := 1; i < len(parts)-1; i++ {
+		idx := indexPart(value, parts[i])
+		if idx < 0 {
+			return false
+		}
+		value = value[idx+len(parts[i]):]
+	}
+	last := parts[len(parts)-1]
+	return len(value) >= len(last) && matchPart(last, value[len(value)-len(last):], true)
+}
+
+// matchPart compares a wildcard-free segment (honouring ?) with the start of value
+func matchPart(part, value string, prefix bool) bool {
+	if len(value) < len(part) {
+		return false
+	}
+	for i := 0; i < len(part); i++ {
+		if part[i] != '?' && part[i] != value[i] {
+			return false
+		}
+	}
+	return prefix || len(value) == len(part)
+}
+
+// indexPart finds the first position of a segment that may contain ?
+func indexPart(value, part string) int {
+	for i := 0; i+len(part) <= len(value); i++ {
+		if matchPart(part, value[i:], true) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,122 @@
+package permissions
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+)
+
+// flagActions maps the coarse permission flags used by routes and templates
+// to the IAM action that grants them
+var flagActions = map[string]string{
+	"canListBuckets":           "s3:ListAllMyBuckets",
+	"canCreateBuckets":         "s3:CreateBucket",
+	"canDeleteBuckets":         "s3:DeleteBucket",
+	"canSetBucketPolicy":       "s3:PutBucketPolicy",
+	"canViewUsers":             "admin:ListUsers",
+	"canManageUsers":           "admin:CreateUser",
+	"canViewGroups":            "admin:ListGroups",
+	"canManageGroups":          "admin:AddUserToGroup",
+	"canViewPolicies":          "admin:ListUserPolicies",
+	"canManagePolicies":        "admin:CreatePolicy",
+	"canAttachPolicies":        "admin:AttachUserOrGroupPolicy",
+	"canViewServiceAccounts":   "admin:ListServiceAccounts",
+	"canManageServiceAccounts": "admin:CreateServiceAccount",
+	"canViewServerInfo":        "admin:ServerInfo",
+}
+
+// Limiter restricts the actions a panel user may perform beyond their policy
+type Limiter interface {
+	Allows(action string) bool
+}
+
+// Set is the effective permission set of a panel session. Flags holds the
+// coarse permissions; the policy answers per-action and per-bucket questions.
+type Set struct {
+	Flags  map[string]bool
+	policy *Policy
+	raw    json.RawMessage
+	limit  Limiter
+}
+
+// NewSet derives the permission set from an effective policy document
+func NewSet(policyJSON []byte) (*Set, error) {
+	policy, err := ParsePolicy(policyJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := make(map[string]bool, len(flagActions)+1)
+	for flag, action := range flagActions {
+		flags[flag] = policy.IsAllowedAnywhere(action)
+	}
+	flags["isAdmin"] = policy.IsAllowed("admin:*", "")
+
+	return &Set{Flags: flags, policy: policy, raw: json.RawMessage(policyJSON)}, nil
+}
+
+// Restore rebuilds a permission set from the flags and policy kept in a session
+func Restore(flags map[string]bool, policyJSON []byte) *Set {
+	policy, err := ParsePolicy(policyJSON)
+	if err != nil {
+		log.Printf("[DEBUG] Ignoring unparsable session policy: %v", err)
+		policy = &Policy{}
+	}
+	if flags == nil {
+		flags = map[string]bool{}
+	}
+	return &Set{Flags: flags, policy: policy, raw: json.RawMessage(policyJSON)}
+}
+
+// Limit returns a copy of the set that additionally requires limiter to allow
+// each action, with the flags narrowed accordingly
+func (s *Set) Limit(limiter Limiter) *Set {
+	flags := make(map[string]bool, len(s.Flags))
+	for flag, granted := range s.Flags {
+		switch {
+		case flag == "isAdmin":
+			flags[flag] = granted && limiter.Allows("admin:*")
+		case flagActions[flag] != "":
+			flags[flag] = granted && limiter.Allows(flagActions[flag])
+		default:
+			flags[flag] = granted
+		}
+	}
+	return &Set{Flags: flags, policy: s.policy, raw: s.raw, limit: limiter}
+}
+
+// PolicyJSON returns the effective policy document the set was derived from
+func (s *Set) PolicyJSON() json.RawMessage {
+	if s == nil {
+		return nil
+	}
+	return s.raw
+}
+
+// Has reports whether a coarse permission flag is set
+func (s *Set) Has(flag string) bool {
+	return s != nil && s.Flags[flag]
+}
+
+// Can reports whether action is allowed on at least one resource
+func (s *Set) Can(action string) bool {
+	return s != nil && s.limits(action) && s.policy.IsAllowedAnywhere(action)
+}
+
+// CanBucket reports whether action is allowed on a bucket. Object actions
+// such as s3:GetObject are checked against the objects in the bucket.
+func (s *Set) CanBucket(action, bucket string) bool {
+	if s == nil {
+		return false
+	}
+	resource := BucketARN(bucket)
+	if strings.Contains(action, "Object") {
+		resource += "/*"
+	}
+	return s.limits(action) && s.policy.IsAllowed(action, resource)
+}
+
+// limits reports whether the limiter, if any, allows action
+func (s *Set) limits(action string) bool {
+	return s.limit == nil || s.limit.Allows(action)
+}
@@ -0,0 +1,206 @@
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"minio-admin-panel/internal/permissions"
+)
+
+// Built-in panel roles, ordered from least to most privileged
+const (
+	RoleViewer     = "viewer"
+	RoleOperator   = "operator"
+	RoleIAMAdmin   = "iam-admin"
+	RoleSuperAdmin = "super-admin"
+)
+
+// readActions are allowed for every role
+var readActions = []string{
+	"s3:List*",
+	"s3:Get*",
+	"admin:List*",
+	"admin:Get*",
+	"admin:ServerInfo",
+	"admin:DataUsageInfo",
+}
+
+// Role limits what a panel user may do on top of their MinIO policy
+type Role struct {
+	Name  string
+	Rank  int
+	Allow []string // IAM action patterns
+}
+
+// Allows reports whether the role permits an IAM action
+func (r *Role) Allows(action string) bool {
+	for _, pattern := range r.Allow {
+		if permissions.MatchPattern(pattern, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// AtLeast reports whether the role is at least as privileged as the named role
+func (r *Role) AtLeast(name string) bool {
+	other, ok := builtinRoles[name]
+	return ok && r.Rank >= other.Rank
+}
+
+var builtinRoles = map[string]*Role{
+	RoleViewer: {
+		Name:  RoleViewer,
+		Rank:  1,
+		Allow: readActions,
+	},
+	RoleOperator: {
+		Name: RoleOperator,
+		Rank: 2,
+		Allow: append([]string{
+			"s3:CreateBucket",
+			"s3:PutBucket*",
+			"s3:PutLifecycleConfiguration",
+			"s3:PutReplicationConfiguration",
+			"s3:PutEncryptionConfiguration",
+			"admin:EnableUser",
+			"admin:DisableUser",
+		}, readActions...),
+	},
+	RoleIAMAdmin: {
+		Name: RoleIAMAdmin,
+		Rank: 3,
+		Allow: append([]string{
+			"admin:CreateUser",
+			"admin:DeleteUser",
+			"admin:EnableUser",
+			"admin:DisableUser",
+			"admin:AddUserToGroup",
+			"admin:RemoveUserFromGroup",
+			"admin:CreatePolicy",
+			"admin:DeletePolicy",
+			"admin:AttachUserOrGroupPolicy",
+			"admin:CreateServiceAccount",
+			"admin:UpdateServiceAccount",
+			"admin:RemoveServiceAccount",
+		}, readActions...),
+	},
+	RoleSuperAdmin: {
+		Name:  RoleSuperAdmin,
+		Rank:  4,
+		Allow: []string{"*"},
+	},
+}
+
+// Binding assigns a role to MinIO users, MinIO groups or OIDC claim values
+type Binding struct {
+	Role   string              `json:"role"`
+	Users  []string            `json:"users,omitempty"`
+	Groups []string            `json:"groups,omitempty"`
+	Claims map[string][]string `json:"claims,omitempty"`
+}
+
+// Config is the role mapping file
+type Config struct {
+	// DefaultRole applies to users without a matching binding; empty denies access
+	DefaultRole string    `json:"default_role"`
+	Bindings    []Binding `json:"bindings"`
+}
+
+// Identity describes a logged-in user for role resolution
+type Identity struct {
+	Username string
+	Groups   []string
+	Claims   map[string][]string
+}
+
+// Registry resolves panel roles. A nil registry means RBAC is disabled.
+type Registry struct {
+	config Config
+}
+
+// Load reads the role mapping file
+func Load(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read role mapping: %v", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid role mapping: %v", err)
+	}
+
+	if cfg.DefaultRole != "" && builtinRoles[cfg.DefaultRole] == nil {
+		return nil, fmt.Errorf("unknown default role %q", cfg.DefaultRole)
+	}
+	for i, binding := range cfg.Bindings {
+		if builtinRoles[binding.Role] == nil {
+			return nil, fmt.Errorf("binding %d: unknown role %q", i, binding.Role)
+		}
+	}
+
+	log.Printf("[DEBUG] Loaded %d role bindings from '%s'", len(cfg.Bindings), path)
+	return &Registry{config: cfg}, nil
+}
+
+// Enabled reports whether panel roles are enforced
+func (r *Registry) Enabled() bool {
+	return r != nil
+}
+
+// Resolve returns the most privileged role bound to the identity, or the
+// default role. An empty result means the identity has no panel access.
+func (r *Registry) Resolve(id Identity) string {
+	if r == nil {
+		return ""
+	}
+
+	best := r.config.DefaultRole
+	for _, binding := range r.config.Bindings {
+		if !binding.matches(id) {
+			continue
+		}
+		if best == "" || builtinRoles[binding.Role].Rank > builtinRoles[best].Rank {
+			best = binding.Role
+		}
+	}
+
+	log.Printf("[DEBUG] Resolved panel role '%s' for user '%s' (groups: %v)", best, id.Username, id.Groups)
+	return best
+}
+
+// Role returns a built-in role by name. A nil registry or unknown name returns nil.
+func (r *Registry) Role(name string) *Role {
+	if r == nil {
+		return nil
+	}
+	return builtinRoles[name]
+}
+
+func (b Binding) matches(id Identity) bool {
+	for _, user := range b.Users {
+		if user == id.Username {
+			return true
+		}
+	}
+	for _, group := range b.Groups {
+		for _, member := range id.Groups {
+			if group == member {
+				return true
+			}
+		}
+	}
+	for claim, values := range b.Claims {
+		for _, want := range values {
+			for _, have := range id.Claims[claim] {
+				if want == have {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package rbac
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// testMapping binds users, groups and OIDC claims to every built-in role
+const testMapping = `{
+	"default_role": "viewer",
+	"bindings": [
+		{"role": "super-admin", "users": ["root"]},
+		{"role": "iam-admin", "groups": ["iam"], "claims": {"roles": ["panel-iam"]}},
+		{"role": "operator", "groups": ["ops", "oncall"], "claims": {"department": ["storage"]}}
+	]
+}`
+
+// loadMapping writes a role mapping file and loads it
+func loadMapping(t *testing.T, mapping string) (*Registry, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "roles.json")
+	if err := os.WriteFile(path, []byte(mapping), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping string
+		err     string // part of the expected error, "" if the mapping is valid
+	}{
+		{"valid", testMapping, ""},
+		{"no default role", `{"bindings":[{"role":"viewer","users":["alice"]}]}`, ""},
+		{"unknown default role", `{"default_role":"owner"}`, `unknown default role "owner"`},
+		{"unknown binding role", `{"bindings":[{"role":"viewer"},{"role":"admin"}]}`, `binding 1: unknown role "admin"`},
+		{"not JSON", `{"bindings":`, "invalid role mapping"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadMapping(t, tt.mapping)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got error %v, want one containing %q", err, tt.err)
+			}
+		})
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("missing mapping file was accepted")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	roles, err := loadMapping(t, testMapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		id   Identity
+		role string
+	}{
+		{"bound user", Identity{Username: "root"}, RoleSuperAdmin},
+		{"bound group", Identity{Username: "bob", Groups: []string{"oncall"}}, RoleOperator},
+		{"bound claim", Identity{Username: "carol", Claims: map[string][]string{"roles": {"other", "panel-iam"}}}, RoleIAMAdmin},
+		{"most privileged binding wins", Identity{Username: "dave", Groups: []string{"ops", "iam"}}, RoleIAMAdmin},
+		{"user binding beats group", Identity{Username: "root", Groups: []string{"ops"}}, RoleSuperAdmin},
+		{"claim name must match", Identity{Username: "erin", Claims: map[string][]string{"groups": {"panel-iam"}}}, RoleViewer},
+		{"group names are case-sensitive", Identity{Username: "frank", Groups: []string{"OPS"}}, RoleViewer},
+		{"unbound user gets default", Identity{Username: "grace"}, RoleViewer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := roles.Resolve(tt.id); got != tt.role {
+				t.Fatalf("Resolve(%+v) = %q, want %q", tt.id, got, tt.role)
+			}
+		})
+	}
+}
+
+func TestResolveWithoutDefault(t *testing.T) {
+	roles, err := loadMapping(t, `{"bindings":[{"role":"operator","users":["alice"]}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := roles.Resolve(Identity{Username: "alice"}); got != RoleOperator {
+		t.Errorf("bound user: got %q, want %q", got, RoleOperator)
+	}
+	if got := roles.Resolve(Identity{Username: "bob"}); got != "" {
+		t.Errorf("unbound user: got %q, want no role", got)
+	}
+}
+
+func TestDisabledRegistry(t *testing.T) {
+	var roles *Registry
+	if roles.Enabled() {
+		t.Error("nil registry is enabled")
+	}
+	if got := roles.Resolve(Identity{Username: "root"}); got != "" {
+		t.Errorf("Resolve = %q, want no role", got)
+	}
+	if roles.Role(RoleSuperAdmin) != nil {
+		t.Error("nil registry returned a role")
+	}
+}
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		action string
+		allows map[string]bool // by role
+	}{
+		{"s3:ListAllMyBuckets", map[string]bool{RoleViewer: true, RoleOperator: true, RoleIAMAdmin: true, RoleSuperAdmin: true}},
+		{"admin:ServerInfo", map[string]bool{RoleViewer: true, RoleOperator: true, RoleIAMAdmin: true, RoleSuperAdmin: true}},
+		{"s3:CreateBucket", map[string]bool{RoleViewer: false, RoleOperator: true, RoleIAMAdmin: false, RoleSuperAdmin: true}},
+		{"s3:PutBucketPolicy", map[string]bool{RoleViewer: false, RoleOperator: true, RoleIAMAdmin: false, RoleSuperAdmin: true}},
+		{"s3:DeleteBucket", map[string]bool{RoleViewer: false, RoleOperator: false, RoleIAMAdmin: false, RoleSuperAdmin: true}},
+		{"s3:PutObject", map[string]bool{RoleViewer: false, RoleOperator: false, RoleIAMAdmin: false, RoleSuperAdmin: true}},
+		{"admin:DisableUser", map[string]bool{RoleViewer: false, RoleOperator: true, RoleIAMAdmin: true, RoleSuperAdmin: true}},
+		{"admin:CreateUser", map[string]bool{RoleViewer: false, RoleOperator: false, RoleIAMAdmin: true, RoleSuperAdmin: true}},
+		{"admin:*", map[string]bool{RoleViewer: false, RoleOperator: false, RoleIAMAdmin: false, RoleSuperAdmin: true}},
+	}
+	roles, err := loadMapping(t, testMapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		for name, want := range tt.allows {
+			if got := roles.Role(name).Allows(tt.action); got != want {
+				t.Errorf("%s allows %s: got %t, want %t", name, tt.action, got, want)
+			}
+		}
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	order := []string{RoleViewer, RoleOperator, RoleIAMAdmin, RoleSuperAdmin}
+	roles, err := loadMapping(t, testMapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range order {
+		role := roles.Role(name)
+		for j, other := range order {
+			if got, want := role.AtLeast(other), i >= j; got != want {
+				t.Errorf("%s.AtLeast(%s) = %t, want %t", name, other, got, want)
+			}
+		}
+		if role.AtLeast("owner") {
+			t.Errorf("%s is at least an unknown role", name)
+		}
+	}
+}
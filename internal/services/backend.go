@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"minio-admin-panel/internal/permissions"
+)
+
+// Every method acts with the credentials passed as username and password; the
+// STS session token, if any, travels in ctx (see WithSessionToken), as does
+// the cluster to act on when the panel manages several (see WithCluster).
+
+// AuthBackend checks credentials and obtains temporary ones
+type AuthBackend interface {
+	ValidateCredentials(ctx context.Context, username, password string) (*UserInfo, error)
+	GetUserPermissions(ctx context.Context, username, password string) *permissions.Set
+	AssumeRoleWithWebIdentity(ctx context.Context, idToken, roleARN string, duration time.Duration) (*STSCredentials, error)
+	AssumeRoleWithLDAPIdentity(ctx context.Context, ldapUsername, ldapPassword string, duration time.Duration) (*STSCredentials, error)
+	LDAPEnabled() bool
+	LDAPSTSDuration() time.Duration
+}
+
+// BucketBackend manages buckets
+type BucketBackend interface {
+	ListBucketsQuick(ctx context.Context, username, password string) ([]BucketInfo, error)
+	GetBucketStatsQuick(ctx context.Context, username, password, bucketName string) (int64, int64)
+	GetDataUsage(ctx context.Context, username, password string) (*DataUsage, error)
+	MeasureBucket(ctx context.Context, username, password, bucketName string) (*BucketStats, error)
+	CreateBucket(ctx context.Context, bucketName string, opts BucketOptions, username, password string) error
+	DeleteBucket(ctx context.Context, bucketName, username, password string) error
+	GetBucketPolicy(ctx context.Context, bucketName, username, password string) (string, error)
+	SetBucketPolicy(ctx context.Context, bucketName, policy, username, password string) error
+	GetBucketVersioning(ctx context.Context, bucketName, username, password string) (*BucketVersioning, error)
+	SetBucketVersioning(ctx context.Context, bucketName string, versioning BucketVersioning, username, password string) error
+	GetBucketObjectLock(ctx context.Context, bucketName, username, password string) (*BucketObjectLock, error)
+	SetBucketRetention(ctx context.Context, bucketName string, lock BucketObjectLock, username, password string) error
+	GetBucketLifecycle(ctx context.Context, bucketName, username, password string) ([]LifecycleRule, error)
+	SetBucketLifecycle(ctx context.Context, bucketName string, rules []LifecycleRule, username, password string) error
+	ListTiers(ctx context.Context, username, password string) ([]string, error)
+	ListReplicationTargets(ctx context.Context, bucketName, username, password string) ([]ReplicationTarget, error)
+	AddReplicationTarget(ctx context.Context, bucketName string, target ReplicationTarget, username, password string) (string, error)
+	RemoveReplicationTarget(ctx context.Context, bucketName, arn, username, password string) error
+	GetBucketReplication(ctx context.Context, bucketName, username, password string) ([]ReplicationRule, error)
+	SetBucketReplication(ctx context.Context, bucketName string, rules []ReplicationRule, username, password string) error
+	GetReplicationMetrics(ctx context.Context, bucketName, username, password string) (*ReplicationMetrics, error)
+	ResyncReplication(ctx context.Context, bucketName, arn, username, password string) (*ReplicationResync, error)
+	GetReplicationResyncStatus(ctx context.Context, bucketName, username, password string) ([]ReplicationResync, error)
+	GetBucketNotification(ctx context.Context, bucketName, username, password string) ([]NotificationRule, error)
+	SetBucketNotification(ctx context.Context, bucketName string, rules []NotificationRule, username, password string) error
+	ListNotificationTargets(ctx context.Context, username, password string) ([]NotificationTarget, error)
+}
+
+// UserBackend manages IAM users
+type UserBackend interface {
+	ListUsers(ctx context.Context, username, password string) ([]UserInfo, error)
+	CreateUser(ctx context.Context, accessKey, secretKey, username, password string) error
+	DeleteUser(ctx context.Context, accessKey, username, password string) error
+	GetUser(ctx context.Context, accessKey, username, password string) (*UserInfo, error)
+	UpdateUserCredentials(ctx context.Context, accessKey, newSecretKey, username, password string) error
+	SetUserStatus(ctx context.Context, accessKey string, enabled bool, username, password string) error
+	SetUserPolicy(ctx context.Context, accessKey, policyName, username, password string) error
+	GetUserPolicy(ctx context.Context, accessKey, username, password string) (string, error)
+	GetUserDetails(ctx context.Context, accessKey, username, password string) (map[string]interface{}, error)
+	GetUserCredentials(ctx context.Context, accessKey, username, password string) (map[string]interface{}, error)
+}
+
+// GroupBackend manages IAM groups and their members
+type GroupBackend interface {
+	ListGroups(ctx context.Context, username, password string) ([]string, error)
+	CreateGroup(ctx context.Context, groupName string, username, password string) error
+	DeleteGroup(ctx context.Context, groupName string, username, password string) error
+	GetGroupInfo(ctx context.Context, groupName string, username, password string) (map[string]interface{}, error)
+	AddUsersToGroup(ctx context.Context, groupName string, usernames []string, username, password string) error
+	RemoveUsersFromGroup(ctx context.Context, groupName string, usernames []string, username, password string) error
+	SetGroupPolicy(ctx context.Context, groupName, policyName string, username, password string) error
+}
+
+// PolicyBackend manages canned IAM policies
+type PolicyBackend interface {
+	ListPolicies(ctx context.Context, username, password string) (map[string]json.RawMessage, error)
+	GetPolicyDocument(ctx context.Context, policyName, username, password string) (string, error)
+	CreateOrUpdatePolicyDocument(ctx context.Context, policyName, policyDocument, username, password string) error
+	DeletePolicyDocument(ctx context.Context, policyName, username, password string) error
+}
+
+// ServiceAccountBackend manages service accounts
+type ServiceAccountBackend interface {
+	GetUserServiceAccounts(ctx context.Context, username, password string) ([]map[string]interface{}, error)
+	ListServiceAccounts(ctx context.Context, targetUser, username, password string) ([]map[string]interface{}, error)
+	CreateServiceAccount(ctx context.Context, targetUser, name, description, username, password string) (map[string]interface{}, error)
+	DeleteServiceAccount(ctx context.Context, serviceAccountKey, username, password string) error
+	GetServiceAccountInfo(ctx context.Context, serviceAccountKey, username, password string) (map[string]interface{}, error)
+}
+
+// IAMBackend covers users, groups, policies and service accounts
+type IAMBackend interface {
+	UserBackend
+	GroupBackend
+	PolicyBackend
+	ServiceAccountBackend
+}
+
+// ServerBackend reports on the MinIO deployment and the panel's connection to it
+type ServerBackend interface {
+	GetServerInfo(ctx context.Context, username, password string) (map[string]interface{}, error)
+	GetMetrics(ctx context.Context, username, password string) (map[string]interface{}, error)
+	ClientCacheStats() CacheStats
+}
+
+// Backend is everything the panel needs from MinIO. MinIOService talks to a
+// real server; MemoryBackend keeps everything in memory.
+type Backend interface {
+	AuthBackend
+	BucketBackend
+	IAMBackend
+	ServerBackend
+	EvictClients(accessKey string)
+	Timeouts() Timeouts
+}
+
+var (
+	_ Backend = (*MinIOService)(nil)
+	_ Backend = (*MemoryBackend)(nil)
+)
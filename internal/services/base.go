@@ -4,8 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
 
 	"minio-admin-panel/internal/config"
+	"minio-admin-panel/internal/permissions"
 
 	"github.com/minio/madmin-go/v3"
 	"github.com/minio/minio-go/v7"
@@ -14,15 +19,154 @@ import (
 
 // MinIOService provides MinIO administration functionality
 type MinIOService struct {
-	config *config.Config
+	config    *config.Config
+	transport http.RoundTripper
+	clients   *clientCache
+	timeouts  Timeouts
 }
 
-// BucketInfo represents bucket information
+// Sources of bucket statistics
+const (
+	StatsSourceScanner = "scanner" // MinIO's data usage scanner
+	StatsSourceListing = "listing" // counted by listing the objects of the bucket
+)
+
+// BucketInfo represents bucket information. StatsUpdatedAt is when the
+// statistics were recorded, if known. A count of -1 means it is not known.
 type BucketInfo struct {
-	Name         string `json:"name"`
-	CreationDate string `json:"creation_date"`
-	Size         int64  `json:"size"`
-	ObjectCount  int64  `json:"object_count"`
+	Name           string     `json:"name"`
+	CreationDate   string     `json:"creation_date"`
+	Size           int64      `json:"size"`
+	ObjectCount    int64      `json:"object_count"`
+	VersionCount   int64      `json:"version_count"`
+	Versioning     string     `json:"versioning,omitempty"`
+	ObjectLocking  bool       `json:"object_locking,omitempty"`
+	StatsSource    string     `json:"stats_source,omitempty"`
+	StatsUpdatedAt *time.Time `json:"stats_updated_at,omitempty"`
+}
+
+// DataUsage is the usage of all buckets of a cluster as last recorded by
+// MinIO's data usage scanner
+type DataUsage struct {
+	UpdatedAt time.Time
+	Buckets   map[string]BucketUsage
+}
+
+// BucketUsage is the size, object and version count of one bucket
+type BucketUsage struct {
+	Size         int64
+	ObjectCount  int64
+	VersionCount int64
+}
+
+// Bucket returns the usage of a bucket, if the scanner has seen it. It is
+// safe to call on a nil DataUsage.
+func (u *DataUsage) Bucket(name string) (BucketUsage, bool) {
+	if u == nil {
+		return BucketUsage{}, false
+	}
+	usage, ok := u.Buckets[name]
+	return usage, ok
+}
+
+// Versioning states of a bucket. A bucket that never had versioning enabled
+// has an empty status; once enabled it can only be suspended.
+const (
+	VersioningEnabled   = "Enabled"
+	VersioningSuspended = "Suspended"
+)
+
+// MaxExcludedPrefixes is how many prefixes MinIO allows to exclude from versioning
+const MaxExcludedPrefixes = 10
+
+// BucketVersioning is the versioning configuration of a bucket. Objects under
+// ExcludedPrefixes, and folder objects if ExcludeFolders is set, are not
+// versioned; both are MinIO extensions that require versioning to be enabled.
+type BucketVersioning struct {
+	Status           string   `json:"status"`
+	ExcludedPrefixes []string `json:"excluded_prefixes"`
+	ExcludeFolders   bool     `json:"exclude_folders"`
+}
+
+// BucketOptions are the settings a bucket is created with. Object locking
+// can only be enabled at creation and never disabled; it also enables
+// versioning for good.
+type BucketOptions struct {
+	ObjectLocking bool `json:"object_locking"`
+}
+
+// Default retention modes and validity units of object locking
+const (
+	RetentionGovernance = "GOVERNANCE"
+	RetentionCompliance = "COMPLIANCE"
+	RetentionDays       = "DAYS"
+	RetentionYears      = "YEARS"
+)
+
+// BucketObjectLock is the object lock configuration of a bucket. Mode,
+// Validity and Unit describe the default retention applied to new objects;
+// Mode is empty when there is none.
+type BucketObjectLock struct {
+	Enabled  bool   `json:"enabled"`
+	Mode     string `json:"mode"`
+	Validity uint   `json:"validity"`
+	Unit     string `json:"unit"`
+}
+
+// BucketStats is the usage of a bucket measured by listing all its object
+// versions. Size and ObjectCount cover the latest versions only; Prefixes
+// breaks them down by top-level prefix, largest first.
+type BucketStats struct {
+	Size         int64         `json:"size"`
+	ObjectCount  int64         `json:"object_count"`
+	VersionCount int64         `json:"version_count"`
+	Prefixes     []PrefixUsage `json:"prefixes,omitempty"`
+}
+
+// PrefixUsage is the usage of the objects under one top-level prefix. Objects
+// at the root of the bucket have an empty prefix.
+type PrefixUsage struct {
+	Prefix      string `json:"prefix"`
+	Size        int64  `json:"size"`
+	ObjectCount int64  `json:"object_count"`
+}
+
+// MaxBucketPrefixes is how many prefixes a BucketStats keeps
+const MaxBucketPrefixes = 25
+
+// addObject counts the latest version of an object under its top-level prefix
+func (s *BucketStats) addObject(key string, size int64, prefixes map[string]*PrefixUsage) {
+	s.Size += size
+	s.ObjectCount++
+
+	prefix := ""
+	if i := strings.Index(key, "/"); i >= 0 {
+		prefix = key[:i+1]
+	}
+	usage, ok := prefixes[prefix]
+	if !ok {
+		usage = &PrefixUsage{Prefix: prefix}
+		prefixes[prefix] = usage
+	}
+	usage.Size += size
+	usage.ObjectCount++
+}
+
+// setPrefixes keeps the largest MaxBucketPrefixes prefixes
+func (s *BucketStats) setPrefixes(prefixes map[string]*PrefixUsage) {
+	s.Prefixes = make([]PrefixUsage, 0, len(prefixes))
+	for _, usage := range prefixes {
+		s.Prefixes = append(s.Prefixes, *usage)
+	}
+	sort.Slice(s.Prefixes, func(i, j int) bool {
+		if s.Prefixes[i].Size != s.Prefixes[j].Size {
+			return s.Prefixes[i].Size > s.Prefixes[j].Size
+		}
+		return s.Prefixes[i].Prefix < s.Prefixes[j].Prefix
+	})
+	if len(s.Prefixes) > MaxBucketPrefixes {
+		s.Prefixes = s.Prefixes[:MaxBucketPrefixes]
+	}
 }
 
 // UserInfo represents user information
@@ -34,23 +178,86 @@ type UserInfo struct {
 	UpdatedAt  string   `json:"updated_at,omitempty"`
 }
 
+// sessionTokenKey is the context key for STS session tokens
+type sessionTokenKey struct{}
+
+// WithSessionToken returns a context carrying the STS session token to sign
+// MinIO requests with. Static credentials use an empty token, which also
+// replaces a token set on the parent context.
+func WithSessionToken(ctx context.Context, sessionToken string) context.Context {
+	if sessionToken == sessionTokenFromContext(ctx) {
+		return ctx
+	}
+	return context.WithValue(ctx, sessionTokenKey{}, sessionToken)
+}
+
+// sessionTokenFromContext returns the STS session token stored in ctx, if any
+func sessionTokenFromContext(ctx context.Context) string {
+	if token, ok := ctx.Value(sessionTokenKey{}).(string); ok {
+		return token
+	}
+	return ""
+}
+
+// clusterKey is the context key for the name of the MinIO cluster to act on
+type clusterKey struct{}
+
+// WithCluster returns a context naming the MinIO cluster that calls made with
+// it act on. Backends serving a single cluster ignore it.
+func WithCluster(ctx context.Context, cluster string) context.Context {
+	if cluster == ClusterFromContext(ctx) {
+		return ctx
+	}
+	return context.WithValue(ctx, clusterKey{}, cluster)
+}
+
+// ClusterFromContext returns the cluster named in ctx, or an empty string for
+// the default cluster
+func ClusterFromContext(ctx context.Context) string {
+	if cluster, ok := ctx.Value(clusterKey{}).(string); ok {
+		return cluster
+	}
+	return ""
+}
+
 // NewMinIOService creates a new MinIO service instance
 func NewMinIOService(cfg *config.Config) *MinIOService {
 	return &MinIOService{
-		config: cfg,
+		config:    cfg,
+		transport: newSharedTransport(cfg.MinIOUseSSL),
+		clients:   newClientCache(time.Duration(cfg.MinIOClientCacheTTL)*time.Second, cfg.MinIOClientCacheSize),
+		timeouts:  NewTimeouts(cfg),
 	}
 }
 
-// CreateClients creates MinIO client and admin client with provided credentials
-func (s *MinIOService) CreateClients(username, password string) (*minio.Client, *madmin.AdminClient, error) {
+// Timeouts returns the configured deadlines of MinIO operations
+func (s *MinIOService) Timeouts() Timeouts {
+	return s.timeouts
+}
+
+// CreateClients returns a MinIO client and admin client for the provided
+// credentials. If ctx carries an STS session token, the clients sign requests
+// with it. Clients are cached per credential and share one connection pool.
+func (s *MinIOService) CreateClients(ctx context.Context, username, password string) (*minio.Client, *madmin.AdminClient, error) {
 	endpoint := s.config.GetMinIOEndpoint()
-	log.Printf("[DEBUG] Creating MinIO clients for user '%s' to endpoint '%s' (SSL: %t)",
-		username, endpoint, s.config.MinIOUseSSL)
+	sessionToken := sessionTokenFromContext(ctx)
+
+	key := clientCacheKey(endpoint, username, password, sessionToken)
+	if cached, ok := s.clients.get(key); ok {
+		log.Printf("[DEBUG] Reusing cached MinIO clients for user '%s'", username)
+		return cached.minioClient, cached.adminClient, nil
+	}
+
+	log.Printf("[DEBUG] Creating MinIO clients for user '%s' to endpoint '%s' (SSL: %t, STS: %t)",
+		username, endpoint, s.config.MinIOUseSSL, sessionToken != "")
+
+	creds := credentials.NewStaticV4(username, password, sessionToken)
 
 	// Initialize MinIO client with provided credentials
 	minioClient, err := minio.New(endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(username, password, ""),
-		Secure: s.config.MinIOUseSSL,
+		Creds:     creds,
+		Secure:    s.config.MinIOUseSSL,
+		Transport: s.transport,
 	})
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create MinIO client: %v", err)
@@ -58,107 +265,133 @@ func (s *MinIOService) CreateClients(username, password string) (*minio.Client,
 	}
 
 	// Initialize MinIO admin client with provided credentials
-	adminClient, err := madmin.New(endpoint, username, password, s.config.MinIOUseSSL)
+	adminClient, err := madmin.NewWithOptions(endpoint, &madmin.Options{
+		Creds:     creds,
+		Secure:    s.config.MinIOUseSSL,
+		Transport: s.transport,
+	})
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create MinIO admin client: %v", err)
 		return nil, nil, fmt.Errorf("failed to initialize MinIO admin client: %v", err)
 	}
 
+	s.clients.put(key, &cachedClients{accessKey: username, minioClient: minioClient, adminClient: adminClient})
 	log.Printf("[DEBUG] Successfully created MinIO clients for user '%s'", username)
 	return minioClient, adminClient, nil
 }
 
+// EvictClients drops the cached clients of an access key. It is called when a
+// session ends and when the credentials of a user or service account change.
+func (s *MinIOService) EvictClients(accessKey string) {
+	if evicted := s.clients.evict(accessKey); evicted > 0 {
+		log.Printf("[DEBUG] Evicted %d cached MinIO client(s) for '%s'", evicted, accessKey)
+	}
+}
+
+// ClientCacheStats returns the hit, miss and eviction counters of the client cache
+func (s *MinIOService) ClientCacheStats() CacheStats {
+	return s.clients.snapshot()
+}
+
 // ValidateCredentials validates MinIO admin credentials by testing connection
 // This validates the provided username/password against MinIO directly
-func (s *MinIOService) ValidateCredentials(username, password string) (*UserInfo, error) {
+func (s *MinIOService) ValidateCredentials(ctx context.Context, username, password string) (*UserInfo, error) {
 	log.Printf("[DEBUG] Validating credentials for user '%s'", username)
 
 	// Create clients with provided credentials to test them
-	minioClient, adminClient, err := s.CreateClients(username, password)
+	minioClient, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients during validation: %v", err)
-		return nil, fmt.Errorf("failed to create MinIO clients: %v", err)
+		return nil, fmt.Errorf("failed to create MinIO clients: %w", err)
 	}
 
-	// Test basic MinIO connection
+	// Test basic MinIO connection. AccountInfo succeeds for every valid identity,
+	// regardless of its policy; ListBuckets covers servers without the admin API.
 	log.Printf("[DEBUG] Testing basic MinIO connection for user '%s'", username)
-	buckets, err := minioClient.ListBuckets(context.Background())
-	if err != nil {
-		log.Printf("[DEBUG] ListBuckets failed for user '%s': %v", username, err)
-		return nil, fmt.Errorf("invalid MinIO credentials or connection failed: %v", err)
+	if _, err := adminClient.AccountInfo(ctx, madmin.AccountOpts{}); err != nil {
+		log.Printf("[DEBUG] AccountInfo failed for user '%s': %v", username, err)
+		if _, err := minioClient.ListBuckets(ctx); err != nil {
+			log.Printf("[DEBUG] ListBuckets failed for user '%s': %v", username, err)
+			// Do not keep clients for rejected credentials, such as mistyped passwords
+			s.clients.remove(clientCacheKey(s.config.GetMinIOEndpoint(), username, password, sessionTokenFromContext(ctx)))
+			return nil, fmt.Errorf("invalid MinIO credentials or connection failed: %w", err)
+		}
 	}
-	log.Printf("[DEBUG] Basic connection successful for user '%s', found %d buckets", username, len(buckets))
+	log.Printf("[DEBUG] Basic connection successful for user '%s'", username)
 
-	// Test admin capabilities
-	log.Printf("[DEBUG] Testing admin privileges for user '%s'", username)
-	users, err := adminClient.ListUsers(context.Background())
-	if err != nil {
-		log.Printf("[DEBUG] Admin privilege test failed for user '%s': %v", username, err)
-		return nil, fmt.Errorf("user does not have admin privileges: %v", err)
+	// Access within the panel is decided by the user's permissions; the policy
+	// name only labels administrators
+	policyName := "user"
+	if _, err := adminClient.ListUsers(ctx); err == nil {
+		log.Printf("[DEBUG] Admin privileges confirmed for user '%s'", username)
+		policyName = "admin"
 	}
-	log.Printf("[DEBUG] Admin privileges confirmed for user '%s', found %d users in system", username, len(users))
 
-	// Create user info for the authenticated admin
 	userInfo := &UserInfo{
 		AccessKey:  username, // Using username as identifier
 		Status:     "enabled",
-		PolicyName: "admin",
+		PolicyName: policyName,
 	}
 
 	log.Printf("[DEBUG] Credential validation successful for user '%s'", username)
 	return userInfo, nil
 }
 
-// GetUserPermissions returns admin permissions for authenticated MinIO user
-// This validates the credentials against MinIO and grants permissions based on admin capabilities
-func (s *MinIOService) GetUserPermissions(username, password string) map[string]bool {
+// GetUserPermissions derives the permission set of the authenticated identity
+// from its effective IAM policy as reported by AccountInfo. If the server does
+// not report a policy, permissions are probed conservatively instead.
+func (s *MinIOService) GetUserPermissions(ctx context.Context, username, password string) *permissions.Set {
 	log.Printf("[DEBUG] Getting user permissions for user '%s'", username)
 
-	// Test the credentials by creating clients
-	minioClient, adminClient, err := s.CreateClients(username, password)
+	minioClient, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients for permission check: %v", err)
-		return map[string]bool{
-			"canListBuckets":    false,
-			"canCreateBuckets":  false,
-			"canDeleteBuckets":  false,
-			"canManageUsers":    false,
-			"canManagePolicies": false,
-			"isAdmin":           false,
-		}
+		set, _ := permissions.NewSet(nil)
+		return set
 	}
 
-	permissions := map[string]bool{
-		"canListBuckets":    false,
-		"canCreateBuckets":  false,
-		"canDeleteBuckets":  false,
-		"canManageUsers":    false,
-		"canManagePolicies": false,
-		"isAdmin":           false,
+	accountInfo, err := adminClient.AccountInfo(ctx, madmin.AccountOpts{})
+	if err == nil && len(accountInfo.Policy) > 0 {
+		set, err := permissions.NewSet(accountInfo.Policy)
+		if err == nil {
+			log.Printf("[DEBUG] Derived permissions for user '%s' from effective policy: %+v", username, set.Flags)
+			return set
+		}
+		log.Printf("[DEBUG] Failed to parse effective policy for user '%s': %v", username, err)
+	} else if err != nil {
+		log.Printf("[DEBUG] AccountInfo failed for user '%s': %v", username, err)
 	}
 
-	// Test bucket operations
+	return s.probeUserPermissions(ctx, username, minioClient, adminClient)
+}
+
+// probeUserPermissions builds a policy from the operations that succeed. Listing
+// buckets only grants read access; listing users implies a full administrator.
+func (s *MinIOService) probeUserPermissions(ctx context.Context, username string, minioClient *minio.Client, adminClient *madmin.AdminClient) *permissions.Set {
+	var statements []string
+
 	log.Printf("[DEBUG] Testing bucket permissions for user '%s'", username)
-	if _, err := minioClient.ListBuckets(context.Background()); err == nil {
-		log.Printf("[DEBUG] User '%s' has bucket permissions", username)
-		permissions["canListBuckets"] = true
-		permissions["canCreateBuckets"] = true
-		permissions["canDeleteBuckets"] = true
+	if _, err := minioClient.ListBuckets(ctx); err == nil {
+		log.Printf("[DEBUG] User '%s' can list buckets", username)
+		statements = append(statements, `{"Effect":"Allow","Action":["s3:ListAllMyBuckets","s3:ListBucket","s3:GetBucket*"],"Resource":["arn:aws:s3:::*"]}`)
 	} else {
 		log.Printf("[DEBUG] User '%s' failed bucket permissions test: %v", username, err)
 	}
 
-	// Test admin operations
 	log.Printf("[DEBUG] Testing admin permissions for user '%s'", username)
-	if _, err := adminClient.ListUsers(context.Background()); err == nil {
+	if _, err := adminClient.ListUsers(ctx); err == nil {
 		log.Printf("[DEBUG] User '%s' has admin permissions", username)
-		permissions["canManageUsers"] = true
-		permissions["canManagePolicies"] = true
-		permissions["isAdmin"] = true
+		statements = append(statements, `{"Effect":"Allow","Action":["admin:*"]}`, `{"Effect":"Allow","Action":["s3:*"],"Resource":["arn:aws:s3:::*"]}`)
 	} else {
 		log.Printf("[DEBUG] User '%s' failed admin permissions test: %v", username, err)
 	}
 
-	log.Printf("[DEBUG] Final permissions for user '%s': %+v", username, permissions)
-	return permissions
+	set, err := permissions.NewSet([]byte(`{"Version":"2012-10-17","Statement":[` + strings.Join(statements, ",") + `]}`))
+	if err != nil {
+		log.Printf("[DEBUG] Failed to build probed permissions for user '%s': %v", username, err)
+		set, _ = permissions.NewSet(nil)
+	}
+
+	log.Printf("[DEBUG] Final permissions for user '%s': %+v", username, set.Flags)
+	return set
 }
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/minio/madmin-go/v3"
+	"github.com/minio/minio-go/v7"
+)
+
+// Connection pool limits of the transport shared by all MinIO clients. All
+// clients talk to the same endpoint, so most idle connections belong to it.
+const (
+	transportMaxIdleConns        = 256
+	transportMaxIdleConnsPerHost = 128
+	transportIdleConnTimeout     = 90 * time.Second
+)
+
+// CacheStats reports how well the client cache is doing
+type CacheStats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	Entries   int   `json:"entries"`
+	TTL       int   `json:"ttl_seconds"`
+	MaxSize   int   `json:"max_size"`
+}
+
+// cachedClients is a MinIO and admin client pair built for one credential
+type cachedClients struct {
+	accessKey   string
+	minioClient *minio.Client
+	adminClient *madmin.AdminClient
+	expiresAt   time.Time
+}
+
+// clientCache keeps MinIO clients keyed by the credentials that sign their
+// requests, so a page that makes many MinIO calls builds its clients once.
+// A zero TTL disables caching.
+type clientCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedClients
+	ttl     time.Duration
+	maxSize int
+	stats   CacheStats
+}
+
+func newClientCache(ttl time.Duration, maxSize int) *clientCache {
+	return &clientCache{
+		entries: make(map[string]*cachedClients),
+		ttl:     ttl,
+		maxSize: maxSize,
+	}
+}
+
+// newSharedTransport returns the tuned HTTP transport all clients share
+func newSharedTransport(secure bool) http.RoundTripper {
+	transport, err := minio.DefaultTransport(secure)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to build MinIO transport, using the default: %v", err)
+		return http.DefaultTransport
+	}
+	transport.MaxIdleConns = transportMaxIdleConns
+	transport.MaxIdleConnsPerHost = transportMaxIdleConnsPerHost
+	transport.IdleConnTimeout = transportIdleConnTimeout
+	return transport
+}
+
+// clientCacheKey hashes the full credential, so clients for an old secret or
+// session token are never returned for a new one
+func clientCacheKey(endpoint, accessKey, secretKey, sessionToken string) string {
+	sum := sha256.Sum256([]byte(endpoint + "\x00" + accessKey + "\x00" + secretKey + "\x00" + sessionToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns the cached clients for key, if they have not expired
+func (c *clientCache) get(key string) (*cachedClients, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if ok && time.Now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		c.stats.Evictions++
+		ok = false
+	}
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	return entry, true
+}
+
+// put stores clients for key. When the cache is full, expired entries are
+// dropped first and then the entry closest to expiry.
+func (c *clientCache) put(key string, entry *cachedClients) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	entry.expiresAt = now.Add(c.ttl)
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.maxSize {
+		c.pruneLocked(now)
+	}
+	c.entries[key] = entry
+}
+
+// pruneLocked makes room for one entry. c.mu must be held.
+func (c *clientCache) pruneLocked(now time.Time) {
+	var oldestKey string
+	var oldest time.Time
+	for key, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, key)
+			c.stats.Evictions++
+			continue
+		}
+		if oldestKey == "" || entry.expiresAt.Before(oldest) {
+			oldestKey, oldest = key, entry.expiresAt
+		}
+	}
+	if len(c.entries) >= c.maxSize && oldestKey != "" {
+		delete(c.entries, oldestKey)
+		c.stats.Evictions++
+	}
+}
+
+// remove drops the clients of a single credential
+func (c *clientCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; ok {
+		delete(c.entries, key)
+		c.stats.Evictions++
+	}
+}
+
+// evict drops all clients of an access key and returns how many were dropped
+func (c *clientCache) evict(accessKey string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	evicted := 0
+	for key, entry := range c.entries {
+		if entry.accessKey == accessKey {
+			delete(c.entries, key)
+			evicted++
+		}
+	}
+	c.stats.Evictions += int64(evicted)
+	return evicted
+}
+
+// snapshot returns the current statistics
+func (c *clientCache) snapshot() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	stats.TTL = int(c.ttl / time.Second)
+	stats.MaxSize = c.maxSize
+	return stats
+}
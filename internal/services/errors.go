@@ -0,0 +1,151 @@
+package services
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/minio/madmin-go/v3"
+	"github.com/minio/minio-go/v7"
+)
+
+// Kinds of failed MinIO calls. Check for them with errors.Is on any error
+// returned by a backend after passing it through Classify.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+	ErrAccessDenied  = errors.New("access denied")
+	ErrInvalid       = errors.New("invalid request")
+	ErrUnavailable   = errors.New("service unavailable")
+)
+
+// Error is a failed MinIO call with its kind and the MinIO error code, if any
+type Error struct {
+	Kind error  // one of the Err* kinds above, nil if unknown
+	Code string // MinIO error code such as "NoSuchBucket"
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap exposes both the kind and the original error to errors.Is and errors.As
+func (e *Error) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// newError builds an error of the given kind, as MinIO would report it
+func newError(kind error, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Err: errors.New(message)}
+}
+
+// errorKinds maps MinIO S3 and admin API error codes to their kind
+var errorKinds = map[string]error{
+	"NoSuchBucket":                            ErrNotFound,
+	"NoSuchKey":                               ErrNotFound,
+	"NoSuchBucketPolicy":                      ErrNotFound,
+	"NoSuchLifecycleConfiguration":            ErrNotFound,
+	"NoSuchObjectLockConfiguration":           ErrNotFound,
+	"ObjectLockConfigurationNotFoundError":    ErrNotFound,
+	"ReplicationConfigurationNotFoundError":   ErrNotFound,
+	"XMinioAdminRemoteTargetNotFoundError":    ErrNotFound,
+	"XMinioAdminNoSuchUser":                   ErrNotFound,
+	"XMinioAdminNoSuchGroup":                  ErrNotFound,
+	"XMinioAdminNoSuchPolicy":                 ErrNotFound,
+	"XMinioAdminNoSuchServiceAccount":         ErrNotFound,
+	"XMinioAdminNoSuchAccessKey":              ErrNotFound,
+	"BucketAlreadyExists":                     ErrAlreadyExists,
+	"BucketAlreadyOwnedByYou":                 ErrAlreadyExists,
+	"XMinioAdminServiceAccountAlreadyExists":  ErrAlreadyExists,
+	"XMinioAdminBucketRemoteAlreadyExists":    ErrAlreadyExists,
+	"AccessDenied":                            ErrAccessDenied,
+	"InvalidAccessKeyId":                      ErrAccessDenied,
+	"SignatureDoesNotMatch":                   ErrAccessDenied,
+	"XMinioInvalidIAMCredentials":             ErrAccessDenied,
+	"XMinioAdminAccessDenied":                 ErrAccessDenied,
+	"InvalidArgument":                         ErrInvalid,
+	"InvalidBucketName":                       ErrInvalid,
+	"BucketNotEmpty":                          ErrInvalid,
+	"MalformedPolicy":                         ErrInvalid,
+	"MalformedXML":                            ErrInvalid,
+	"XMinioMalformedJSON":                     ErrInvalid,
+	"XMinioAdminInvalidArgument":              ErrInvalid,
+	"XMinioAdminInvalidAccessKey":             ErrInvalid,
+	"XMinioAdminInvalidSecretKey":             ErrInvalid,
+	"XMinioAdminResourceInvalidArgument":      ErrInvalid,
+	"XMinioAdminGroupNotEmpty":                ErrInvalid,
+	"XMinioAdminCannedPolicyMalformed":        ErrInvalid,
+	"IllegalVersioningConfigurationException": ErrInvalid,
+	"InvalidBucketState":                      ErrInvalid,
+	"InvalidStorageClass":                     ErrInvalid,
+	"ReplicationSourceNotVersionedError":      ErrInvalid,
+	"RemoteTargetNotVersionedError":           ErrInvalid,
+	"XMinioAdminRemoteRemoveDisallowed":       ErrInvalid,
+	"XMinioReplicationNoExistingObjects":      ErrInvalid,
+	"XMinioServerNotInitialized":              ErrUnavailable,
+	"ServiceUnavailable":                      ErrUnavailable,
+	"SlowDown":                                ErrUnavailable,
+	"SlowDownRead":                            ErrUnavailable,
+	"SlowDownWrite":                           ErrUnavailable,
+}
+
+// statusKinds classifies errors with an unknown code by their HTTP status
+var statusKinds = map[int]error{
+	http.StatusNotFound:           ErrNotFound,
+	http.StatusConflict:           ErrAlreadyExists,
+	http.StatusForbidden:          ErrAccessDenied,
+	http.StatusUnauthorized:       ErrAccessDenied,
+	http.StatusBadRequest:         ErrInvalid,
+	http.StatusServiceUnavailable: ErrUnavailable,
+	http.StatusBadGateway:         ErrUnavailable,
+}
+
+// Classify returns err as an *Error, decoding the kind from the MinIO error
+// response it wraps. Errors MinIO did not describe get a nil Kind, except
+// connection failures, which are ErrUnavailable. Timeouts are left to IsTimeout.
+func Classify(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var typed *Error
+	if errors.As(err, &typed) {
+		if typed == err {
+			return typed
+		}
+		return &Error{Kind: typed.Kind, Code: typed.Code, Err: err}
+	}
+
+	var s3Err minio.ErrorResponse
+	if errors.As(err, &s3Err) {
+		return &Error{Kind: errorKind(s3Err.Code, s3Err.StatusCode), Code: s3Err.Code, Err: err}
+	}
+
+	var adminErr madmin.ErrorResponse
+	if errors.As(err, &adminErr) {
+		// madmin keeps no status; unparsable responses carry it in the code ("404 Not Found")
+		status, _ := strconv.Atoi(strings.SplitN(adminErr.Code, " ", 2)[0])
+		return &Error{Kind: errorKind(adminErr.Code, status), Code: adminErr.Code, Err: err}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && !netErr.Timeout() {
+		return &Error{Kind: ErrUnavailable, Err: err}
+	}
+
+	return &Error{Err: err}
+}
+
+// errorKind looks up the kind of a MinIO error code, falling back to the status
+func errorKind(code string, status int) error {
+	if kind, ok := errorKinds[code]; ok {
+		return kind
+	}
+	return statusKinds[status]
+}
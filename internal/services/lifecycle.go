@@ -0,0 +1,249 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"sort"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+)
+
+// MaxLifecycleRules is how many rules a lifecycle configuration may hold
+const MaxLifecycleRules = 1000
+
+// LifecycleDateLayout is the format of expiration and transition dates. S3
+// only accepts dates at midnight UTC.
+const LifecycleDateLayout = "2006-01-02"
+
+// Lifecycle export formats: S3's LifecycleConfiguration XML, or the JSON
+// written by "mc ilm rule export"
+const (
+	LifecycleFormatXML  = "xml"
+	LifecycleFormatJSON = "json"
+)
+
+// LifecycleRule is one rule of a bucket's lifecycle configuration. The rule
+// applies to the objects under Prefix that carry all Tags and fall within
+// the size bounds; zero values leave a criterion or an action unset. Days
+// count from object creation, or from becoming noncurrent for the
+// Noncurrent* actions. Transitions move objects to a remote tier.
+type LifecycleRule struct {
+	ID      string `json:"id"`
+	Enabled bool   `json:"enabled"`
+
+	Prefix                string            `json:"prefix,omitempty"`
+	Tags                  map[string]string `json:"tags,omitempty"`
+	ObjectSizeGreaterThan int64             `json:"object_size_greater_than,omitempty"`
+	ObjectSizeLessThan    int64             `json:"object_size_less_than,omitempty"`
+
+	ExpirationDays int    `json:"expiration_days,omitempty"`
+	ExpirationDate string `json:"expiration_date,omitempty"`
+	// ExpireDeleteMarker removes delete markers left without any versions
+	ExpireDeleteMarker bool `json:"expire_delete_marker,omitempty"`
+	// ExpireAllVersions makes ExpirationDays remove all versions of an
+	// object instead of adding a delete marker (MinIO extension)
+	ExpireAllVersions bool `json:"expire_all_versions,omitempty"`
+	// DeleteMarkerExpirationDays removes delete markers, and every version
+	// behind them, that many days after they were set (MinIO extension)
+	DeleteMarkerExpirationDays int `json:"delete_marker_expiration_days,omitempty"`
+
+	NoncurrentExpirationDays int `json:"noncurrent_expiration_days,omitempty"`
+	// NewerNoncurrentVersions is how many noncurrent versions to keep
+	NewerNoncurrentVersions int `json:"newer_noncurrent_versions,omitempty"`
+
+	TransitionDays           int    `json:"transition_days,omitempty"`
+	TransitionDate           string `json:"transition_date,omitempty"`
+	TransitionTier           string `json:"transition_tier,omitempty"`
+	NoncurrentTransitionDays int    `json:"noncurrent_transition_days,omitempty"`
+	NoncurrentTransitionTier string `json:"noncurrent_transition_tier,omitempty"`
+
+	AbortIncompleteUploadDays int `json:"abort_incomplete_upload_days,omitempty"`
+}
+
+// HasAction reports whether the rule does anything
+func (r LifecycleRule) HasAction() bool {
+	return r.ExpirationDays > 0 || r.ExpirationDate != "" || r.ExpireDeleteMarker ||
+		r.DeleteMarkerExpirationDays > 0 ||
+		r.NoncurrentExpirationDays > 0 || r.NewerNoncurrentVersions > 0 ||
+		r.TransitionTier != "" || r.NoncurrentTransitionTier != "" ||
+		r.AbortIncompleteUploadDays > 0
+}
+
+// lifecycleStatus is the S3 status of a rule
+func lifecycleStatus(enabled bool) string {
+	if enabled {
+		return "Enabled"
+	}
+	return "Disabled"
+}
+
+// lifecycleDate parses a YYYY-MM-DD date; an empty string is no date
+func lifecycleDate(value string) (lifecycle.ExpirationDate, error) {
+	if value == "" {
+		return lifecycle.ExpirationDate{}, nil
+	}
+	date, err := time.Parse(LifecycleDateLayout, value)
+	if err != nil {
+		return lifecycle.ExpirationDate{}, newError(ErrInvalid, "InvalidArgument", "Dates must have the form YYYY-MM-DD.")
+	}
+	return lifecycle.ExpirationDate{Time: date}, nil
+}
+
+// formatLifecycleDate formats a date, or returns an empty string for none
+func formatLifecycleDate(date lifecycle.ExpirationDate) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.UTC().Format(LifecycleDateLayout)
+}
+
+// toLifecycleConfiguration converts rules to the minio-go configuration
+func toLifecycleConfiguration(rules []LifecycleRule) (*lifecycle.Configuration, error) {
+	config := lifecycle.NewConfiguration()
+	for _, rule := range rules {
+		converted := lifecycle.Rule{ID: rule.ID, Status: lifecycleStatus(rule.Enabled)}
+
+		// Several criteria must be combined with And; S3 allows one otherwise
+		tags := make([]lifecycle.Tag, 0, len(rule.Tags))
+		for key, value := range rule.Tags {
+			tags = append(tags, lifecycle.Tag{Key: key, Value: value})
+		}
+		sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+		criteria := len(tags)
+		for _, set := range []bool{rule.Prefix != "", rule.ObjectSizeGreaterThan > 0, rule.ObjectSizeLessThan > 0} {
+			if set {
+				criteria++
+			}
+		}
+		switch {
+		case criteria > 1:
+			converted.RuleFilter.And = lifecycle.And{Prefix: rule.Prefix, Tags: tags,
+				ObjectSizeGreaterThan: rule.ObjectSizeGreaterThan, ObjectSizeLessThan: rule.ObjectSizeLessThan}
+		case len(tags) == 1:
+			converted.RuleFilter.Tag = tags[0]
+		default:
+			converted.RuleFilter.Prefix = rule.Prefix
+			converted.RuleFilter.ObjectSizeGreaterThan = rule.ObjectSizeGreaterThan
+			converted.RuleFilter.ObjectSizeLessThan = rule.ObjectSizeLessThan
+		}
+
+		expirationDate, err := lifecycleDate(rule.ExpirationDate)
+		if err != nil {
+			return nil, err
+		}
+		transitionDate, err := lifecycleDate(rule.TransitionDate)
+		if err != nil {
+			return nil, err
+		}
+		converted.Expiration = lifecycle.Expiration{
+			Days:         lifecycle.ExpirationDays(rule.ExpirationDays),
+			Date:         expirationDate,
+			DeleteMarker: lifecycle.ExpireDeleteMarker(rule.ExpireDeleteMarker),
+			DeleteAll:    lifecycle.ExpirationBoolean(rule.ExpireAllVersions),
+		}
+		converted.DelMarkerExpiration.Days = rule.DeleteMarkerExpirationDays
+		converted.NoncurrentVersionExpiration = lifecycle.NoncurrentVersionExpiration{
+			NoncurrentDays:          lifecycle.ExpirationDays(rule.NoncurrentExpirationDays),
+			NewerNoncurrentVersions: rule.NewerNoncurrentVersions,
+		}
+		converted.Transition = lifecycle.Transition{
+			Days:         lifecycle.ExpirationDays(rule.TransitionDays),
+			Date:         transitionDate,
+			StorageClass: rule.TransitionTier,
+		}
+		converted.NoncurrentVersionTransition = lifecycle.NoncurrentVersionTransition{
+			NoncurrentDays: lifecycle.ExpirationDays(rule.NoncurrentTransitionDays),
+			StorageClass:   rule.NoncurrentTransitionTier,
+		}
+		converted.AbortIncompleteMultipartUpload.DaysAfterInitiation = lifecycle.ExpirationDays(rule.AbortIncompleteUploadDays)
+		config.Rules = append(config.Rules, converted)
+	}
+	return config, nil
+}
+
+// fromLifecycleConfiguration converts a minio-go configuration to rules
+func fromLifecycleConfiguration(config *lifecycle.Configuration) []LifecycleRule {
+	rules := make([]LifecycleRule, 0, len(config.Rules))
+	for _, rule := range config.Rules {
+		converted := LifecycleRule{
+			ID:                         rule.ID,
+			Enabled:                    rule.Status == "Enabled",
+			Prefix:                     rule.RuleFilter.Prefix,
+			ObjectSizeGreaterThan:      rule.RuleFilter.ObjectSizeGreaterThan,
+			ObjectSizeLessThan:         rule.RuleFilter.ObjectSizeLessThan,
+			ExpirationDays:             int(rule.Expiration.Days),
+			ExpirationDate:             formatLifecycleDate(rule.Expiration.Date),
+			ExpireDeleteMarker:         rule.Expiration.DeleteMarker.IsEnabled(),
+			ExpireAllVersions:          rule.Expiration.DeleteAll.IsEnabled(),
+			DeleteMarkerExpirationDays: rule.DelMarkerExpiration.Days,
+			NoncurrentExpirationDays:   int(rule.NoncurrentVersionExpiration.NoncurrentDays),
+			NewerNoncurrentVersions:    rule.NoncurrentVersionExpiration.NewerNoncurrentVersions,
+			TransitionDays:             int(rule.Transition.Days),
+			TransitionDate:             formatLifecycleDate(rule.Transition.Date),
+			TransitionTier:             rule.Transition.StorageClass,
+			NoncurrentTransitionDays:   int(rule.NoncurrentVersionTransition.NoncurrentDays),
+			NoncurrentTransitionTier:   rule.NoncurrentVersionTransition.StorageClass,
+			AbortIncompleteUploadDays:  int(rule.AbortIncompleteMultipartUpload.DaysAfterInitiation),
+		}
+
+		tags := []lifecycle.Tag{rule.RuleFilter.Tag}
+		if and := rule.RuleFilter.And; !and.IsEmpty() {
+			converted.Prefix = and.Prefix
+			converted.ObjectSizeGreaterThan, converted.ObjectSizeLessThan = and.ObjectSizeGreaterThan, and.ObjectSizeLessThan
+			tags = and.Tags
+		}
+		for _, tag := range tags {
+			if tag.IsEmpty() {
+				continue
+			}
+			if converted.Tags == nil {
+				converted.Tags = make(map[string]string)
+			}
+			converted.Tags[tag.Key] = tag.Value
+		}
+		// Rules written before filters existed keep their prefix outside
+		if rule.RuleFilter.IsNull() && rule.Prefix != "" {
+			converted.Prefix = rule.Prefix
+		}
+		rules = append(rules, converted)
+	}
+	return rules
+}
+
+// ExportLifecycle encodes rules as LifecycleConfiguration XML, or as the
+// JSON "mc ilm rule import" reads
+func ExportLifecycle(rules []LifecycleRule, format string) ([]byte, error) {
+	config, err := toLifecycleConfiguration(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case LifecycleFormatXML:
+		data, err := xml.MarshalIndent(config, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(xml.Header), data...), nil
+	case LifecycleFormatJSON:
+		return json.MarshalIndent(config, "", "  ")
+	}
+	return nil, newError(ErrInvalid, "InvalidArgument", "Unknown lifecycle format "+format)
+}
+
+// ParseLifecycle decodes a lifecycle configuration exported as XML, by S3
+// tools or ExportLifecycle, or as JSON, by mc or ExportLifecycle
+func ParseLifecycle(data []byte) ([]LifecycleRule, error) {
+	data = bytes.TrimSpace(data)
+	config := lifecycle.NewConfiguration()
+	if bytes.HasPrefix(data, []byte("<")) {
+		if err := xml.Unmarshal(data, config); err != nil {
+			return nil, newError(ErrInvalid, "MalformedXML", "The lifecycle XML is malformed: "+err.Error())
+		}
+	} else if err := json.Unmarshal(data, config); err != nil {
+		return nil, newError(ErrInvalid, "XMinioMalformedJSON", "The lifecycle JSON is malformed: "+err.Error())
+	}
+	return fromLifecycleConfiguration(config), nil
+}
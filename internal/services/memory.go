@@ -0,0 +1,1612 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"minio-admin-panel/internal/permissions"
+
+	"github.com/minio/madmin-go/v3"
+	"github.com/minio/minio-go/v7/pkg/s3utils"
+)
+
+// Errors returned by MemoryBackend, with the codes and wording of their MinIO counterparts
+var (
+	errMemoryInvalidCredentials = newError(ErrAccessDenied, "InvalidAccessKeyId", "The Access Key Id you provided does not exist in our records.")
+	errMemoryAccessDenied       = newError(ErrAccessDenied, "AccessDenied", "Access Denied.")
+	errMemorySTSUnavailable     = newError(ErrUnavailable, "", "STS is not available in the in-memory backend")
+)
+
+// memoryRootPolicy is the effective policy of the root user
+const memoryRootPolicy = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["admin:*"]},{"Effect":"Allow","Action":["s3:*"],"Resource":["arn:aws:s3:::*"]}]}`
+
+// memoryCannedPolicies are the policies MinIO ships with
+var memoryCannedPolicies = map[string]string{
+	"readonly":     `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["s3:GetBucketLocation","s3:GetObject"],"Resource":["arn:aws:s3:::*"]}]}`,
+	"readwrite":    `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["s3:*"],"Resource":["arn:aws:s3:::*"]}]}`,
+	"writeonly":    `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["s3:PutObject"],"Resource":["arn:aws:s3:::*"]}]}`,
+	"diagnostics":  `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["admin:ServerInfo","admin:Profiling","admin:ServerTrace","admin:ConsoleLog","admin:OBDInfo","admin:TopLocksInfo","admin:BandwidthMonitor","admin:Prometheus"]}]}`,
+	"consoleAdmin": `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["admin:*"]},{"Effect":"Allow","Action":["kms:*"]},{"Effect":"Allow","Action":["s3:*"],"Resource":["arn:aws:s3:::*"]}]}`,
+}
+
+type memoryBucket struct {
+	created    time.Time
+	policy     string
+	versioning BucketVersioning
+	objectLock BucketObjectLock
+	lifecycle  []LifecycleRule
+	objects    map[string]int64 // object key -> size
+
+	targets     []ReplicationTarget
+	replication []ReplicationRule
+	resyncs     []ReplicationResync
+
+	notifications []NotificationRule
+}
+
+type memoryUser struct {
+	secretKey string
+	enabled   bool
+	policy    string // comma-separated policy names
+	updatedAt time.Time
+}
+
+type memoryGroup struct {
+	members   []string
+	policy    string
+	enabled   bool
+	updatedAt time.Time
+}
+
+type memoryServiceAccount struct {
+	secretKey   string
+	parent      string
+	name        string
+	description string
+	enabled     bool
+}
+
+// MemoryBackend is an in-memory stand-in for a MinIO server. It keeps buckets,
+// users, groups, policies and service accounts in maps and authorizes every
+// call against the caller's policies the way MinIO does, so handlers and
+// permission checks can be exercised without a running server.
+type MemoryBackend struct {
+	mu              sync.Mutex
+	rootUser        string
+	rootPassword    string
+	buckets         map[string]*memoryBucket
+	users           map[string]*memoryUser
+	groups          map[string]*memoryGroup
+	policies        map[string]json.RawMessage
+	serviceAccounts map[string]*memoryServiceAccount
+	tiers           map[string]bool
+	// notificationTargets holds the ARNs of notification targets
+	notificationTargets map[string]bool
+}
+
+// NewMemoryBackend creates an empty deployment with the given root credentials
+// and MinIO's built-in policies
+func NewMemoryBackend(rootUser, rootPassword string) *MemoryBackend {
+	b := &MemoryBackend{
+		rootUser:        rootUser,
+		rootPassword:    rootPassword,
+		buckets:         make(map[string]*memoryBucket),
+		users:           make(map[string]*memoryUser),
+		groups:          make(map[string]*memoryGroup),
+		policies:        make(map[string]json.RawMessage),
+		serviceAccounts: make(map[string]*memoryServiceAccount),
+		tiers:           make(map[string]bool),
+
+		notificationTargets: make(map[string]bool),
+	}
+	for name, doc := range memoryCannedPolicies {
+		b.policies[name] = json.RawMessage(doc)
+	}
+	return b
+}
+
+// PutObject records an object so that bucket statistics have something to count
+func (b *MemoryBackend) PutObject(bucketName, objectName string, size int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bucket, ok := b.buckets[bucketName]
+	if !ok {
+		return newError(ErrNotFound, "NoSuchBucket", "The specified bucket does not exist")
+	}
+	bucket.objects[objectName] = size
+	return nil
+}
+
+// AddTier registers a remote tier that lifecycle rules can transition objects to
+func (b *MemoryBackend) AddTier(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tiers[name] = true
+}
+
+// AddNotificationTarget registers a notification target, such as
+// arn:minio:sqs::primary:webhook, that bucket notifications can send to
+func (b *MemoryBackend) AddNotificationTarget(arn string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.notificationTargets[arn] = true
+}
+
+// ValidateCredentials checks the credentials like MinIOService does
+func (b *MemoryBackend) ValidateCredentials(ctx context.Context, username, password string) (*UserInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	policy, err := b.authenticate(username, password)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MinIO credentials or connection failed: %w", err)
+	}
+
+	policyName := "user"
+	if policy.IsAllowed("admin:ListUsers", "") {
+		policyName = "admin"
+	}
+	return &UserInfo{AccessKey: username, Status: "enabled", PolicyName: policyName}, nil
+}
+
+// GetUserPermissions derives the permission set from the caller's effective policy
+func (b *MemoryBackend) GetUserPermissions(ctx context.Context, username, password string) *permissions.Set {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	doc, err := b.effectivePolicyJSON(username)
+	if err == nil {
+		_, err = b.authenticate(username, password)
+	}
+	if err != nil {
+		set, _ := permissions.NewSet(nil)
+		return set
+	}
+
+	set, err := permissions.NewSet(doc)
+	if err != nil {
+		set, _ = permissions.NewSet(nil)
+	}
+	return set
+}
+
+// AssumeRoleWithWebIdentity is not supported by the in-memory backend
+func (b *MemoryBackend) AssumeRoleWithWebIdentity(ctx context.Context, idToken, roleARN string, duration time.Duration) (*STSCredentials, error) {
+	return nil, errMemorySTSUnavailable
+}
+
+// AssumeRoleWithLDAPIdentity is not supported by the in-memory backend
+func (b *MemoryBackend) AssumeRoleWithLDAPIdentity(ctx context.Context, ldapUsername, ldapPassword string, duration time.Duration) (*STSCredentials, error) {
+	return nil, errMemorySTSUnavailable
+}
+
+// LDAPEnabled reports false; there is no directory behind the in-memory backend
+func (b *MemoryBackend) LDAPEnabled() bool {
+	return false
+}
+
+// LDAPSTSDuration returns the default STS lifetime
+func (b *MemoryBackend) LDAPSTSDuration() time.Duration {
+	return time.Hour
+}
+
+// ListBucketsQuick returns all buckets without statistics
+func (b *MemoryBackend) ListBucketsQuick(ctx context.Context, username, password string) ([]BucketInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "s3:ListAllMyBuckets", "arn:aws:s3:::*"); err != nil {
+		return nil, err
+	}
+
+	var infos []BucketInfo
+	for _, name := range sortedKeys(b.buckets) {
+		infos = append(infos, BucketInfo{
+			Name:         name,
+			CreationDate: b.buckets[name].created.Format("2006-01-02 15:04:05"),
+			Size:         -1,
+			ObjectCount:  -1,
+			VersionCount: -1,
+		})
+	}
+	return infos, nil
+}
+
+// GetDataUsage returns the current usage of all buckets, as if the scanner had just run
+func (b *MemoryBackend) GetDataUsage(ctx context.Context, username, password string) (*DataUsage, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:DataUsageInfo", ""); err != nil {
+		return nil, err
+	}
+
+	usage := &DataUsage{UpdatedAt: time.Now(), Buckets: make(map[string]BucketUsage, len(b.buckets))}
+	for name, bucket := range b.buckets {
+		size, count := bucket.stats()
+		usage.Buckets[name] = BucketUsage{Size: size, ObjectCount: count, VersionCount: count}
+	}
+	return usage, nil
+}
+
+// MeasureBucket counts the objects of a bucket; every object has a single version
+func (b *MemoryBackend) MeasureBucket(ctx context.Context, username, password, bucketName string) (*BucketStats, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "s3:ListBucket", permissions.BucketARN(bucketName)); err != nil {
+		return nil, err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &BucketStats{VersionCount: int64(len(bucket.objects))}
+	prefixes := make(map[string]*PrefixUsage)
+	for key, size := range bucket.objects {
+		stats.addObject(key, size, prefixes)
+	}
+	stats.setPrefixes(prefixes)
+	return stats, nil
+}
+
+// GetBucketStatsQuick returns the size and object count of a bucket, or -1 on error
+func (b *MemoryBackend) GetBucketStatsQuick(ctx context.Context, username, password, bucketName string) (int64, int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bucket, ok := b.buckets[bucketName]
+	if !ok || b.authorize(username, password, "s3:ListBucket", permissions.BucketARN(bucketName)) != nil {
+		return -1, -1
+	}
+	return bucket.stats()
+}
+
+// CreateBucket creates an empty bucket. Object locking enables versioning, as on MinIO.
+func (b *MemoryBackend) CreateBucket(ctx context.Context, bucketName string, opts BucketOptions, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := s3utils.CheckValidBucketNameStrict(bucketName); err != nil {
+		return &Error{Kind: ErrInvalid, Code: "InvalidBucketName", Err: err}
+	}
+	if err := b.authorize(username, password, "s3:CreateBucket", permissions.BucketARN(bucketName)); err != nil {
+		return err
+	}
+	if _, exists := b.buckets[bucketName]; exists {
+		return newError(ErrAlreadyExists, "BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded and you already own it.")
+	}
+
+	if opts.ObjectLocking {
+		if err := b.authorize(username, password, "s3:PutBucketObjectLockConfiguration", permissions.BucketARN(bucketName)); err != nil {
+			return err
+		}
+	}
+
+	bucket := &memoryBucket{created: time.Now(), objects: make(map[string]int64)}
+	if opts.ObjectLocking {
+		bucket.objectLock.Enabled = true
+		bucket.versioning.Status = VersioningEnabled
+	}
+	b.buckets[bucketName] = bucket
+	return nil
+}
+
+// DeleteBucket deletes an empty bucket
+func (b *MemoryBackend) DeleteBucket(ctx context.Context, bucketName, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "s3:DeleteBucket", permissions.BucketARN(bucketName)); err != nil {
+		return err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	if len(bucket.objects) > 0 {
+		return newError(ErrInvalid, "BucketNotEmpty", "The bucket you tried to delete is not empty")
+	}
+
+	delete(b.buckets, bucketName)
+	return nil
+}
+
+// GetBucketPolicy returns the bucket policy, or an empty string if none is set
+func (b *MemoryBackend) GetBucketPolicy(ctx context.Context, bucketName, username, password string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "s3:GetBucketPolicy", permissions.BucketARN(bucketName)); err != nil {
+		return "", err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return "", err
+	}
+	return bucket.policy, nil
+}
+
+// SetBucketPolicy sets the bucket policy; an empty policy removes it
+func (b *MemoryBackend) SetBucketPolicy(ctx context.Context, bucketName, policy, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "s3:PutBucketPolicy", permissions.BucketARN(bucketName)); err != nil {
+		return err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	if policy != "" && !json.Valid([]byte(policy)) {
+		return newError(ErrInvalid, "MalformedPolicy", "Policy has invalid resource.")
+	}
+
+	bucket.policy = policy
+	return nil
+}
+
+// GetBucketVersioning returns the versioning configuration of a bucket
+func (b *MemoryBackend) GetBucketVersioning(ctx context.Context, bucketName, username, password string) (*BucketVersioning, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "s3:GetBucketVersioning", permissions.BucketARN(bucketName)); err != nil {
+		return nil, err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	versioning := bucket.versioning
+	versioning.ExcludedPrefixes = append([]string{}, bucket.versioning.ExcludedPrefixes...)
+	return &versioning, nil
+}
+
+// SetBucketVersioning enables or suspends versioning, rejecting what MinIO rejects
+func (b *MemoryBackend) SetBucketVersioning(ctx context.Context, bucketName string, versioning BucketVersioning, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "s3:PutBucketVersioning", permissions.BucketARN(bucketName)); err != nil {
+		return err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	switch {
+	case bucket.objectLock.Enabled && versioning.Status != VersioningEnabled:
+		return newError(ErrInvalid, "InvalidBucketState", "An Object Lock configuration is present on this bucket, so the versioning state cannot be changed.")
+	case versioning.Status != VersioningEnabled && versioning.Status != VersioningSuspended:
+		return newError(ErrInvalid, "IllegalVersioningConfigurationException", "The versioning configuration specified in the request is invalid.")
+	case versioning.Status == VersioningSuspended && (len(versioning.ExcludedPrefixes) > 0 || versioning.ExcludeFolders):
+		return newError(ErrInvalid, "IllegalVersioningConfigurationException", "Excluded prefixes and folders require versioning to be enabled.")
+	case len(bucket.replication) > 0 && versioning.Status != VersioningEnabled:
+		return newError(ErrInvalid, "InvalidBucketState", "A replication configuration is present on this bucket, so the versioning state cannot be changed.")
+	case len(versioning.ExcludedPrefixes) > MaxExcludedPrefixes:
+		return newError(ErrInvalid, "IllegalVersioningConfigurationException", "Too many excluded prefixes.")
+	}
+
+	versioning.ExcludedPrefixes = append([]string{}, versioning.ExcludedPrefixes...)
+	bucket.versioning = versioning
+	return nil
+}
+
+// GetBucketObjectLock returns the object lock configuration of a bucket
+func (b *MemoryBackend) GetBucketObjectLock(ctx context.Context, bucketName, username, password string) (*BucketObjectLock, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "s3:GetBucketObjectLockConfiguration", permissions.BucketARN(bucketName)); err != nil {
+		return nil, err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := bucket.objectLock
+	return &lock, nil
+}
+
+// SetBucketRetention sets or removes the default retention of an object lock enabled bucket
+func (b *MemoryBackend) SetBucketRetention(ctx context.Context, bucketName string, lock BucketObjectLock, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "s3:PutBucketObjectLockConfiguration", permissions.BucketARN(bucketName)); err != nil {
+		return err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	if !bucket.objectLock.Enabled {
+		return newError(ErrInvalid, "InvalidBucketState", "Object Lock configuration cannot be enabled on existing buckets")
+	}
+
+	bucket.objectLock = BucketObjectLock{Enabled: true}
+	if lock.Mode != "" {
+		bucket.objectLock.Mode, bucket.objectLock.Validity, bucket.objectLock.Unit = lock.Mode, lock.Validity, lock.Unit
+	}
+	return nil
+}
+
+// GetBucketLifecycle returns the lifecycle rules of a bucket
+func (b *MemoryBackend) GetBucketLifecycle(ctx context.Context, bucketName, username, password string) ([]LifecycleRule, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "s3:GetLifecycleConfiguration", permissions.BucketARN(bucketName)); err != nil {
+		return nil, err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyLifecycle(bucket.lifecycle), nil
+}
+
+// SetBucketLifecycle replaces the lifecycle rules of a bucket, rejecting
+// duplicate rule IDs and transitions to unknown tiers like MinIO does
+func (b *MemoryBackend) SetBucketLifecycle(ctx context.Context, bucketName string, rules []LifecycleRule, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "s3:PutLifecycleConfiguration", permissions.BucketARN(bucketName)); err != nil {
+		return err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	if len(rules) > MaxLifecycleRules {
+		return newError(ErrInvalid, "InvalidArgument", "Lifecycle configuration allows a maximum of 1000 rules")
+	}
+	ids := make(map[string]bool)
+	for _, rule := range rules {
+		if ids[rule.ID] {
+			return newError(ErrInvalid, "InvalidArgument", "Rule ID must be unique. Found same ID for more than one rule")
+		}
+		ids[rule.ID] = true
+		for _, tier := range []string{rule.TransitionTier, rule.NoncurrentTransitionTier} {
+			if tier != "" && !b.tiers[tier] {
+				return newError(ErrInvalid, "InvalidStorageClass", "Invalid remote tier "+tier)
+			}
+		}
+	}
+
+	bucket.lifecycle = copyLifecycle(rules)
+	return nil
+}
+
+// copyLifecycle copies rules so that callers cannot change stored ones
+func copyLifecycle(rules []LifecycleRule) []LifecycleRule {
+	copied := make([]LifecycleRule, len(rules))
+	for i, rule := range rules {
+		if rule.Tags != nil {
+			tags := make(map[string]string, len(rule.Tags))
+			for key, value := range rule.Tags {
+				tags[key] = value
+			}
+			rule.Tags = tags
+		}
+		copied[i] = rule
+	}
+	return copied
+}
+
+// ListTiers returns the names of the tiers added with AddTier
+func (b *MemoryBackend) ListTiers(ctx context.Context, username, password string) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:ListTier", ""); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(b.tiers))
+	for name := range b.tiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ListReplicationTargets returns the remote targets of a bucket
+func (b *MemoryBackend) ListReplicationTargets(ctx context.Context, bucketName, username, password string) ([]ReplicationTarget, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:GetBucketTarget", ""); err != nil {
+		return nil, err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]ReplicationTarget, len(bucket.targets))
+	for i, target := range bucket.targets {
+		target.SecretKey = ""
+		targets[i] = target
+	}
+	return targets, nil
+}
+
+// AddReplicationTarget adds a remote target to a versioned bucket. Nothing
+// is contacted, so every target is online.
+func (b *MemoryBackend) AddReplicationTarget(ctx context.Context, bucketName string, target ReplicationTarget, username, password string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:SetBucketTarget", ""); err != nil {
+		return "", err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return "", err
+	}
+	if bucket.versioning.Status != VersioningEnabled {
+		return "", newError(ErrInvalid, "ReplicationSourceNotVersionedError", "Replication source does not have versioning enabled")
+	}
+	if target.Endpoint == "" || target.TargetBucket == "" || target.AccessKey == "" || target.SecretKey == "" {
+		return "", newError(ErrInvalid, "XMinioAdminInvalidArgument", "Invalid arguments specified.")
+	}
+	for _, existing := range bucket.targets {
+		if existing.Endpoint == target.Endpoint && existing.TargetBucket == target.TargetBucket {
+			return "", newError(ErrAlreadyExists, "XMinioAdminBucketRemoteAlreadyExists", "The remote target already exists")
+		}
+	}
+
+	id, err := randomKey(20)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	target.ARN = ReplicationARN(strings.ToLower(id), target.TargetBucket)
+	target.Online = true
+	target.LastOnline = &now
+	bucket.targets = append(bucket.targets, target)
+	return target.ARN, nil
+}
+
+// RemoveReplicationTarget removes a remote target that no rule uses
+func (b *MemoryBackend) RemoveReplicationTarget(ctx context.Context, bucketName, arn, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:SetBucketTarget", ""); err != nil {
+		return err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	index := memoryTargetIndex(bucket, arn)
+	if index < 0 {
+		return newError(ErrNotFound, "XMinioAdminRemoteTargetNotFoundError", "The remote target does not exist")
+	}
+	for _, rule := range bucket.replication {
+		if rule.TargetARN == arn {
+			return newError(ErrInvalid, "XMinioAdminRemoteRemoveDisallowed", "Replication configuration exists with this ARN.")
+		}
+	}
+
+	bucket.targets = append(bucket.targets[:index], bucket.targets[index+1:]...)
+	return nil
+}
+
+// GetBucketReplication returns the replication rules of a bucket
+func (b *MemoryBackend) GetBucketReplication(ctx context.Context, bucketName, username, password string) ([]ReplicationRule, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "s3:GetReplicationConfiguration", permissions.BucketARN(bucketName)); err != nil {
+		return nil, err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyReplication(bucket.replication), nil
+}
+
+// SetBucketReplication replaces the replication rules of a bucket,
+// rejecting what MinIO rejects
+func (b *MemoryBackend) SetBucketReplication(ctx context.Context, bucketName string, rules []ReplicationRule, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "s3:PutReplicationConfiguration", permissions.BucketARN(bucketName)); err != nil {
+		return err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	if len(rules) > 0 && bucket.versioning.Status != VersioningEnabled {
+		return newError(ErrInvalid, "ReplicationSourceNotVersionedError", "Replication source does not have versioning enabled")
+	}
+	ids := make(map[string]bool)
+	priorities := make(map[int]bool)
+	for _, rule := range rules {
+		if ids[rule.ID] {
+			return newError(ErrInvalid, "InvalidArgument", "Rule ID must be unique. Found same ID for more than one rule")
+		}
+		if priorities[rule.Priority] {
+			return newError(ErrInvalid, "InvalidArgument", "Replication configuration has duplicate priority")
+		}
+		ids[rule.ID], priorities[rule.Priority] = true, true
+		if memoryTargetIndex(bucket, rule.TargetARN) < 0 {
+			return newError(ErrNotFound, "XMinioAdminRemoteTargetNotFoundError", "The remote target does not exist")
+		}
+	}
+
+	bucket.replication = copyReplication(rules)
+	sortReplicationRules(bucket.replication)
+	return nil
+}
+
+// copyReplication copies rules so that callers cannot change stored ones
+func copyReplication(rules []ReplicationRule) []ReplicationRule {
+	copied := make([]ReplicationRule, len(rules))
+	for i, rule := range rules {
+		if rule.Tags != nil {
+			tags := make(map[string]string, len(rule.Tags))
+			for key, value := range rule.Tags {
+				tags[key] = value
+			}
+			rule.Tags = tags
+		}
+		copied[i] = rule
+	}
+	return copied
+}
+
+// GetReplicationMetrics reports every target as fully replicated, since
+// nothing is ever queued
+func (b *MemoryBackend) GetReplicationMetrics(ctx context.Context, bucketName, username, password string) (*ReplicationMetrics, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "s3:GetReplicationConfiguration", permissions.BucketARN(bucketName)); err != nil {
+		return nil, err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &ReplicationMetrics{Targets: []ReplicationTargetMetrics{}}
+	for _, target := range bucket.targets {
+		metrics.Targets = append(metrics.Targets, ReplicationTargetMetrics{ARN: target.ARN, BandwidthLimit: target.BandwidthLimit})
+	}
+	return metrics, nil
+}
+
+// ResyncReplication records a resync that completes at once, like MinIO
+// requiring a rule for the target that replicates existing objects
+func (b *MemoryBackend) ResyncReplication(ctx context.Context, bucketName, arn, username, password string) (*ReplicationResync, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "s3:ResetBucketReplicationState", permissions.BucketARN(bucketName)); err != nil {
+		return nil, err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	if memoryTargetIndex(bucket, arn) < 0 {
+		return nil, newError(ErrNotFound, "XMinioAdminRemoteTargetNotFoundError", "The remote target does not exist")
+	}
+	existing := false
+	for _, rule := range bucket.replication {
+		existing = existing || (rule.TargetARN == arn && rule.Enabled && rule.ExistingObjects)
+	}
+	if !existing {
+		return nil, newError(ErrInvalid, "XMinioReplicationNoExistingObjects", "No matching ExistingObjects rule enabled")
+	}
+
+	resetID, err := randomKey(20)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	resync := ReplicationResync{ARN: arn, ResetID: strings.ToLower(resetID), Status: "Completed", StartTime: &now, EndTime: &now}
+	for _, size := range bucket.objects {
+		resync.Replicated.Count++
+		resync.Replicated.Bytes += size
+	}
+	resyncs := []ReplicationResync{resync}
+	for _, previous := range bucket.resyncs {
+		if previous.ARN != arn {
+			resyncs = append(resyncs, previous)
+		}
+	}
+	bucket.resyncs = resyncs
+	return &resync, nil
+}
+
+// GetReplicationResyncStatus returns the latest resync of each target
+func (b *MemoryBackend) GetReplicationResyncStatus(ctx context.Context, bucketName, username, password string) ([]ReplicationResync, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "s3:ResetBucketReplicationState", permissions.BucketARN(bucketName)); err != nil {
+		return nil, err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]ReplicationResync{}, bucket.resyncs...), nil
+}
+
+// GetBucketNotification returns the notification rules of a bucket
+func (b *MemoryBackend) GetBucketNotification(ctx context.Context, bucketName, username, password string) ([]NotificationRule, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "s3:GetBucketNotification", permissions.BucketARN(bucketName)); err != nil {
+		return nil, err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyNotifications(bucket.notifications), nil
+}
+
+// SetBucketNotification replaces the notification rules of a bucket. Like
+// MinIO, it rejects unknown targets and event types.
+func (b *MemoryBackend) SetBucketNotification(ctx context.Context, bucketName string, rules []NotificationRule, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "s3:PutBucketNotification", permissions.BucketARN(bucketName)); err != nil {
+		return err
+	}
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(NotificationEvents))
+	for _, event := range NotificationEvents {
+		known[event] = true
+	}
+	for _, rule := range rules {
+		if !b.notificationTargets[rule.ARN] {
+			return newError(ErrInvalid, "InvalidArgument", "A specified destination ARN does not exist or is not well-formed. Verify the destination ARN.")
+		}
+		if len(rule.Events) == 0 {
+			return newError(ErrInvalid, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema.")
+		}
+		for _, event := range rule.Events {
+			if !known[event] {
+				return newError(ErrInvalid, "InvalidArgument", "A specified event is not supported for notifications.")
+			}
+		}
+	}
+
+	bucket.notifications = copyNotifications(rules)
+	return nil
+}
+
+// copyNotifications copies rules so that callers cannot change stored ones
+func copyNotifications(rules []NotificationRule) []NotificationRule {
+	copied := make([]NotificationRule, len(rules))
+	for i, rule := range rules {
+		rule.Events = append([]string{}, rule.Events...)
+		copied[i] = rule
+	}
+	return copied
+}
+
+// ListNotificationTargets returns the targets added with AddNotificationTarget
+func (b *MemoryBackend) ListNotificationTargets(ctx context.Context, username, password string) ([]NotificationTarget, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:ServerInfo", ""); err != nil {
+		return nil, err
+	}
+
+	return notificationTargets(sortedKeys(b.notificationTargets)), nil
+}
+
+// ListUsers returns all users
+func (b *MemoryBackend) ListUsers(ctx context.Context, username, password string) ([]UserInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:ListUsers", ""); err != nil {
+		return nil, err
+	}
+
+	var infos []UserInfo
+	for _, accessKey := range sortedKeys(b.users) {
+		infos = append(infos, UserInfo{
+			AccessKey: accessKey,
+			Status:    statusName(b.users[accessKey].enabled),
+			MemberOf:  b.memberOf(accessKey),
+		})
+	}
+	return infos, nil
+}
+
+// CreateUser adds a user, or sets the secret key of an existing one
+func (b *MemoryBackend) CreateUser(ctx context.Context, accessKey, secretKey, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:CreateUser", ""); err != nil {
+		return err
+	}
+	if err := validateMemoryCredentials(accessKey, secretKey); err != nil {
+		return err
+	}
+	if accessKey == b.rootUser {
+		return newError(ErrInvalid, "XMinioAdminInvalidArgument", "Cannot modify the root user")
+	}
+
+	if user, ok := b.users[accessKey]; ok {
+		user.secretKey = secretKey
+		user.updatedAt = time.Now()
+		return nil
+	}
+	b.users[accessKey] = &memoryUser{secretKey: secretKey, enabled: true, updatedAt: time.Now()}
+	return nil
+}
+
+// DeleteUser removes a user, its group memberships and its service accounts
+func (b *MemoryBackend) DeleteUser(ctx context.Context, accessKey, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:DeleteUser", ""); err != nil {
+		return err
+	}
+	if _, err := b.user(accessKey); err != nil {
+		return err
+	}
+
+	delete(b.users, accessKey)
+	for _, group := range b.groups {
+		group.members = removeAll(group.members, []string{accessKey})
+	}
+	for key, sa := range b.serviceAccounts {
+		if sa.parent == accessKey {
+			delete(b.serviceAccounts, key)
+		}
+	}
+	return nil
+}
+
+// GetUser returns a user with its status and groups
+func (b *MemoryBackend) GetUser(ctx context.Context, accessKey, username, password string) (*UserInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:ListUsers", ""); err != nil {
+		return nil, err
+	}
+	user, ok := b.users[accessKey]
+	if !ok {
+		return nil, newError(ErrNotFound, "XMinioAdminNoSuchUser", fmt.Sprintf("user '%s' not found", accessKey))
+	}
+	return &UserInfo{AccessKey: accessKey, Status: statusName(user.enabled), MemberOf: b.memberOf(accessKey)}, nil
+}
+
+// UpdateUserCredentials sets the secret key of a user
+func (b *MemoryBackend) UpdateUserCredentials(ctx context.Context, accessKey, newSecretKey, username, password string) error {
+	return b.CreateUser(ctx, accessKey, newSecretKey, username, password)
+}
+
+// SetUserStatus enables or disables a user
+func (b *MemoryBackend) SetUserStatus(ctx context.Context, accessKey string, enabled bool, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	action := "admin:DisableUser"
+	if enabled {
+		action = "admin:EnableUser"
+	}
+	if err := b.authorize(username, password, action, ""); err != nil {
+		return err
+	}
+	user, err := b.user(accessKey)
+	if err != nil {
+		return err
+	}
+
+	user.enabled = enabled
+	user.updatedAt = time.Now()
+	return nil
+}
+
+// SetUserPolicy attaches a comma-separated list of policies to a user
+func (b *MemoryBackend) SetUserPolicy(ctx context.Context, accessKey, policyName, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:AttachUserOrGroupPolicy", ""); err != nil {
+		return err
+	}
+	user, err := b.user(accessKey)
+	if err != nil {
+		return err
+	}
+	if err := b.checkPolicies(policyName); err != nil {
+		return err
+	}
+
+	user.policy = policyName
+	user.updatedAt = time.Now()
+	return nil
+}
+
+// GetUserPolicy returns the policies attached to a user
+func (b *MemoryBackend) GetUserPolicy(ctx context.Context, accessKey, username, password string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:GetUser", ""); err != nil {
+		return "", err
+	}
+	user, err := b.user(accessKey)
+	if err != nil {
+		return "", err
+	}
+	return user.policy, nil
+}
+
+// GetUserDetails returns the details MinIOService.GetUserDetails reports
+func (b *MemoryBackend) GetUserDetails(ctx context.Context, accessKey, username, password string) (map[string]interface{}, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:GetUser", ""); err != nil {
+		return nil, err
+	}
+	user, err := b.user(accessKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"access_key":  accessKey,
+		"status":      statusName(user.enabled),
+		"policy_name": user.policy,
+		"member_of":   b.memberOf(accessKey),
+		"updated_at":  user.updatedAt,
+	}, nil
+}
+
+// GetUserCredentials returns the credential details with a masked secret key
+func (b *MemoryBackend) GetUserCredentials(ctx context.Context, accessKey, username, password string) (map[string]interface{}, error) {
+	details, err := b.GetUserDetails(ctx, accessKey, username, password)
+	if err != nil {
+		return nil, err
+	}
+	details["secret_key"] = "••••••••••••••••"
+	details["has_secret"] = true
+	return details, nil
+}
+
+// ListGroups returns the names of all groups
+func (b *MemoryBackend) ListGroups(ctx context.Context, username, password string) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:ListGroups", ""); err != nil {
+		return nil, err
+	}
+	return sortedKeys(b.groups), nil
+}
+
+// CreateGroup creates an empty group
+func (b *MemoryBackend) CreateGroup(ctx context.Context, groupName string, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:AddUserToGroup", ""); err != nil {
+		return err
+	}
+	if _, exists := b.groups[groupName]; !exists {
+		b.groups[groupName] = &memoryGroup{enabled: true, updatedAt: time.Now()}
+	}
+	return nil
+}
+
+// DeleteGroup removes a group
+func (b *MemoryBackend) DeleteGroup(ctx context.Context, groupName string, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:RemoveUserFromGroup", ""); err != nil {
+		return err
+	}
+	if _, err := b.group(groupName); err != nil {
+		return err
+	}
+
+	delete(b.groups, groupName)
+	return nil
+}
+
+// GetGroupInfo returns the members, policy and status of a group
+func (b *MemoryBackend) GetGroupInfo(ctx context.Context, groupName string, username, password string) (map[string]interface{}, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:GetGroup", ""); err != nil {
+		return nil, err
+	}
+	group, err := b.group(groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"name":       groupName,
+		"members":    append([]string{}, group.members...),
+		"policy":     group.policy,
+		"status":     statusName(group.enabled),
+		"updated_at": group.updatedAt,
+	}, nil
+}
+
+// AddUsersToGroup adds existing users to a group, creating the group if needed
+func (b *MemoryBackend) AddUsersToGroup(ctx context.Context, groupName string, usernames []string, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:AddUserToGroup", ""); err != nil {
+		return err
+	}
+	for _, member := range usernames {
+		if _, err := b.user(member); err != nil {
+			return err
+		}
+	}
+
+	group, ok := b.groups[groupName]
+	if !ok {
+		group = &memoryGroup{enabled: true}
+		b.groups[groupName] = group
+	}
+	for _, member := range usernames {
+		if !contains(group.members, member) {
+			group.members = append(group.members, member)
+		}
+	}
+	group.updatedAt = time.Now()
+	return nil
+}
+
+// RemoveUsersFromGroup removes users from a group
+func (b *MemoryBackend) RemoveUsersFromGroup(ctx context.Context, groupName string, usernames []string, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:RemoveUserFromGroup", ""); err != nil {
+		return err
+	}
+	group, err := b.group(groupName)
+	if err != nil {
+		return err
+	}
+
+	group.members = removeAll(group.members, usernames)
+	group.updatedAt = time.Now()
+	return nil
+}
+
+// SetGroupPolicy attaches a comma-separated list of policies to a group
+func (b *MemoryBackend) SetGroupPolicy(ctx context.Context, groupName, policyName string, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:AttachUserOrGroupPolicy", ""); err != nil {
+		return err
+	}
+	group, err := b.group(groupName)
+	if err != nil {
+		return err
+	}
+	if err := b.checkPolicies(policyName); err != nil {
+		return err
+	}
+
+	group.policy = policyName
+	group.updatedAt = time.Now()
+	return nil
+}
+
+// ListPolicies returns all canned policies
+func (b *MemoryBackend) ListPolicies(ctx context.Context, username, password string) (map[string]json.RawMessage, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:ListUserPolicies", ""); err != nil {
+		return nil, err
+	}
+
+	policies := make(map[string]json.RawMessage, len(b.policies))
+	for name, doc := range b.policies {
+		policies[name] = doc
+	}
+	return policies, nil
+}
+
+// GetPolicyDocument returns the document of a canned policy
+func (b *MemoryBackend) GetPolicyDocument(ctx context.Context, policyName, username, password string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:GetPolicy", ""); err != nil {
+		return "", err
+	}
+	doc, ok := b.policies[policyName]
+	if !ok {
+		return "", newError(ErrNotFound, "XMinioAdminNoSuchPolicy", "The canned policy does not exist")
+	}
+	return string(doc), nil
+}
+
+// CreateOrUpdatePolicyDocument stores a canned policy
+func (b *MemoryBackend) CreateOrUpdatePolicyDocument(ctx context.Context, policyName, policyDocument, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:CreatePolicy", ""); err != nil {
+		return err
+	}
+	if policyDocument == "" {
+		return newError(ErrInvalid, "XMinioAdminCannedPolicyMalformed", "policy document is empty")
+	}
+	if _, err := permissions.ParsePolicy([]byte(policyDocument)); err != nil {
+		return &Error{Kind: ErrInvalid, Code: "XMinioAdminCannedPolicyMalformed", Err: err}
+	}
+
+	b.policies[policyName] = json.RawMessage(policyDocument)
+	return nil
+}
+
+// DeletePolicyDocument removes a canned policy
+func (b *MemoryBackend) DeletePolicyDocument(ctx context.Context, policyName, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.authorize(username, password, "admin:DeletePolicy", ""); err != nil {
+		return err
+	}
+	if _, ok := b.policies[policyName]; !ok {
+		return newError(ErrNotFound, "XMinioAdminNoSuchPolicy", "The canned policy does not exist")
+	}
+
+	delete(b.policies, policyName)
+	return nil
+}
+
+// GetUserServiceAccounts lists the service accounts of the caller
+func (b *MemoryBackend) GetUserServiceAccounts(ctx context.Context, username, password string) ([]map[string]interface{}, error) {
+	return b.ListServiceAccounts(ctx, username, username, password)
+}
+
+// ListServiceAccounts lists the service accounts of targetUser, or of the
+// caller when targetUser is empty
+func (b *MemoryBackend) ListServiceAccounts(ctx context.Context, targetUser, username, password string) ([]map[string]interface{}, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if targetUser == "" {
+		targetUser = username
+	}
+	if err := b.authorizeOwn(username, password, "admin:ListServiceAccounts", targetUser); err != nil {
+		return nil, err
+	}
+
+	var result []map[string]interface{}
+	for _, accessKey := range sortedKeys(b.serviceAccounts) {
+		if sa := b.serviceAccounts[accessKey]; sa.parent == targetUser {
+			info := sa.info()
+			info["access_key"] = accessKey
+			result = append(result, info)
+		}
+	}
+	return result, nil
+}
+
+// CreateServiceAccount creates a service account with generated credentials
+func (b *MemoryBackend) CreateServiceAccount(ctx context.Context, targetUser, name, description, username, password string) (map[string]interface{}, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if targetUser == "" {
+		targetUser = username
+	}
+	if err := b.authorizeOwn(username, password, "admin:CreateServiceAccount", targetUser); err != nil {
+		return nil, err
+	}
+	if _, err := b.user(targetUser); err != nil && targetUser != b.rootUser {
+		return nil, err
+	}
+
+	accessKey, err := randomKey(20)
+	if err != nil {
+		return nil, err
+	}
+	secretKey, err := randomKey(40)
+	if err != nil {
+		return nil, err
+	}
+	b.serviceAccounts[accessKey] = &memoryServiceAccount{
+		secretKey:   secretKey,
+		parent:      targetUser,
+		name:        name,
+		description: description,
+		enabled:     true,
+	}
+
+	return map[string]interface{}{
+		"access_key":  accessKey,
+		"secret_key":  secretKey,
+		"name":        name,
+		"description": description,
+		"parent_user": targetUser,
+		"status":      "enabled",
+	}, nil
+}
+
+// DeleteServiceAccount removes a service account
+func (b *MemoryBackend) DeleteServiceAccount(ctx context.Context, serviceAccountKey, username, password string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sa, err := b.serviceAccount(serviceAccountKey)
+	if err != nil {
+		if _, authErr := b.authenticate(username, password); authErr != nil {
+			return authErr
+		}
+		return err
+	}
+	if err := b.authorizeOwn(username, password, "admin:RemoveServiceAccount", sa.parent); err != nil {
+		return err
+	}
+
+	delete(b.serviceAccounts, serviceAccountKey)
+	return nil
+}
+
+// GetServiceAccountInfo returns the details of a service account
+func (b *MemoryBackend) GetServiceAccountInfo(ctx context.Context, serviceAccountKey, username, password string) (map[string]interface{}, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sa, err := b.serviceAccount(serviceAccountKey)
+	if err != nil {
+		if _, authErr := b.authenticate(username, password); authErr != nil {
+			return nil, authErr
+		}
+		return nil, err
+	}
+	if err := b.authorizeOwn(username, password, "admin:ListServiceAccounts", sa.parent); err != nil {
+		return nil, err
+	}
+	return sa.info(), nil
+}
+
+// GetServerInfo describes the in-memory deployment in the shape of madmin.InfoMessage
+func (b *MemoryBackend) GetServerInfo(ctx context.Context, username, password string) (map[string]interface{}, error) {
+	b.mu.Lock()
+	info, err := b.serverInfo(username, password)
+	b.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	infoBytes, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	err = json.Unmarshal(infoBytes, &result)
+	return result, err
+}
+
+// GetMetrics reports the deployment as online
+func (b *MemoryBackend) GetMetrics(ctx context.Context, username, password string) (map[string]interface{}, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	info, err := b.serverInfo(username, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server info: %w", err)
+	}
+	return map[string]interface{}{
+		"server_info": info,
+		"online":      true,
+	}, nil
+}
+
+// ClientCacheStats reports an empty cache; the in-memory backend has no clients
+func (b *MemoryBackend) ClientCacheStats() CacheStats {
+	return CacheStats{}
+}
+
+// EvictClients does nothing; the in-memory backend has no clients
+func (b *MemoryBackend) EvictClients(accessKey string) {}
+
+// Timeouts returns no deadlines; in-memory calls do not block
+func (b *MemoryBackend) Timeouts() Timeouts {
+	return Timeouts{}
+}
+
+// serverInfo builds the server description. b.mu must be held.
+func (b *MemoryBackend) serverInfo(username, password string) (*madmin.InfoMessage, error) {
+	if err := b.authorize(username, password, "admin:ServerInfo", ""); err != nil {
+		return nil, err
+	}
+
+	var objects, size int64
+	for _, bucket := range b.buckets {
+		bucketSize, count := bucket.stats()
+		objects += count
+		size += bucketSize
+	}
+	return &madmin.InfoMessage{
+		Mode:         "online",
+		DeploymentID: "in-memory",
+		Buckets:      madmin.Buckets{Count: uint64(len(b.buckets))},
+		Objects:      madmin.Objects{Count: uint64(objects)},
+		Usage:        madmin.Usage{Size: uint64(size)},
+		Servers:      []madmin.ServerProperties{{State: "online", Endpoint: "memory", Version: "in-memory"}},
+	}, nil
+}
+
+// authenticate checks the credentials of the caller and returns its effective
+// policy. b.mu must be held.
+func (b *MemoryBackend) authenticate(username, password string) (*permissions.Policy, error) {
+	switch {
+	case username == b.rootUser && password == b.rootPassword:
+	case b.users[username] != nil && b.users[username].secretKey == password:
+		if !b.users[username].enabled {
+			return nil, errMemoryAccessDenied
+		}
+	case b.serviceAccounts[username] != nil && b.serviceAccounts[username].secretKey == password:
+		if !b.serviceAccounts[username].enabled {
+			return nil, errMemoryAccessDenied
+		}
+	default:
+		return nil, errMemoryInvalidCredentials
+	}
+
+	doc, err := b.effectivePolicyJSON(username)
+	if err != nil {
+		return nil, err
+	}
+	return permissions.ParsePolicy(doc)
+}
+
+// authorize checks the credentials of the caller and that its policy allows
+// action on resource. b.mu must be held.
+func (b *MemoryBackend) authorize(username, password, action, resource string) error {
+	policy, err := b.authenticate(username, password)
+	if err != nil {
+		return err
+	}
+	if !policy.IsAllowed(action, resource) {
+		return errMemoryAccessDenied
+	}
+	return nil
+}
+
+// authorizeOwn is authorize for service account calls, which MinIO allows on
+// the caller's own service accounts without the admin action. b.mu must be held.
+func (b *MemoryBackend) authorizeOwn(username, password, action, owner string) error {
+	if owner == username {
+		_, err := b.authenticate(username, password)
+		return err
+	}
+	return b.authorize(username, password, action, "")
+}
+
+// effectivePolicyJSON merges the policies of an identity, its groups and, for
+// service accounts, its parent. b.mu must be held.
+func (b *MemoryBackend) effectivePolicyJSON(accessKey string) ([]byte, error) {
+	if sa, ok := b.serviceAccounts[accessKey]; ok {
+		accessKey = sa.parent
+	}
+	if accessKey == b.rootUser {
+		return []byte(memoryRootPolicy), nil
+	}
+
+	var names []string
+	if user, ok := b.users[accessKey]; ok {
+		names = append(names, splitPolicies(user.policy)...)
+	}
+	for _, groupName := range b.memberOf(accessKey) {
+		if group := b.groups[groupName]; group.enabled {
+			names = append(names, splitPolicies(group.policy)...)
+		}
+	}
+
+	merged := permissions.Policy{Version: "2012-10-17", Statements: []permissions.Statement{}}
+	for _, name := range names {
+		policy, err := permissions.ParsePolicy(b.policies[name])
+		if err != nil {
+			return nil, err
+		}
+		merged.Statements = append(merged.Statements, policy.Statements...)
+	}
+	return json.Marshal(merged)
+}
+
+// checkPolicies verifies that every policy in a comma-separated list exists.
+// b.mu must be held.
+func (b *MemoryBackend) checkPolicies(policyNames string) error {
+	for _, name := range splitPolicies(policyNames) {
+		if _, ok := b.policies[name]; !ok {
+			return newError(ErrNotFound, "XMinioAdminNoSuchPolicy", "The canned policy does not exist")
+		}
+	}
+	return nil
+}
+
+// memberOf returns the groups a user belongs to. b.mu must be held.
+func (b *MemoryBackend) memberOf(accessKey string) []string {
+	var groups []string
+	for _, name := range sortedKeys(b.groups) {
+		if contains(b.groups[name].members, accessKey) {
+			groups = append(groups, name)
+		}
+	}
+	return groups
+}
+
+func (b *MemoryBackend) bucket(name string) (*memoryBucket, error) {
+	if bucket, ok := b.buckets[name]; ok {
+		return bucket, nil
+	}
+	return nil, newError(ErrNotFound, "NoSuchBucket", "The specified bucket does not exist")
+}
+
+// memoryTargetIndex returns the index of the target with arn, or -1
+func memoryTargetIndex(bucket *memoryBucket, arn string) int {
+	for i, target := range bucket.targets {
+		if target.ARN == arn {
+			return i
+		}
+	}
+	return -1
+}
+
+func (b *MemoryBackend) user(accessKey string) (*memoryUser, error) {
+	if user, ok := b.users[accessKey]; ok {
+		return user, nil
+	}
+	return nil, newError(ErrNotFound, "XMinioAdminNoSuchUser", "The specified user does not exist. (Specified user does not exist)")
+}
+
+func (b *MemoryBackend) group(name string) (*memoryGroup, error) {
+	if group, ok := b.groups[name]; ok {
+		return group, nil
+	}
+	return nil, newError(ErrNotFound, "XMinioAdminNoSuchGroup", "The specified group does not exist.")
+}
+
+func (b *MemoryBackend) serviceAccount(accessKey string) (*memoryServiceAccount, error) {
+	if sa, ok := b.serviceAccounts[accessKey]; ok {
+		return sa, nil
+	}
+	return nil, newError(ErrNotFound, "XMinioAdminNoSuchServiceAccount", "The specified service account is not found (Specified service account does not exist)")
+}
+
+// stats returns the total size and object count of the bucket
+func (bucket *memoryBucket) stats() (int64, int64) {
+	var size int64
+	for _, objectSize := range bucket.objects {
+		size += objectSize
+	}
+	return size, int64(len(bucket.objects))
+}
+
+// info returns the details reported for a service account
+func (sa *memoryServiceAccount) info() map[string]interface{} {
+	return map[string]interface{}{
+		"status":         statusName(sa.enabled),
+		"name":           sa.name,
+		"description":    sa.description,
+		"parent_user":    sa.parent,
+		"implied_policy": true,
+	}
+}
+
+// validateMemoryCredentials applies MinIO's length limits to new credentials
+func validateMemoryCredentials(accessKey, secretKey string) error {
+	if len(accessKey) < 3 || len(accessKey) > 20 {
+		return newError(ErrInvalid, "XMinioAdminInvalidAccessKey", "The access key is invalid.")
+	}
+	if len(secretKey) < 8 || len(secretKey) > 40 {
+		return newError(ErrInvalid, "XMinioAdminInvalidSecretKey", "The secret key is invalid.")
+	}
+	return nil
+}
+
+// randomKey returns n random characters from MinIO's access key alphabet
+func randomKey(n int) (string, error) {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b), nil
+}
+
+func statusName(enabled bool) string {
+	if enabled {
+		return string(madmin.AccountEnabled)
+	}
+	return string(madmin.AccountDisabled)
+}
+
+func splitPolicies(names string) []string {
+	var result []string
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func removeAll(values, remove []string) []string {
+	kept := values[:0]
+	for _, v := range values {
+		if !contains(remove, v) {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
@@ -3,60 +3,18 @@ package services
 import (
 	"context"
 	"log"
-	"strings"
+	"sort"
 	"time"
 
+	"github.com/minio/madmin-go/v3"
 	"github.com/minio/minio-go/v7"
 )
 
-// ListBuckets returns a list of all buckets with detailed statistics
-func (s *MinIOService) ListBuckets(ctx context.Context, username, password string) ([]BucketInfo, error) {
-	log.Printf("[DEBUG] MinIO service ListBuckets called for user '%s'", username)
-
-	client, _, err := s.CreateClients(username, password)
-	if err != nil {
-		log.Printf("[DEBUG] Failed to create clients in ListBuckets: %v", err)
-		return nil, err
-	}
-
-	log.Printf("[DEBUG] Calling MinIO ListBuckets API")
-	buckets, err := client.ListBuckets(ctx)
-	if err != nil {
-		log.Printf("[DEBUG] MinIO ListBuckets API failed: %v", err)
-		return nil, err
-	}
-
-	log.Printf("[DEBUG] MinIO ListBuckets API returned %d buckets", len(buckets))
-
-	var bucketInfos []BucketInfo
-	for _, bucket := range buckets {
-		info := BucketInfo{
-			Name:         bucket.Name,
-			CreationDate: bucket.CreationDate.Format("2006-01-02 15:04:05"),
-			Size:         0, // Will be calculated below
-			ObjectCount:  0, // Will be calculated below
-		}
-
-		// Get bucket statistics (size and object count)
-		log.Printf("[DEBUG] Getting statistics for bucket '%s'", bucket.Name)
-		size, objectCount := s.getBucketStats(ctx, client, bucket.Name)
-		info.Size = size
-		info.ObjectCount = objectCount
-
-		bucketInfos = append(bucketInfos, info)
-		log.Printf("[DEBUG] Bucket: %s (created: %s, size: %d bytes, objects: %d)",
-			bucket.Name, bucket.CreationDate.Format("2006-01-02 15:04:05"), size, objectCount)
-	}
-
-	log.Printf("[DEBUG] Returning %d bucket infos", len(bucketInfos))
-	return bucketInfos, nil
-}
-
 // ListBucketsQuick provides a faster bucket listing without size/count calculation
 func (s *MinIOService) ListBucketsQuick(ctx context.Context, username, password string) ([]BucketInfo, error) {
 	log.Printf("[DEBUG] MinIO service ListBucketsQuick called for user '%s'", username)
 
-	client, _, err := s.CreateClients(username, password)
+	client, _, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in ListBucketsQuick: %v", err)
 		return nil, err
@@ -78,6 +36,7 @@ func (s *MinIOService) ListBucketsQuick(ctx context.Context, username, password
 			CreationDate: bucket.CreationDate.Format("2006-01-02 15:04:05"),
 			Size:         -1, // -1 indicates not calculated
 			ObjectCount:  -1, // -1 indicates not calculated
+			VersionCount: -1, // -1 indicates not calculated
 		}
 		bucketInfos = append(bucketInfos, info)
 		log.Printf("[DEBUG] Bucket: %s (created: %s, stats: not calculated)",
@@ -88,60 +47,91 @@ func (s *MinIOService) ListBucketsQuick(ctx context.Context, username, password
 	return bucketInfos, nil
 }
 
-// getBucketStats calculates the total size and object count for a bucket
-func (s *MinIOService) getBucketStats(ctx context.Context, client *minio.Client, bucketName string) (int64, int64) {
-	var totalSize int64
-	var objectCount int64
+// GetDataUsage returns the bucket usage recorded by MinIO's data usage
+// scanner. It needs the admin:DataUsageInfo permission.
+func (s *MinIOService) GetDataUsage(ctx context.Context, username, password string) (*DataUsage, error) {
+	log.Printf("[DEBUG] GetDataUsage called by user '%s'", username)
 
-	// Create a timeout context for bucket stats calculation (30 seconds max)
-	statsCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
+	_, adminClient, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in GetDataUsage: %v", err)
+		return nil, err
+	}
+	return s.dataUsage(ctx, adminClient)
+}
 
-	log.Printf("[DEBUG] Starting stats calculation for bucket '%s' (max 30s timeout)", bucketName)
+// dataUsage fetches the scanner's usage data. An error is returned if the
+// caller may not read it or the scanner has not completed a cycle yet.
+func (s *MinIOService) dataUsage(ctx context.Context, adminClient *madmin.AdminClient) (*DataUsage, error) {
+	info, err := adminClient.DataUsageInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if info.LastUpdate.IsZero() {
+		return nil, newError(ErrUnavailable, "", "data usage has not been calculated yet")
+	}
 
-	// List all objects in the bucket to calculate size and count
-	objectCh := client.ListObjects(statsCtx, bucketName, minio.ListObjectsOptions{Recursive: true})
+	usage := &DataUsage{UpdatedAt: info.LastUpdate, Buckets: make(map[string]BucketUsage, len(info.BucketsUsage))}
+	for name, bucket := range info.BucketsUsage {
+		usage.Buckets[name] = BucketUsage{Size: int64(bucket.Size), ObjectCount: int64(bucket.ObjectsCount), VersionCount: int64(bucket.VersionsCount)}
+	}
+	log.Printf("[DEBUG] Data usage of %d buckets, last updated %s", len(usage.Buckets), info.LastUpdate.Format(time.RFC3339))
+	return usage, nil
+}
 
-	for object := range objectCh {
+// MeasureBucket lists every object version of a bucket to count its size,
+// objects and versions, broken down by top-level prefix. It runs until ctx
+// is done; callers set the deadline.
+func (s *MinIOService) MeasureBucket(ctx context.Context, username, password, bucketName string) (*BucketStats, error) {
+	log.Printf("[DEBUG] MeasureBucket called for bucket '%s' by user '%s'", bucketName, username)
+
+	client, _, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in MeasureBucket: %v", err)
+		return nil, err
+	}
+
+	stats := &BucketStats{}
+	prefixes := make(map[string]*PrefixUsage)
+	for object := range client.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Recursive: true, WithVersions: true}) {
 		if object.Err != nil {
-			log.Printf("[DEBUG] Error listing object in bucket '%s': %v", bucketName, object.Err)
-			// Check if it's a timeout or context cancellation
-			if statsCtx.Err() != nil {
-				log.Printf("[DEBUG] Stats calculation timed out for bucket '%s'", bucketName)
-				return -1, -1 // Return -1 to indicate timeout/error
-			}
+			log.Printf("[DEBUG] Measuring bucket '%s' failed after %d versions: %v", bucketName, stats.VersionCount, object.Err)
+			return nil, object.Err
+		}
+		if object.IsDeleteMarker {
 			continue
 		}
-		totalSize += object.Size
-		objectCount++
-
-		// Check for timeout periodically
-		select {
-		case <-statsCtx.Done():
-			log.Printf("[DEBUG] Stats calculation timed out for bucket '%s' after %d objects", bucketName, objectCount)
-			return -1, -1
-		default:
-			// Continue processing
+		stats.VersionCount++
+		if object.IsLatest || object.VersionID == "" {
+			stats.addObject(object.Key, object.Size, prefixes)
 		}
 	}
+	if err := ctx.Err(); err != nil {
+		log.Printf("[DEBUG] Measuring bucket '%s' stopped after %d versions: %v", bucketName, stats.VersionCount, err)
+		return nil, err
+	}
+	stats.setPrefixes(prefixes)
 
-	log.Printf("[DEBUG] Stats calculation completed for bucket '%s': %d bytes, %d objects",
-		bucketName, totalSize, objectCount)
-	return totalSize, objectCount
+	log.Printf("[DEBUG] Bucket '%s' measured: %d objects, %d versions, %d bytes, %d prefixes",
+		bucketName, stats.ObjectCount, stats.VersionCount, stats.Size, len(prefixes))
+	return stats, nil
 }
 
 // CreateBucket creates a new bucket
-func (s *MinIOService) CreateBucket(ctx context.Context, bucketName, username, password string) error {
-	client, _, err := s.CreateClients(username, password)
+func (s *MinIOService) CreateBucket(ctx context.Context, bucketName string, opts BucketOptions, username, password string) error {
+	client, _, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		return err
 	}
-	return client.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{})
+	if opts.ObjectLocking {
+		log.Printf("[DEBUG] Creating bucket '%s' with object locking for user '%s'", bucketName, username)
+	}
+	return client.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{ObjectLocking: opts.ObjectLocking})
 }
 
 // DeleteBucket deletes an existing bucket
 func (s *MinIOService) DeleteBucket(ctx context.Context, bucketName, username, password string) error {
-	client, _, err := s.CreateClients(username, password)
+	client, _, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		return err
 	}
@@ -152,7 +142,7 @@ func (s *MinIOService) DeleteBucket(ctx context.Context, bucketName, username, p
 func (s *MinIOService) GetBucketPolicy(ctx context.Context, bucketName, username, password string) (string, error) {
 	log.Printf("[DEBUG] MinIO service GetBucketPolicy called for bucket '%s' by user '%s'", bucketName, username)
 
-	client, _, err := s.CreateClients(username, password)
+	client, _, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in GetBucketPolicy: %v", err)
 		return "", err
@@ -163,11 +153,8 @@ func (s *MinIOService) GetBucketPolicy(ctx context.Context, bucketName, username
 	if err != nil {
 		log.Printf("[DEBUG] MinIO GetBucketPolicy API response for bucket '%s': %v", bucketName, err)
 
-		// Check if this is a "no policy" error, which is normal
-		errStr := err.Error()
-		if strings.Contains(errStr, "policy does not exist") ||
-			strings.Contains(errStr, "NoSuchBucketPolicy") ||
-			strings.Contains(errStr, "The bucket policy does not exist") {
+		// A bucket without a policy is normal
+		if Classify(err).Code == "NoSuchBucketPolicy" {
 			log.Printf("[DEBUG] No policy exists for bucket '%s' (this is normal)", bucketName)
 			return "", nil // Return empty string, not an error
 		}
@@ -185,7 +172,7 @@ func (s *MinIOService) GetBucketPolicy(ctx context.Context, bucketName, username
 func (s *MinIOService) SetBucketPolicy(ctx context.Context, bucketName, policy, username, password string) error {
 	log.Printf("[DEBUG] MinIO service SetBucketPolicy called for bucket '%s' by user '%s', policy length: %d", bucketName, username, len(policy))
 
-	client, _, err := s.CreateClients(username, password)
+	client, _, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in SetBucketPolicy: %v", err)
 		return err
@@ -202,18 +189,198 @@ func (s *MinIOService) SetBucketPolicy(ctx context.Context, bucketName, policy,
 	return nil
 }
 
+// GetBucketVersioning returns the versioning configuration of a bucket
+func (s *MinIOService) GetBucketVersioning(ctx context.Context, bucketName, username, password string) (*BucketVersioning, error) {
+	log.Printf("[DEBUG] MinIO service GetBucketVersioning called for bucket '%s' by user '%s'", bucketName, username)
+
+	client, _, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in GetBucketVersioning: %v", err)
+		return nil, err
+	}
+
+	config, err := client.GetBucketVersioning(ctx, bucketName)
+	if err != nil {
+		log.Printf("[DEBUG] MinIO GetBucketVersioning API failed for bucket '%s': %v", bucketName, err)
+		return nil, err
+	}
+
+	versioning := &BucketVersioning{Status: config.Status, ExcludedPrefixes: []string{}, ExcludeFolders: config.ExcludeFolders}
+	for _, excluded := range config.ExcludedPrefixes {
+		versioning.ExcludedPrefixes = append(versioning.ExcludedPrefixes, excluded.Prefix)
+	}
+	log.Printf("[DEBUG] Versioning of bucket '%s': status %q, %d excluded prefixes, exclude folders: %t",
+		bucketName, versioning.Status, len(versioning.ExcludedPrefixes), versioning.ExcludeFolders)
+	return versioning, nil
+}
+
+// SetBucketVersioning enables or suspends versioning of a bucket
+func (s *MinIOService) SetBucketVersioning(ctx context.Context, bucketName string, versioning BucketVersioning, username, password string) error {
+	log.Printf("[DEBUG] MinIO service SetBucketVersioning called for bucket '%s' by user '%s', status %q", bucketName, username, versioning.Status)
+
+	client, _, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in SetBucketVersioning: %v", err)
+		return err
+	}
+
+	config := minio.BucketVersioningConfiguration{Status: versioning.Status, ExcludeFolders: versioning.ExcludeFolders}
+	for _, prefix := range versioning.ExcludedPrefixes {
+		config.ExcludedPrefixes = append(config.ExcludedPrefixes, minio.ExcludedPrefix{Prefix: prefix})
+	}
+	if err := client.SetBucketVersioning(ctx, bucketName, config); err != nil {
+		log.Printf("[DEBUG] MinIO SetBucketVersioning API failed for bucket '%s': %v", bucketName, err)
+		return err
+	}
+
+	log.Printf("[DEBUG] SetBucketVersioning successful for bucket '%s'", bucketName)
+	return nil
+}
+
+// GetBucketObjectLock returns the object lock configuration of a bucket.
+// Buckets created without object locking report Enabled false.
+func (s *MinIOService) GetBucketObjectLock(ctx context.Context, bucketName, username, password string) (*BucketObjectLock, error) {
+	log.Printf("[DEBUG] MinIO service GetBucketObjectLock called for bucket '%s' by user '%s'", bucketName, username)
+
+	client, _, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in GetBucketObjectLock: %v", err)
+		return nil, err
+	}
+
+	enabled, mode, validity, unit, err := client.GetObjectLockConfig(ctx, bucketName)
+	if err != nil {
+		switch Classify(err).Code {
+		case "ObjectLockConfigurationNotFoundError", "NoSuchObjectLockConfiguration":
+			log.Printf("[DEBUG] Bucket '%s' has no object lock configuration", bucketName)
+			return &BucketObjectLock{}, nil
+		}
+		log.Printf("[DEBUG] MinIO GetObjectLockConfig API failed for bucket '%s': %v", bucketName, err)
+		return nil, err
+	}
+
+	lock := &BucketObjectLock{Enabled: enabled == "Enabled"}
+	if mode != nil && validity != nil && unit != nil {
+		lock.Mode, lock.Validity, lock.Unit = string(*mode), *validity, string(*unit)
+	}
+	log.Printf("[DEBUG] Object lock of bucket '%s': enabled %t, default retention %s %d %s", bucketName, lock.Enabled, lock.Mode, lock.Validity, lock.Unit)
+	return lock, nil
+}
+
+// SetBucketRetention sets the default retention of an object lock enabled
+// bucket, or removes it if lock.Mode is empty
+func (s *MinIOService) SetBucketRetention(ctx context.Context, bucketName string, lock BucketObjectLock, username, password string) error {
+	log.Printf("[DEBUG] MinIO service SetBucketRetention called for bucket '%s' by user '%s': %s %d %s", bucketName, username, lock.Mode, lock.Validity, lock.Unit)
+
+	client, _, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in SetBucketRetention: %v", err)
+		return err
+	}
+
+	var mode *minio.RetentionMode
+	var validity *uint
+	var unit *minio.ValidityUnit
+	if lock.Mode != "" {
+		retentionMode, validityUnit := minio.RetentionMode(lock.Mode), minio.ValidityUnit(lock.Unit)
+		mode, validity, unit = &retentionMode, &lock.Validity, &validityUnit
+	}
+	if err := client.SetObjectLockConfig(ctx, bucketName, mode, validity, unit); err != nil {
+		log.Printf("[DEBUG] MinIO SetObjectLockConfig API failed for bucket '%s': %v", bucketName, err)
+		return err
+	}
+
+	log.Printf("[DEBUG] SetBucketRetention successful for bucket '%s'", bucketName)
+	return nil
+}
+
+// GetBucketLifecycle returns the lifecycle rules of a bucket, none if it has
+// no lifecycle configuration
+func (s *MinIOService) GetBucketLifecycle(ctx context.Context, bucketName, username, password string) ([]LifecycleRule, error) {
+	log.Printf("[DEBUG] MinIO service GetBucketLifecycle called for bucket '%s' by user '%s'", bucketName, username)
+
+	client, _, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in GetBucketLifecycle: %v", err)
+		return nil, err
+	}
+
+	config, err := client.GetBucketLifecycle(ctx, bucketName)
+	if err != nil {
+		if Classify(err).Code == "NoSuchLifecycleConfiguration" {
+			log.Printf("[DEBUG] Bucket '%s' has no lifecycle configuration", bucketName)
+			return []LifecycleRule{}, nil
+		}
+		log.Printf("[DEBUG] MinIO GetBucketLifecycle API failed for bucket '%s': %v", bucketName, err)
+		return nil, err
+	}
+
+	rules := fromLifecycleConfiguration(config)
+	log.Printf("[DEBUG] Bucket '%s' has %d lifecycle rules", bucketName, len(rules))
+	return rules, nil
+}
+
+// SetBucketLifecycle replaces the lifecycle rules of a bucket, removing its
+// lifecycle configuration if there are none
+func (s *MinIOService) SetBucketLifecycle(ctx context.Context, bucketName string, rules []LifecycleRule, username, password string) error {
+	log.Printf("[DEBUG] MinIO service SetBucketLifecycle called for bucket '%s' by user '%s' with %d rules", bucketName, username, len(rules))
+
+	client, _, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in SetBucketLifecycle: %v", err)
+		return err
+	}
+
+	config, err := toLifecycleConfiguration(rules)
+	if err != nil {
+		return err
+	}
+	if err := client.SetBucketLifecycle(ctx, bucketName, config); err != nil {
+		log.Printf("[DEBUG] MinIO SetBucketLifecycle API failed for bucket '%s': %v", bucketName, err)
+		return err
+	}
+
+	log.Printf("[DEBUG] SetBucketLifecycle successful for bucket '%s'", bucketName)
+	return nil
+}
+
+// ListTiers returns the names of the remote tiers objects can transition to
+func (s *MinIOService) ListTiers(ctx context.Context, username, password string) ([]string, error) {
+	log.Printf("[DEBUG] MinIO service ListTiers called by user '%s'", username)
+
+	_, adminClient, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in ListTiers: %v", err)
+		return nil, err
+	}
+
+	tiers, err := adminClient.ListTiers(ctx)
+	if err != nil {
+		log.Printf("[DEBUG] MinIO ListTiers API failed: %v", err)
+		return nil, err
+	}
+
+	names := make([]string, 0, len(tiers))
+	for _, tier := range tiers {
+		names = append(names, tier.Name)
+	}
+	sort.Strings(names)
+	log.Printf("[DEBUG] Found %d remote tiers", len(names))
+	return names, nil
+}
+
 // GetBucketStatsQuick returns bucket statistics with a shorter timeout for dashboard use
 func (s *MinIOService) GetBucketStatsQuick(ctx context.Context, username, password, bucketName string) (int64, int64) {
 	log.Printf("[DEBUG] GetBucketStatsQuick called for bucket '%s' by user '%s'", bucketName, username)
 
-	client, _, err := s.CreateClients(username, password)
+	client, _, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in GetBucketStatsQuick: %v", err)
 		return -1, -1
 	}
 
-	// Use shorter timeout for dashboard (5 seconds max)
-	return s.getBucketStatsWithTimeout(ctx, client, bucketName, 5*time.Second)
+	// Use the shorter dashboard timeout
+	return s.getBucketStatsWithTimeout(ctx, client, bucketName, s.timeouts.QuickStats)
 }
 
 // getBucketStatsWithTimeout calculates bucket stats with configurable timeout
@@ -225,7 +392,7 @@ func (s *MinIOService) getBucketStatsWithTimeout(ctx context.Context, client *mi
 	statsCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	log.Printf("[DEBUG] Starting quick stats calculation for bucket '%s' (max %.0fs timeout)", bucketName, timeout.Seconds())
+	log.Printf("[DEBUG] Starting stats calculation for bucket '%s' (max %.0fs timeout)", bucketName, timeout.Seconds())
 
 	// List all objects in the bucket to calculate size and count
 	objectCh := client.ListObjects(statsCtx, bucketName, minio.ListObjectsOptions{Recursive: true})
@@ -235,7 +402,7 @@ func (s *MinIOService) getBucketStatsWithTimeout(ctx context.Context, client *mi
 			log.Printf("[DEBUG] Error listing object in bucket '%s': %v", bucketName, object.Err)
 			// Check if it's a timeout or context cancellation
 			if statsCtx.Err() != nil {
-				log.Printf("[DEBUG] Quick stats calculation timed out for bucket '%s'", bucketName)
+				log.Printf("[DEBUG] Stats calculation timed out for bucket '%s'", bucketName)
 				return -1, -1 // Return -1 to indicate timeout/error
 			}
 			continue
@@ -246,14 +413,14 @@ func (s *MinIOService) getBucketStatsWithTimeout(ctx context.Context, client *mi
 		// Check for timeout periodically
 		select {
 		case <-statsCtx.Done():
-			log.Printf("[DEBUG] Quick stats calculation timed out for bucket '%s' after %d objects", bucketName, objectCount)
+			log.Printf("[DEBUG] Stats calculation timed out for bucket '%s' after %d objects", bucketName, objectCount)
 			return -1, -1
 		default:
 			// Continue processing
 		}
 	}
 
-	log.Printf("[DEBUG] Quick stats calculation completed for bucket '%s': %d bytes, %d objects",
+	log.Printf("[DEBUG] Stats calculation completed for bucket '%s': %d bytes, %d objects",
 		bucketName, totalSize, objectCount)
 	return totalSize, objectCount
 }
@@ -11,7 +11,7 @@ import (
 func (s *MinIOService) ListGroups(ctx context.Context, username, password string) ([]string, error) {
 	log.Printf("[DEBUG] MinIO service ListGroups called by admin '%s'", username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in ListGroups: %v", err)
 		return nil, err
@@ -32,7 +32,7 @@ func (s *MinIOService) ListGroups(ctx context.Context, username, password string
 func (s *MinIOService) CreateGroup(ctx context.Context, groupName string, username, password string) error {
 	log.Printf("[DEBUG] MinIO service CreateGroup called for group '%s' by admin '%s'", groupName, username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in CreateGroup: %v", err)
 		return err
@@ -57,7 +57,7 @@ func (s *MinIOService) CreateGroup(ctx context.Context, groupName string, userna
 func (s *MinIOService) DeleteGroup(ctx context.Context, groupName string, username, password string) error {
 	log.Printf("[DEBUG] MinIO service DeleteGroup called for group '%s' by admin '%s'", groupName, username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in DeleteGroup: %v", err)
 		return err
@@ -93,7 +93,7 @@ func (s *MinIOService) DeleteGroup(ctx context.Context, groupName string, userna
 func (s *MinIOService) GetGroupInfo(ctx context.Context, groupName string, username, password string) (map[string]interface{}, error) {
 	log.Printf("[DEBUG] MinIO service GetGroupInfo called for group '%s' by admin '%s'", groupName, username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in GetGroupInfo: %v", err)
 		return nil, err
@@ -124,7 +124,7 @@ func (s *MinIOService) AddUsersToGroup(ctx context.Context, groupName string, us
 	log.Printf("[DEBUG] MinIO service AddUsersToGroup called for group '%s' with %d users by admin '%s'",
 		groupName, len(usernames), username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in AddUsersToGroup: %v", err)
 		return err
@@ -150,7 +150,7 @@ func (s *MinIOService) RemoveUsersFromGroup(ctx context.Context, groupName strin
 	log.Printf("[DEBUG] MinIO service RemoveUsersFromGroup called for group '%s' with %d users by admin '%s'",
 		groupName, len(usernames), username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in RemoveUsersFromGroup: %v", err)
 		return err
@@ -176,7 +176,7 @@ func (s *MinIOService) SetGroupPolicy(ctx context.Context, groupName, policyName
 	log.Printf("[DEBUG] MinIO service SetGroupPolicy called for group '%s' with policy '%s' by admin '%s'",
 		groupName, policyName, username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in SetGroupPolicy: %v", err)
 		return err
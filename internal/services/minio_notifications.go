@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"log"
+)
+
+// GetBucketNotification returns the notification rules of a bucket
+func (s *MinIOService) GetBucketNotification(ctx context.Context, bucketName, username, password string) ([]NotificationRule, error) {
+	log.Printf("[DEBUG] MinIO service GetBucketNotification called for bucket '%s' by user '%s'", bucketName, username)
+
+	client, _, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in GetBucketNotification: %v", err)
+		return nil, err
+	}
+
+	config, err := client.GetBucketNotification(ctx, bucketName)
+	if err != nil {
+		log.Printf("[DEBUG] MinIO GetBucketNotification API failed for bucket '%s': %v", bucketName, err)
+		return nil, err
+	}
+
+	rules := fromNotificationConfig(config)
+	log.Printf("[DEBUG] Bucket '%s' has %d notification rules", bucketName, len(rules))
+	return rules, nil
+}
+
+// SetBucketNotification replaces the notification rules of a bucket,
+// removing all notifications if there are none
+func (s *MinIOService) SetBucketNotification(ctx context.Context, bucketName string, rules []NotificationRule, username, password string) error {
+	log.Printf("[DEBUG] MinIO service SetBucketNotification called for bucket '%s' by user '%s' with %d rules", bucketName, username, len(rules))
+
+	client, _, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in SetBucketNotification: %v", err)
+		return err
+	}
+
+	if len(rules) == 0 {
+		err = client.RemoveAllBucketNotification(ctx, bucketName)
+	} else {
+		err = client.SetBucketNotification(ctx, bucketName, toNotificationConfig(rules))
+	}
+	if err != nil {
+		log.Printf("[DEBUG] MinIO SetBucketNotification API failed for bucket '%s': %v", bucketName, err)
+		return err
+	}
+
+	log.Printf("[DEBUG] SetBucketNotification successful for bucket '%s'", bucketName)
+	return nil
+}
+
+// ListNotificationTargets returns the notification targets configured on
+// the server, which MinIO reports with its server info
+func (s *MinIOService) ListNotificationTargets(ctx context.Context, username, password string) ([]NotificationTarget, error) {
+	log.Printf("[DEBUG] MinIO service ListNotificationTargets called by user '%s'", username)
+
+	_, adminClient, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in ListNotificationTargets: %v", err)
+		return nil, err
+	}
+
+	info, err := adminClient.ServerInfo(ctx)
+	if err != nil {
+		log.Printf("[DEBUG] MinIO ServerInfo API failed in ListNotificationTargets: %v", err)
+		return nil, err
+	}
+
+	targets := notificationTargets(info.SQSARN)
+	log.Printf("[DEBUG] Server has %d notification targets", len(targets))
+	return targets, nil
+}
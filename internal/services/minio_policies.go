@@ -10,7 +10,7 @@ import (
 func (s *MinIOService) ListPolicies(ctx context.Context, username, password string) (map[string]json.RawMessage, error) {
 	log.Printf("[DEBUG] MinIO service ListPolicies called by admin '%s'", username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in ListPolicies: %v", err)
 		return nil, err
@@ -31,7 +31,7 @@ func (s *MinIOService) ListPolicies(ctx context.Context, username, password stri
 func (s *MinIOService) GetPolicyDocument(ctx context.Context, policyName, username, password string) (string, error) {
 	log.Printf("[DEBUG] MinIO service GetPolicyDocument called for policy '%s' by admin '%s'", policyName, username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in GetPolicyDocument: %v", err)
 		return "", err
@@ -52,7 +52,7 @@ func (s *MinIOService) GetPolicyDocument(ctx context.Context, policyName, userna
 func (s *MinIOService) CreateOrUpdatePolicyDocument(ctx context.Context, policyName, policyDocument, username, password string) error {
 	log.Printf("[DEBUG] MinIO service CreateOrUpdatePolicyDocument called for policy '%s' by admin '%s', document length: %d", policyName, username, len(policyDocument))
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in CreateOrUpdatePolicyDocument: %v", err)
 		return err
@@ -73,7 +73,7 @@ func (s *MinIOService) CreateOrUpdatePolicyDocument(ctx context.Context, policyN
 func (s *MinIOService) DeletePolicyDocument(ctx context.Context, policyName, username, password string) error {
 	log.Printf("[DEBUG] MinIO service DeletePolicyDocument called for policy '%s' by admin '%s'", policyName, username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in DeletePolicyDocument: %v", err)
 		return err
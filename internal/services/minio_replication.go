@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"github.com/minio/madmin-go/v3"
+)
+
+// ListReplicationTargets returns the remote targets a bucket can replicate to
+func (s *MinIOService) ListReplicationTargets(ctx context.Context, bucketName, username, password string) ([]ReplicationTarget, error) {
+	log.Printf("[DEBUG] MinIO service ListReplicationTargets called for bucket '%s' by user '%s'", bucketName, username)
+
+	_, adminClient, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in ListReplicationTargets: %v", err)
+		return nil, err
+	}
+
+	targets, err := adminClient.ListRemoteTargets(ctx, bucketName, string(madmin.ReplicationService))
+	if err != nil {
+		log.Printf("[DEBUG] MinIO ListRemoteTargets API failed for bucket '%s': %v", bucketName, err)
+		return nil, err
+	}
+
+	converted := make([]ReplicationTarget, 0, len(targets))
+	for _, target := range targets {
+		converted = append(converted, fromBucketTarget(target))
+	}
+	log.Printf("[DEBUG] Bucket '%s' has %d replication targets", bucketName, len(converted))
+	return converted, nil
+}
+
+// AddReplicationTarget adds a remote target to a bucket and returns its ARN.
+// MinIO checks that the target is reachable and versioned.
+func (s *MinIOService) AddReplicationTarget(ctx context.Context, bucketName string, target ReplicationTarget, username, password string) (string, error) {
+	log.Printf("[DEBUG] MinIO service AddReplicationTarget called for bucket '%s' by user '%s' with target '%s/%s'", bucketName, username, target.Endpoint, target.TargetBucket)
+
+	_, adminClient, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in AddReplicationTarget: %v", err)
+		return "", err
+	}
+
+	arn, err := adminClient.SetRemoteTarget(ctx, bucketName, toBucketTarget(bucketName, target))
+	if err != nil {
+		log.Printf("[DEBUG] MinIO SetRemoteTarget API failed for bucket '%s': %v", bucketName, err)
+		return "", err
+	}
+
+	log.Printf("[DEBUG] AddReplicationTarget successful for bucket '%s': %s", bucketName, arn)
+	return arn, nil
+}
+
+// RemoveReplicationTarget removes a remote target from a bucket. MinIO
+// refuses while a replication rule still uses it.
+func (s *MinIOService) RemoveReplicationTarget(ctx context.Context, bucketName, arn, username, password string) error {
+	log.Printf("[DEBUG] MinIO service RemoveReplicationTarget called for bucket '%s' by user '%s' with target '%s'", bucketName, username, arn)
+
+	_, adminClient, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in RemoveReplicationTarget: %v", err)
+		return err
+	}
+
+	if err := adminClient.RemoveRemoteTarget(ctx, bucketName, arn); err != nil {
+		log.Printf("[DEBUG] MinIO RemoveRemoteTarget API failed for bucket '%s': %v", bucketName, err)
+		return err
+	}
+
+	log.Printf("[DEBUG] RemoveReplicationTarget successful for bucket '%s'", bucketName)
+	return nil
+}
+
+// GetBucketReplication returns the replication rules of a bucket
+func (s *MinIOService) GetBucketReplication(ctx context.Context, bucketName, username, password string) ([]ReplicationRule, error) {
+	log.Printf("[DEBUG] MinIO service GetBucketReplication called for bucket '%s' by user '%s'", bucketName, username)
+
+	client, _, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in GetBucketReplication: %v", err)
+		return nil, err
+	}
+
+	config, err := client.GetBucketReplication(ctx, bucketName)
+	if err != nil {
+		if Classify(err).Code == "ReplicationConfigurationNotFoundError" {
+			log.Printf("[DEBUG] Bucket '%s' has no replication configuration", bucketName)
+			return []ReplicationRule{}, nil
+		}
+		log.Printf("[DEBUG] MinIO GetBucketReplication API failed for bucket '%s': %v", bucketName, err)
+		return nil, err
+	}
+
+	rules := fromReplicationConfig(config)
+	log.Printf("[DEBUG] Bucket '%s' has %d replication rules", bucketName, len(rules))
+	return rules, nil
+}
+
+// SetBucketReplication replaces the replication rules of a bucket, removing
+// its replication configuration if there are none
+func (s *MinIOService) SetBucketReplication(ctx context.Context, bucketName string, rules []ReplicationRule, username, password string) error {
+	log.Printf("[DEBUG] MinIO service SetBucketReplication called for bucket '%s' by user '%s' with %d rules", bucketName, username, len(rules))
+
+	client, _, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in SetBucketReplication: %v", err)
+		return err
+	}
+
+	if len(rules) == 0 {
+		err = client.RemoveBucketReplication(ctx, bucketName)
+	} else {
+		err = client.SetBucketReplication(ctx, bucketName, toReplicationConfig(rules))
+	}
+	if err != nil {
+		log.Printf("[DEBUG] MinIO SetBucketReplication API failed for bucket '%s': %v", bucketName, err)
+		return err
+	}
+
+	log.Printf("[DEBUG] SetBucketReplication successful for bucket '%s'", bucketName)
+	return nil
+}
+
+// GetReplicationMetrics returns how much of a bucket is replicated, pending
+// or failed, in total and per target
+func (s *MinIOService) GetReplicationMetrics(ctx context.Context, bucketName, username, password string) (*ReplicationMetrics, error) {
+	log.Printf("[DEBUG] MinIO service GetReplicationMetrics called for bucket '%s' by user '%s'", bucketName, username)
+
+	client, _, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in GetReplicationMetrics: %v", err)
+		return nil, err
+	}
+
+	metrics, err := client.GetBucketReplicationMetrics(ctx, bucketName)
+	if err != nil {
+		log.Printf("[DEBUG] MinIO GetBucketReplicationMetrics API failed for bucket '%s': %v", bucketName, err)
+		return nil, err
+	}
+
+	converted := fromReplicationMetrics(metrics)
+	log.Printf("[DEBUG] Bucket '%s' has %d pending and %d failed replications", bucketName, converted.Pending.Count, converted.Failed.Count)
+	return converted, nil
+}
+
+// ResyncReplication starts replicating every existing object of a bucket to
+// a target again. The target's rules must replicate existing objects.
+func (s *MinIOService) ResyncReplication(ctx context.Context, bucketName, arn, username, password string) (*ReplicationResync, error) {
+	log.Printf("[DEBUG] MinIO service ResyncReplication called for bucket '%s' by user '%s' with target '%s'", bucketName, username, arn)
+
+	client, _, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in ResyncReplication: %v", err)
+		return nil, err
+	}
+
+	info, err := client.ResetBucketReplicationOnTarget(ctx, bucketName, 0, arn)
+	if err != nil {
+		log.Printf("[DEBUG] MinIO ResetBucketReplicationOnTarget API failed for bucket '%s': %v", bucketName, err)
+		return nil, err
+	}
+
+	resync := ReplicationResync{ARN: arn}
+	if resyncs := fromResyncTargets(info); len(resyncs) > 0 {
+		resync = resyncs[0]
+	}
+	log.Printf("[DEBUG] ResyncReplication started for bucket '%s' with reset ID '%s'", bucketName, resync.ResetID)
+	return &resync, nil
+}
+
+// GetReplicationResyncStatus returns the state of the latest resync of each
+// target of a bucket
+func (s *MinIOService) GetReplicationResyncStatus(ctx context.Context, bucketName, username, password string) ([]ReplicationResync, error) {
+	log.Printf("[DEBUG] MinIO service GetReplicationResyncStatus called for bucket '%s' by user '%s'", bucketName, username)
+
+	client, _, err := s.CreateClients(ctx, username, password)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create clients in GetReplicationResyncStatus: %v", err)
+		return nil, err
+	}
+
+	info, err := client.GetBucketReplicationResyncStatus(ctx, bucketName, "")
+	if err != nil {
+		if Classify(err).Code == "ReplicationConfigurationNotFoundError" {
+			return []ReplicationResync{}, nil
+		}
+		log.Printf("[DEBUG] MinIO GetBucketReplicationResyncStatus API failed for bucket '%s': %v", bucketName, err)
+		return nil, err
+	}
+
+	resyncs := fromResyncTargets(info)
+	log.Printf("[DEBUG] Bucket '%s' has %d resyncs", bucketName, len(resyncs))
+	return resyncs, nil
+}
@@ -8,7 +8,7 @@ import (
 
 // GetServerInfo retrieves MinIO server information
 func (s *MinIOService) GetServerInfo(ctx context.Context, username, password string) (map[string]interface{}, error) {
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		return nil, err
 	}
@@ -31,7 +31,7 @@ func (s *MinIOService) GetServerInfo(ctx context.Context, username, password str
 
 // GetMetrics retrieves basic server metrics and status
 func (s *MinIOService) GetMetrics(ctx context.Context, username, password string) (map[string]interface{}, error) {
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create admin client: %v", err)
 	}
@@ -39,7 +39,7 @@ func (s *MinIOService) GetMetrics(ctx context.Context, username, password string
 	// Get basic server information instead of service status
 	info, err := adminClient.ServerInfo(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get server info: %v", err)
+		return nil, fmt.Errorf("failed to get server info: %w", err)
 	}
 
 	return map[string]interface{}{
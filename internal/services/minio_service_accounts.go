@@ -11,7 +11,7 @@ import (
 func (s *MinIOService) GetUserServiceAccounts(ctx context.Context, username, password string) ([]map[string]interface{}, error) {
 	log.Printf("[DEBUG] MinIO service GetUserServiceAccounts called for user '%s'", username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in GetUserServiceAccounts: %v", err)
 		return nil, err
@@ -45,7 +45,7 @@ func (s *MinIOService) GetUserServiceAccounts(ctx context.Context, username, pas
 func (s *MinIOService) CreateServiceAccount(ctx context.Context, targetUser, name, description, username, password string) (map[string]interface{}, error) {
 	log.Printf("[DEBUG] MinIO service CreateServiceAccount called for target user '%s' by admin '%s', name: '%s'", targetUser, username, name)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in CreateServiceAccount: %v", err)
 		return nil, err
@@ -82,7 +82,7 @@ func (s *MinIOService) CreateServiceAccount(ctx context.Context, targetUser, nam
 func (s *MinIOService) DeleteServiceAccount(ctx context.Context, serviceAccountKey, username, password string) error {
 	log.Printf("[DEBUG] MinIO service DeleteServiceAccount called for service account '%s' by admin '%s'", serviceAccountKey, username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in DeleteServiceAccount: %v", err)
 		return err
@@ -96,6 +96,7 @@ func (s *MinIOService) DeleteServiceAccount(ctx context.Context, serviceAccountK
 	}
 
 	log.Printf("[DEBUG] DeleteServiceAccount successful for service account '%s'", serviceAccountKey)
+	s.EvictClients(serviceAccountKey)
 	return nil
 }
 
@@ -103,7 +104,7 @@ func (s *MinIOService) DeleteServiceAccount(ctx context.Context, serviceAccountK
 func (s *MinIOService) GetServiceAccountInfo(ctx context.Context, serviceAccountKey, username, password string) (map[string]interface{}, error) {
 	log.Printf("[DEBUG] MinIO service GetServiceAccountInfo called for service account '%s' by admin '%s'", serviceAccountKey, username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in GetServiceAccountInfo: %v", err)
 		return nil, err
@@ -132,7 +133,7 @@ func (s *MinIOService) GetServiceAccountInfo(ctx context.Context, serviceAccount
 func (s *MinIOService) ListServiceAccounts(ctx context.Context, targetUser, username, password string) ([]map[string]interface{}, error) {
 	log.Printf("[DEBUG] MinIO service ListServiceAccounts called for target user '%s' by admin '%s'", targetUser, username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in ListServiceAccounts: %v", err)
 		return nil, err
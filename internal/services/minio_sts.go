@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// STSCredentials represents temporary credentials issued by the MinIO STS API
+type STSCredentials struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Expiration   time.Time
+}
+
+// stsEndpoint returns the URL of the MinIO STS API
+func (s *MinIOService) stsEndpoint() string {
+	scheme := "http"
+	if s.config.MinIOUseSSL {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, s.config.GetMinIOEndpoint())
+}
+
+// AssumeRoleWithWebIdentity exchanges an OpenID Connect ID token for temporary
+// MinIO credentials. roleARN may be empty when MinIO uses claim-based policies.
+func (s *MinIOService) AssumeRoleWithWebIdentity(ctx context.Context, idToken, roleARN string, duration time.Duration) (*STSCredentials, error) {
+	log.Printf("[DEBUG] MinIO service AssumeRoleWithWebIdentity called (role ARN: '%s')", roleARN)
+
+	creds, err := credentials.NewSTSWebIdentity(s.stsEndpoint(), func() (*credentials.WebIdentityToken, error) {
+		return &credentials.WebIdentityToken{
+			Token:  idToken,
+			Expiry: int(duration.Seconds()),
+		}, nil
+	}, func(i *credentials.STSWebIdentity) {
+		i.RoleARN = roleARN
+	})
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create web identity provider: %v", err)
+		return nil, err
+	}
+
+	value, err := creds.Get()
+	if err != nil {
+		log.Printf("[DEBUG] MinIO AssumeRoleWithWebIdentity API failed: %v", err)
+		return nil, fmt.Errorf("failed to obtain STS credentials: %v", err)
+	}
+
+	log.Printf("[DEBUG] AssumeRoleWithWebIdentity successful, temporary credentials expire at %s", value.Expiration.Format(time.RFC3339))
+	return &STSCredentials{
+		AccessKey:    value.AccessKeyID,
+		SecretKey:    value.SecretAccessKey,
+		SessionToken: value.SessionToken,
+		Expiration:   value.Expiration,
+	}, nil
+}
+
+// AssumeRoleWithLDAPIdentity exchanges LDAP/Active Directory credentials for
+// temporary MinIO credentials
+func (s *MinIOService) AssumeRoleWithLDAPIdentity(ctx context.Context, ldapUsername, ldapPassword string, duration time.Duration) (*STSCredentials, error) {
+	log.Printf("[DEBUG] MinIO service AssumeRoleWithLDAPIdentity called for user '%s'", ldapUsername)
+
+	creds, err := credentials.NewLDAPIdentity(s.stsEndpoint(), ldapUsername, ldapPassword, credentials.LDAPIdentityExpiryOpt(duration))
+	if err != nil {
+		log.Printf("[DEBUG] Failed to create LDAP identity provider: %v", err)
+		return nil, err
+	}
+
+	value, err := creds.Get()
+	if err != nil {
+		log.Printf("[DEBUG] MinIO AssumeRoleWithLDAPIdentity API failed for user '%s': %v", ldapUsername, err)
+		return nil, fmt.Errorf("failed to obtain STS credentials: %v", err)
+	}
+
+	log.Printf("[DEBUG] AssumeRoleWithLDAPIdentity successful for user '%s', temporary credentials expire at %s", ldapUsername, value.Expiration.Format(time.RFC3339))
+	return &STSCredentials{
+		AccessKey:    value.AccessKeyID,
+		SecretKey:    value.SecretAccessKey,
+		SessionToken: value.SessionToken,
+		Expiration:   value.Expiration,
+	}, nil
+}
+
+// LDAPEnabled reports whether the LDAP login mode is offered
+func (s *MinIOService) LDAPEnabled() bool {
+	return s.config.LDAPEnabled
+}
+
+// LDAPSTSDuration returns the requested lifetime of LDAP STS credentials
+func (s *MinIOService) LDAPSTSDuration() time.Duration {
+	return time.Duration(s.config.LDAPSTSDuration) * time.Minute
+}
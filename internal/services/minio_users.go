@@ -12,7 +12,7 @@ import (
 func (s *MinIOService) ListUsers(ctx context.Context, username, password string) ([]UserInfo, error) {
 	log.Printf("[DEBUG] MinIO service ListUsers called for user '%s'", username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in ListUsers: %v", err)
 		return nil, err
@@ -49,7 +49,7 @@ func (s *MinIOService) ListUsers(ctx context.Context, username, password string)
 
 // CreateUser creates a new user
 func (s *MinIOService) CreateUser(ctx context.Context, accessKey, secretKey, username, password string) error {
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		return err
 	}
@@ -58,16 +58,20 @@ func (s *MinIOService) CreateUser(ctx context.Context, accessKey, secretKey, use
 
 // DeleteUser deletes an existing user
 func (s *MinIOService) DeleteUser(ctx context.Context, accessKey, username, password string) error {
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		return err
 	}
-	return adminClient.RemoveUser(ctx, accessKey)
+	if err := adminClient.RemoveUser(ctx, accessKey); err != nil {
+		return err
+	}
+	s.EvictClients(accessKey)
+	return nil
 }
 
 // SetUserPolicy sets the policy for a user
 func (s *MinIOService) SetUserPolicy(ctx context.Context, accessKey, policyName, username, password string) error {
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		return err
 	}
@@ -78,7 +82,7 @@ func (s *MinIOService) SetUserPolicy(ctx context.Context, accessKey, policyName,
 func (s *MinIOService) GetUser(ctx context.Context, accessKey, username, password string) (*UserInfo, error) {
 	log.Printf("[DEBUG] MinIO service GetUser called for user '%s' by admin '%s'", accessKey, username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in GetUser: %v", err)
 		return nil, err
@@ -94,7 +98,7 @@ func (s *MinIOService) GetUser(ctx context.Context, accessKey, username, passwor
 	user, exists := users[accessKey]
 	if !exists {
 		log.Printf("[DEBUG] User '%s' not found", accessKey)
-		return nil, fmt.Errorf("user '%s' not found", accessKey)
+		return nil, newError(ErrNotFound, "XMinioAdminNoSuchUser", fmt.Sprintf("user '%s' not found", accessKey))
 	}
 
 	userInfo := &UserInfo{
@@ -111,7 +115,7 @@ func (s *MinIOService) GetUser(ctx context.Context, accessKey, username, passwor
 func (s *MinIOService) UpdateUserCredentials(ctx context.Context, accessKey, newSecretKey, username, password string) error {
 	log.Printf("[DEBUG] MinIO service UpdateUserCredentials called for user '%s' by admin '%s'", accessKey, username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in UpdateUserCredentials: %v", err)
 		return err
@@ -125,6 +129,7 @@ func (s *MinIOService) UpdateUserCredentials(ctx context.Context, accessKey, new
 	}
 
 	log.Printf("[DEBUG] UpdateUserCredentials successful for user '%s'", accessKey)
+	s.EvictClients(accessKey)
 	return nil
 }
 
@@ -132,7 +137,7 @@ func (s *MinIOService) UpdateUserCredentials(ctx context.Context, accessKey, new
 func (s *MinIOService) SetUserStatus(ctx context.Context, accessKey string, enabled bool, username, password string) error {
 	log.Printf("[DEBUG] MinIO service SetUserStatus called for user '%s' (enabled=%t) by admin '%s'", accessKey, enabled, username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in SetUserStatus: %v", err)
 		return err
@@ -153,6 +158,7 @@ func (s *MinIOService) SetUserStatus(ctx context.Context, accessKey string, enab
 	}
 
 	log.Printf("[DEBUG] SetUserStatus successful for user '%s'", accessKey)
+	s.EvictClients(accessKey)
 	return nil
 }
 
@@ -160,7 +166,7 @@ func (s *MinIOService) SetUserStatus(ctx context.Context, accessKey string, enab
 func (s *MinIOService) GetUserPolicy(ctx context.Context, accessKey, username, password string) (string, error) {
 	log.Printf("[DEBUG] MinIO service GetUserPolicy called for user '%s' by admin '%s'", accessKey, username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in GetUserPolicy: %v", err)
 		return "", err
@@ -181,7 +187,7 @@ func (s *MinIOService) GetUserPolicy(ctx context.Context, accessKey, username, p
 func (s *MinIOService) GetUserDetails(ctx context.Context, accessKey, username, password string) (map[string]interface{}, error) {
 	log.Printf("[DEBUG] MinIO service GetUserDetails called for user '%s' by admin '%s'", accessKey, username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in GetUserDetails: %v", err)
 		return nil, err
@@ -212,7 +218,7 @@ func (s *MinIOService) GetUserDetails(ctx context.Context, accessKey, username,
 func (s *MinIOService) GetUserCredentials(ctx context.Context, accessKey, username, password string) (map[string]interface{}, error) {
 	log.Printf("[DEBUG] MinIO service GetUserCredentials called for user '%s' by admin '%s'", accessKey, username)
 
-	_, adminClient, err := s.CreateClients(username, password)
+	_, adminClient, err := s.CreateClients(ctx, username, password)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create clients in GetUserCredentials: %v", err)
 		return nil, err
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/minio/minio-go/v7/pkg/notification"
+)
+
+// NotificationEvents are the event types a bucket notification rule can
+// subscribe to, in the order the UI offers them
+var NotificationEvents = []string{
+	string(notification.ObjectCreatedAll),
+	string(notification.ObjectCreatedPut),
+	string(notification.ObjectCreatedPost),
+	string(notification.ObjectCreatedCopy),
+	string(notification.ObjectCreatedCompleteMultipartUpload),
+	string(notification.ObjectCreatedPutRetention),
+	string(notification.ObjectCreatedPutLegalHold),
+	string(notification.ObjectCreatedPutTagging),
+	string(notification.ObjectCreatedDeleteTagging),
+	string(notification.ObjectAccessedAll),
+	string(notification.ObjectAccessedGet),
+	string(notification.ObjectAccessedHead),
+	string(notification.ObjectAccessedGetRetention),
+	string(notification.ObjectAccessedGetLegalHold),
+	string(notification.ObjectRemovedAll),
+	string(notification.ObjectRemovedDelete),
+	string(notification.ObjectRemovedDeleteMarkerCreated),
+	string(notification.ILMDelMarkerExpirationDelete),
+	string(notification.ObjectReplicationAll),
+	string(notification.ObjectReplicationOperationCompletedReplication),
+	string(notification.ObjectReplicationOperationFailedReplication),
+	string(notification.ObjectReplicationOperationMissedThreshold),
+	string(notification.ObjectReplicationOperationNotTracked),
+	string(notification.ObjectReplicationOperationReplicatedAfterThreshold),
+	string(notification.ObjectTransitionAll),
+	string(notification.ObjectTransitionComplete),
+	string(notification.ObjectTransitionFailed),
+	string(notification.ObjectTransitionPost),
+	string(notification.ObjectTransitionCompleted),
+	string(notification.ObjectScannerAll),
+	string(notification.ObjectScannerManyVersions),
+	string(notification.ObjectScannerBigPrefix),
+}
+
+// NotificationRule is one rule of a bucket's notification configuration. It
+// sends the Events of objects whose keys start with Prefix and end with
+// Suffix to the target with ARN. Rules stored without an ID, such as those
+// mc adds, get one derived from their content.
+type NotificationRule struct {
+	ID     string   `json:"id"`
+	ARN    string   `json:"arn"`
+	Events []string `json:"events"`
+	Prefix string   `json:"prefix,omitempty"`
+	Suffix string   `json:"suffix,omitempty"`
+}
+
+// NotificationTarget is a notification target configured on the server,
+// such as a webhook or a Kafka topic
+type NotificationTarget struct {
+	ARN    string `json:"arn"`
+	Type   string `json:"type"` // e.g. webhook, kafka, amqp
+	ID     string `json:"id"`   // the target's name in the server config
+	Region string `json:"region,omitempty"`
+}
+
+// ParseNotificationTarget splits a notification target ARN such as
+// arn:minio:sqs::primary:webhook into its parts
+func ParseNotificationTarget(arn string) (NotificationTarget, error) {
+	parsed, err := notification.NewArnFromString(arn)
+	if err != nil {
+		return NotificationTarget{}, err
+	}
+	return NotificationTarget{ARN: arn, Type: parsed.Resource, ID: parsed.AccountID, Region: parsed.Region}, nil
+}
+
+// toNotificationConfig builds a minio-go notification configuration. MinIO
+// delivers to queue ARNs; topic and lambda ARNs are kept for other servers.
+func toNotificationConfig(rules []NotificationRule) notification.Configuration {
+	var config notification.Configuration
+	for _, rule := range rules {
+		entry := notification.Config{ID: rule.ID}
+		for _, event := range rule.Events {
+			entry.AddEvents(notification.EventType(event))
+		}
+		if rule.Prefix != "" {
+			entry.AddFilterPrefix(rule.Prefix)
+		}
+		if rule.Suffix != "" {
+			entry.AddFilterSuffix(rule.Suffix)
+		}
+
+		service := ""
+		if arn, err := notification.NewArnFromString(rule.ARN); err == nil {
+			service = arn.Service
+		}
+		switch service {
+		case "sns":
+			config.TopicConfigs = append(config.TopicConfigs, notification.TopicConfig{Config: entry, Topic: rule.ARN})
+		case "lambda":
+			config.LambdaConfigs = append(config.LambdaConfigs, notification.LambdaConfig{Config: entry, Lambda: rule.ARN})
+		default:
+			config.QueueConfigs = append(config.QueueConfigs, notification.QueueConfig{Config: entry, Queue: rule.ARN})
+		}
+	}
+	return config
+}
+
+// fromNotificationConfig converts a minio-go notification configuration,
+// queues first, keeping the order of the rules of each kind
+func fromNotificationConfig(config notification.Configuration) []NotificationRule {
+	rules := make([]NotificationRule, 0, len(config.QueueConfigs)+len(config.TopicConfigs)+len(config.LambdaConfigs))
+	for _, queue := range config.QueueConfigs {
+		rules = append(rules, fromNotificationEntry(queue.Config, queue.Queue))
+	}
+	for _, topic := range config.TopicConfigs {
+		rules = append(rules, fromNotificationEntry(topic.Config, topic.Topic))
+	}
+	for _, lambda := range config.LambdaConfigs {
+		rules = append(rules, fromNotificationEntry(lambda.Config, lambda.Lambda))
+	}
+	return rules
+}
+
+// fromNotificationEntry converts one rule of a notification configuration
+func fromNotificationEntry(entry notification.Config, arn string) NotificationRule {
+	rule := NotificationRule{ID: entry.ID, ARN: arn, Events: make([]string, 0, len(entry.Events))}
+	for _, event := range entry.Events {
+		rule.Events = append(rule.Events, string(event))
+	}
+	if entry.Filter != nil {
+		for _, filter := range entry.Filter.S3Key.FilterRules {
+			switch filter.Name {
+			case "prefix":
+				rule.Prefix = filter.Value
+			case "suffix":
+				rule.Suffix = filter.Value
+			}
+		}
+	}
+	if rule.ID == "" {
+		rule.ID = notificationRuleID(rule)
+	}
+	return rule
+}
+
+// notificationRuleID derives a stable ID for a rule stored without one from
+// its target, events and filters, which MinIO does not allow to repeat
+func notificationRuleID(rule NotificationRule) string {
+	sum := sha256.Sum256([]byte(strings.Join(append([]string{rule.ARN, rule.Prefix, rule.Suffix}, rule.Events...), "\x00")))
+	return hex.EncodeToString(sum[:10])
+}
+
+// notificationTargets converts the notification target ARNs a server
+// reports, sorted by type and ID. Unparsable ARNs are skipped.
+func notificationTargets(arns []string) []NotificationTarget {
+	targets := make([]NotificationTarget, 0, len(arns))
+	for _, arn := range arns {
+		if target, err := ParseNotificationTarget(arn); err == nil {
+			targets = append(targets, target)
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].Type != targets[j].Type {
+			return targets[i].Type < targets[j].Type
+		}
+		return targets[i].ID < targets[j].ID
+	})
+	return targets
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/minio/madmin-go/v3"
+	"github.com/minio/minio-go/v7/pkg/replication"
+)
+
+// ReplicationTarget is a remote bucket that a bucket replicates to. MinIO
+// assigns the ARN when the target is added; replication rules refer to the
+// target by it.
+type ReplicationTarget struct {
+	ARN          string `json:"arn"`
+	Endpoint     string `json:"endpoint"` // host[:port]
+	Secure       bool   `json:"secure"`
+	TargetBucket string `json:"target_bucket"`
+	Region       string `json:"region,omitempty"`
+	AccessKey    string `json:"access_key"`
+	// SecretKey is only sent when adding a target; MinIO never returns it
+	SecretKey string `json:"-"`
+	// Synchronous makes uploads wait until the object is replicated
+	Synchronous bool `json:"synchronous"`
+	// BandwidthLimit is in bytes per second, zero for no limit
+	BandwidthLimit     int64      `json:"bandwidth_limit,omitempty"`
+	HealthCheckSeconds int        `json:"health_check_seconds,omitempty"`
+	DisableProxy       bool       `json:"disable_proxy"`
+	Online             bool       `json:"online"`
+	LastOnline         *time.Time `json:"last_online,omitempty"`
+}
+
+// ReplicationRule is one rule of a bucket's replication configuration. It
+// replicates the objects under Prefix that carry all Tags to the target
+// with TargetARN; rules with a higher Priority win when several match.
+type ReplicationRule struct {
+	ID       string `json:"id"`
+	Enabled  bool   `json:"enabled"`
+	Priority int    `json:"priority"`
+
+	Prefix string            `json:"prefix,omitempty"`
+	Tags   map[string]string `json:"tags,omitempty"`
+
+	TargetARN    string `json:"target_arn"`
+	StorageClass string `json:"storage_class,omitempty"`
+
+	DeleteMarkers bool `json:"delete_markers"`
+	// Deletes replicates permanent deletes of versions (MinIO extension)
+	Deletes bool `json:"deletes"`
+	// ExistingObjects replicates objects written before the rule, which a
+	// resync requires
+	ExistingObjects bool `json:"existing_objects"`
+	// MetadataSync replicates metadata changes made on the target back
+	MetadataSync bool `json:"metadata_sync"`
+}
+
+// ReplicationStat is a number of objects and their size in bytes
+type ReplicationStat struct {
+	Count int64 `json:"count"`
+	Bytes int64 `json:"bytes"`
+}
+
+// ReplicationTargetMetrics are the replication metrics of one target
+type ReplicationTargetMetrics struct {
+	ARN        string          `json:"arn"`
+	Pending    ReplicationStat `json:"pending"`
+	Failed     ReplicationStat `json:"failed"`
+	Replicated ReplicationStat `json:"replicated"`
+	// Bandwidth in bytes per second
+	BandwidthLimit   int64   `json:"bandwidth_limit"`
+	CurrentBandwidth float64 `json:"current_bandwidth"`
+}
+
+// ReplicationMetrics are the replication metrics of a bucket. Replica
+// counts the objects replicated into the bucket from elsewhere.
+type ReplicationMetrics struct {
+	Pending    ReplicationStat            `json:"pending"`
+	Failed     ReplicationStat            `json:"failed"`
+	Replicated ReplicationStat            `json:"replicated"`
+	Replica    ReplicationStat            `json:"replica"`
+	Targets    []ReplicationTargetMetrics `json:"targets"`
+}
+
+// ReplicationResync is the state of a resync, which replicates every
+// existing object of a bucket to a target again
+type ReplicationResync struct {
+	ARN        string          `json:"arn"`
+	ResetID    string          `json:"reset_id"`
+	Status     string          `json:"status"`
+	StartTime  *time.Time      `json:"start_time,omitempty"`
+	EndTime    *time.Time      `json:"end_time,omitempty"`
+	Replicated ReplicationStat `json:"replicated"`
+	Failed     ReplicationStat `json:"failed"`
+}
+
+// replicationStatus converts a flag to a replication status
+func replicationStatus(enabled bool) replication.Status {
+	if enabled {
+		return replication.Enabled
+	}
+	return replication.Disabled
+}
+
+// optionalTime returns nil for the zero time
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// toBucketTarget converts a target to the madmin one for bucketName
+func toBucketTarget(bucketName string, target ReplicationTarget) *madmin.BucketTarget {
+	return &madmin.BucketTarget{
+		SourceBucket:        bucketName,
+		Endpoint:            target.Endpoint,
+		Credentials:         &madmin.Credentials{AccessKey: target.AccessKey, SecretKey: target.SecretKey},
+		TargetBucket:        target.TargetBucket,
+		Secure:              target.Secure,
+		Path:                "auto",
+		API:                 "s3v4",
+		Type:                madmin.ReplicationService,
+		Region:              target.Region,
+		BandwidthLimit:      target.BandwidthLimit,
+		ReplicationSync:     target.Synchronous,
+		HealthCheckDuration: time.Duration(target.HealthCheckSeconds) * time.Second,
+		DisableProxy:        target.DisableProxy,
+	}
+}
+
+// fromBucketTarget converts a madmin target
+func fromBucketTarget(target madmin.BucketTarget) ReplicationTarget {
+	converted := ReplicationTarget{
+		ARN:                target.Arn,
+		Endpoint:           target.Endpoint,
+		Secure:             target.Secure,
+		TargetBucket:       target.TargetBucket,
+		Region:             target.Region,
+		Synchronous:        target.ReplicationSync,
+		BandwidthLimit:     target.BandwidthLimit,
+		HealthCheckSeconds: int(target.HealthCheckDuration / time.Second),
+		DisableProxy:       target.DisableProxy,
+		Online:             target.Online,
+		LastOnline:         optionalTime(target.LastOnline),
+	}
+	if target.Credentials != nil {
+		converted.AccessKey = target.Credentials.AccessKey
+	}
+	return converted
+}
+
+// toReplicationConfig converts rules to the minio-go configuration
+func toReplicationConfig(rules []ReplicationRule) replication.Config {
+	config := replication.Config{}
+	for _, rule := range rules {
+		converted := replication.Rule{
+			ID:                      rule.ID,
+			Status:                  replicationStatus(rule.Enabled),
+			Priority:                rule.Priority,
+			DeleteMarkerReplication: replication.DeleteMarkerReplication{Status: replicationStatus(rule.DeleteMarkers)},
+			DeleteReplication:       replication.DeleteReplication{Status: replicationStatus(rule.Deletes)},
+			Destination:             replication.Destination{Bucket: rule.TargetARN, StorageClass: rule.StorageClass},
+			SourceSelectionCriteria: replication.SourceSelectionCriteria{
+				ReplicaModifications: replication.ReplicaModifications{Status: replicationStatus(rule.MetadataSync)},
+			},
+			ExistingObjectReplication: replication.ExistingObjectReplication{Status: replicationStatus(rule.ExistingObjects)},
+		}
+
+		// A prefix and tags, or several tags, must be combined with And
+		tags := make([]replication.Tag, 0, len(rule.Tags))
+		for key, value := range rule.Tags {
+			tags = append(tags, replication.Tag{Key: key, Value: value})
+		}
+		sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+		switch {
+		case len(tags) > 1 || (len(tags) == 1 && rule.Prefix != ""):
+			converted.Filter.And = replication.And{Prefix: rule.Prefix, Tags: tags}
+		case len(tags) == 1:
+			converted.Filter.Tag = tags[0]
+		default:
+			converted.Filter.Prefix = rule.Prefix
+		}
+		config.Rules = append(config.Rules, converted)
+	}
+	return config
+}
+
+// fromReplicationConfig converts a minio-go configuration to rules, sorted
+// by descending priority
+func fromReplicationConfig(config replication.Config) []ReplicationRule {
+	rules := make([]ReplicationRule, 0, len(config.Rules))
+	for _, rule := range config.Rules {
+		converted := ReplicationRule{
+			ID:              rule.ID,
+			Enabled:         rule.Status == replication.Enabled,
+			Priority:        rule.Priority,
+			Prefix:          rule.Prefix(),
+			TargetARN:       rule.Destination.Bucket,
+			StorageClass:    rule.Destination.StorageClass,
+			DeleteMarkers:   rule.DeleteMarkerReplication.Status == replication.Enabled,
+			Deletes:         rule.DeleteReplication.Status == replication.Enabled,
+			ExistingObjects: rule.ExistingObjectReplication.Status == replication.Enabled,
+			MetadataSync:    rule.SourceSelectionCriteria.ReplicaModifications.Status == replication.Enabled,
+		}
+		// Configurations with the legacy role keep the target ARN there
+		if config.Role != "" && !isReplicationARN(converted.TargetARN) {
+			converted.TargetARN = config.Role
+		}
+
+		tags := []replication.Tag{rule.Filter.Tag}
+		if len(rule.Filter.And.Tags) > 0 {
+			tags = rule.Filter.And.Tags
+		}
+		for _, tag := range tags {
+			if tag.IsEmpty() {
+				continue
+			}
+			if converted.Tags == nil {
+				converted.Tags = make(map[string]string)
+			}
+			converted.Tags[tag.Key] = tag.Value
+		}
+		rules = append(rules, converted)
+	}
+	sortReplicationRules(rules)
+	return rules
+}
+
+// sortReplicationRules sorts rules by descending priority, then by ID
+func sortReplicationRules(rules []ReplicationRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		return rules[i].ID < rules[j].ID
+	})
+}
+
+// isReplicationARN reports whether arn names a replication target
+func isReplicationARN(arn string) bool {
+	parsed, err := madmin.ParseARN(arn)
+	return err == nil && parsed.Type == madmin.ReplicationService
+}
+
+// fromReplicationMetrics converts minio-go metrics. Servers released since
+// 2023 report queued objects and error totals instead of the deprecated
+// pending and failed counters, so those are used when the counters are zero.
+func fromReplicationMetrics(metrics replication.Metrics) *ReplicationMetrics {
+	converted := &ReplicationMetrics{
+		Pending:    ReplicationStat{Count: int64(metrics.PendingCount), Bytes: int64(metrics.PendingSize)},
+		Failed:     ReplicationStat{Count: int64(metrics.FailedCount), Bytes: int64(metrics.FailedSize)},
+		Replicated: ReplicationStat{Count: metrics.ReplicatedCount, Bytes: int64(metrics.ReplicatedSize)},
+		Replica:    ReplicationStat{Count: metrics.ReplicaCount, Bytes: int64(metrics.ReplicaSize)},
+		Targets:    []ReplicationTargetMetrics{},
+	}
+	if converted.Pending.Count == 0 {
+		queued := metrics.QStats.Curr
+		converted.Pending = ReplicationStat{Count: int64(queued.Count), Bytes: int64(queued.Bytes)}
+	}
+	if converted.Failed.Count == 0 {
+		converted.Failed = ReplicationStat{Count: int64(metrics.Errors.Totals.Count), Bytes: metrics.Errors.Totals.Bytes}
+	}
+
+	for arn, stats := range metrics.Stats {
+		target := ReplicationTargetMetrics{
+			ARN:              arn,
+			Pending:          ReplicationStat{Count: int64(stats.PendingCount), Bytes: int64(stats.PendingSize)},
+			Failed:           ReplicationStat{Count: int64(stats.FailedCount), Bytes: int64(stats.FailedSize)},
+			Replicated:       ReplicationStat{Count: int64(stats.ReplicatedCount), Bytes: int64(stats.ReplicatedSize)},
+			BandwidthLimit:   stats.BandWidthLimitInBytesPerSecond,
+			CurrentBandwidth: stats.CurrentBandwidthInBytesPerSecond,
+		}
+		if target.Failed.Count == 0 {
+			target.Failed = ReplicationStat{Count: int64(stats.Failed.Totals.Count), Bytes: stats.Failed.Totals.Bytes}
+		}
+		converted.Targets = append(converted.Targets, target)
+	}
+	sort.Slice(converted.Targets, func(i, j int) bool { return converted.Targets[i].ARN < converted.Targets[j].ARN })
+	return converted
+}
+
+// fromResyncTargets converts minio-go resync states
+func fromResyncTargets(info replication.ResyncTargetsInfo) []ReplicationResync {
+	resyncs := make([]ReplicationResync, 0, len(info.Targets))
+	for _, target := range info.Targets {
+		resyncs = append(resyncs, ReplicationResync{
+			ARN:        target.Arn,
+			ResetID:    target.ResetID,
+			Status:     target.ResyncStatus,
+			StartTime:  optionalTime(target.StartTime),
+			EndTime:    optionalTime(target.EndTime),
+			Replicated: ReplicationStat{Count: target.ReplicatedCount, Bytes: target.ReplicatedSize},
+			Failed:     ReplicationStat{Count: target.FailedCount, Bytes: target.FailedSize},
+		})
+	}
+	return resyncs
+}
+
+// ReplicationARN is the ARN MinIO gives a replication target with the
+// given ID for targetBucket
+func ReplicationARN(id, targetBucket string) string {
+	return madmin.ARN{Type: madmin.ReplicationService, ID: id, Bucket: targetBucket}.String()
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"minio-admin-panel/internal/config"
+)
+
+// Operation classifies MinIO calls by how long they may take
+type Operation string
+
+const (
+	OpRead  Operation = "read"  // lookups made for page loads and GET requests
+	OpWrite Operation = "write" // changes to buckets, users, groups and policies
+	OpList  Operation = "list"  // listings that compute statistics for every bucket
+)
+
+// Timeouts holds the deadline of each kind of operation and the limits for
+// computing the statistics of a single bucket
+type Timeouts struct {
+	Read       time.Duration
+	Write      time.Duration
+	List       time.Duration
+	QuickStats time.Duration // per bucket on the dashboard
+}
+
+// NewTimeouts reads the MinIO timeouts from the configuration
+func NewTimeouts(cfg *config.Config) Timeouts {
+	return Timeouts{
+		Read:       time.Duration(cfg.MinIOReadTimeout) * time.Second,
+		Write:      time.Duration(cfg.MinIOWriteTimeout) * time.Second,
+		List:       time.Duration(cfg.MinIOListTimeout) * time.Second,
+		QuickStats: time.Duration(cfg.MinIOQuickStatsTimeout) * time.Second,
+	}
+}
+
+// For returns the deadline of an operation; zero means none
+func (t Timeouts) For(op Operation) time.Duration {
+	switch op {
+	case OpWrite:
+		return t.Write
+	case OpList:
+		return t.List
+	default:
+		return t.Read
+	}
+}
+
+// IsTimeout reports whether err means MinIO did not answer in time
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,123 @@
+package session
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FileStore persists each session as a JSON file inside a directory so that
+// sessions survive restarts. Credentials are already encrypted by the Manager.
+type FileStore struct {
+	mu  sync.RWMutex
+	dir string
+}
+
+// NewFileStore creates a file-backed session store rooted at dir
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %v", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Save writes the session to disk atomically
+func (s *FileStore) Save(sess *Session) error {
+	path, err := s.path(sess.ID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load reads the session with the given ID from disk
+func (s *FileStore) Load(id string) (*Session, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	s.mu.RLock()
+	data, err := os.ReadFile(path)
+	s.mu.RUnlock()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	var sess Session
+	if err := json.Unmarshal(data, &sess); err != nil {
+		return nil, fmt.Errorf("corrupt session file: %v", err)
+	}
+	return &sess, nil
+}
+
+// Delete removes the session file
+func (s *FileStore) Delete(id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List reads all sessions from disk, skipping unreadable files
+func (s *FileStore) List() ([]*Session, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Session
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		sess, err := s.Load(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			continue
+		}
+		result = append(result, sess)
+	}
+	return result, nil
+}
+
+// path maps a session ID to its file, rejecting anything that is not a
+// generated hex identifier so IDs can never escape the store directory
+func (s *FileStore) path(id string) (string, error) {
+	if id == "" {
+		return "", ErrNotFound
+	}
+	if _, err := hex.DecodeString(id); err != nil {
+		return "", ErrNotFound
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
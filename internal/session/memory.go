@@ -0,0 +1,73 @@
+package session
+
+import "sync"
+
+// MemoryStore keeps sessions in process memory. Sessions are lost on restart.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*Session
+}
+
+// NewMemoryStore creates an empty in-memory session store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sessions: make(map[string]*Session),
+	}
+}
+
+// Save stores a copy of the session
+func (s *MemoryStore) Save(sess *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[sess.ID] = copySession(sess)
+	return nil
+}
+
+// Load returns a copy of the session with the given ID
+func (s *MemoryStore) Load(id string) (*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sess, exists := s.sessions[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	return copySession(sess), nil
+}
+
+// Delete removes the session with the given ID
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, id)
+	return nil
+}
+
+// List returns copies of all stored sessions
+func (s *MemoryStore) List() ([]*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*Session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		result = append(result, copySession(sess))
+	}
+	return result, nil
+}
+
+// copySession copies a session together with its connections, so that
+// callers changing a copy do not change the stored session
+func copySession(sess *Session) *Session {
+	copied := *sess
+	if sess.Connections != nil {
+		copied.Connections = make(map[string]*Connection, len(sess.Connections))
+		for cluster, conn := range sess.Connections {
+			connCopy := *conn
+			copied.Connections[cluster] = &connCopy
+		}
+	}
+	return &copied
+}
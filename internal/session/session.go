@@ -0,0 +1,593 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when a session does not exist or has expired
+var ErrNotFound = errors.New("session not found")
+
+// ErrRevoked is returned for a session that an administrator has ended
+var ErrRevoked = errors.New("session revoked")
+
+// refreshWindow is how long before expiry temporary credentials are renewed
+const refreshWindow = 5 * time.Minute
+
+// sessionLocks is the number of mutexes that updates to sessions are spread over
+const sessionLocks = 64
+
+// Credentials holds the MinIO credentials backing a panel session. SessionToken
+// and Expiration are only set for temporary STS credentials. LDAPUsername and
+// LDAPPassword are kept for LDAP logins so the STS credentials can be renewed.
+// Cluster names the MinIO cluster they belong to, empty for the default one.
+type Credentials struct {
+	Cluster      string    `json:"cluster,omitempty"`
+	AccessKey    string    `json:"access_key"`
+	SecretKey    string    `json:"secret_key"`
+	SessionToken string    `json:"session_token,omitempty"`
+	Expiration   time.Time `json:"expiration"`
+	LDAPUsername string    `json:"ldap_username,omitempty"`
+	LDAPPassword string    `json:"ldap_password,omitempty"`
+}
+
+// Refreshable reports whether the temporary credentials can be renewed
+func (c Credentials) Refreshable() bool {
+	return c.LDAPUsername != "" && !c.Expiration.IsZero()
+}
+
+// Second-factor states of a session that has passed the password check
+const (
+	MFAPending = "pending" // a TOTP or recovery code must be entered
+	MFAEnroll  = "enroll"  // the user's role requires TOTP, which is not set up yet
+)
+
+// Refresher obtains new temporary credentials for refreshable credentials
+type Refresher func(creds Credentials) (Credentials, error)
+
+// Evictor is told when the credentials of a session stop being used, so that
+// anything cached for them can be released
+type Evictor func(creds Credentials)
+
+// Connection holds the credentials and permissions a session uses on a
+// cluster other than the one it logged in to
+type Connection struct {
+	AccessKey            string          `json:"access_key"`
+	PolicyName           string          `json:"policy_name"`
+	Permissions          map[string]bool `json:"permissions"`
+	Policy               json.RawMessage `json:"policy,omitempty"`
+	EncryptedCredentials []byte          `json:"encrypted_credentials"`
+	ConnectedAt          time.Time       `json:"connected_at"`
+}
+
+// Session represents a logged-in panel user. The MinIO credentials are only
+// ever kept in encrypted form inside EncryptedCredentials. Cluster names the
+// cluster the user logged in to; Connections holds the other clusters the user
+// has connected to since, by name.
+type Session struct {
+	ID                   string                 `json:"id"`
+	Username             string                 `json:"username"`
+	Cluster              string                 `json:"cluster,omitempty"`
+	Connections          map[string]*Connection `json:"connections,omitempty"`
+	PolicyName           string                 `json:"policy_name"`
+	Permissions          map[string]bool        `json:"permissions"`
+	Policy               json.RawMessage        `json:"policy,omitempty"`
+	Role                 string                 `json:"role,omitempty"`
+	MFAState             string                 `json:"mfa_state,omitempty"`
+	EncryptedCredentials []byte                 `json:"encrypted_credentials"`
+	ClientIP             string                 `json:"client_ip,omitempty"`
+	UserAgent            string                 `json:"user_agent,omitempty"`
+	CreatedAt            time.Time              `json:"created_at"`
+	ExpiresAt            time.Time              `json:"expires_at"`          // idle expiry, extended while the session is used
+	AbsoluteExpiresAt    time.Time              `json:"absolute_expires_at"` // hard limit that activity cannot extend
+	LastSeenAt           time.Time              `json:"last_seen_at"`
+	RevokedAt            time.Time              `json:"revoked_at,omitempty"`
+	RevokedBy            string                 `json:"revoked_by,omitempty"`
+}
+
+// Revoked reports whether the session has been ended by an administrator
+func (s *Session) Revoked() bool {
+	return !s.RevokedAt.IsZero()
+}
+
+// Expired reports whether the session is past its idle or absolute expiry time
+func (s *Session) Expired() bool {
+	now := time.Now()
+	return (!s.ExpiresAt.IsZero() && now.After(s.ExpiresAt)) ||
+		(!s.AbsoluteExpiresAt.IsZero() && now.After(s.AbsoluteExpiresAt))
+}
+
+// Store persists sessions. Implementations must be safe for concurrent use.
+type Store interface {
+	Save(sess *Session) error
+	Load(id string) (*Session, error)
+	Delete(id string) error
+	List() ([]*Session, error)
+}
+
+// Manager creates and resolves sessions on top of a Store, encrypting
+// credentials before they reach the store. Sessions expire after idleTimeout
+// without activity and after maxLifetime regardless of activity.
+type Manager struct {
+	store       Store
+	cipher      *Cipher
+	idleTimeout time.Duration
+	maxLifetime time.Duration
+	refresher   Refresher
+	evictor     Evictor
+	refreshMu   sync.Mutex
+	locks       [sessionLocks]sync.Mutex
+}
+
+// NewManager creates a session manager. A zero maxLifetime disables the
+// absolute timeout.
+func NewManager(store Store, cipher *Cipher, idleTimeout, maxLifetime time.Duration) *Manager {
+	return &Manager{
+		store:       store,
+		cipher:      cipher,
+		idleTimeout: idleTimeout,
+		maxLifetime: maxLifetime,
+	}
+}
+
+// SetRefresher installs the function used to renew expiring temporary credentials
+func (m *Manager) SetRefresher(refresher Refresher) {
+	m.refresher = refresher
+}
+
+// SetEvictor installs the function called when session credentials are retired
+func (m *Manager) SetEvictor(evictor Evictor) {
+	m.evictor = evictor
+}
+
+// Create starts a new session for the given user and credentials
+func (m *Manager) Create(sess *Session, creds Credentials) (*Session, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session ID: %v", err)
+	}
+
+	encrypted, err := m.encryptCredentials(creds)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sess.ID = id
+	sess.EncryptedCredentials = encrypted
+	sess.CreatedAt = now
+	sess.LastSeenAt = now
+	if m.maxLifetime > 0 {
+		sess.AbsoluteExpiresAt = now.Add(m.maxLifetime)
+	}
+
+	// A session cannot outlive temporary credentials that cannot be renewed
+	if !creds.Refreshable() && !creds.Expiration.IsZero() &&
+		(sess.AbsoluteExpiresAt.IsZero() || creds.Expiration.Before(sess.AbsoluteExpiresAt)) {
+		sess.AbsoluteExpiresAt = creds.Expiration
+	}
+	sess.ExpiresAt = m.idleExpiry(now, sess)
+
+	if err := m.store.Save(sess); err != nil {
+		return nil, fmt.Errorf("failed to save session: %v", err)
+	}
+
+	log.Printf("[DEBUG] Created session for user '%s' (expires %s)", sess.Username, sess.ExpiresAt.Format(time.RFC3339))
+	return sess, nil
+}
+
+// Get returns an active session by ID, removing it if it has expired.
+// Revoked sessions are kept until they expire so their users can be told why
+// they were signed out; for them ErrRevoked is returned.
+func (m *Manager) Get(id string) (*Session, error) {
+	sess, err := m.store.Load(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if sess.Expired() {
+		log.Printf("[DEBUG] Session for user '%s' has expired", sess.Username)
+		_ = m.store.Delete(id)
+		return nil, ErrNotFound
+	}
+
+	if sess.Revoked() {
+		log.Printf("[DEBUG] Session for user '%s' was revoked by '%s'", sess.Username, sess.RevokedBy)
+		return nil, ErrRevoked
+	}
+
+	return sess, nil
+}
+
+// Touch records activity on a session and extends its idle expiry, up to its
+// absolute expiry. The session is saved at most once a minute. It reports
+// whether the expiry changed.
+func (m *Manager) Touch(sess *Session) (bool, error) {
+	now := time.Now()
+	if now.Sub(sess.LastSeenAt) < time.Minute {
+		return false, nil
+	}
+
+	// Re-read the session so a concurrent revocation is not overwritten
+	defer m.lock(sess.ID)()
+	current, err := m.Get(sess.ID)
+	if err != nil {
+		return false, err
+	}
+
+	expiry := m.idleExpiry(now, current)
+	extended := expiry.After(current.ExpiresAt)
+	current.ExpiresAt = expiry
+	current.LastSeenAt = now
+	if err := m.store.Save(current); err != nil {
+		return false, fmt.Errorf("failed to save session: %v", err)
+	}
+
+	*sess = *current
+	return extended, nil
+}
+
+// lock serializes changes to the session with the given ID, so that a change
+// read from the store and saved back cannot undo one made meanwhile, such as
+// a revocation. It returns the function that releases the lock.
+func (m *Manager) lock(id string) func() {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	mu := &m.locks[h.Sum32()%sessionLocks]
+	mu.Lock()
+	return mu.Unlock
+}
+
+// idleExpiry returns the idle expiry for activity at now
+func (m *Manager) idleExpiry(now time.Time, sess *Session) time.Time {
+	expiry := now.Add(m.idleTimeout)
+	if !sess.AbsoluteExpiresAt.IsZero() && sess.AbsoluteExpiresAt.Before(expiry) {
+		expiry = sess.AbsoluteExpiresAt
+	}
+	return expiry
+}
+
+// Credentials decrypts the MinIO credentials stored in a session. Refreshable
+// temporary credentials that are about to expire are renewed and saved first.
+func (m *Manager) Credentials(sess *Session) (Credentials, error) {
+	creds, err := m.decryptCredentials(sess)
+	if err != nil {
+		return creds, err
+	}
+
+	if !creds.Refreshable() || m.refresher == nil || time.Until(creds.Expiration) > refreshWindow {
+		return creds, nil
+	}
+
+	return m.refresh(sess)
+}
+
+// refresh renews the temporary credentials of a session. Concurrent requests
+// are serialized and re-read the session so credentials are renewed only once.
+func (m *Manager) refresh(sess *Session) (Credentials, error) {
+	m.refreshMu.Lock()
+	defer m.refreshMu.Unlock()
+	defer m.lock(sess.ID)()
+
+	current, err := m.Get(sess.ID)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	creds, err := m.decryptCredentials(current)
+	if err != nil {
+		return creds, err
+	}
+	if time.Until(creds.Expiration) > refreshWindow {
+		*sess = *current
+		return creds, nil
+	}
+
+	log.Printf("[DEBUG] Refreshing temporary credentials for user '%s' (expire %s)", sess.Username, creds.Expiration.Format(time.RFC3339))
+	renewed, err := m.refresher(creds)
+	if err != nil {
+		if time.Now().Before(creds.Expiration) {
+			// Keep using the old credentials until they actually expire
+			log.Printf("[DEBUG] Credential refresh failed for user '%s', retrying on next request: %v", sess.Username, err)
+			return creds, nil
+		}
+		return creds, fmt.Errorf("failed to refresh credentials: %v", err)
+	}
+
+	encrypted, err := m.encryptCredentials(renewed)
+	if err != nil {
+		return creds, err
+	}
+	current.EncryptedCredentials = encrypted
+
+	if err := m.store.Save(current); err != nil {
+		return creds, fmt.Errorf("failed to save session: %v", err)
+	}
+
+	m.evictCredentials(creds)
+	*sess = *current
+	return renewed, nil
+}
+
+// encryptCredentials encodes and encrypts credentials for storage
+func (m *Manager) encryptCredentials(creds Credentials) ([]byte, error) {
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode credentials: %v", err)
+	}
+
+	encrypted, err := m.cipher.Encrypt(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt credentials: %v", err)
+	}
+
+	return encrypted, nil
+}
+
+// decryptCredentials decrypts and decodes the credentials of a session
+func (m *Manager) decryptCredentials(sess *Session) (Credentials, error) {
+	return m.decrypt(sess.EncryptedCredentials)
+}
+
+// decrypt decrypts and decodes stored credentials
+func (m *Manager) decrypt(encrypted []byte) (Credentials, error) {
+	var creds Credentials
+
+	plaintext, err := m.cipher.Decrypt(encrypted)
+	if err != nil {
+		return creds, fmt.Errorf("failed to decrypt credentials: %v", err)
+	}
+
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return creds, fmt.Errorf("failed to decode credentials: %v", err)
+	}
+
+	return creds, nil
+}
+
+// Connect stores credentials for another cluster in a session, replacing any
+// it had for that cluster
+func (m *Manager) Connect(sess *Session, cluster string, conn *Connection, creds Credentials) error {
+	encrypted, err := m.encryptCredentials(creds)
+	if err != nil {
+		return err
+	}
+
+	// Re-read the session so a concurrent revocation is not overwritten
+	defer m.lock(sess.ID)()
+	current, err := m.Get(sess.ID)
+	if err != nil {
+		return err
+	}
+
+	previous := current.Connections[cluster]
+	conn.EncryptedCredentials = encrypted
+	conn.ConnectedAt = time.Now()
+	connections := copyConnections(current.Connections)
+	connections[cluster] = conn
+	current.Connections = connections
+	if err := m.store.Save(current); err != nil {
+		return fmt.Errorf("failed to save session: %v", err)
+	}
+
+	if previous != nil {
+		m.evictConnection(current, previous)
+	}
+	*sess = *current
+	log.Printf("[DEBUG] Session of user '%s' connected to cluster '%s' as '%s'", sess.Username, cluster, conn.AccessKey)
+	return nil
+}
+
+// Disconnect removes the credentials a session holds for another cluster
+func (m *Manager) Disconnect(sess *Session, cluster string) error {
+	defer m.lock(sess.ID)()
+	current, err := m.Get(sess.ID)
+	if err != nil {
+		return err
+	}
+
+	conn, ok := current.Connections[cluster]
+	if !ok {
+		return ErrNotFound
+	}
+	connections := copyConnections(current.Connections)
+	delete(connections, cluster)
+	current.Connections = connections
+	if err := m.store.Save(current); err != nil {
+		return fmt.Errorf("failed to save session: %v", err)
+	}
+
+	m.evictConnection(current, conn)
+	*sess = *current
+	log.Printf("[DEBUG] Session of user '%s' disconnected from cluster '%s'", sess.Username, cluster)
+	return nil
+}
+
+// copyConnections returns a new map with the connections of a session, so
+// that changing it does not change a session another request holds
+func copyConnections(connections map[string]*Connection) map[string]*Connection {
+	copied := make(map[string]*Connection, len(connections)+1)
+	for cluster, conn := range connections {
+		copied[cluster] = conn
+	}
+	return copied
+}
+
+// ConnectionCredentials decrypts the credentials a session holds for another
+// cluster. ErrNotFound is returned if it has not connected to the cluster.
+func (m *Manager) ConnectionCredentials(sess *Session, cluster string) (Credentials, error) {
+	conn, ok := sess.Connections[cluster]
+	if !ok {
+		return Credentials{}, ErrNotFound
+	}
+	return m.decrypt(conn.EncryptedCredentials)
+}
+
+// CompleteMFA marks the second factor of a session as verified. It fails if
+// the session has expired or been revoked meanwhile.
+func (m *Manager) CompleteMFA(sess *Session) error {
+	// Re-read the session so a concurrent revocation is not overwritten
+	defer m.lock(sess.ID)()
+	current, err := m.Get(sess.ID)
+	if err != nil {
+		return err
+	}
+
+	current.MFAState = ""
+	if err := m.store.Save(current); err != nil {
+		return fmt.Errorf("failed to save session: %v", err)
+	}
+
+	*sess = *current
+	log.Printf("[DEBUG] Second factor verified for user '%s'", sess.Username)
+	return nil
+}
+
+// Delete ends a session
+func (m *Manager) Delete(id string) error {
+	defer m.lock(id)()
+	if sess, err := m.store.Load(id); err == nil {
+		m.evict(sess)
+	}
+	return m.store.Delete(id)
+}
+
+// List returns the active sessions, most recently used first. Sessions that
+// are still waiting for their second factor are included.
+func (m *Manager) List() ([]*Session, error) {
+	sessions, err := m.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	active := make([]*Session, 0, len(sessions))
+	for _, sess := range sessions {
+		if !sess.Expired() && !sess.Revoked() {
+			active = append(active, sess)
+		}
+	}
+
+	sort.Slice(active, func(i, j int) bool { return active[i].LastSeenAt.After(active[j].LastSeenAt) })
+	return active, nil
+}
+
+// Revoke ends a session on behalf of an administrator. The session is kept,
+// marked as revoked, until it would have expired.
+func (m *Manager) Revoke(id, revokedBy string) (*Session, error) {
+	defer m.lock(id)()
+	sess, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	sess.RevokedAt = time.Now()
+	sess.RevokedBy = revokedBy
+	if err := m.store.Save(sess); err != nil {
+		return nil, fmt.Errorf("failed to save session: %v", err)
+	}
+
+	m.evict(sess)
+	log.Printf("[DEBUG] Session of user '%s' revoked by '%s'", sess.Username, revokedBy)
+	return sess, nil
+}
+
+// RevokeUser revokes every active session of a user except the one with ID
+// keep, which lets administrators sign a user out everywhere but here. It
+// returns the number of revoked sessions.
+func (m *Manager) RevokeUser(username, revokedBy, keep string) (int, error) {
+	sessions, err := m.List()
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, sess := range sessions {
+		if sess.Username != username || sess.ID == keep {
+			continue
+		}
+		if _, err := m.Revoke(sess.ID, revokedBy); err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrRevoked) {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// PurgeExpired removes all expired sessions from the store
+func (m *Manager) PurgeExpired() error {
+	sessions, err := m.store.List()
+	if err != nil {
+		return err
+	}
+
+	for _, sess := range sessions {
+		if sess.Expired() {
+			if err := m.purge(sess); err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// purge removes an expired session from the store
+func (m *Manager) purge(sess *Session) error {
+	defer m.lock(sess.ID)()
+	m.evict(sess)
+	return m.store.Delete(sess.ID)
+}
+
+// evict passes the credentials of a session that is ending, including those
+// of its cluster connections, to the evictor
+func (m *Manager) evict(sess *Session) {
+	if m.evictor == nil {
+		return
+	}
+	creds, err := m.decryptCredentials(sess)
+	if err != nil {
+		log.Printf("[DEBUG] Cannot release credentials of session for user '%s': %v", sess.Username, err)
+		return
+	}
+	m.evictCredentials(creds)
+	for _, conn := range sess.Connections {
+		m.evictConnection(sess, conn)
+	}
+}
+
+// evictConnection passes the credentials of a cluster connection to the evictor
+func (m *Manager) evictConnection(sess *Session, conn *Connection) {
+	if m.evictor == nil {
+		return
+	}
+	creds, err := m.decrypt(conn.EncryptedCredentials)
+	if err != nil {
+		log.Printf("[DEBUG] Cannot release cluster credentials of session for user '%s': %v", sess.Username, err)
+		return
+	}
+	m.evictCredentials(creds)
+}
+
+// evictCredentials passes replaced credentials to the evictor
+func (m *Manager) evictCredentials(creds Credentials) {
+	if m.evictor != nil {
+		m.evictor(creds)
+	}
+}
+
+// newID returns a random, URL-safe session identifier
+func newID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
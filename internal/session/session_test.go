@@ -0,0 +1,168 @@
+package session
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// pausingStore holds up the first Load of a session until released, so a
+// test can run another change while a read-modify-write is under way
+type pausingStore struct {
+	*MemoryStore
+	loads   atomic.Int32
+	paused  chan struct{}
+	release chan struct{}
+}
+
+func newPausingStore() *pausingStore {
+	return &pausingStore{
+		MemoryStore: NewMemoryStore(),
+		paused:      make(chan struct{}),
+		release:     make(chan struct{}),
+	}
+}
+
+// Load returns the session, holding up the first call until release is closed
+func (s *pausingStore) Load(id string) (*Session, error) {
+	sess, err := s.MemoryStore.Load(id)
+	if s.loads.Add(1) == 1 {
+		close(s.paused)
+		<-s.release
+	}
+	return sess, err
+}
+
+func newTestManager(t *testing.T, store Store) *Manager {
+	t.Helper()
+	cipher, err := NewCipher("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewManager(store, cipher, time.Hour, 0)
+}
+
+// newIdleSession saves a session last used long enough ago to be touched
+func newIdleSession(t *testing.T, store Store) *Session {
+	t.Helper()
+	now := time.Now()
+	sess := &Session{
+		ID:         "session-1",
+		Username:   "alice",
+		CreatedAt:  now.Add(-10 * time.Minute),
+		LastSeenAt: now.Add(-10 * time.Minute),
+		ExpiresAt:  now.Add(50 * time.Minute),
+	}
+	if err := store.Save(sess); err != nil {
+		t.Fatal(err)
+	}
+	return sess
+}
+
+func TestConcurrentChangesKeepRevocation(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(m *Manager, sess *Session) error
+	}{
+		{"touch", func(m *Manager, sess *Session) error { _, err := m.Touch(sess); return err }},
+		{"complete MFA", func(m *Manager, sess *Session) error { return m.CompleteMFA(sess) }},
+		{"connect", func(m *Manager, sess *Session) error {
+			return m.Connect(sess, "backup", &Connection{AccessKey: "alice"}, Credentials{Cluster: "backup", AccessKey: "alice", SecretKey: "secret"})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newPausingStore()
+			m := newTestManager(t, store)
+			sess := newIdleSession(t, store)
+
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				if err := tt.change(m, sess); err != nil {
+					t.Errorf("change: %v", err)
+				}
+			}()
+
+			// Revoke while the change has read the session but not saved it
+			<-store.paused
+			go func() {
+				defer wg.Done()
+				if _, err := m.Revoke("session-1", "admin"); err != nil {
+					t.Errorf("Revoke: %v", err)
+				}
+			}()
+			time.Sleep(20 * time.Millisecond)
+			close(store.release)
+			wg.Wait()
+
+			if _, err := m.Get("session-1"); !errors.Is(err, ErrRevoked) {
+				t.Fatalf("Get after revocation: got error %v, want %v", err, ErrRevoked)
+			}
+		})
+	}
+}
+
+func TestRevokedSessionCannotBeChanged(t *testing.T) {
+	store := NewMemoryStore()
+	m := newTestManager(t, store)
+	sess := newIdleSession(t, store)
+	if _, err := m.Revoke(sess.ID, "admin"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Touch(sess); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Touch: got error %v, want %v", err, ErrRevoked)
+	}
+	if err := m.CompleteMFA(sess); !errors.Is(err, ErrRevoked) {
+		t.Errorf("CompleteMFA: got error %v, want %v", err, ErrRevoked)
+	}
+	if _, err := m.Revoke(sess.ID, "admin"); !errors.Is(err, ErrRevoked) {
+		t.Errorf("second Revoke: got error %v, want %v", err, ErrRevoked)
+	}
+}
+
+func TestParallelTouchAndRevokeUser(t *testing.T) {
+	store := NewMemoryStore()
+	m := newTestManager(t, store)
+
+	var sessions []*Session
+	for i := 0; i < 20; i++ {
+		sess, err := m.Create(&Session{Username: "alice"}, Credentials{AccessKey: "alice", SecretKey: "secret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sess.LastSeenAt = sess.LastSeenAt.Add(-10 * time.Minute)
+		sessions = append(sessions, sess)
+	}
+
+	var wg sync.WaitGroup
+	for _, sess := range sessions {
+		wg.Add(1)
+		go func(sess *Session) {
+			defer wg.Done()
+			if _, err := m.Touch(sess); err != nil && !errors.Is(err, ErrRevoked) {
+				t.Errorf("Touch: %v", err)
+			}
+		}(sess)
+	}
+	if _, err := m.RevokeUser("alice", "admin", ""); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	if active, err := m.List(); err != nil || len(active) != 0 {
+		t.Fatalf("List() = %d sessions, %v; want none after RevokeUser", len(active), err)
+	}
+}
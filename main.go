@@ -1,17 +1,28 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
+	"minio-admin-panel/internal/apitoken"
+	"minio-admin-panel/internal/audit"
+	"minio-admin-panel/internal/bucketstats"
+	"minio-admin-panel/internal/cluster"
 	"minio-admin-panel/internal/config"
 	"minio-admin-panel/internal/handlers"
 	"minio-admin-panel/internal/i18n"
+	"minio-admin-panel/internal/loginlimit"
+	"minio-admin-panel/internal/mfa"
 	"minio-admin-panel/internal/middleware"
+	"minio-admin-panel/internal/oidc"
+	"minio-admin-panel/internal/rbac"
 	"minio-admin-panel/internal/services"
+	"minio-admin-panel/internal/session"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,6 +35,12 @@ var (
 	builtBy = "unknown"
 )
 
+// Root credentials of the in-memory MinIO backend, the same defaults as a fresh MinIO server
+const (
+	memoryRootUser     = "minioadmin"
+	memoryRootPassword = "minioadmin"
+)
+
 func main() {
 	// Print version information
 	log.Printf("MinIO Admin Panel %s (commit: %s, built: %s by %s)", version, commit, date, builtBy)
@@ -36,25 +53,174 @@ func main() {
 
 	// Load configuration
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+	if cfg.JWTSecret == config.DefaultJWTSecret {
+		log.Printf("Warning: Using the default JWT secret, this is only acceptable in development mode")
+	}
+
+	// Initialize JWT signing keys
+	jwtKeys, err := middleware.NewKeyRing(cfg.JWTSecret, cfg.JWTPreviousSecrets)
+	if err != nil {
+		log.Fatal("Failed to initialize JWT keys:", err)
+	}
+
+	// Initialize the MinIO clusters; the backend passes each call on to the
+	// cluster of the request
+	clusters, err := newClusterRegistry(cfg)
+	if err != nil {
+		log.Fatal("Failed to load clusters:", err)
+	}
+	minioService := clusters.Backend()
+	handlers.SetClusters(clusters)
+	handlers.SetMinIOTimeouts(minioService.Timeouts())
+
+	// Initialize session storage
+	sessions, err := newSessionManager(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize session store:", err)
+	}
 
-	// Initialize MinIO service
-	minioService := services.NewMinIOService(cfg)
+	// Release cached MinIO clients when a session ends or renews its credentials
+	sessions.SetEvictor(func(creds session.Credentials) {
+		minioService.EvictClients(creds.AccessKey)
+	})
+
+	// Renew LDAP STS credentials transparently before they expire
+	if cfg.LDAPEnabled {
+		log.Printf("LDAP login enabled (STS duration: %d minutes)", cfg.LDAPSTSDuration)
+		sessions.SetRefresher(func(creds session.Credentials) (session.Credentials, error) {
+			ctx := services.WithCluster(context.Background(), creds.Cluster)
+			stsCreds, err := minioService.AssumeRoleWithLDAPIdentity(ctx, creds.LDAPUsername, creds.LDAPPassword, minioService.LDAPSTSDuration())
+			if err != nil {
+				return creds, err
+			}
+			creds.AccessKey = stsCreds.AccessKey
+			creds.SecretKey = stsCreds.SecretKey
+			creds.SessionToken = stsCreds.SessionToken
+			creds.Expiration = stsCreds.Expiration
+			return creds, nil
+		})
+	}
+
+	// Initialize OpenID Connect single sign-on if configured
+	var oidcProvider *oidc.Provider
+	if cfg.OIDCEnabled() {
+		log.Printf("OpenID Connect login enabled with issuer %s", cfg.OIDCIssuer)
+		oidcProvider = oidc.NewProvider(oidc.Config{
+			Issuer:        cfg.OIDCIssuer,
+			ClientID:      cfg.OIDCClientID,
+			ClientSecret:  cfg.OIDCClientSecret,
+			RedirectURL:   cfg.OIDCRedirectURL,
+			Scopes:        cfg.OIDCScopes,
+			UsernameClaim: cfg.OIDCUsernameClaim,
+			RoleMapping: oidc.RoleMapping{
+				Claim:   cfg.OIDCRoleClaim,
+				Roles:   cfg.OIDCRoleMap,
+				Default: cfg.OIDCRoleARN,
+			},
+		})
+	}
+
+	// Load panel roles if configured
+	var roles *rbac.Registry
+	if cfg.RBACConfigPath != "" {
+		roles, err = rbac.Load(cfg.RBACConfigPath)
+		if err != nil {
+			log.Fatal("Failed to load panel roles:", err)
+		}
+		log.Printf("Panel roles enabled from %s", cfg.RBACConfigPath)
+	}
+
+	// Open the audit log
+	auditLog, err := audit.NewLogger(audit.Options{
+		Path:       cfg.AuditLogPath,
+		MaxSize:    int64(cfg.AuditMaxSizeMB) * 1024 * 1024,
+		MaxFiles:   cfg.AuditMaxFiles,
+		WebhookURL: cfg.AuditWebhookURL,
+	})
+	if err != nil {
+		log.Fatal("Failed to open audit log:", err)
+	}
+
+	// Throttle repeated failed logins
+	loginLimiter, err := loginlimit.New(loginlimit.Config{
+		MaxAttempts: cfg.LoginMaxAttempts,
+		BaseDelay:   time.Duration(cfg.LoginBackoffSeconds) * time.Second,
+		Lockout:     time.Duration(cfg.LoginLockoutMinutes) * time.Minute,
+		AllowList:   cfg.LoginAllowList,
+	})
+	if err != nil {
+		log.Fatal("Failed to initialize login limiter:", err)
+	}
+
+	// Measure bucket statistics in the background. With dedicated credentials
+	// every cluster is refreshed periodically; otherwise refreshes run on
+	// demand with the credentials of the user viewing the statistics.
+	bucketStats := bucketstats.New(minioService, bucketstats.Options{
+		Concurrency: cfg.StatsConcurrency,
+		Interval:    time.Duration(cfg.StatsRefreshInterval) * time.Minute,
+		Timeout:     time.Duration(cfg.StatsBucketTimeout) * time.Second,
+	})
+	if cfg.StatsAccessKey != "" {
+		var names []string
+		for _, c := range clusters.All() {
+			names = append(names, c.Name)
+		}
+		go bucketStats.Run(context.Background(), names, cfg.StatsAccessKey, cfg.StatsSecretKey)
+	}
+
+	// Enable personal API tokens if configured
+	var (
+		tokenStore      *apitoken.Store
+		apiTokenHandler *handlers.APITokenHandler
+	)
+	if cfg.APITokensEnabled {
+		tokenStore, err = newAPITokenStore(cfg)
+		if err != nil {
+			log.Fatal("Failed to initialize API token store:", err)
+		}
+		apiTokenHandler = handlers.NewAPITokenHandler(tokenStore, minioService, cfg.APITokenMaxLifetimeDays)
+		handlers.SetTemplateDefault("api_tokens_enabled", true)
+		log.Printf("Personal API tokens enabled (max lifetime: %d days)", cfg.APITokenMaxLifetimeDays)
+	}
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(minioService)
-	bucketHandler := handlers.NewBucketHandler(minioService)
-	userHandler := handlers.NewUserHandler(minioService)
+	authHandler := handlers.NewAuthHandler(minioService, sessions, jwtKeys, oidcProvider, roles, auditLog, loginLimiter)
+	bucketHandler := handlers.NewBucketHandler(minioService, bucketStats)
+	userHandler := handlers.NewUserHandler(minioService, tokenStore)
 	policyHandler := handlers.NewPolicyHandler(minioService)
 	groupHandler := handlers.NewGroupHandler(minioService)
 	serviceAccountHandler := handlers.NewServiceAccountHandler(minioService)
-	apiHandler := handlers.NewAPIHandler(minioService)
+	apiHandler := handlers.NewAPIHandler(minioService, bucketStats)
+	// Enable the TOTP second factor if configured
+	var mfaHandler *handlers.MFAHandler
+	if cfg.MFAEnabled {
+		mfaStore, err := newMFAStore(cfg)
+		if err != nil {
+			log.Fatal("Failed to initialize MFA store:", err)
+		}
+		enforce := mfa.Enforcement(cfg.MFARequiredRoles)
+		authHandler.SetMFA(mfaStore, enforce)
+		mfaHandler = handlers.NewMFAHandler(mfaStore, enforce, sessions, loginLimiter, auditLog, cfg.MFAIssuer)
+		handlers.SetTemplateDefault("mfa_enabled", true)
+		log.Printf("TOTP second factor enabled (required for roles: %v)", cfg.MFARequiredRoles)
+	}
+
 	settingsHandler := handlers.NewSettingsHandler(minioService, version, commit, date, builtBy)
+	auditHandler := handlers.NewAuditHandler(auditLog)
+	sessionHandler := handlers.NewSessionHandler(sessions, tokenStore)
+	clusterHandler := handlers.NewClusterHandler(clusters, minioService, sessions, loginLimiter)
 
 	// Setup Gin router
 	r := gin.Default()
 
-	// Add language middleware
-	r.Use(middleware.LanguageMiddleware())
+	// Take the client IP from X-Forwarded-For only when the request comes from
+	// a trusted proxy, so clients cannot pick the IP that is throttled and audited
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
 
 	// Load templates from main directory and partials subdirectory
 	funcMap := template.FuncMap{
@@ -73,7 +239,7 @@ func main() {
 	tmpl := template.New("").Funcs(funcMap)
 
 	// Parse templates from main directory
-	tmpl, err := tmpl.ParseGlob("web/templates/*.html")
+	tmpl, err = tmpl.ParseGlob("web/templates/*.html")
 	if err != nil {
 		log.Fatal("Failed to load main templates:", err)
 	}
@@ -85,10 +251,34 @@ func main() {
 	}
 
 	r.SetHTMLTemplate(tmpl)
-	r.Static("/static", "./web/static")
 
-	// Routes
-	setupRoutes(r, authHandler, bucketHandler, userHandler, policyHandler, groupHandler, serviceAccountHandler, apiHandler, settingsHandler)
+	// Middleware and routes
+	sameSite, _ := cfg.CookieSameSiteMode()
+	handlers.SetupRoutes(r, handlers.Routes{
+		Version:  version,
+		Cookies:  middleware.CookiePolicy{SameSite: sameSite, Secure: cfg.CookieSecure},
+		JWTKeys:  jwtKeys,
+		Sessions: sessions,
+		Clusters: clusters,
+		Roles:    roles,
+		Tokens:   tokenStore,
+		AuditLog: auditLog,
+
+		AuthHandler:           authHandler,
+		BucketHandler:         bucketHandler,
+		UserHandler:           userHandler,
+		PolicyHandler:         policyHandler,
+		GroupHandler:          groupHandler,
+		ServiceAccountHandler: serviceAccountHandler,
+		APIHandler:            apiHandler,
+		SettingsHandler:       settingsHandler,
+		AuditHandler:          auditHandler,
+		SessionHandler:        sessionHandler,
+		APITokenHandler:       apiTokenHandler,
+		MFAHandler:            mfaHandler,
+		ClusterHandler:        clusterHandler,
+	})
+	r.Static("/static", "./web/static")
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -99,161 +289,90 @@ func main() {
 	log.Fatal(http.ListenAndServe(":"+port, r))
 }
 
-func setupRoutes(r *gin.Engine, authHandler *handlers.AuthHandler, bucketHandler *handlers.BucketHandler, userHandler *handlers.UserHandler, policyHandler *handlers.PolicyHandler, groupHandler *handlers.GroupHandler, serviceAccountHandler *handlers.ServiceAccountHandler, apiHandler *handlers.APIHandler, settingsHandler *handlers.SettingsHandler) {
-	// Health check endpoint
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok", "version": version})
-	})
-
-	// Auth routes
-	r.GET("/", authHandler.LoginPage)
-	r.POST("/login", authHandler.Login)
-	r.POST("/logout", authHandler.Logout)
-
-	// Language switching endpoint
-	r.POST("/set-language", func(c *gin.Context) {
-		var langData struct {
-			Language string `json:"language" form:"language"`
-		}
-
-		if err := c.ShouldBind(&langData); err != nil {
-			c.JSON(400, gin.H{"error": "Invalid language parameter"})
-			return
-		}
-
-		// Validate the language
-		supportedLanguages := i18n.GetAvailableLanguages()
-		isValid := false
-		for _, lang := range supportedLanguages {
-			if langData.Language == lang {
-				isValid = true
-				break
-			}
-		}
-
-		if !isValid {
-			c.JSON(400, gin.H{"error": "Unsupported language"})
-			return
-		}
+// newClusterRegistry loads the configured clusters, or the single cluster of
+// MINIO_HOST if no cluster file is set
+func newClusterRegistry(cfg *config.Config) (*cluster.Registry, error) {
+	if cfg.MinIOBackend == "memory" {
+		log.Printf("Warning: Using the in-memory MinIO backend, log in as %s/%s; nothing is persisted", memoryRootUser, memoryRootPassword)
+	}
+	if cfg.ClustersConfigPath == "" {
+		return cluster.Single(cfg, newMinIOBackend), nil
+	}
 
-		// Set the language cookie
-		c.SetCookie("language", langData.Language, 86400*30, "/", "", false, false) // 30 days
+	clusters, err := cluster.Load(cfg.ClustersConfigPath, cfg, newMinIOBackend)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Multi-cluster mode enabled from %s", cfg.ClustersConfigPath)
+	return clusters, nil
+}
 
-		// If it's a JSON request, return JSON response
-		if c.GetHeader("Content-Type") == "application/json" || c.GetHeader("Accept") == "application/json" {
-			c.JSON(200, gin.H{"success": true, "language": langData.Language})
-			return
-		}
+// newMinIOBackend builds the configured MinIO backend of one cluster
+func newMinIOBackend(cfg *config.Config) services.Backend {
+	if cfg.MinIOBackend == "memory" {
+		return services.NewMemoryBackend(memoryRootUser, memoryRootPassword)
+	}
+	return services.NewMinIOService(cfg)
+}
 
-		// For form submissions, redirect back to the referring page
-		referer := c.GetHeader("Referer")
-		if referer == "" {
-			referer = "/"
-		}
-		c.Redirect(302, referer)
-	})
+// newSessionManager builds the session manager from the configured store backend
+func newSessionManager(cfg *config.Config) (*session.Manager, error) {
+	if cfg.SessionEncryptionKey == "" {
+		log.Printf("Warning: SESSION_ENCRYPTION_KEY is not set, using a random key (sessions will not survive a restart)")
+	}
 
-	// Protected routes
-	protected := r.Group("/")
-	protected.Use(middleware.AuthRequired())
-	{
-		// Dashboard - accessible to all authenticated users
-		protected.GET("/dashboard", func(c *gin.Context) {
-			permissions := middleware.GetUserPermissions(c)
-			username, _ := c.Get("username")
-			policyName, _ := c.Get("policy_name")
-
-			// Use the helper for consistent translation handling
-			handlers.RenderWithTranslations(c, "dashboard.html", gin.H{
-				"title":       "MinIO Admin Panel",
-				"username":    username,
-				"policy_name": policyName,
-				"permissions": permissions,
-			})
-		})
+	cipher, err := session.NewCipher(cfg.SessionEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
 
-		// Bucket management - require bucket list permission
-		bucketRoutes := protected.Group("/buckets")
-		bucketRoutes.Use(middleware.RequirePermission("canListBuckets"))
-		{
-			bucketRoutes.GET("", bucketHandler.ListBuckets)
-			bucketRoutes.POST("", middleware.RequirePermission("canCreateBuckets"), bucketHandler.CreateBucket)
-			bucketRoutes.DELETE("/:name", middleware.RequirePermission("canDeleteBuckets"), bucketHandler.DeleteBucket)
-			bucketRoutes.GET("/:name/policy", bucketHandler.GetBucketPolicy)
-			bucketRoutes.PUT("/:name/policy", middleware.RequirePermission("canManagePolicies"), bucketHandler.SetBucketPolicy)
+	var store session.Store
+	switch cfg.SessionStore {
+	case "memory":
+		store = session.NewMemoryStore()
+	case "file":
+		store, err = session.NewFileStore(cfg.SessionStorePath)
+		if err != nil {
+			return nil, err
 		}
+	default:
+		return nil, fmt.Errorf("unknown session store %q", cfg.SessionStore)
+	}
 
-		// User management - require admin permissions
-		userRoutes := protected.Group("/users")
-		userRoutes.Use(middleware.RequirePermission("canManageUsers"))
-		{
-			userRoutes.GET("", userHandler.ListUsers)
-			userRoutes.POST("", userHandler.CreateUser)
-			userRoutes.GET("/:name", userHandler.GetUser)
-			userRoutes.GET("/:name/details", userHandler.GetUserDetails)
-			userRoutes.GET("/:name/credentials", userHandler.GetUserCredentials)
-			userRoutes.DELETE("/:name", userHandler.DeleteUser)
-			userRoutes.PUT("/:name/credentials", userHandler.UpdateUserCredentials)
-			userRoutes.PUT("/:name/status", userHandler.SetUserStatus)
-			userRoutes.GET("/:name/policy", userHandler.GetUserPolicy)
-			userRoutes.PUT("/:name/policy", userHandler.SetUserPolicy)
-			userRoutes.PUT("/:name/groups", groupHandler.SetUserGroups)
-		}
+	log.Printf("Using %s session store", cfg.SessionStore)
+	manager := session.NewManager(store, cipher,
+		time.Duration(cfg.SessionTimeout)*time.Minute,
+		time.Duration(cfg.SessionMaxLifetime)*time.Minute)
+	if err := manager.PurgeExpired(); err != nil {
+		log.Printf("Warning: Failed to purge expired sessions: %v", err)
+	}
+	return manager, nil
+}
 
-		// Group management - require admin permissions
-		groupRoutes := protected.Group("/groups")
-		groupRoutes.Use(middleware.RequirePermission("canManageUsers"))
-		{
-			groupRoutes.GET("", groupHandler.ListGroups)
-			groupRoutes.POST("", groupHandler.CreateGroup)
-			groupRoutes.GET("/:name", groupHandler.GetGroupInfo)
-			groupRoutes.DELETE("/:name", groupHandler.DeleteGroup)
-			groupRoutes.PUT("/:name/members", groupHandler.UpdateGroupMembers)
-			groupRoutes.PUT("/:name/policy", groupHandler.SetGroupPolicy)
-		}
+// newMFAStore opens the encrypted TOTP enrollment store
+func newMFAStore(cfg *config.Config) (*mfa.Store, error) {
+	if cfg.MFAEncryptionKey == "" {
+		log.Printf("Warning: MFA_ENCRYPTION_KEY is not set, using a random key (TOTP enrollments will not survive a restart)")
+	}
 
-		// Service Account management - require admin permissions
-		serviceAccountRoutes := protected.Group("/service-accounts")
-		serviceAccountRoutes.Use(middleware.RequirePermission("canManageUsers"))
-		{
-			serviceAccountRoutes.GET("", serviceAccountHandler.ListServiceAccounts)
-			serviceAccountRoutes.POST("", serviceAccountHandler.CreateServiceAccount)
-			serviceAccountRoutes.GET("/:accessKey", serviceAccountHandler.GetServiceAccountInfo)
-			serviceAccountRoutes.DELETE("/:accessKey", serviceAccountHandler.DeleteServiceAccount)
-		}
+	cipher, err := session.NewCipher(cfg.MFAEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return mfa.NewStore(cfg.MFAStorePath, cipher)
+}
 
-		// Policy management - require admin permissions
-		policyRoutes := protected.Group("/policies")
-		policyRoutes.Use(middleware.RequirePermission("canManageUsers"))
-		{
-			policyRoutes.GET("", policyHandler.ListPolicies)
-			policyRoutes.GET("/:name", policyHandler.GetPolicyDocument)
-			policyRoutes.POST("/:name", policyHandler.CreateOrUpdatePolicy)
-			policyRoutes.PUT("/:name", policyHandler.CreateOrUpdatePolicy)
-			policyRoutes.DELETE("/:name", policyHandler.DeletePolicy)
-		}
+// newAPITokenStore opens the encrypted personal API token store
+func newAPITokenStore(cfg *config.Config) (*apitoken.Store, error) {
+	if cfg.APITokenEncryptionKey == "" {
+		log.Printf("Warning: API_TOKEN_ENCRYPTION_KEY is not set, using a random key (API tokens will not survive a restart)")
+	}
 
-		// Settings - require admin permissions
-		protected.GET("/settings", middleware.RequirePermission("isAdmin"), settingsHandler.ShowSettings)
-
-		// API routes for AJAX
-		api := protected.Group("/api")
-		{
-			api.GET("/server-info", apiHandler.GetServerInfo)
-			api.GET("/metrics", apiHandler.GetMetrics)
-			api.GET("/storage-usage", apiHandler.GetStorageUsage)
-			api.GET("/policies", userHandler.ListPolicies)
-			api.GET("/groups", func(c *gin.Context) {
-				// Forward to group handler with JSON accept header
-				c.Request.Header.Set("Accept", "application/json")
-				groupHandler.ListGroups(c)
-			})
-			api.GET("/service-accounts", serviceAccountHandler.ListServiceAccounts)
-			api.POST("/service-accounts", serviceAccountHandler.CreateServiceAccount)
-			api.GET("/service-accounts/:accessKey", serviceAccountHandler.GetServiceAccountInfo)
-			api.DELETE("/service-accounts/:accessKey", serviceAccountHandler.DeleteServiceAccount)
-		}
+	cipher, err := session.NewCipher(cfg.APITokenEncryptionKey)
+	if err != nil {
+		return nil, err
 	}
+	return apitoken.NewStore(cfg.APITokenStorePath, cipher)
 }
 
 // formatBytes converts bytes to human readable format
@@ -131,6 +131,183 @@ const API = {
     }
 };
 
+// CSRF protection: state-changing requests must echo the token the server
+// rendered into the csrf-token meta tag
+const CSRF = {
+    header: 'X-CSRF-Token',
+    field: 'csrf_token',
+
+    // Current page token
+    token() {
+        const meta = document.querySelector('meta[name="csrf-token"]');
+        return meta ? meta.content : '';
+    },
+
+    // Whether a request to url with method needs the token
+    required(method, url) {
+        const safe = ['GET', 'HEAD', 'OPTIONS'].includes((method || 'GET').toUpperCase());
+        return !safe && new URL(url, window.location.href).origin === window.location.origin;
+    },
+
+    // Add a hidden token field to a form that does not carry one
+    addToForm(form) {
+        if (!this.required(form.method, form.action)) {
+            return;
+        }
+        let input = form.querySelector(`input[name="${this.field}"]`);
+        if (!input) {
+            input = document.createElement('input');
+            input.type = 'hidden';
+            input.name = this.field;
+            form.appendChild(input);
+        }
+        if (!input.value) {
+            input.value = this.token();
+        }
+    }
+};
+
+// Session expiry: activity on the page keeps the session alive, and when it
+// does expire, unsaved form input is kept until the user has signed in again
+const Session = {
+    refreshInterval: 60 * 1000,
+    draftPrefix: 'draft:',
+    lastRefresh: Date.now(),
+    lastActivity: 0,
+    timer: null,
+    expired: false,
+
+    // Start tracking activity on pages that belong to a signed-in session
+    init() {
+        if (!document.querySelector('.sidebar')) {
+            return;
+        }
+        ['keydown', 'input', 'click', 'scroll'].forEach(type => {
+            document.addEventListener(type, () => this.activity(), { passive: true, capture: true });
+        });
+        this.check();
+    },
+
+    // Record user activity and extend the session at most once a minute
+    activity() {
+        this.lastActivity = Date.now();
+        if (!this.expired && this.lastActivity - this.lastRefresh > this.refreshInterval) {
+            this.refresh();
+        }
+    },
+
+    async refresh() {
+        this.lastRefresh = Date.now();
+        const response = await fetch('/api/session/refresh', { method: 'POST' });
+        if (response.ok) {
+            this.schedule(await response.json());
+        }
+    },
+
+    // Ask the server when the session expires without extending it
+    async check() {
+        const response = await fetch('/api/session');
+        if (response.ok) {
+            this.schedule(await response.json());
+        }
+    },
+
+    // Check again shortly after the session is due to expire
+    schedule(status) {
+        clearTimeout(this.timer);
+        const delay = new Date(status.expires_at).getTime() - Date.now() + 5000;
+        this.timer = setTimeout(() => this.check(), Math.max(delay, 5000));
+    },
+
+    // Keep the page's form input and continue on the login page
+    handleExpired(data) {
+        if (this.expired) {
+            return;
+        }
+        this.expired = true;
+        this.saveDraft(data.notice);
+        window.location.href = data.redirect || '/';
+    },
+
+    // Form fields worth restoring; secrets and tokens are never stored
+    fields() {
+        return Array.from(document.querySelectorAll('input, select, textarea')).filter(field => {
+            const key = field.id || field.name;
+            return key && !['password', 'hidden', 'file', 'submit', 'button'].includes(field.type);
+        });
+    },
+
+    saveDraft(notice) {
+        const values = {};
+        this.fields().forEach(field => {
+            const value = ['checkbox', 'radio'].includes(field.type) ? field.checked : field.value;
+            values[field.id || field.name] = value;
+        });
+        try {
+            sessionStorage.setItem(this.draftPrefix + window.location.pathname, JSON.stringify({ notice, values }));
+        } catch (err) {
+            console.error('Failed to save form draft: ', err);
+        }
+    },
+
+    restoreDraft() {
+        const key = this.draftPrefix + window.location.pathname;
+        const saved = sessionStorage.getItem(key);
+        if (!saved) {
+            return;
+        }
+        sessionStorage.removeItem(key);
+
+        const draft = JSON.parse(saved);
+        let restored = 0;
+        this.fields().forEach(field => {
+            const name = field.id || field.name;
+            if (!(name in draft.values)) {
+                return;
+            }
+            if (['checkbox', 'radio'].includes(field.type)) {
+                field.checked = draft.values[name];
+            } else {
+                field.value = draft.values[name];
+            }
+            restored++;
+        });
+        if (restored > 0 && draft.notice) {
+            Utils.showToast(draft.notice, 'info');
+        }
+    }
+};
+
+// Send the CSRF token with every same-origin state-changing fetch, mark
+// same-origin requests as AJAX and handle expired sessions
+const nativeFetch = window.fetch.bind(window);
+window.fetch = async function (input, init = {}) {
+    const request = input instanceof Request ? input : null;
+    const method = init.method || (request ? request.method : 'GET');
+    const url = request ? request.url : String(input);
+    const sameOrigin = new URL(url, window.location.href).origin === window.location.origin;
+
+    if (sameOrigin) {
+        const headers = new Headers(init.headers || (request ? request.headers : undefined));
+        headers.set('X-Requested-With', 'XMLHttpRequest');
+        if (CSRF.required(method, url)) {
+            headers.set(CSRF.header, CSRF.token());
+        }
+        init = { ...init, headers };
+    }
+
+    const response = await nativeFetch(input, init);
+    if (sameOrigin && response.status === 401 && response.headers.get('X-Session-Expired')) {
+        Session.handleExpired(await response.clone().json().catch(() => ({})));
+    }
+    return response;
+};
+
+// Make sure every submitted form carries the CSRF token
+document.addEventListener('submit', function (e) {
+    CSRF.addToForm(e.target);
+}, true);
+
 // Form validation helpers
 const Validation = {
     // Validate bucket name
@@ -180,6 +357,10 @@ const Validation = {
 
 // Initialize common functionality when DOM is loaded
 document.addEventListener('DOMContentLoaded', function () {
+    // Bring back form input saved when the session expired
+    Session.restoreDraft();
+    Session.init();
+
     // Add click handlers for copy buttons
     document.querySelectorAll('.copy-btn').forEach(btn => {
         btn.addEventListener('click', function () {
@@ -218,3 +399,5 @@ function toggleSidebar() {
 window.Utils = Utils;
 window.API = API;
 window.Validation = Validation;
+window.CSRF = CSRF;
+window.Session = Session;